@@ -0,0 +1,258 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package audit provides the audit command for the application.
+package audit
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/audit"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/claim"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/storageclass"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/version"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
+)
+
+const (
+	auditUse   = `audit`
+	auditShort = "Audit the usage of MapR tickets and report findings"
+	auditLong  = `
+		Audit the usage of MapR tickets by persistent volumes, claims and StorageClasses and
+		report findings with a severity of info, warning or critical.
+
+		The following checks are run:
+		  - ExpiredTicketInUse (critical): expired tickets still in use by bound persistent volumes
+		  - TicketExpiringSoon (warning): tickets expiring within the --expires-within threshold
+		  - MissingSecret (critical): persistent volumes referencing secrets that do not exist
+		  - UnusedSecret (info): ticket secrets not used by any volume or StorageClass
+		  - RootTicket (warning): tickets issued for the root user with UID 0
+		  - ClusterMismatch (critical): tickets issued for a different cluster than the
+		    StorageClass or persistent volume uses
+		  - DuplicateTicket (info): the same ticket stored in secrets in multiple namespaces
+
+		Besides a table, the findings can be printed as JSON, JUnit XML or SARIF. Use
+		--fail-on to exit with a non-zero exit code if there are findings of at least the
+		given severity, e.g. to use the audit as a gate in CI pipelines.
+		`
+	auditExample = `
+		# Audit the MapR tickets in the current namespace
+		%[1]s audit
+
+		# Audit the MapR tickets in all namespaces and report tickets expiring within 30 days
+		%[1]s audit --all-namespaces --expires-within 30d
+
+		# Audit the MapR tickets in all namespaces and fail if there are critical findings
+		%[1]s audit --all-namespaces --fail-on critical
+
+		# Audit the MapR tickets in all namespaces and write a JUnit XML report
+		%[1]s audit --all-namespaces -o junit > mapr-ticket-audit.xml
+
+		# Audit the MapR tickets in all namespaces and write a SARIF log
+		%[1]s audit --all-namespaces -o sarif > mapr-ticket-audit.sarif
+		`
+)
+
+const (
+	// failOnNone disables failing on findings
+	failOnNone = "none"
+)
+
+var (
+	// valid output formats for the command
+	auditValidOutputFormats = []string{"table", "json", "junit", "sarif"}
+
+	// valid values of the --fail-on flag
+	auditValidFailOn = []string{failOnNone, string(audit.SeverityInfo), string(audit.SeverityWarning), string(audit.SeverityCritical)}
+)
+
+type options struct {
+	*common.Options
+
+	// Args are the arguments passed to the command
+	args []string
+
+	// OutputFormat is the format to use for output
+	OutputFormat string
+
+	// AllNamespaces indicates whether to audit the tickets in all namespaces
+	AllNamespaces bool
+
+	// ExpiresWithin is the threshold for tickets to be reported as expiring soon
+	ExpiresWithin common.DurationValue
+
+	// FailOn is the lowest severity of findings that result in a non-zero exit code
+	FailOn string
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:       opts,
+		ExpiresWithin: common.DurationValue(audit.DefaultExpiresWithin),
+	}
+}
+
+// NewCmd creates a new audit command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          auditUse,
+		Short:        auditShort,
+		Long:         common.CliLongDesc(auditLong),
+		Example:      common.CliExample(auditExample, common.CliBinName),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "table", fmt.Sprintf("Output format. One of (%s)", common.StringSliceToFlagOptions(auditValidOutputFormats)))
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "Audit the MapR tickets in all namespaces")
+	cmd.Flags().Var(&o.ExpiresWithin, "expires-within", "Report tickets that expire within the specified duration from now")
+	cmd.Flags().StringVar(&o.FailOn, "fail-on", failOnNone, fmt.Sprintf("Exit with a non-zero exit code if there are findings of at least the specified severity. One of (%s)", common.StringSliceToFlagOptions(auditValidFailOn)))
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	// parse the arguments
+	o.args = args
+
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	// validate output format
+	if !slices.Contains(auditValidOutputFormats, o.OutputFormat) {
+		return fmt.Errorf("invalid output format %q. Must be one of (%s)", o.OutputFormat, common.StringSliceToFlagOptions(auditValidOutputFormats))
+	}
+
+	// validate severity to fail on
+	if !slices.Contains(auditValidFailOn, o.FailOn) {
+		return fmt.Errorf("invalid severity %q for --fail-on. Must be one of (%s)", o.FailOn, common.StringSliceToFlagOptions(auditValidFailOn))
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	namespace := *o.KubernetesConfigFlags.Namespace
+
+	// the volume, claim and StorageClass listers only need the tickets, not their usage
+	ticketLister := secret.NewLister(client, namespace, o.SecretListerOptions()...)
+
+	secretOpts := append(o.SecretListerOptions(), secret.WithShowInUse())
+	secretOpts = append(secretOpts, common.InUseListerOptions(client)...)
+
+	auditor := audit.NewAuditor(
+		client,
+		audit.WithExpiresWithin(o.ExpiresWithin.Duration()),
+		audit.WithSecretLister(secret.NewLister(client, namespace, secretOpts...)),
+		audit.WithVolumeLister(volume.NewLister(client, util.SecretAll, namespace, volume.WithSecretLister(ticketLister))),
+		audit.WithClaimLister(claim.NewLister(client, namespace, claim.WithSecretLister(ticketLister))),
+		audit.WithStorageClassLister(storageclass.NewLister(client, storageclass.WithSecretLister(ticketLister))),
+	)
+
+	findings, err := auditor.Audit()
+	if err != nil {
+		return err
+	}
+
+	if err := o.print(cmd, findings); err != nil {
+		return err
+	}
+
+	if o.FailOn == failOnNone {
+		return nil
+	}
+
+	if count := audit.CountAtLeast(findings, audit.Severity(o.FailOn)); count > 0 {
+		return fmt.Errorf("found %d finding(s) with severity %s or higher", count, o.FailOn)
+	}
+
+	return nil
+}
+
+// print prints the findings in the configured output format
+func (o *options) print(cmd *cobra.Command, findings []audit.Finding) error {
+	switch o.OutputFormat {
+	case "table":
+		if len(findings) == 0 {
+			fmt.Fprintln(o.IOStreams.ErrOut, "No findings.")
+			return nil
+		}
+
+		return audit.Print(cmd, findings)
+	case "json":
+		return audit.PrintJSON(cmd.OutOrStdout(), findings)
+	case "junit":
+		return audit.PrintJUnit(cmd.OutOrStdout(), findings)
+	case "sarif":
+		return audit.PrintSARIF(cmd.OutOrStdout(), findings, version.NewVersion().String())
+	default:
+		return fmt.Errorf("invalid output format %q. Must be one of (%s)", o.OutputFormat, common.StringSliceToFlagOptions(auditValidOutputFormats))
+	}
+}
+
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringValues(auditValidOutputFormats, toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = cmd.RegisterFlagCompletionFunc("fail-on", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringValues(auditValidFailOn, toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
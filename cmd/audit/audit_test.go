@@ -0,0 +1,6 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package audit_test
@@ -0,0 +1,221 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package check provides the check command for the application.
+package check
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/policy"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+)
+
+// command string constants for use in help and usage text
+const (
+	checkUse   = `check`
+	checkShort = "Check MapR tickets against a compliance policy"
+	checkLong  = `
+		Check the MapR tickets deployed as secrets against the rules of a declarative YAML
+		policy and exit with a non-zero exit code if any ticket violates a rule.
+
+		Each rule has a name and one or more constraints, and can be limited to tickets of
+		certain clusters, users or namespaces using match:
+
+		  rules:
+		    - name: no-root-tickets
+		      deniedUIDs: [0]
+		    - name: max-ticket-span
+		      maxLifetime: 90d
+		    - name: no-tickets-without-expiry
+		      requireExpiry: true
+		    - name: cluster-x-only-in-team-x
+		      match:
+		        clusters: [x.mapr.com]
+		      namespaceSelector:
+		        matchLabels:
+		          team: x
+
+		The following constraints are supported: allowedUIDs, deniedUIDs, deniedGIDs,
+		allowedUsers, deniedUsers, allowedClusters, requireExpiry, maxLifetime (between
+		creation and expiration), maxAge (since creation), minValidity (until expiration) and
+		namespaceSelector (labels of the namespace the secret is stored in). Tickets that
+		can't be parsed are always reported as violating the invalid-ticket rule.
+		`
+	checkExample = `
+		# Check the MapR tickets in the current namespace against a policy
+		%[1]s check --policy policy.yaml
+
+		# Check the MapR tickets in all namespaces and print the violations as JSON
+		%[1]s check --all-namespaces --policy policy.yaml -o json
+		`
+)
+
+var (
+	// valid output formats for the command
+	checkValidOutputFormats = []string{"table", "json"}
+)
+
+type options struct {
+	*common.Options
+
+	// PolicyFile is the path of the policy to check the tickets against
+	PolicyFile string
+
+	// OutputFormat is the format to use for output
+	OutputFormat string
+
+	// AllNamespaces indicates whether to check the tickets in all namespaces
+	AllNamespaces bool
+
+	// policy is the loaded policy
+	policy *policy.Policy
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options: opts,
+	}
+}
+
+// NewCmd creates a new check command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          checkUse,
+		Short:        checkShort,
+		Long:         common.CliLongDesc(checkLong),
+		Example:      common.CliExample(checkExample, common.CliBinName),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	cmd.Flags().StringVar(&o.PolicyFile, "policy", "", "Path of the YAML policy to check the tickets against")
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "table", fmt.Sprintf("Output format. One of (%s)", common.StringSliceToFlagOptions(checkValidOutputFormats)))
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If true, check the tickets across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+
+	if err := cmd.MarkFlagRequired("policy"); err != nil {
+		panic(err)
+	}
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	var err error
+
+	// validate output format
+	if !slices.Contains(checkValidOutputFormats, o.OutputFormat) {
+		return fmt.Errorf("invalid output format %q. Must be one of (%s)", o.OutputFormat, common.StringSliceToFlagOptions(checkValidOutputFormats))
+	}
+
+	// load the policy early, so invalid policies are reported before contacting the cluster
+	if o.policy, err = policy.Load(o.PolicyFile); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	lister := secret.NewLister(client, *o.KubernetesConfigFlags.Namespace, o.SecretListerOptions()...)
+
+	secrets, err := lister.List()
+	if err != nil {
+		return err
+	}
+
+	violations, err := policy.NewChecker(client, o.policy).Check(secrets)
+	if err != nil {
+		return err
+	}
+
+	switch o.OutputFormat {
+	case "table":
+		if len(violations) == 0 {
+			fmt.Fprintln(o.IOStreams.ErrOut, "No policy violations found.")
+			return nil
+		}
+
+		if err := policy.Print(cmd, violations); err != nil {
+			return err
+		}
+	case "json":
+		if err := policy.PrintJSON(cmd.OutOrStdout(), violations); err != nil {
+			return err
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("found %d policy violation(s)", len(violations))
+	}
+
+	return nil
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringValues(checkValidOutputFormats, toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = cmd.MarkFlagFilename("policy", "yaml", "yml")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
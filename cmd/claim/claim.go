@@ -10,12 +10,15 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/exp/slices"
 
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/claim"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
 
 // command string constants for use in help and usage text
@@ -35,20 +38,45 @@ const (
 		%[1]s claim --all-namespaces
 
 		# List all persistent volumes claims in all namespaces that use a MapR ticket, sorted by expiration date
-		%[1]s claim --all-namespaces --sort-by expiryTime
-		`
-)
+		%[1]s claim --all-namespaces --sort-by expiration
+
+		# List all persistent volumes claims in all namespaces that use a MapR ticket, soonest to expire last
+		%[1]s claim --all-namespaces --sort-by -expiration
+
+		# List all persistent volumes claims labelled with app=spark that use a MapR ticket
+		%[1]s claim -l app=spark
+
+		# List all persistent volumes claims in the current namespace that use a MapR ticket as a JSON list
+		%[1]s claim --output json
+
+		# Print the names of all persistent volume claims with expired MapR tickets using a Go template
+		%[1]s claim -o go-template='{{range .items}}{{if .ticket.expired}}{{.claim.name}}{{"\n"}}{{end}}{{end}}'
+
+		# List all persistent volumes claims in all namespaces that use a MapR ticket of the user mapr
+		%[1]s claim --all-namespaces --filter "ticket.user == 'mapr'"
+
+		# List all persistent volumes claims in all namespaces of all kubeconfig contexts that use a MapR ticket
+		%[1]s claim --all-namespaces --all-contexts
 
-var (
-	// valid output formats for the command
-	claimValidOutputFormats = []string{"table", "wide"}
+		# List all persistent volumes claims in the current namespace of the contexts "prod" and "staging"
+		%[1]s claim --context prod,staging
+		`
 )
 
 type options struct {
 	*common.Options
 
-	// OutputFormat is the format to use for output
-	OutputFormat string
+	// PrintFlags are the flags used to configure the output format
+	PrintFlags *common.PrintFlags
+
+	// SelectorOptions are the label and field selectors used to select the persistent volume claims to list
+	SelectorOptions *common.SelectorOptions
+
+	// FilterOptions are the options used to filter the listed persistent volume claims using a CEL expression
+	FilterOptions *common.FilterOptions
+
+	// ContextOptions are the options used to list persistent volume claims across multiple kubeconfig contexts
+	ContextOptions *common.ContextOptions
 
 	// AllNamespaces indicates whether to list secrets in all namespaces
 	AllNamespaces bool
@@ -59,7 +87,11 @@ type options struct {
 
 func newOptions(opts *common.Options) *options {
 	return &options{
-		Options: opts,
+		Options:         opts,
+		PrintFlags:      common.NewPrintFlags(),
+		SelectorOptions: common.NewSelectorOptions(),
+		FilterOptions:   common.NewFilterOptions(filter.ClaimVariables),
+		ContextOptions:  common.NewContextOptions(),
 	}
 }
 
@@ -99,9 +131,12 @@ func NewCmd(opts *common.Options) *cobra.Command {
 	cmd.SetErr(o.IOStreams.ErrOut)
 
 	// add flags
-	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "table", fmt.Sprintf("Output format. One of (%s)", common.StringSliceToFlagOptions(claimValidOutputFormats)))
+	o.PrintFlags.AddFlags(cmd)
+	o.SelectorOptions.AddFlags(cmd, "persistent volume claims")
+	o.FilterOptions.AddFlags(cmd, "persistent volume claims")
+	o.ContextOptions.AddFlags(cmd)
 	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "List persistent volumes claims that use a MapR ticket in all namespaces")
-	cmd.Flags().StringSliceVar(&o.SortBy, "sort-by", []string{}, fmt.Sprintf("Sort list of persistent volumes claims by the specified fields. One or more of (%s)", common.StringSliceToFlagOptions(claim.SortOptionsList)))
+	cmd.Flags().StringSliceVar(&o.SortBy, "sort-by", []string{}, fmt.Sprintf("Sort list of persistent volumes claims by the specified fields, prefix a field with '-' or suffix it with ':desc' to sort in descending order. One or more of (%s)", common.StringSliceToFlagOptions(claim.SortOptionsList)))
 
 	// register completions for flags
 	if err := o.registerCompletions(cmd); err != nil {
@@ -113,6 +148,16 @@ func NewCmd(opts *common.Options) *cobra.Command {
 
 // Complete sets any default values for the command flags not handled automatically
 func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	// determine the contexts to list persistent volume claims in
+	if err := o.ContextOptions.Complete(o.Options); err != nil {
+		return err
+	}
+
+	// when listing across multiple contexts, the namespace is determined per context
+	if o.ContextOptions.IsMultiContext() {
+		return nil
+	}
+
 	// set namespace based on flags
 	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
 	o.KubernetesConfigFlags.Namespace = &ns
@@ -123,12 +168,22 @@ func (o *options) Complete(cmd *cobra.Command, args []string) error {
 // Validate ensures that all required arguments and flag values are provided
 func (o *options) Validate() error {
 	// validate output format
-	if !slices.Contains(claimValidOutputFormats, o.OutputFormat) {
-		return fmt.Errorf("invalid output format %q. Must be one of (%s)", o.OutputFormat, common.StringSliceToFlagOptions(claimValidOutputFormats))
+	if err := o.PrintFlags.Validate(); err != nil {
+		return err
+	}
+
+	// validate label and field selectors
+	if err := o.SelectorOptions.Validate(); err != nil {
+		return err
+	}
+
+	// validate filter expression
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
 	}
 
 	// ensure that the sort options are valid
-	if err := util.ValidateSortOptions(claim.SortOptionsList, o.SortBy); err != nil {
+	if err := util.ValidateDirectionalSortOptions(claim.SortOptionsList, o.SortBy); err != nil {
 		return err
 	}
 
@@ -137,15 +192,46 @@ func (o *options) Validate() error {
 
 // Run executes the command logic
 func (o *options) Run(cmd *cobra.Command, args []string) error {
-	client, err := util.ClientFromFlags(o.KubernetesConfigFlags)
+	var (
+		volumeClaims []types.MaprVolumeClaim
+		err          error
+	)
+
+	if o.ContextOptions.IsMultiContext() {
+		volumeClaims, err = o.listContexts(cmd)
+	} else {
+		volumeClaims, err = o.list(cmd, o.KubernetesConfigFlags, *o.KubernetesConfigFlags.Namespace)
+	}
+
 	if err != nil {
 		return err
 	}
 
+	// print output, either as a table or using one of the structured printers
+	if o.PrintFlags.IsTableFormat() {
+		return claim.Print(cmd, volumeClaims)
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	return claim.PrintObjects(cmd, printer, volumeClaims)
+}
+
+// list lists the persistent volume claims in the given namespace of the cluster the flags point to
+func (o *options) list(cmd *cobra.Command, flags *genericclioptions.ConfigFlags, namespace string) ([]types.MaprVolumeClaim, error) {
+	client, err := o.ClientFromFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+
 	// create secret lister
 	secretLister := secret.NewLister(
 		client,
 		util.NamespaceAll,
+		o.SecretListerOptions()...,
 	)
 
 	// create list options and pass them to the lister
@@ -153,41 +239,80 @@ func (o *options) Run(cmd *cobra.Command, args []string) error {
 		claim.WithSecretLister(secretLister),
 	}
 
+	// only list the persistent volume claims matching the selectors
+	if o.SelectorOptions.LabelSelector != "" {
+		opts = append(opts, claim.WithLabelSelector(o.SelectorOptions.LabelSelector))
+	}
+
+	if o.SelectorOptions.FieldSelector != "" {
+		opts = append(opts, claim.WithFieldSelector(o.SelectorOptions.FieldSelector))
+	}
+
+	// only keep the persistent volume claims matching the filter expression
+	if o.FilterOptions.Expression != nil {
+		opts = append(opts, claim.WithFilterByExpression(o.FilterOptions.Expression))
+	}
+
 	// set sort options
 	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
-		sortOptions := make([]claim.SortOption, 0, len(o.SortBy))
-		for _, sortBy := range o.SortBy {
-			sortOptions = append(sortOptions, claim.SortOption(sortBy))
-		}
-
-		opts = append(opts, claim.WithSortBy(sortOptions))
+		opts = append(opts, claim.WithSortBy(o.sortOptions()))
 	}
 
 	// create lister
 	lister := claim.NewLister(
 		client,
-		*o.KubernetesConfigFlags.Namespace,
+		namespace,
 		opts...,
 	)
 
-	// run lister
-	volumeClaims, err := lister.List()
+	return lister.List()
+}
+
+// listContexts lists the persistent volume claims in all requested contexts concurrently. Errors
+// of single contexts are printed instead of aborting the whole run.
+func (o *options) listContexts(cmd *cobra.Command) ([]types.MaprVolumeClaim, error) {
+	results := util.ForEachContext(o.ContextOptions.Contexts, func(name string) ([]types.MaprVolumeClaim, error) {
+		flags := util.ConfigFlagsForContext(o.KubernetesConfigFlags, name)
+
+		volumeClaims, err := o.list(cmd, flags, util.GetNamespace(flags, o.AllNamespaces))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range volumeClaims {
+			volumeClaims[i].Context = name
+		}
+
+		return volumeClaims, nil
+	})
+
+	volumeClaims, err := common.MergeContextResults(o.IOStreams.ErrOut, results)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// print output
-	if err := claim.Print(cmd, volumeClaims); err != nil {
-		return err
+	// the results of each context are already sorted, so only sort across contexts if requested
+	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
+		claim.SortItems(volumeClaims, o.sortOptions())
 	}
 
-	return nil
+	return volumeClaims, nil
+}
+
+// sortOptions converts the sort flag values to claim sort options
+func (o *options) sortOptions() []claim.SortOption {
+	sortOptions := make([]claim.SortOption, 0, len(o.SortBy))
+	for _, sortBy := range o.SortBy {
+		sortOptions = append(sortOptions, claim.SortOption(sortBy))
+	}
+
+	return sortOptions
 }
 
 // registerCompletions registers completions for the command flags
 func (o *options) registerCompletions(cmd *cobra.Command) error {
 	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return common.CompleteStringValues(claimValidOutputFormats, toComplete)
+		return o.PrintFlags.CompleteOutputFormats(toComplete)
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,251 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	// value printed for cells where the JSONPath expression did not match anything
+	customColumnsNone = "<none>"
+)
+
+var (
+	// customColumnsFormats are the output formats supported by the custom columns printer
+	customColumnsFormats = []string{"custom-columns", "custom-columns-file"}
+)
+
+// CustomColumnsPrintFlags provides the custom-columns and custom-columns-file output formats known
+// from kubectl. It is not part of k8s.io/cli-runtime, so we provide our own implementation.
+type CustomColumnsPrintFlags struct{}
+
+// NewCustomColumnsPrintFlags returns a new CustomColumnsPrintFlags struct
+func NewCustomColumnsPrintFlags() *CustomColumnsPrintFlags {
+	return &CustomColumnsPrintFlags{}
+}
+
+// AllowedFormats returns the output formats supported by the custom columns printer
+func (f *CustomColumnsPrintFlags) AllowedFormats() []string {
+	return customColumnsFormats
+}
+
+// ToPrinter returns a custom columns printer for the given output format, which is expected to
+// be in the form "custom-columns=HEADER:JSONPATH,..." or "custom-columns-file=FILE".
+func (f *CustomColumnsPrintFlags) ToPrinter(outputFormat string) (printers.ResourcePrinter, error) {
+	format, spec, _ := strings.Cut(outputFormat, "=")
+
+	switch format {
+	case "custom-columns":
+		if spec == "" {
+			return nil, fmt.Errorf("custom-columns format specified but no custom columns given")
+		}
+
+		return NewCustomColumnsPrinterFromSpec(spec)
+	case "custom-columns-file":
+		if spec == "" {
+			return nil, fmt.Errorf("custom-columns-file format specified but no file given")
+		}
+
+		file, err := os.Open(spec)
+		if err != nil {
+			return nil, fmt.Errorf("error reading custom columns file %q: %w", spec, err)
+		}
+		defer file.Close()
+
+		return NewCustomColumnsPrinterFromTemplate(file)
+	default:
+		return nil, genericclioptions.NoCompatiblePrinterError{OutputFormat: &outputFormat, AllowedFormats: f.AllowedFormats()}
+	}
+}
+
+// customColumn is a single column of the custom columns printer
+type customColumn struct {
+	header string
+	parser *jsonpath.JSONPath
+}
+
+// CustomColumnsPrinter prints the items of a list, or a single object, as a table with columns
+// defined by JSONPath expressions.
+type CustomColumnsPrinter struct {
+	columns []customColumn
+}
+
+// NewCustomColumnsPrinterFromSpec creates a custom columns printer from a comma separated list of
+// <header>:<jsonpath> pairs, e.g. "NAME:.secret.name,CLUSTER:.ticket.cluster".
+func NewCustomColumnsPrinterFromSpec(spec string) (*CustomColumnsPrinter, error) {
+	headers := []string{}
+	paths := []string{}
+
+	for _, part := range strings.Split(spec, ",") {
+		header, path, ok := strings.Cut(part, ":")
+		if !ok || header == "" || path == "" {
+			return nil, fmt.Errorf("unexpected custom-columns spec: %s, expected <header>:<json-path-expr>", part)
+		}
+
+		headers = append(headers, header)
+		paths = append(paths, path)
+	}
+
+	return newCustomColumnsPrinter(headers, paths)
+}
+
+// NewCustomColumnsPrinterFromTemplate creates a custom columns printer from a template, which
+// consists of a line of whitespace separated headers followed by a line of whitespace separated
+// JSONPath expressions.
+func NewCustomColumnsPrinterFromTemplate(templateReader io.Reader) (*CustomColumnsPrinter, error) {
+	scanner := bufio.NewScanner(templateReader)
+
+	lines := []string{}
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("invalid template, expected two lines, got %d", len(lines))
+	}
+
+	headers := strings.Fields(lines[0])
+	paths := strings.Fields(lines[1])
+
+	if len(headers) != len(paths) {
+		return nil, fmt.Errorf("number of headers (%d) does not match number of columns (%d)", len(headers), len(paths))
+	}
+
+	return newCustomColumnsPrinter(headers, paths)
+}
+
+// newCustomColumnsPrinter parses the given JSONPath expressions and returns a new printer
+func newCustomColumnsPrinter(headers, paths []string) (*CustomColumnsPrinter, error) {
+	columns := make([]customColumn, 0, len(headers))
+
+	for i := range headers {
+		parser := jsonpath.New(headers[i]).AllowMissingKeys(true)
+		if err := parser.Parse(relaxedJSONPathExpression(paths[i])); err != nil {
+			return nil, fmt.Errorf("invalid JSONPath expression %q: %w", paths[i], err)
+		}
+
+		columns = append(columns, customColumn{
+			header: headers[i],
+			parser: parser,
+		})
+	}
+
+	return &CustomColumnsPrinter{
+		columns: columns,
+	}, nil
+}
+
+// PrintObj implements the printers.ResourcePrinter interface. If the object is a list, each item
+// of the list is printed as a separate row.
+func (p *CustomColumnsPrinter) PrintObj(obj runtime.Object, out io.Writer) error {
+	w := printers.GetNewTabWriter(out)
+	defer w.Flush()
+
+	headers := make([]string, 0, len(p.columns))
+	for _, column := range p.columns {
+		headers = append(headers, column.header)
+	}
+
+	if _, err := fmt.Fprintln(w, strings.Join(headers, "\t")); err != nil {
+		return err
+	}
+
+	objects := []runtime.Object{obj}
+
+	if meta.IsListType(obj) {
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return err
+		}
+
+		objects = items
+	}
+
+	for _, item := range objects {
+		if err := p.printRow(item, w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printRow prints a single object as a row of the table
+func (p *CustomColumnsPrinter) printRow(obj runtime.Object, w io.Writer) error {
+	var data any = obj
+
+	if unstructured, ok := obj.(runtime.Unstructured); ok {
+		data = unstructured.UnstructuredContent()
+	}
+
+	cells := make([]string, 0, len(p.columns))
+
+	for _, column := range p.columns {
+		results, err := column.parser.FindResults(data)
+		if err != nil {
+			return err
+		}
+
+		values := []string{}
+		for _, result := range results {
+			for _, value := range result {
+				var buf bytes.Buffer
+				if err := column.parser.PrintResults(&buf, []reflect.Value{value}); err != nil {
+					return err
+				}
+
+				values = append(values, buf.String())
+			}
+		}
+
+		if len(values) == 0 {
+			cells = append(cells, customColumnsNone)
+			continue
+		}
+
+		cells = append(cells, strings.Join(values, ","))
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(cells, "\t"))
+
+	return err
+}
+
+// relaxedJSONPathExpression converts a JSONPath expression that is not wrapped in curly braces and
+// may be missing the leading dot into a valid JSONPath template, e.g. "ticket.cluster" is converted
+// to "{.ticket.cluster}".
+func relaxedJSONPathExpression(path string) string {
+	path = strings.TrimSpace(path)
+
+	if strings.HasPrefix(path, "{") && strings.HasSuffix(path, "}") {
+		return path
+	}
+
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path
+	}
+
+	return "{" + path + "}"
+}
@@ -92,16 +92,20 @@ func CompleteNamespaceNames(client kubernetes.Interface, toComplete string) ([]s
 // tickets and the toComplete string. If toComplete is empty, all tickets are
 // returned. Otherwise, only tickets that start with toComplete are returned.
 // Tickets that have already been completed as part of the command are not
-// returned.
-func CompleteTicketNames(client kubernetes.Interface, namespace string, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+// returned. Secrets are considered to contain a ticket if they contain the
+// given ticket key, or any key that can be parsed as a ticket if
+// detectTicketKeys is true.
+func CompleteTicketNames(client kubernetes.Interface, namespace, ticketKey string, detectTicketKeys bool, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	secrets, err := client.CoreV1().Secrets(namespace).List(context.TODO(), metaV1.ListOptions{})
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveError
 	}
 
 	var suggestions []string
-	for _, secret := range secrets.Items {
-		if _, ok := secret.Data[ticket.SecretMaprTicketKey]; !ok {
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		if len(ticket.SecretTicketKeys(secret, ticketKey, detectTicketKeys)) == 0 {
 			continue
 		}
 
@@ -442,7 +442,7 @@ func TestCompleteTicketNames(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			suggestions, directive := CompleteTicketNames(test.args.client, test.args.namespace, test.args.args, test.args.toComplete)
+			suggestions, directive := CompleteTicketNames(test.args.client, test.args.namespace, ticket.SecretMaprTicketKey, false, test.args.args, test.args.toComplete)
 
 			assert.Len(t, suggestions, len(test.want.suggestions))
 			assert.ElementsMatch(t, test.want.suggestions, suggestions)
@@ -0,0 +1,85 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+)
+
+// ContextOptions holds the options used to run a command across multiple kubeconfig contexts,
+// either all contexts using --all-contexts or a comma separated list passed to --context
+type ContextOptions struct {
+	// AllContexts indicates whether to run the command for all kubeconfig contexts
+	AllContexts bool
+
+	// Contexts are the kubeconfig contexts to run the command for, empty if the command runs for
+	// a single context only
+	Contexts []string
+}
+
+// NewContextOptions returns a new ContextOptions struct
+func NewContextOptions() *ContextOptions {
+	return &ContextOptions{}
+}
+
+// AddFlags adds the flags for running a command across multiple contexts to the command
+func (o *ContextOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&o.AllContexts, "all-contexts", false, "If true, run for all contexts of the kubeconfig. Multiple contexts can also be passed to --context as a comma separated list")
+}
+
+// Complete determines the contexts to run the command for based on the flags. Running for
+// multiple contexts is not supported when reading from a cluster dump.
+func (o *ContextOptions) Complete(opts *Options) error {
+	contexts, err := util.ContextsFromFlags(opts.KubernetesConfigFlags, o.AllContexts)
+	if err != nil {
+		return err
+	}
+
+	if len(contexts) > 0 && opts.FromDump != "" {
+		return fmt.Errorf("--from-dump can't be used with multiple contexts")
+	}
+
+	o.Contexts = contexts
+
+	return nil
+}
+
+// IsMultiContext returns true if the command runs for multiple contexts
+func (o *ContextOptions) IsMultiContext() bool {
+	return len(o.Contexts) > 0
+}
+
+// MergeContextResults merges the items of all contexts into a single list, in the order of the
+// contexts. Errors of single contexts, e.g. unreachable clusters, are printed to errOut instead of
+// aborting the whole run. An error is only returned if none of the contexts succeeded.
+func MergeContextResults[T any](errOut io.Writer, results []util.ContextResult[T]) ([]T, error) {
+	var (
+		items  []T
+		failed int
+	)
+
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(errOut, "error: context %q: %v\n", result.Context, result.Err)
+
+			continue
+		}
+
+		items = append(items, result.Items...)
+	}
+
+	if len(results) > 0 && failed == len(results) {
+		return nil, fmt.Errorf("failed to list resources in any of the %d contexts", len(results))
+	}
+
+	return items, nil
+}
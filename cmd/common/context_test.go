@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+)
+
+func TestMergeContextResults(t *testing.T) {
+	t.Parallel()
+
+	errUnreachable := errors.New("unreachable")
+
+	tests := []struct {
+		name       string
+		results    []util.ContextResult[string]
+		want       []string
+		wantErrOut string
+		wantErr    bool
+	}{
+		{
+			name: "all contexts succeed",
+			results: []util.ContextResult[string]{
+				{Context: "prod", Items: []string{"a", "b"}},
+				{Context: "staging", Items: []string{"c"}},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "single context fails",
+			results: []util.ContextResult[string]{
+				{Context: "prod", Err: errUnreachable},
+				{Context: "staging", Items: []string{"c"}},
+			},
+			want:       []string{"c"},
+			wantErrOut: "error: context \"prod\": unreachable\n",
+		},
+		{
+			name: "all contexts fail",
+			results: []util.ContextResult[string]{
+				{Context: "prod", Err: errUnreachable},
+				{Context: "staging", Err: errUnreachable},
+			},
+			wantErrOut: "error: context \"prod\": unreachable\nerror: context \"staging\": unreachable\n",
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			errOut := &bytes.Buffer{}
+
+			got, err := MergeContextResults(errOut, test.results)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantErrOut, errOut.String())
+		})
+	}
+}
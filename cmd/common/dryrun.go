@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DryRunStrategy describes if and where a dry run of a mutating command is performed
+type DryRunStrategy int
+
+const (
+	// DryRunNone indicates the changes are actually persisted
+	DryRunNone DryRunStrategy = iota
+
+	// DryRunClient indicates the changes are only computed locally and never sent to the server
+	DryRunClient
+
+	// DryRunServer indicates the changes are sent to the server with the dry run option set, so
+	// they are validated but not persisted
+	DryRunServer
+)
+
+var (
+	// dryRunValues are the valid values of the dry-run flag
+	dryRunValues = []string{"none", "client", "server"}
+)
+
+// AddDryRunFlag adds the dry-run flag known from kubectl to the command. Passing the flag without
+// a value is the same as passing "client".
+func AddDryRunFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "dry-run", "none", fmt.Sprintf("Must be one of (%s). If client, only print the object that would be sent, without sending it. If server, submit the request without persisting the resource.", StringSliceToFlagOptions(dryRunValues)))
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = "client"
+}
+
+// CompleteDryRunValues returns a list of suggestions for the dry-run flag
+func CompleteDryRunValues(toComplete string) ([]string, cobra.ShellCompDirective) {
+	return CompleteStringValues(dryRunValues, toComplete)
+}
+
+// ParseDryRunStrategy parses the value of the dry-run flag into a DryRunStrategy
+func ParseDryRunStrategy(value string) (DryRunStrategy, error) {
+	switch value {
+	case "", "none":
+		return DryRunNone, nil
+	case "client":
+		return DryRunClient, nil
+	case "server":
+		return DryRunServer, nil
+	default:
+		return DryRunNone, fmt.Errorf("invalid dry-run value %q. Must be one of (%s)", value, StringSliceToFlagOptions(dryRunValues))
+	}
+}
+
+// ServerDryRun returns the value for the DryRun field of the API request options. It is only set
+// for DryRunServer, as client side dry runs never reach the server.
+func (s DryRunStrategy) ServerDryRun() []string {
+	if s == DryRunServer {
+		return []string{metaV1.DryRunAll}
+	}
+
+	return nil
+}
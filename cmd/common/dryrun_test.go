@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common_test
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseDryRunStrategy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		value      string
+		want       DryRunStrategy
+		wantDryRun []string
+		wantErr    bool
+	}{
+		{name: "empty", value: "", want: DryRunNone},
+		{name: "none", value: "none", want: DryRunNone},
+		{name: "client", value: "client", want: DryRunClient},
+		{name: "server", value: "server", want: DryRunServer, wantDryRun: []string{metaV1.DryRunAll}},
+		{name: "invalid", value: "true", wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseDryRunStrategy(test.value)
+
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantDryRun, got.ServerDryRun())
+		})
+	}
+}
+
+func TestAddDryRunFlag(t *testing.T) {
+	t.Parallel()
+
+	var value string
+
+	cmd := &cobra.Command{}
+	AddDryRunFlag(cmd, &value)
+
+	assert.Equal(t, "none", value)
+
+	// passing the flag without a value defaults to a client side dry run
+	err := cmd.ParseFlags([]string{"--dry-run"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "client", value)
+}
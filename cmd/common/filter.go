@@ -0,0 +1,57 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
+)
+
+// FilterOptions holds the CEL expression used to filter the listed items after they have been
+// retrieved and enriched with their tickets
+type FilterOptions struct {
+	// Filter is the raw filter expression
+	Filter string
+
+	// Expression is the compiled filter expression, set by Validate if a filter was given
+	Expression *filter.Expression
+
+	// variables are the names of the variables available in the filter expression
+	variables []string
+}
+
+// NewFilterOptions returns a new FilterOptions struct for filter expressions using the given
+// variables, see filter.SecretVariables, filter.VolumeVariables and filter.ClaimVariables
+func NewFilterOptions(variables []string) *FilterOptions {
+	return &FilterOptions{
+		variables: variables,
+	}
+}
+
+// AddFlags adds the filter flag to the command. The kind is used in the help text of the flag.
+func (o *FilterOptions) AddFlags(cmd *cobra.Command, kind string) {
+	cmd.Flags().StringVar(&o.Filter, "filter", "", fmt.Sprintf("CEL expression to filter %s on, e.g. \"ticket.user in ['a', 'b'] && ticket.expiresIn < duration('72h')\". Available variables: %s", kind, strings.Join(o.variables, ", ")))
+}
+
+// Validate ensures that the filter expression can be compiled and stores the compiled expression
+func (o *FilterOptions) Validate() error {
+	if o.Filter == "" {
+		return nil
+	}
+
+	expression, err := filter.Compile(o.Filter, o.variables)
+	if err != nil {
+		return err
+	}
+
+	o.Expression = expression
+
+	return nil
+}
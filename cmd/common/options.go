@@ -6,10 +6,27 @@
 package common
 
 import (
+	"errors"
+	"fmt"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/dump"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/pod"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/storageclass"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/kubernetes"
 )
 
+// DefaultChunkSize is the default number of objects retrieved per list request, same as kubectl
+const DefaultChunkSize int64 = 500
+
 // Options is a struct to hold common options for all commands
 type Options struct {
 	KubernetesConfigFlags *genericclioptions.ConfigFlags
@@ -17,6 +34,25 @@ type Options struct {
 
 	// Debug flag to enable Debug logging
 	Debug bool
+
+	// TicketKey is the data key of the secrets the MapR ticket is stored under
+	TicketKey string
+
+	// DetectTicketKeys flag to try to parse every data key of the secrets as a MapR ticket
+	DetectTicketKeys bool
+
+	// ChunkSize is the number of secrets to retrieve per list request, 0 disables chunking
+	ChunkSize int64
+
+	// LabeledOnly flag to only retrieve secrets labeled as containing a MapR ticket, falling back
+	// to retrieving all secrets if none are labeled
+	LabeledOnly bool
+
+	// FromDump is the path of a cluster dump to read objects from instead of the API server
+	FromDump string
+
+	// dumpClient is the fake client holding the objects of the cluster dump, loaded on first use
+	dumpClient kubernetes.Interface
 }
 
 // NewOptions returns a new common options struct
@@ -24,5 +60,78 @@ func NewOptions(flags *genericclioptions.ConfigFlags, streams genericiooptions.I
 	return &Options{
 		KubernetesConfigFlags: flags,
 		IOStreams:             streams,
+		TicketKey:             ticket.SecretMaprTicketKey,
+		ChunkSize:             DefaultChunkSize,
+	}
+}
+
+// ClientFromFlags creates a Kubernetes client from the given flags. If a cluster dump was passed
+// using --from-dump, a fake client holding the objects of the dump is returned instead.
+func (o *Options) ClientFromFlags(flags *genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+	if o.FromDump == "" {
+		return util.ClientFromFlags(flags)
+	}
+
+	if o.dumpClient == nil {
+		client, err := dump.NewClient(o.FromDump)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cluster dump: %w", err)
+		}
+
+		o.dumpClient = client
+	}
+
+	return o.dumpClient, nil
+}
+
+// Client creates a Kubernetes client from the global flags, see ClientFromFlags
+func (o *Options) Client() (kubernetes.Interface, error) {
+	return o.ClientFromFlags(o.KubernetesConfigFlags)
+}
+
+// SecretListerOptions returns the secret lister options derived from the global flags, ie. which
+// data keys of the secrets are expected to contain MapR tickets, how many secrets to retrieve per
+// list request and whether to only retrieve labeled secrets
+func (o *Options) SecretListerOptions() []secret.ListerOption {
+	opts := []secret.ListerOption{
+		secret.WithTicketKey(o.TicketKey),
+	}
+
+	if o.ChunkSize > 0 {
+		opts = append(opts, secret.WithChunkSize(o.ChunkSize))
+	}
+
+	if o.DetectTicketKeys {
+		opts = append(opts, secret.WithDetectTicketKeys())
+	}
+
+	if o.LabeledOnly {
+		opts = append(opts, secret.WithLabeledOnly())
 	}
+
+	return opts
+}
+
+// InUseListerOptions returns the secret lister options required to determine which secrets are in
+// use by persistent volumes, inline volumes of pods and StorageClasses
+func InUseListerOptions(client kubernetes.Interface) []secret.ListerOption {
+	return []secret.ListerOption{
+		secret.WithVolumeLister(volume.NewLister(client, util.SecretAll, metaV1.NamespaceAll)),
+		secret.WithInlineVolumeLister(pod.NewInlineVolumeLister(client, metaV1.NamespaceAll)),
+		secret.WithStorageClassLister(storageclass.NewLister(client)),
+	}
+}
+
+// SelectTicketKey returns the data key of the secret the MapR ticket is stored under, based on the
+// global flags. It returns an error if the secret does not contain a ticket, or if ticket key
+// detection finds several tickets and none of them is stored under the configured ticket key.
+func (o *Options) SelectTicketKey(s *coreV1.Secret) (string, error) {
+	key, err := ticket.SelectSecretTicketKey(s, o.TicketKey, o.DetectTicketKeys)
+
+	var errNoTicket ticket.ErrSecretDoesNotContainMaprTicket
+	if err != nil && !errors.As(err, &errNoTicket) {
+		return "", fmt.Errorf("%w, select one using --ticket-key", err)
+	}
+
+	return key, err
 }
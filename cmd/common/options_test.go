@@ -11,7 +11,10 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	. "github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
 
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
 )
@@ -29,3 +32,78 @@ func TestNewOptions(t *testing.T) {
 	assert.Equal(t, flags, options.KubernetesConfigFlags)
 	assert.Equal(t, streams, options.IOStreams)
 }
+
+func TestOptions_SelectTicketKey(t *testing.T) {
+	t.Parallel()
+
+	validTicket := []byte("demo.mapr.com +Cze+qwYCbAXGbz56OO7UF+lGqL3WPXrNkO1SLawEEDmSbgNl019xBeBY3kvh+R13iz/mCnwpzsLQw4Y5jEnv5GtuIWbeoC95ha8VKwX8MKcE6Kn9nZ2AF0QminkHwNVBx6TDriGZffyJCfZzivBwBSdKoQEWhBOPFCIMAi7w2zV/SX5Ut7u4qIKvEpr0JHV7sLMWYLhYncM6CKMd7iECGvECsBvEZRVj+dpbEY0BaRN/W54/7wNWaSVELUF6JWHQ8dmsqty4cZlI0/MV10HZzIbl9sMLFQ=")
+
+	tests := []struct {
+		name    string
+		key     string
+		detect  bool
+		data    map[string][]byte
+		want    string
+		wantErr string
+	}{
+		{
+			name: "default key",
+			key:  ticket.SecretMaprTicketKey,
+			data: map[string][]byte{ticket.SecretMaprTicketKey: validTicket},
+			want: ticket.SecretMaprTicketKey,
+		},
+		{
+			name:    "missing key",
+			key:     "maprticket",
+			data:    map[string][]byte{ticket.SecretMaprTicketKey: validTicket},
+			wantErr: "secret default/secret does not contain a MapR ticket",
+		},
+		{
+			name:   "single detected key",
+			key:    ticket.SecretMaprTicketKey,
+			detect: true,
+			data:   map[string][]byte{"maprticket": validTicket},
+			want:   "maprticket",
+		},
+		{
+			name:   "several detected keys including the configured key",
+			key:    "user-b",
+			detect: true,
+			data:   map[string][]byte{"user-a": validTicket, "user-b": validTicket},
+			want:   "user-b",
+		},
+		{
+			name:    "several detected keys",
+			key:     ticket.SecretMaprTicketKey,
+			detect:  true,
+			data:    map[string][]byte{"user-a": validTicket, "user-b": validTicket},
+			wantErr: "secret default/secret holds several MapR tickets under the keys user-a, user-b, select one using --ticket-key",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			options := NewOptions(&genericclioptions.ConfigFlags{}, genericiooptions.IOStreams{})
+			options.TicketKey = test.key
+			options.DetectTicketKeys = test.detect
+
+			secret := &coreV1.Secret{
+				ObjectMeta: metaV1.ObjectMeta{Namespace: "default", Name: "secret"},
+				Data:       test.data,
+			}
+
+			got, err := options.SelectTicketKey(secret)
+
+			if test.wantErr != "" {
+				assert.EqualError(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+var (
+	// tableOutputFormats are the output formats that are handled by the human readable table
+	// printers of the list commands instead of the printers returned by PrintFlags.ToPrinter
+	tableOutputFormats = []string{"table", "wide"}
+)
+
+// PrintFlags composes the printer flags shared by all list commands. Besides the human readable
+// table formats, it supports the same structured output formats as kubectl, i.e. json, yaml,
+// jsonpath, go-template and custom-columns.
+type PrintFlags struct {
+	JSONYamlPrintFlags      *genericclioptions.JSONYamlPrintFlags
+	TemplatePrinterFlags    *genericclioptions.KubeTemplatePrintFlags
+	CustomColumnsPrintFlags *CustomColumnsPrintFlags
+
+	OutputFormat *string
+}
+
+// NewPrintFlags returns a new PrintFlags struct with the output format defaulting to "table"
+func NewPrintFlags() *PrintFlags {
+	outputFormat := "table"
+
+	return &PrintFlags{
+		JSONYamlPrintFlags:      genericclioptions.NewJSONYamlPrintFlags(),
+		TemplatePrinterFlags:    genericclioptions.NewKubeTemplatePrintFlags(),
+		CustomColumnsPrintFlags: NewCustomColumnsPrintFlags(),
+		OutputFormat:            &outputFormat,
+	}
+}
+
+// AllowedFormats returns the list of all output formats supported by the print flags
+func (f *PrintFlags) AllowedFormats() []string {
+	formats := slices.Clone(tableOutputFormats)
+	formats = append(formats, f.JSONYamlPrintFlags.AllowedFormats()...)
+	formats = append(formats, f.TemplatePrinterFlags.AllowedFormats()...)
+	formats = append(formats, f.CustomColumnsPrintFlags.AllowedFormats()...)
+
+	return formats
+}
+
+// AddFlags adds the output flag and all flags required by the template printers to the command
+func (f *PrintFlags) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(f.OutputFormat, "output", "o", *f.OutputFormat, fmt.Sprintf("Output format. One of (%s)", StringSliceToFlagOptions(f.AllowedFormats())))
+	f.TemplatePrinterFlags.AddFlags(cmd)
+}
+
+// IsTableFormat returns true if the requested output format is one of the human readable table
+// formats
+func (f *PrintFlags) IsTableFormat() bool {
+	return slices.Contains(tableOutputFormats, *f.OutputFormat)
+}
+
+// Validate ensures that the requested output format is supported
+func (f *PrintFlags) Validate() error {
+	format, _, _ := strings.Cut(*f.OutputFormat, "=")
+
+	if !slices.Contains(f.AllowedFormats(), format) {
+		return fmt.Errorf("invalid output format %q. Must be one of (%s)", *f.OutputFormat, StringSliceToFlagOptions(f.AllowedFormats()))
+	}
+
+	return nil
+}
+
+// ToPrinter returns a printer for the requested structured output format. Table formats are not
+// handled here and need to be printed by the list commands themselves.
+func (f *PrintFlags) ToPrinter() (printers.ResourcePrinter, error) {
+	outputFormat := *f.OutputFormat
+
+	if p, err := f.JSONYamlPrintFlags.ToPrinter(outputFormat); !genericclioptions.IsNoCompatiblePrinterError(err) {
+		return p, err
+	}
+
+	if p, err := f.TemplatePrinterFlags.ToPrinter(outputFormat); !genericclioptions.IsNoCompatiblePrinterError(err) {
+		return p, err
+	}
+
+	if p, err := f.CustomColumnsPrintFlags.ToPrinter(outputFormat); !genericclioptions.IsNoCompatiblePrinterError(err) {
+		return p, err
+	}
+
+	return nil, genericclioptions.NoCompatiblePrinterError{OutputFormat: &outputFormat, AllowedFormats: f.AllowedFormats()}
+}
+
+// CompleteOutputFormats returns a list of suggestions for the output flag
+func (f *PrintFlags) CompleteOutputFormats(toComplete string) ([]string, cobra.ShellCompDirective) {
+	return CompleteStringValues(f.AllowedFormats(), toComplete)
+}
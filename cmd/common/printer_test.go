@@ -0,0 +1,175 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPrintFlags_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{name: "table", format: "table", wantErr: false},
+		{name: "wide", format: "wide", wantErr: false},
+		{name: "json", format: "json", wantErr: false},
+		{name: "yaml", format: "yaml", wantErr: false},
+		{name: "jsonpath", format: "jsonpath={.items[*].ticket.cluster}", wantErr: false},
+		{name: "go-template", format: "go-template={{.kind}}", wantErr: false},
+		{name: "custom-columns", format: "custom-columns=NAME:.secret.name", wantErr: false},
+		{name: "invalid", format: "xml", wantErr: true},
+		{name: "empty", format: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := NewPrintFlags()
+			*f.OutputFormat = test.format
+
+			err := f.Validate()
+
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPrintFlags_IsTableFormat(t *testing.T) {
+	t.Parallel()
+
+	f := NewPrintFlags()
+	assert.True(t, f.IsTableFormat())
+
+	*f.OutputFormat = "wide"
+	assert.True(t, f.IsTableFormat())
+
+	*f.OutputFormat = "json"
+	assert.False(t, f.IsTableFormat())
+}
+
+func TestPrintFlags_ToPrinter(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	columnsFile := filepath.Join(dir, "columns.txt")
+	err := os.WriteFile(columnsFile, []byte("NAME CLUSTER\n.secret.name .ticket.cluster\n"), 0o600)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "jsonpath",
+			format: "jsonpath={.items[*].ticket.cluster}",
+			want:   "demo.mapr.com other.mapr.com",
+		},
+		{
+			name:   "go-template",
+			format: "go-template={{range .items}}{{.secret.name}};{{end}}",
+			want:   "secret-a;secret-b;",
+		},
+		{
+			name:   "custom-columns",
+			format: "custom-columns=NAME:.secret.name,CLUSTER:ticket.cluster,MISSING:.ticket.foo",
+			want:   "NAME       CLUSTER          MISSING\nsecret-a   demo.mapr.com    <none>\nsecret-b   other.mapr.com   <none>\n",
+		},
+		{
+			name:   "custom-columns keeps headers as given",
+			format: "custom-columns=Name:.secret.name,cluster:.ticket.cluster",
+			want:   "Name       cluster\nsecret-a   demo.mapr.com\nsecret-b   other.mapr.com\n",
+		},
+		{
+			name:   "custom-columns-file",
+			format: "custom-columns-file=" + columnsFile,
+			want:   "NAME       CLUSTER\nsecret-a   demo.mapr.com\nsecret-b   other.mapr.com\n",
+		},
+		{
+			name:    "custom-columns without spec",
+			format:  "custom-columns=",
+			wantErr: true,
+		},
+		{
+			name:    "custom-columns with invalid spec",
+			format:  "custom-columns=NAME",
+			wantErr: true,
+		},
+		{
+			name:    "table",
+			format:  "table",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := NewPrintFlags()
+			*f.OutputFormat = test.format
+
+			printer, err := f.ToPrinter()
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			out := &bytes.Buffer{}
+			err = printer.PrintObj(newTestList(), out)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, out.String())
+		})
+	}
+}
+
+func newTestList() *unstructured.UnstructuredList {
+	newItem := func(name, cluster string) unstructured.Unstructured {
+		return unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "mapr-ticket.nobbs.dev/v1alpha1",
+				"kind":       "MaprSecret",
+				"secret": map[string]any{
+					"name": name,
+				},
+				"ticket": map[string]any{
+					"cluster": cluster,
+				},
+			},
+		}
+	}
+
+	return &unstructured.UnstructuredList{
+		Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "List",
+		},
+		Items: []unstructured.Unstructured{
+			newItem("secret-a", "demo.mapr.com"),
+			newItem("secret-b", "other.mapr.com"),
+		},
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// SelectorOptions holds the label and field selectors known from kubectl, which are passed to the
+// API server to only list matching objects
+type SelectorOptions struct {
+	// LabelSelector is the label query to filter the listed objects on
+	LabelSelector string
+
+	// FieldSelector is the field query to filter the listed objects on
+	FieldSelector string
+}
+
+// NewSelectorOptions returns a new SelectorOptions struct
+func NewSelectorOptions() *SelectorOptions {
+	return &SelectorOptions{}
+}
+
+// AddFlags adds the selector flags to the command. The kind is used in the help texts of the flags.
+func (o *SelectorOptions) AddFlags(cmd *cobra.Command, kind string) {
+	cmd.Flags().StringVarP(&o.LabelSelector, "selector", "l", "", fmt.Sprintf("Selector (label query) to filter %s on, supports '=', '==', '!=', 'in', 'notin' and 'exists' (e.g. -l key1=value1,key2=value2)", kind))
+	cmd.Flags().StringVar(&o.FieldSelector, "field-selector", "", fmt.Sprintf("Selector (field query) to filter %s on, supports '=', '==', and '!=' (e.g. --field-selector key1=value1,key2=value2). The server only supports a limited number of field queries per type.", kind))
+}
+
+// Validate ensures that the selectors can be parsed
+func (o *SelectorOptions) Validate() error {
+	if _, err := labels.Parse(o.LabelSelector); err != nil {
+		return fmt.Errorf("invalid label selector %q: %w", o.LabelSelector, err)
+	}
+
+	if _, err := fields.ParseSelector(o.FieldSelector); err != nil {
+		return fmt.Errorf("invalid field selector %q: %w", o.FieldSelector, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package controller provides the controller command for the application.
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/controller"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedCoreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+// command string constants for use in help and usage text
+const (
+	controllerUse   = `controller`
+	controllerShort = "Run a controller emitting events and annotations for MapR tickets nearing expiry"
+	controllerLong  = `
+		Run a long-running controller that periodically evaluates the MapR tickets deployed as
+		secrets, meant to be deployed in-cluster.
+
+		Each secret containing a ticket is annotated with the expiration time of the ticket
+		as mapr.com/ticket-expiry and its status as mapr.com/ticket-status, one of Valid,
+		ExpiringSoon or Expired. For expired tickets and tickets expiring within the
+		--expires-within threshold, the Kubernetes Events TicketExpired and
+		TicketExpiringSoon are emitted for the secret and all persistent volume claims using
+		it, so they show up in kubectl describe pvc without installing this plugin.
+
+		When running multiple replicas, leader election ensures that only one of them is
+		active at a time. It uses a Lease in the namespace given by
+		--leader-election-namespace, defaulting to the namespace of the service account.
+		`
+	controllerExample = `
+		# Run the controller for all namespaces
+		%[1]s controller --all-namespaces
+
+		# Run the controller for a single namespace without leader election
+		%[1]s controller --namespace team-a --leader-elect=false
+
+		# Report tickets expiring within 14 days and evaluate them every 30 minutes
+		%[1]s controller --all-namespaces --expires-within 14d --interval 30m
+		`
+)
+
+const (
+	// defaultReconcileInterval is the default interval between two reconciliations
+	defaultReconcileInterval = 10 * time.Minute
+
+	// defaultLeaderElectionID is the default name of the Lease used for leader election
+	defaultLeaderElectionID = "kubectl-mapr-ticket-controller"
+
+	// eventComponent is the component reported as source of the emitted events
+	eventComponent = "mapr-ticket-controller"
+
+	// leader election timings, same as the defaults of the Kubernetes controller manager
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+type options struct {
+	*common.Options
+
+	// AllNamespaces indicates whether to evaluate secrets in all namespaces
+	AllNamespaces bool
+
+	// Interval is the interval between two reconciliations
+	Interval common.DurationValue
+
+	// ExpiresWithin is the threshold for tickets to be considered as expiring soon
+	ExpiresWithin common.DurationValue
+
+	// LeaderElect indicates whether to use leader election
+	LeaderElect bool
+
+	// LeaderElectionID is the name of the Lease used for leader election
+	LeaderElectionID string
+
+	// LeaderElectionNamespace is the namespace of the Lease used for leader election
+	LeaderElectionNamespace string
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:          opts,
+		Interval:         common.DurationValue(defaultReconcileInterval),
+		ExpiresWithin:    common.DurationValue(controller.DefaultExpiresWithin),
+		LeaderElect:      true,
+		LeaderElectionID: defaultLeaderElectionID,
+	}
+}
+
+// NewCmd creates a new controller command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          controllerUse,
+		Short:        controllerShort,
+		Long:         common.CliLongDesc(controllerLong),
+		Example:      common.CliExample(controllerExample, common.CliBinName),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If true, evaluate secrets across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().Var(&o.Interval, "interval", "Interval between two evaluations of the tickets")
+	cmd.Flags().Var(&o.ExpiresWithin, "expires-within", "Report tickets that expire within the specified duration from now as expiring soon")
+	cmd.Flags().BoolVar(&o.LeaderElect, "leader-elect", o.LeaderElect, "Use leader election to ensure only one replica of the controller is active")
+	cmd.Flags().StringVar(&o.LeaderElectionID, "leader-election-id", o.LeaderElectionID, "Name of the Lease used for leader election")
+	cmd.Flags().StringVar(&o.LeaderElectionNamespace, "leader-election-namespace", "", "Namespace of the Lease used for leader election. Defaults to the namespace of the current context or service account")
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	// the lease lives in the namespace of the current context, ie. the service account when
+	// running in-cluster, regardless of the namespaces being evaluated
+	if o.LeaderElectionNamespace == "" {
+		o.LeaderElectionNamespace = util.GetNamespace(o.KubernetesConfigFlags, false)
+	}
+
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	if o.Interval.Duration() <= 0 {
+		return fmt.Errorf("invalid interval %q, must be greater than zero", o.Interval.String())
+	}
+
+	if o.ExpiresWithin.Duration() < 0 {
+		return fmt.Errorf("invalid duration %q for --expires-within, must not be negative", o.ExpiresWithin.String())
+	}
+
+	if o.LeaderElect && o.LeaderElectionID == "" {
+		return fmt.Errorf("leader election id must not be empty")
+	}
+
+	if o.FromDump != "" {
+		return fmt.Errorf("--from-dump can't be used to run the controller, a cluster dump is read-only")
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedCoreV1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	defer broadcaster.Shutdown()
+
+	recorder := broadcaster.NewRecorder(scheme.Scheme, coreV1.EventSource{Component: eventComponent})
+
+	c := controller.NewController(
+		client,
+		*o.KubernetesConfigFlags.Namespace,
+		recorder,
+		controller.WithSecretListerOptions(o.SecretListerOptions()...),
+		controller.WithExpiresWithin(o.ExpiresWithin.Duration()),
+	)
+
+	slog.Info("starting ticket controller", "interval", o.Interval.String(), "expiresWithin", o.ExpiresWithin.String())
+
+	if !o.LeaderElect {
+		c.Run(ctx, o.Interval.Duration())
+		return nil
+	}
+
+	return o.runWithLeaderElection(ctx, client, recorder, c)
+}
+
+// runWithLeaderElection runs the controller while holding the lease. If the lease is lost before
+// the context is cancelled, an error is returned so the controller is restarted.
+func (o *options) runWithLeaderElection(ctx context.Context, client kubernetes.Interface, recorder record.EventRecorder, c *controller.Controller) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("failed to determine identity for leader election: %w", err)
+	}
+
+	// add a unique suffix, so multiple replicas on the same host don't share an identity
+	identity := hostname + "_" + string(uuid.NewUUID())
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metaV1.ObjectMeta{
+			Name:      o.LeaderElectionID,
+			Namespace: o.LeaderElectionNamespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity:      identity,
+			EventRecorder: recorder,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				slog.Info("acquired leadership", "identity", identity)
+				c.Run(ctx, o.Interval.Duration())
+			},
+			OnStoppedLeading: func() {
+				slog.Info("stopped leading", "identity", identity)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("waiting for leadership", "lease", o.LeaderElectionNamespace+"/"+o.LeaderElectionID, "identity", identity)
+	elector.Run(ctx)
+
+	if ctx.Err() == nil {
+		return errors.New("lost leadership")
+	}
+
+	return nil
+}
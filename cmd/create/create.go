@@ -0,0 +1,234 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package create provides the create command for the application.
+package create
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// command string constants for use in help and usage text
+const (
+	createUse   = `create NAME --from-file FILE`
+	createShort = "Create a secret containing a MapR ticket from a local ticket file"
+	createLong  = `
+		Create a secret containing a MapR ticket from a local ticket file.
+
+		The ticket file is parsed and validated before the secret is created, expired or
+		unparsable tickets are rejected. Both MapR ticket files as well as secret manifests
+		are supported as input. The ticket is stored under the CONTAINER_TICKET key and the
+		secret is labeled and annotated with the cluster, user and expiration time of the
+		ticket.
+		`
+	createExample = `
+		# Create a secret from a MapR ticket file in the current namespace
+		%[1]s create mapr-ticket-secret --from-file ./maprticket
+
+		# Create a secret from a MapR ticket file in a specific namespace
+		%[1]s create mapr-ticket-secret --from-file ./maprticket --namespace kube-system
+
+		# Print the secret manifest instead of creating the secret
+		%[1]s create mapr-ticket-secret --from-file ./maprticket --dry-run=client -o yaml
+		`
+)
+
+type options struct {
+	*common.Options
+
+	// PrintFlags are the flags used to configure the output format
+	PrintFlags *genericclioptions.PrintFlags
+
+	// SecretName is the name of the secret to create
+	SecretName string
+
+	// File is the path to the MapR ticket file
+	File string
+
+	// DryRun is the raw value of the dry-run flag
+	DryRun string
+
+	// DryRunStrategy is the parsed dry run strategy
+	DryRunStrategy common.DryRunStrategy
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:    opts,
+		PrintFlags: genericclioptions.NewPrintFlags("created").WithTypeSetter(scheme.Scheme),
+	}
+}
+
+// NewCmd creates a new create command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          createUse,
+		Short:        createShort,
+		Long:         common.CliLongDesc(createLong),
+		Example:      common.CliExample(createExample, common.CliBinName),
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			// the name of a new secret can't be completed
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	o.PrintFlags.AddFlags(cmd)
+	common.AddDryRunFlag(cmd, &o.DryRun)
+	cmd.Flags().StringVarP(&o.File, "from-file", "f", "", "Path to the MapR ticket file")
+
+	if err := cmd.MarkFlagRequired("from-file"); err != nil {
+		panic(err)
+	}
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	o.SecretName = args[0]
+
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, false)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	// parse dry run strategy
+	if o.DryRunStrategy, err = common.ParseDryRunStrategy(o.DryRun); err != nil {
+		return err
+	}
+
+	// adapt the success message of the name printer if we are not persisting anything
+	if o.DryRunStrategy != common.DryRunNone {
+		if err := o.PrintFlags.Complete("%s (dry run)"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	if o.SecretName == "" {
+		return fmt.Errorf("secret name must not be empty")
+	}
+
+	if o.File == "" {
+		return fmt.Errorf("a MapR ticket file must be provided via --from-file")
+	}
+
+	if o.FromDump != "" {
+		return fmt.Errorf("--from-dump can't be used to create secrets, a cluster dump is read-only")
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	bytes, err := util.ReadFile(o.File)
+	if err != nil {
+		return err
+	}
+
+	// parse and validate the ticket
+	maprTicket, ticketBytes, err := ticket.DecodeMaprTicket(bytes, o.TicketKey, o.DetectTicketKeys)
+	if err != nil {
+		return fmt.Errorf("failed to parse MapR ticket from %q: %w", o.File, err)
+	}
+
+	if maprTicket.IsExpired() {
+		return fmt.Errorf("MapR ticket from %q expired at %s", o.File, maprTicket.ExpirationTime().Format(ticket.DefaultTimeFormat))
+	}
+
+	// store the ticket as read from the file instead of encoding it again
+	secret, err := ticket.NewSecretFromBytes(*o.KubernetesConfigFlags.Namespace, o.SecretName, o.TicketKey, ticketBytes)
+	if err != nil {
+		return err
+	}
+
+	// only send the secret to the server if we are not doing a client side dry run
+	if o.DryRunStrategy != common.DryRunClient {
+		client, err := util.ClientFromFlags(o.KubernetesConfigFlags)
+		if err != nil {
+			return err
+		}
+
+		secret, err = client.CoreV1().Secrets(secret.Namespace).Create(context.TODO(), secret, metaV1.CreateOptions{
+			DryRun: o.DryRunStrategy.ServerDryRun(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(secret, o.IOStreams.Out)
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringValues(o.PrintFlags.AllowedFormats(), toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = cmd.RegisterFlagCompletionFunc("dry-run", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteDryRunValues(toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package create_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	. "github.com/nobbs/kubectl-mapr-ticket/cmd/create"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+// ticket for cluster demo.mapr.com and user mapr, expiring in 2100
+const demoTicket = "demo.mapr.com HQvWzXnHpDGcBithT6ohjzlVGzHvOUfUC4PK5XNpQ0qi99wP/+GXU6XII5VEK0tQn00HyZCno8YJUrM="
+
+func TestNewCmd(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	ticketFile := writeFile(t, dir, "maprticket", demoTicket)
+	manifestFile := writeFile(t, dir, "secret.yaml", "apiVersion: v1\nkind: Secret\nmetadata:\n  name: other\ndata:\n  TICKET: "+base64.StdEncoding.EncodeToString([]byte(demoTicket))+"\n")
+
+	tests := []struct {
+		name             string
+		file             string
+		ticketKey        string
+		detectTicketKeys bool
+		wantKey          string
+		wantErr          string
+	}{
+		{
+			name:      "ticket file",
+			file:      ticketFile,
+			ticketKey: ticket.SecretMaprTicketKey,
+			wantKey:   ticket.SecretMaprTicketKey,
+		},
+		{
+			name:      "ticket file stored under custom key",
+			file:      ticketFile,
+			ticketKey: "TICKET",
+			wantKey:   "TICKET",
+		},
+		{
+			name:      "secret manifest with ticket under custom key",
+			file:      manifestFile,
+			ticketKey: "TICKET",
+			wantKey:   "TICKET",
+		},
+		{
+			name:             "secret manifest with detected ticket key",
+			file:             manifestFile,
+			ticketKey:        ticket.SecretMaprTicketKey,
+			detectTicketKeys: true,
+			wantKey:          ticket.SecretMaprTicketKey,
+		},
+		{
+			name:      "secret manifest without ticket under key",
+			file:      manifestFile,
+			ticketKey: ticket.SecretMaprTicketKey,
+			wantErr:   "secret /other does not contain a MapR ticket",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			streams, _, out, _ := genericiooptions.NewTestIOStreams()
+
+			flags := genericclioptions.NewConfigFlags(false)
+			flags.Namespace = ptr.To("team-a")
+
+			opts := common.NewOptions(flags, streams)
+			opts.TicketKey = test.ticketKey
+			opts.DetectTicketKeys = test.detectTicketKeys
+
+			cmd := NewCmd(opts)
+			cmd.SetArgs([]string{"mapr-ticket-secret", "--from-file", test.file, "--dry-run=client", "-o", "yaml"})
+			err := cmd.Execute()
+
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			secret := &coreV1.Secret{}
+			assert.NoError(t, yaml.Unmarshal(out.Bytes(), secret))
+
+			// the ticket is stored as read, under the requested key only
+			assert.Equal(t, "team-a", secret.Namespace)
+			assert.Equal(t, "mapr-ticket-secret", secret.Name)
+			assert.Equal(t, map[string][]byte{test.wantKey: []byte(demoTicket)}, secret.Data)
+		})
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package diff provides the diff command for the application.
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/diff"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// command string constants for use in help and usage text
+const (
+	diffUse   = `diff <a> <b>`
+	diffShort = "Compare two MapR tickets field by field"
+	diffLong  = `
+		Compare two MapR tickets field by field, e.g. to see what changed when a rotated
+		ticket breaks mounts.
+
+		Each side can be the name of a secret in the current namespace, a secret given as
+		namespace/name or a local file containing a MapR ticket or a secret manifest. Local
+		files take precedence, prefix a path with ./ if it could be mistaken for a secret.
+
+		The cluster, user, UID, GIDs, creation and expiration time, maximum renewal duration
+		and a SHA-256 fingerprint of the encrypted ticket payload are compared. The payload
+		fingerprint changes whenever a ticket is regenerated, even if all other fields stay
+		the same. The differences are printed as a diff, colored if the output is a
+		terminal, or as a JSON patch.
+		`
+	diffExample = `
+		# Compare the MapR tickets of two secrets in the current namespace
+		%[1]s diff mapr-ticket-secret mapr-ticket-secret-new
+
+		# Compare the MapR ticket of a secret in another namespace with a local ticket file
+		%[1]s diff kube-system/mapr-ticket-secret ./maprticket_5000
+
+		# Print the differences as a JSON patch
+		%[1]s diff mapr-ticket-secret ./maprticket_5000 -o json-patch
+		`
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+var (
+	// valid output formats for the command
+	diffValidOutputFormats = []string{"diff", "json-patch"}
+
+	// valid values of the --color flag
+	diffValidColors = []string{colorAuto, colorAlways, colorNever}
+)
+
+type options struct {
+	*common.Options
+
+	// Args are the arguments passed to the command
+	args []string
+
+	// OutputFormat is the format to use for output
+	OutputFormat string
+
+	// Color controls whether the diff is colored, one of auto, always or never
+	Color string
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options: opts,
+	}
+}
+
+// NewCmd creates a new diff command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          diffUse,
+		Short:        diffShort,
+		Long:         common.CliLongDesc(diffLong),
+		Example:      common.CliExample(diffExample, common.CliBinName),
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			// we only want two arguments, so don't complete once we have them
+			if len(args) > 1 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// set namespace based on flags
+			namespace := util.GetNamespace(o.KubernetesConfigFlags, false)
+			o.KubernetesConfigFlags.Namespace = &namespace
+
+			// get client
+			client, err := o.Client()
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+
+			// complete secret names, but also allow completing local files
+			suggestions, _ := common.CompleteTicketNames(client, namespace, o.TicketKey, o.DetectTicketKeys, args, toComplete)
+
+			return suggestions, cobra.ShellCompDirectiveDefault
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "diff", fmt.Sprintf("Output format. One of (%s)", common.StringSliceToFlagOptions(diffValidOutputFormats)))
+	cmd.Flags().StringVar(&o.Color, "color", colorAuto, fmt.Sprintf("Color the diff. One of (%s)", common.StringSliceToFlagOptions(diffValidColors)))
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	// parse the arguments
+	o.args = args
+
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, false)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	// validate output format
+	if !slices.Contains(diffValidOutputFormats, o.OutputFormat) {
+		return fmt.Errorf("invalid output format %q. Must be one of (%s)", o.OutputFormat, common.StringSliceToFlagOptions(diffValidOutputFormats))
+	}
+
+	// validate color mode
+	if !slices.Contains(diffValidColors, o.Color) {
+		return fmt.Errorf("invalid color mode %q. Must be one of (%s)", o.Color, common.StringSliceToFlagOptions(diffValidColors))
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	a, err := o.loadTicket(o.args[0])
+	if err != nil {
+		return err
+	}
+
+	b, err := o.loadTicket(o.args[1])
+	if err != nil {
+		return err
+	}
+
+	diffs := diff.Compare(diff.NewSummary(a), diff.NewSummary(b))
+
+	switch o.OutputFormat {
+	case "diff":
+		return diff.PrintDiff(cmd.OutOrStdout(), o.args[0], o.args[1], diffs, o.useColor(cmd.OutOrStdout()))
+	case "json-patch":
+		return diff.PrintJSONPatch(cmd.OutOrStdout(), diffs)
+	default:
+		return fmt.Errorf("invalid output format %q. Must be one of (%s)", o.OutputFormat, common.StringSliceToFlagOptions(diffValidOutputFormats))
+	}
+}
+
+// loadTicket loads the ticket from a local file if it exists, otherwise from the secret given by
+// name or namespace/name
+func (o *options) loadTicket(arg string) (*ticket.Ticket, error) {
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+		bytes, err := util.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		t, _, err := ticket.DecodeMaprTicket(bytes, o.TicketKey, o.DetectTicketKeys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse MapR ticket from file %s: %w", arg, err)
+		}
+
+		return t, nil
+	}
+
+	namespace, name := *o.KubernetesConfigFlags.Namespace, arg
+	if ns, n, found := strings.Cut(arg, "/"); found {
+		namespace, name = ns, n
+	}
+
+	if namespace == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("%q is neither a local file nor a secret given as name or namespace/name", arg)
+	}
+
+	client, err := o.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := o.SelectTicketKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ticket.NewMaprTicketFromSecretKey(secret, key)
+}
+
+// useColor returns true if the diff should be colored. In auto mode, the diff is colored if the
+// output is a terminal and the NO_COLOR environment variable is not set.
+func (o *options) useColor(w io.Writer) bool {
+	switch o.Color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringValues(diffValidOutputFormats, toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = cmd.RegisterFlagCompletionFunc("color", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringValues(diffValidColors, toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package diff_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/create"
+	. "github.com/nobbs/kubectl-mapr-ticket/cmd/diff"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// ticket for cluster demo.mapr.com and user mapr, expiring in 2100
+	demoTicket = "demo.mapr.com HQvWzXnHpDGcBithT6ohjzlVGzHvOUfUC4PK5XNpQ0qi99wP/+GXU6XII5VEK0tQn00HyZCno8YJUrM="
+
+	// same ticket as demoTicket, but for cluster other.mapr.com
+	otherTicket = "other.mapr.com HBgtp02BSkArZ/rmF5a4m4jl7lemA/GAYJb1QkBMWKoe4AORAHK9ZSarTVDqAV3nMCX4QC8suINHpFs="
+)
+
+func TestNewCmd(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	demoFile := writeFile(t, dir, "demo_ticket", demoTicket)
+	otherFile := writeFile(t, dir, "other_ticket", otherTicket)
+
+	// create a secret from the ticket file the same way the create command does and use it as a
+	// cluster dump
+	out, _, err := run(t, "", create.NewCmd, "mapr-ticket-secret", "--from-file", demoFile, "--dry-run=client", "-o", "yaml")
+	assert.NoError(t, err)
+
+	dumpFile := writeFile(t, dir, "dump.yaml", out)
+
+	tests := []struct {
+		name      string
+		args      []string
+		expected  []string
+		unchanged bool
+		wantErr   string
+	}{
+		{
+			name:      "secret created from a file does not differ from the file",
+			args:      []string{"team-a/mapr-ticket-secret", demoFile, "-o", "json-patch"},
+			expected:  []string{"[]\n"},
+			unchanged: true,
+		},
+		{
+			name: "secret in the current namespace shows all fields as unchanged",
+			args: []string{"mapr-ticket-secret", demoFile, "--color", "never"},
+			expected: []string{
+				"--- mapr-ticket-secret\n+++ " + demoFile + "\n",
+				"\n  cluster ",
+				"\n  fingerprint ",
+				"demo.mapr.com\n",
+			},
+			unchanged: true,
+		},
+		{
+			name: "different tickets",
+			args: []string{demoFile, otherFile, "-o", "json-patch"},
+			expected: []string{
+				`"path": "/cluster",`,
+				`"value": "other.mapr.com"`,
+			},
+		},
+		{
+			name:    "invalid secret reference",
+			args:    []string{"team-a/mapr/ticket", demoFile},
+			wantErr: `"team-a/mapr/ticket" is neither a local file nor a secret given as name or namespace/name`,
+		},
+		{
+			name:    "missing secret",
+			args:    []string{"team-a/missing", demoFile},
+			wantErr: `secrets "missing" not found`,
+		},
+		{
+			name:    "invalid output format",
+			args:    []string{demoFile, otherFile, "-o", "yaml"},
+			wantErr: `invalid output format "yaml"`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			out, _, err := run(t, dumpFile, NewCmd, test.args...)
+
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			for _, expected := range test.expected {
+				assert.Contains(t, out, expected)
+			}
+
+			// unchanged fields are never printed as removed or added
+			if test.unchanged {
+				assert.NotContains(t, out, "\n- ")
+				assert.NotContains(t, out, "\n+ ")
+			}
+		})
+	}
+}
+
+// run executes the command created by newCmd in namespace team-a with the given arguments, reading
+// objects from the given cluster dump, and returns its output
+func run(t *testing.T, dumpFile string, newCmd func(*common.Options) *cobra.Command, args ...string) (string, string, error) {
+	t.Helper()
+
+	streams, _, out, errOut := genericiooptions.NewTestIOStreams()
+
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.Namespace = ptr.To("team-a")
+
+	opts := common.NewOptions(flags, streams)
+	opts.FromDump = dumpFile
+
+	cmd := newCmd(opts)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+
+	return out.String(), errOut.String(), err
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
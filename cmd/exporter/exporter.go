@@ -0,0 +1,191 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package exporter provides the exporter command for the application.
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/exporter"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+)
+
+// command string constants for use in help and usage text
+const (
+	exporterUse   = `exporter`
+	exporterShort = "Run a Prometheus exporter exposing MapR ticket expiry metrics"
+	exporterLong  = `
+		Run a long-running Prometheus exporter exposing metrics about the MapR tickets
+		deployed as secrets.
+
+		The secrets are listed periodically and the following metrics are served on
+		/metrics, all of them labeled by namespace, secret, data key of the ticket, MapR
+		cluster and user:
+
+		  mapr_ticket_expiry_timestamp_seconds    expiration time of the ticket
+		  mapr_ticket_creation_timestamp_seconds  creation time of the ticket
+		  mapr_ticket_expired                     whether the ticket has expired
+		  mapr_ticket_pv_count                    number of persistent volumes using the ticket
+
+		Secrets containing a ticket that can't be parsed are exposed as
+		mapr_ticket_invalid, labeled by namespace, secret and data key only.
+		`
+	exporterExample = `
+		# Export the tickets of all namespaces on port 9090
+		%[1]s exporter --all-namespaces --listen :9090
+
+		# Export the tickets of a single namespace and refresh them every 10 minutes
+		%[1]s exporter --namespace kube-system --interval 10m
+		`
+)
+
+const (
+	// defaultRefreshInterval is the default interval between two refreshes of the metrics
+	defaultRefreshInterval = time.Minute
+
+	// shutdownTimeout is the maximum time to wait for open connections on shutdown
+	shutdownTimeout = 5 * time.Second
+)
+
+type options struct {
+	*common.Options
+
+	// AllNamespaces indicates whether to export secrets in all namespaces
+	AllNamespaces bool
+
+	// ListenAddress is the address the metrics server listens on
+	ListenAddress string
+
+	// Interval is the interval between two refreshes of the metrics
+	Interval common.DurationValue
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:       opts,
+		ListenAddress: ":9090",
+		Interval:      common.DurationValue(defaultRefreshInterval),
+	}
+}
+
+// NewCmd creates a new exporter command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          exporterUse,
+		Short:        exporterShort,
+		Long:         common.CliLongDesc(exporterLong),
+		Example:      common.CliExample(exporterExample, common.CliBinName),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If true, export secrets across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().StringVar(&o.ListenAddress, "listen", o.ListenAddress, "Address the metrics server listens on")
+	cmd.Flags().Var(&o.Interval, "interval", "Interval between two refreshes of the ticket metrics")
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	if o.ListenAddress == "" {
+		return fmt.Errorf("listen address must not be empty")
+	}
+
+	if o.Interval.Duration() <= 0 {
+		return fmt.Errorf("invalid interval %q, must be greater than zero", o.Interval.String())
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	e := exporter.NewExporter(client, *o.KubernetesConfigFlags.Namespace, o.SecretListerOptions()...)
+	go e.Run(ctx, o.Interval.Duration())
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              o.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to shut down metrics server", "error", err)
+		}
+	}()
+
+	slog.Info("serving ticket metrics", "address", o.ListenAddress, "interval", o.Interval.String())
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
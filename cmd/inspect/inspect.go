@@ -101,12 +101,12 @@ func NewCmd(opts *common.Options) *cobra.Command {
 			o.KubernetesConfigFlags.Namespace = &namespace
 
 			// get client
-			client, err := util.ClientFromFlags(o.KubernetesConfigFlags)
+			client, err := o.Client()
 			if err != nil {
 				return nil, cobra.ShellCompDirectiveError
 			}
 
-			return common.CompleteTicketNames(client, namespace, args, toComplete)
+			return common.CompleteTicketNames(client, namespace, o.TicketKey, o.DetectTicketKeys, args, toComplete)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := o.Complete(cmd, args); err != nil {
@@ -194,7 +194,7 @@ func (o *options) Run(cmd *cobra.Command, args []string) error {
 
 // inspectSecret inspects a MapR ticket read from a secret in a Kubernetes cluster
 func (o *options) inspectSecret() error {
-	client, err := util.ClientFromFlags(o.KubernetesConfigFlags)
+	client, err := o.Client()
 	if err != nil {
 		return err
 	}
@@ -206,7 +206,12 @@ func (o *options) inspectSecret() error {
 	}
 
 	// get ticket from secret
-	ticket, err := ticket.NewMaprTicketFromSecret(secret)
+	key, err := o.SelectTicketKey(secret)
+	if err != nil {
+		return err
+	}
+
+	ticket, err := ticket.NewMaprTicketFromSecretKey(secret, key)
 	if err != nil {
 		return err
 	}
@@ -227,7 +232,7 @@ func (o *options) inspectFile() error {
 	}
 
 	// get ticket from file
-	ticket, err := ticket.NewMaprTicketFromBytes(bytes)
+	ticket, _, err := ticket.DecodeMaprTicket(bytes, o.TicketKey, o.DetectTicketKeys)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,259 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package label provides the label command for the application.
+package label
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/label"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// command string constants for use in help and usage text
+const (
+	labelUse   = `label [SECRET]`
+	labelShort = "Label existing secrets containing a MapR ticket for faster listing"
+	labelLong  = `
+		Label existing secrets containing a MapR ticket with the label
+		` + ticket.TicketSelector + `.
+
+		Secrets created or rotated by this plugin are labeled automatically. Secrets created
+		by other means can be labeled using this command, either a single secret given by
+		name or all secrets containing a MapR ticket in the namespace. Once labeled, listing
+		commands only retrieve the labeled secrets instead of the data of all secrets in the
+		namespace, which reduces load and memory usage on large clusters. Only the label is
+		patched, the data of the secrets is left untouched.
+		`
+	labelExample = `
+		# Label all secrets containing a MapR ticket in the current namespace
+		%[1]s label
+
+		# Label all secrets containing a MapR ticket in all namespaces
+		%[1]s label --all-namespaces
+
+		# Label a specific secret
+		%[1]s label mapr-ticket-secret
+
+		# Show which secrets would be labeled without labeling them
+		%[1]s label --all-namespaces --dry-run=client
+		`
+)
+
+type options struct {
+	*common.Options
+
+	// PrintFlags are the flags used to configure the output format
+	PrintFlags *genericclioptions.PrintFlags
+
+	// SecretName is the name of the secret to label, or util.SecretAll to label all secrets
+	SecretName string
+
+	// AllNamespaces indicates whether to label secrets in all namespaces
+	AllNamespaces bool
+
+	// DryRun is the raw value of the dry-run flag
+	DryRun string
+
+	// DryRunStrategy is the parsed dry run strategy
+	DryRunStrategy common.DryRunStrategy
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:    opts,
+		PrintFlags: genericclioptions.NewPrintFlags("labeled").WithTypeSetter(scheme.Scheme),
+	}
+}
+
+// NewCmd creates a new label command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          labelUse,
+		Short:        labelShort,
+		Long:         common.CliLongDesc(labelLong),
+		Example:      common.CliExample(labelExample, common.CliBinName),
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			// we only want one argument, so don't complete once we have one
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// set namespace based on flags
+			namespace := util.GetNamespace(o.KubernetesConfigFlags, false)
+			o.KubernetesConfigFlags.Namespace = &namespace
+
+			// get client
+			client, err := o.Client()
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+
+			return common.CompleteTicketNames(client, namespace, o.TicketKey, o.DetectTicketKeys, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	o.PrintFlags.AddFlags(cmd)
+	common.AddDryRunFlag(cmd, &o.DryRun)
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If true, label the secrets across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) > 0 {
+		o.SecretName = args[0]
+	} else {
+		o.SecretName = util.SecretAll
+	}
+
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	// parse dry run strategy
+	if o.DryRunStrategy, err = common.ParseDryRunStrategy(o.DryRun); err != nil {
+		return err
+	}
+
+	// adapt the success message of the name printer if we are not persisting anything
+	if o.DryRunStrategy != common.DryRunNone {
+		if err := o.PrintFlags.Complete("%s (dry run)"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	if o.SecretName == "" {
+		return fmt.Errorf("secret name must not be empty")
+	}
+
+	if o.AllNamespaces && o.SecretName != util.SecretAll {
+		return fmt.Errorf("a secret name can't be used together with --all-namespaces")
+	}
+
+	if o.FromDump != "" {
+		return fmt.Errorf("--from-dump can't be used to label secrets, a cluster dump is read-only")
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	labelerOpts := []label.Option{
+		label.WithTicketKey(o.TicketKey),
+		label.WithChunkSize(o.ChunkSize),
+		label.WithDryRun(o.DryRunStrategy.ServerDryRun()),
+	}
+
+	if o.DetectTicketKeys {
+		labelerOpts = append(labelerOpts, label.WithDetectTicketKeys())
+	}
+
+	labeler := label.NewLabeler(client, *o.KubernetesConfigFlags.Namespace, labelerOpts...)
+
+	secrets, err := labeler.Unlabeled(o.SecretName)
+	if err != nil {
+		return err
+	}
+
+	if len(secrets) == 0 {
+		fmt.Fprintln(o.IOStreams.ErrOut, "No unlabeled secrets containing a MapR ticket found.")
+		return nil
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	for i := range secrets {
+		var labeled *coreV1.Secret
+
+		// only send the patch to the server if we are not doing a client side dry run
+		if o.DryRunStrategy == common.DryRunClient {
+			labeled = label.Mark(&secrets[i])
+		} else if labeled, err = labeler.Label(&secrets[i]); err != nil {
+			return err
+		}
+
+		if err := printer.PrintObj(labeled, o.IOStreams.Out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringValues(o.PrintFlags.AllowedFormats(), toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = cmd.RegisterFlagCompletionFunc("dry-run", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteDryRunValues(toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
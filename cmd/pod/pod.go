@@ -0,0 +1,243 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package pod provides the pod command for the application.
+package pod
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/claim"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/pod"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+)
+
+// command string constants for use in help and usage text
+const (
+	podUse   = `pod`
+	podShort = "List all pods that mount a volume using a MapR ticket in the current namespace"
+	podLong  = `
+		List all pods that mount a persistent volume claim bound to a MapR CSI based
+		persistent volume in the current namespace, or that use one of the MapR CSI
+		drivers through an inline CSI volume, together with the MapR ticket used by
+		that volume.
+
+		Pods mounting multiple such volumes are listed once per volume. The controller
+		column shows the workload owning the pod, e.g. the Deployment instead of the
+		ReplicaSet, making it easy to tell which workloads break once a ticket expires.
+		`
+	podExample = `
+		# List all pods in the current namespace that mount a volume using a MapR ticket
+		%[1]s pod
+
+		# List all pods in all namespaces that mount a volume using a MapR ticket
+		%[1]s pod --all-namespaces
+
+		# List all pods in all namespaces, sorted by the expiration date of the MapR ticket
+		%[1]s pod --all-namespaces --sort-by expiration
+
+		# List all pods including the persistent volume and secret used as a JSON list
+		%[1]s pod --output json
+
+		# Print the controllers of all pods using an expired MapR ticket using a Go template
+		%[1]s pod -o go-template='{{range .items}}{{if .ticket.expired}}{{.controller.kind}}/{{.controller.name}}{{"\n"}}{{end}}{{end}}'
+		`
+)
+
+type options struct {
+	*common.Options
+
+	// PrintFlags are the flags used to configure the output format
+	PrintFlags *common.PrintFlags
+
+	// SelectorOptions are the label and field selectors used to select the pods to list
+	SelectorOptions *common.SelectorOptions
+
+	// AllNamespaces indicates whether to list pods in all namespaces
+	AllNamespaces bool
+
+	// SortBy is the list of fields to sort by
+	SortBy []string
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:         opts,
+		PrintFlags:      common.NewPrintFlags(),
+		SelectorOptions: common.NewSelectorOptions(),
+	}
+}
+
+// NewCmd creates a new pod command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Aliases: []string{"po"},
+		Use:     podUse,
+		Short:   podShort,
+		Long:    common.CliLongDesc(podLong),
+		Example: common.CliExample(podExample, common.CliBinName),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	o.PrintFlags.AddFlags(cmd)
+	o.SelectorOptions.AddFlags(cmd, "pods")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "List pods that mount a volume using a MapR ticket in all namespaces")
+	cmd.Flags().StringSliceVar(&o.SortBy, "sort-by", []string{}, fmt.Sprintf("Sort list of pods by the specified fields. One or more of (%s)", common.StringSliceToFlagOptions(pod.SortOptionsList)))
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	// validate output format
+	if err := o.PrintFlags.Validate(); err != nil {
+		return err
+	}
+
+	// validate label and field selectors
+	if err := o.SelectorOptions.Validate(); err != nil {
+		return err
+	}
+
+	// ensure that the sort options are valid
+	if err := util.ValidateSortOptions(pod.SortOptionsList, o.SortBy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	// create secret and claim listers used to resolve the claims mounted by the pods
+	secretLister := secret.NewLister(
+		client,
+		util.NamespaceAll,
+		o.SecretListerOptions()...,
+	)
+
+	claimLister := claim.NewLister(
+		client,
+		*o.KubernetesConfigFlags.Namespace,
+		claim.WithSecretLister(secretLister),
+	)
+
+	// create list options and pass them to the lister
+	opts := []pod.ListerOption{
+		pod.WithClaimLister(claimLister),
+		pod.WithSecretLister(secretLister),
+	}
+
+	// only list the pods matching the selectors
+	if o.SelectorOptions.LabelSelector != "" {
+		opts = append(opts, pod.WithLabelSelector(o.SelectorOptions.LabelSelector))
+	}
+
+	if o.SelectorOptions.FieldSelector != "" {
+		opts = append(opts, pod.WithFieldSelector(o.SelectorOptions.FieldSelector))
+	}
+
+	// set sort options
+	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
+		sortOptions := make([]pod.SortOption, 0, len(o.SortBy))
+		for _, sortBy := range o.SortBy {
+			sortOptions = append(sortOptions, pod.SortOption(sortBy))
+		}
+
+		opts = append(opts, pod.WithSortBy(sortOptions))
+	}
+
+	// create lister
+	lister := pod.NewLister(
+		client,
+		*o.KubernetesConfigFlags.Namespace,
+		opts...,
+	)
+
+	// run lister
+	pods, err := lister.List()
+	if err != nil {
+		return err
+	}
+
+	// print output, either as a table or using one of the structured printers
+	if o.PrintFlags.IsTableFormat() {
+		return pod.Print(cmd, pods)
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	return pod.PrintObjects(cmd, printer, pods)
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return o.PrintFlags.CompleteOutputFormats(toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = cmd.RegisterFlagCompletionFunc("sort-by", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringSliceValues(pod.SortOptionsList, toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
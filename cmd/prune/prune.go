@@ -0,0 +1,295 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package prune provides the prune command for the application.
+package prune
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/prune"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// command string constants for use in help and usage text
+const (
+	pruneUse   = `prune`
+	pruneShort = "Delete secrets containing MapR tickets that are not in use"
+	pruneLong  = `
+		Find secrets containing MapR tickets that are not used by any persistent volume,
+		inline volume of a pod or StorageClass and optionally delete them. Secrets referenced
+		by inline volumes in the pod templates of Deployments, StatefulSets, DaemonSets,
+		ReplicaSets, Jobs or CronJobs are kept, even if the workload currently has no pods.
+
+		The secrets planned for deletion are always printed first. Nothing is deleted unless
+		--confirm is given. Use --only-expired to only consider secrets whose tickets are all
+		expired and --older-than to only consider secrets created before the given duration.
+		A secret containing several tickets is only deleted if none of them is in use.
+
+		Before deleting, a manifest of the planned secrets is written to the file given by
+		--backup-file, which can be used to restore them using kubectl apply. Secrets that
+		are modified after the plan was made are not deleted.
+		`
+	pruneExample = `
+		# Show the unused secrets containing a MapR ticket in the current namespace
+		%[1]s prune
+
+		# Delete the unused secrets containing an expired MapR ticket in all namespaces
+		%[1]s prune --all-namespaces --only-expired --confirm
+
+		# Delete the unused secrets created more than 90 days ago and write the backup to a given file
+		%[1]s prune --older-than 90d --confirm --backup-file backup.yaml
+
+		# Validate the deletion of the unused secrets against the API server without deleting them
+		%[1]s prune --all-namespaces --dry-run=server
+		`
+)
+
+const (
+	// backupFileFormat is the format of the default backup file name, filled with the current time
+	backupFileFormat = "mapr-ticket-prune-backup-%s.yaml"
+
+	// backupFileTimeLayout is the time layout used in the default backup file name
+	backupFileTimeLayout = "20060102-150405"
+)
+
+type options struct {
+	*common.Options
+
+	// PrintFlags are the flags used to configure the output of deleted secrets
+	PrintFlags *genericclioptions.PrintFlags
+
+	// AllNamespaces indicates whether to prune secrets in all namespaces
+	AllNamespaces bool
+
+	// OnlyExpired indicates whether to only prune secrets whose tickets are all expired
+	OnlyExpired bool
+
+	// OlderThan is the minimum age of secrets to be pruned
+	OlderThan common.DurationValue
+
+	// Confirm indicates whether the planned secrets should actually be deleted
+	Confirm bool
+
+	// BackupFile is the path of the file the backup manifest is written to
+	BackupFile string
+
+	// DryRun is the raw value of the dry-run flag
+	DryRun string
+
+	// DryRunStrategy is the parsed dry run strategy
+	DryRunStrategy common.DryRunStrategy
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:    opts,
+		PrintFlags: genericclioptions.NewPrintFlags("deleted").WithTypeSetter(scheme.Scheme),
+	}
+}
+
+// NewCmd creates a new prune command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          pruneUse,
+		Short:        pruneShort,
+		Long:         common.CliLongDesc(pruneLong),
+		Example:      common.CliExample(pruneExample, common.CliBinName),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	common.AddDryRunFlag(cmd, &o.DryRun)
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If true, prune the secrets across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+	cmd.Flags().BoolVar(&o.OnlyExpired, "only-expired", false, "Only prune secrets whose MapR tickets are all expired")
+	cmd.Flags().Var(&o.OlderThan, "older-than", "Only prune secrets created before the specified duration from now")
+	cmd.Flags().BoolVar(&o.Confirm, "confirm", false, "Delete the planned secrets. Without this flag, the plan is only printed")
+	cmd.Flags().StringVar(&o.BackupFile, "backup-file", "", "File to write the backup manifest of the deleted secrets to. Defaults to mapr-ticket-prune-backup-<timestamp>.yaml in the current directory")
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	// parse dry run strategy
+	if o.DryRunStrategy, err = common.ParseDryRunStrategy(o.DryRun); err != nil {
+		return err
+	}
+
+	// adapt the success message of the name printer if we are not persisting anything
+	if o.DryRunStrategy != common.DryRunNone {
+		if err := o.PrintFlags.Complete("%s (dry run)"); err != nil {
+			return err
+		}
+	}
+
+	if o.BackupFile == "" {
+		o.BackupFile = fmt.Sprintf(backupFileFormat, time.Now().Format(backupFileTimeLayout))
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	if o.OlderThan.Duration() < 0 {
+		return fmt.Errorf("--older-than must not be negative")
+	}
+
+	if o.FromDump != "" && (o.Confirm || o.DryRunStrategy != common.DryRunNone) {
+		return fmt.Errorf("--from-dump can't be used to prune secrets, a cluster dump is read-only")
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	secretOpts := append(o.SecretListerOptions(), secret.WithShowInUse())
+	secretOpts = append(secretOpts, common.InUseListerOptions(client)...)
+
+	pruneOpts := []prune.Option{
+		prune.WithOlderThan(o.OlderThan.Duration()),
+		prune.WithDryRun(o.DryRunStrategy.ServerDryRun()),
+	}
+
+	if o.OnlyExpired {
+		pruneOpts = append(pruneOpts, prune.WithOnlyExpired())
+	}
+
+	pruner := prune.NewPruner(
+		client,
+		secret.NewLister(client, *o.KubernetesConfigFlags.Namespace, secretOpts...),
+		pruneOpts...,
+	)
+
+	candidates, err := pruner.Plan()
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		fmt.Fprintln(o.IOStreams.ErrOut, "No unused secrets containing a MapR ticket found.")
+		return nil
+	}
+
+	if err := prune.Print(cmd, candidates); err != nil {
+		return err
+	}
+
+	if !o.Confirm && o.DryRunStrategy == common.DryRunNone {
+		fmt.Fprintf(o.IOStreams.ErrOut, "\n%d secret(s) would be deleted. Run again with --confirm to delete them.\n", len(candidates))
+		return nil
+	}
+
+	// only write a backup if the secrets are actually deleted
+	if o.DryRunStrategy == common.DryRunNone {
+		if err := o.writeBackup(candidates); err != nil {
+			return err
+		}
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	for i := range candidates {
+		// only send the deletion to the server if we are not doing a client side dry run
+		if o.DryRunStrategy != common.DryRunClient {
+			if err := pruner.Delete(&candidates[i]); err != nil {
+				return fmt.Errorf("failed to delete secret %s/%s: %w", candidates[i].Secret.Namespace, candidates[i].Secret.Name, err)
+			}
+		}
+
+		if err := printer.PrintObj(candidates[i].Secret, o.IOStreams.Out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeBackup writes the backup manifest of the candidates to the backup file. An existing file
+// is never overwritten.
+func (o *options) writeBackup(candidates []prune.Candidate) error {
+	f, err := os.OpenFile(o.BackupFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer f.Close()
+
+	if err := prune.WriteBackup(f, candidates); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+
+	fmt.Fprintf(o.IOStreams.ErrOut, "\nWrote backup of %d secret(s) to %s\n", len(candidates), o.BackupFile)
+
+	return nil
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("dry-run", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteDryRunValues(toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
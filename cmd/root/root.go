@@ -11,12 +11,25 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/audit"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/check"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/claim"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/controller"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/create"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/diff"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/exporter"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/inspect"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/label"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/pod"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/prune"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/rotate"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/storageclass"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/version"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/volume"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/webhook"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -58,19 +71,40 @@ func NewCmd(flags *genericclioptions.ConfigFlags, streams genericiooptions.IOStr
 
 	// add own global flags
 	rootCmd.PersistentFlags().BoolVar(&o.Debug, "debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().StringVar(&o.TicketKey, "ticket-key", ticket.SecretMaprTicketKey, "Data key of the secrets the MapR ticket is stored under")
+	rootCmd.PersistentFlags().BoolVar(&o.DetectTicketKeys, "detect-ticket-keys", false, "If true, try to parse every data key of the secrets as a MapR ticket and list secrets holding several tickets once per ticket")
+	rootCmd.PersistentFlags().Int64Var(&o.ChunkSize, "chunk-size", common.DefaultChunkSize, "Return large lists of secrets in chunks rather than all at once. Pass 0 to disable.")
+	rootCmd.PersistentFlags().BoolVar(&o.LabeledOnly, "labeled-only", false, "If true, only retrieve secrets labeled as containing a MapR ticket (see the label command), falling back to retrieving all secrets if none are labeled")
+	rootCmd.PersistentFlags().StringVar(&o.FromDump, "from-dump", "", "Read objects from a cluster dump instead of the API server. Either a YAML or JSON file, a directory or a .tar.gz archive containing such files")
+
+	if err := rootCmd.MarkPersistentFlagFilename("from-dump"); err != nil {
+		panic(err)
+	}
 
 	// add subcommands
 	rootCmd.AddCommand(
+		audit.NewCmd(o),
+		check.NewCmd(o),
 		claim.NewCmd(o),
+		controller.NewCmd(o),
+		create.NewCmd(o),
+		diff.NewCmd(o),
+		exporter.NewCmd(o),
 		inspect.NewCmd(o),
+		label.NewCmd(o),
+		pod.NewCmd(o),
+		prune.NewCmd(o),
+		rotate.NewCmd(o),
 		secret.NewCmd(o),
+		storageclass.NewCmd(o),
 		version.NewCmd(o),
 		volume.NewCmd(o),
+		webhook.NewCmd(o),
 	)
 
 	// add completions
 	err := rootCmd.RegisterFlagCompletionFunc("namespace", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		client, err := util.ClientFromFlags(o.KubernetesConfigFlags)
+		client, err := o.Client()
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveError
 		}
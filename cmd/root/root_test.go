@@ -10,13 +10,25 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/audit"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/check"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/claim"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/controller"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/create"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/diff"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/exporter"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/inspect"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/label"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/pod"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/prune"
 	. "github.com/nobbs/kubectl-mapr-ticket/cmd/root"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/rotate"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/storageclass"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/version"
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/volume"
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/webhook"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
@@ -71,11 +83,23 @@ func TestNewCmd(t *testing.T) {
 		// check if all subcommands are registered
 		assert.ElementsMatch(t,
 			[]string{
+				audit.NewCmd(opts).Use,
+				check.NewCmd(opts).Use,
 				claim.NewCmd(opts).Use,
+				controller.NewCmd(opts).Use,
+				create.NewCmd(opts).Use,
+				diff.NewCmd(opts).Use,
+				exporter.NewCmd(opts).Use,
 				inspect.NewCmd(opts).Use,
+				label.NewCmd(opts).Use,
+				pod.NewCmd(opts).Use,
+				prune.NewCmd(opts).Use,
+				rotate.NewCmd(opts).Use,
 				secret.NewCmd(opts).Use,
+				storageclass.NewCmd(opts).Use,
 				version.NewCmd(opts).Use,
 				volume.NewCmd(opts).Use,
+				webhook.NewCmd(opts).Use,
 			},
 			func() (cmdCommandsUse []string) {
 				for _, c := range cmd.Commands() {
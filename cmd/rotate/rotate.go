@@ -0,0 +1,304 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package rotate provides the rotate command for the application.
+package rotate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/rotate"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// command string constants for use in help and usage text
+const (
+	rotateUse   = `rotate SECRET --from-file FILE`
+	rotateShort = "Replace the MapR ticket in an existing secret with a renewed ticket"
+	rotateLong  = `
+		Replace the MapR ticket in an existing secret with a renewed ticket read from a
+		local file.
+
+		The new ticket must be for the same MapR cluster and user as the current ticket and
+		must expire later than the current ticket. After the secret has been updated, all
+		persistent volumes and claims using the secret and the pods mounting them or an
+		inline volume using the secret are listed, as these pods may need to be restarted
+		to pick up the new ticket. If listing them is not allowed, a warning is logged
+		instead.
+		`
+	rotateExample = `
+		# Replace the ticket in a secret in the current namespace
+		%[1]s rotate mapr-ticket-secret --from-file ./maprticket
+
+		# Show the differences between the current and the new ticket without updating the secret
+		%[1]s rotate mapr-ticket-secret --from-file ./maprticket --diff
+
+		# Print the updated secret manifest instead of updating the secret
+		%[1]s rotate mapr-ticket-secret --from-file ./maprticket --dry-run=client -o yaml
+		`
+)
+
+type options struct {
+	*common.Options
+
+	// PrintFlags are the flags used to configure the output format
+	PrintFlags *genericclioptions.PrintFlags
+
+	// SecretName is the name of the secret to update
+	SecretName string
+
+	// File is the path to the new MapR ticket file
+	File string
+
+	// Diff indicates whether to only show the differences between the current and the new ticket
+	Diff bool
+
+	// DryRun is the raw value of the dry-run flag
+	DryRun string
+
+	// DryRunStrategy is the parsed dry run strategy
+	DryRunStrategy common.DryRunStrategy
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:    opts,
+		PrintFlags: genericclioptions.NewPrintFlags("rotated").WithTypeSetter(scheme.Scheme),
+	}
+}
+
+// NewCmd creates a new rotate command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          rotateUse,
+		Short:        rotateShort,
+		Long:         common.CliLongDesc(rotateLong),
+		Example:      common.CliExample(rotateExample, common.CliBinName),
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			// we only want one argument, so don't complete once we have one
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			// set namespace based on flags
+			namespace := util.GetNamespace(o.KubernetesConfigFlags, false)
+			o.KubernetesConfigFlags.Namespace = &namespace
+
+			// get client
+			client, err := o.Client()
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+
+			return common.CompleteTicketNames(client, namespace, o.TicketKey, o.DetectTicketKeys, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	o.PrintFlags.AddFlags(cmd)
+	common.AddDryRunFlag(cmd, &o.DryRun)
+	cmd.Flags().StringVarP(&o.File, "from-file", "f", "", "Path to the new MapR ticket file")
+	cmd.Flags().BoolVar(&o.Diff, "diff", false, "Only show the differences between the current and the new ticket and the affected volumes, claims and pods, without updating the secret")
+
+	if err := cmd.MarkFlagRequired("from-file"); err != nil {
+		panic(err)
+	}
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	o.SecretName = args[0]
+
+	// set namespace based on flags
+	ns := util.GetNamespace(o.KubernetesConfigFlags, false)
+	o.KubernetesConfigFlags.Namespace = &ns
+
+	// parse dry run strategy
+	if o.DryRunStrategy, err = common.ParseDryRunStrategy(o.DryRun); err != nil {
+		return err
+	}
+
+	// adapt the success message of the name printer if we are not persisting anything
+	if o.DryRunStrategy != common.DryRunNone {
+		if err := o.PrintFlags.Complete("%s (dry run)"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	if o.SecretName == "" {
+		return fmt.Errorf("secret name must not be empty")
+	}
+
+	if o.File == "" {
+		return fmt.Errorf("a MapR ticket file must be provided via --from-file")
+	}
+
+	if o.Diff && o.DryRunStrategy != common.DryRunNone {
+		return fmt.Errorf("--diff and --dry-run can't be used together, --diff never updates the secret")
+	}
+
+	if o.FromDump != "" && !o.Diff {
+		return fmt.Errorf("--from-dump can only be used together with --diff, a cluster dump is read-only")
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	replacement, err := util.ReadFile(o.File)
+	if err != nil {
+		return err
+	}
+
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	secret, err := client.CoreV1().Secrets(*o.KubernetesConfigFlags.Namespace).Get(context.TODO(), o.SecretName, metaV1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	key, err := o.SelectTicketKey(secret)
+	if err != nil {
+		return err
+	}
+
+	rotation, err := rotate.NewRotation(secret, key, replacement)
+	if err != nil {
+		return err
+	}
+
+	// in diff mode, only show what would change
+	if o.Diff {
+		if err := rotate.PrintDiff(o.IOStreams.Out, rotation); err != nil {
+			return err
+		}
+
+		if !collectAffected(client, rotation) {
+			return nil
+		}
+
+		return rotate.PrintAffected(o.IOStreams.Out, rotation)
+	}
+
+	updated := rotation.Updated
+
+	// only send the secret to the server if we are not doing a client side dry run
+	if o.DryRunStrategy != common.DryRunClient {
+		updated, err = client.CoreV1().Secrets(updated.Namespace).Update(context.TODO(), updated, metaV1.UpdateOptions{
+			DryRun: o.DryRunStrategy.ServerDryRun(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	if err := printer.PrintObj(updated, o.IOStreams.Out); err != nil {
+		return err
+	}
+
+	// the secret is already updated, so the affected volumes, claims and pods are only reported if
+	// they can be listed
+	if !collectAffected(client, rotation) {
+		return nil
+	}
+
+	// report the affected volumes, claims and pods on stderr, so the printed secret stays parsable
+	return rotate.PrintAffected(o.IOStreams.ErrOut, rotation)
+}
+
+// collectAffected collects the persistent volumes, claims and pods affected by the rotation. As
+// users allowed to update the secret may not be allowed to list them cluster-wide, failing to
+// collect them only logs a warning. It returns false if the affected objects could not be collected.
+func collectAffected(client kubernetes.Interface, rotation *rotate.Rotation) bool {
+	err := rotation.CollectAffected(client)
+
+	switch {
+	case err == nil:
+		return true
+	case apiErrors.IsForbidden(err):
+		slog.Warn("not allowed to list the persistent volumes, claims and pods using the secret, pods mounting it may need to be restarted", "error", err)
+	default:
+		slog.Warn("failed to list the persistent volumes, claims and pods using the secret, pods mounting it may need to be restarted", "error", err)
+	}
+
+	return false
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringValues(o.PrintFlags.AllowedFormats(), toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = cmd.RegisterFlagCompletionFunc("dry-run", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteDryRunValues(toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
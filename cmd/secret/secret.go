@@ -7,16 +7,22 @@
 package secret
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
-	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
 
-	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes"
 )
 
 // command string constants for use in help and usage text
@@ -37,27 +43,61 @@ const (
 		# List only expired MapR tickets
 		%[1]s secret --only-expired
 
+		# List only secrets containing MapR tickets that can't be parsed
+		%[1]s secret --only-invalid --all-namespaces
+
 		# List only MapR tickets that expire in the next 7 days
 		%[1]s secret --expires-before 7d
 
+		# List only MapR tickets in secrets labelled with team=a in all namespaces
+		%[1]s secret --selector team=a --all-namespaces
+
 		# List MapR tickets for a specific MapR user in all namespaces
 		%[1]s secret --mapr-user mapr --all-namespaces
 
 		# List MapR tickets with number of persistent volumes that use them
 		%[1]s secret --show-in-use
-		`
-)
 
-var (
-	// valid output formats for the command
-	secretValidOutputFormats = []string{"table", "wide"}
+		# List MapR tickets used by the most persistent volumes first, then by expiration
+		%[1]s secret --show-in-use --sort-by -npvcs,expiration
+
+		# List all MapR tickets in all namespaces as a JSON list
+		%[1]s secret --all-namespaces --output json
+
+		# Print the MapR cluster and user of all MapR tickets using a JSONPath expression
+		%[1]s secret -o jsonpath='{range .items[*]}{.secret.name}{"\t"}{.ticket.cluster}{"\t"}{.ticket.user}{"\n"}{end}'
+
+		# List all MapR tickets with custom columns
+		%[1]s secret -o custom-columns=NAME:.secret.name,USER:.ticket.user,EXPIRES:.ticket.expirationTime
+
+		# List all MapR tickets in all namespaces and keep watching for changes
+		%[1]s secret --all-namespaces --watch --output-watch-events
+
+		# List only MapR tickets of the users a or b expiring within 3 days in namespaces starting with team-
+		%[1]s secret --all-namespaces --filter "ticket.user in ['a', 'b'] && ticket.expiresIn < duration('72h') && secret.namespace.startsWith('team-')"
+
+		# List all MapR tickets in all namespaces of all kubeconfig contexts
+		%[1]s secret --all-namespaces --all-contexts
+
+		# List all MapR tickets in the current namespace of the contexts "prod" and "staging"
+		%[1]s secret --context prod,staging
+		`
 )
 
 type options struct {
 	*common.Options
 
-	// OutputFormat is the format to use for output
-	OutputFormat string
+	// PrintFlags are the flags used to configure the output format
+	PrintFlags *common.PrintFlags
+
+	// SelectorOptions are the label and field selectors used to select the secrets to list
+	SelectorOptions *common.SelectorOptions
+
+	// FilterOptions are the options used to filter the listed secrets using a CEL expression
+	FilterOptions *common.FilterOptions
+
+	// ContextOptions are the options used to list secrets across multiple kubeconfig contexts
+	ContextOptions *common.ContextOptions
 
 	// AllNamespaces indicates whether to list secrets in all namespaces
 	AllNamespaces bool
@@ -73,6 +113,10 @@ type options struct {
 	// that have not expired
 	FilterOnlyUnexpired bool
 
+	// FilterOnlyInvalid indicates whether to filter secrets to only those
+	// containing a ticket that can't be parsed
+	FilterOnlyInvalid bool
+
 	// FilterByMaprCluster indicates whether to filter secrets to only those
 	// that have a ticket for the specified MapR cluster
 	FilterByMaprCluster string
@@ -100,11 +144,23 @@ type options struct {
 	// ShowInUse indicates whether to show only secrets that are in use by a
 	// persistent volume
 	ShowInUse bool
+
+	// Watch indicates whether to keep watching for changes after listing the
+	// secrets
+	Watch bool
+
+	// OutputWatchEvents indicates whether to include the event type in the
+	// output while watching
+	OutputWatchEvents bool
 }
 
 func newOptions(opts *common.Options) *options {
 	return &options{
-		Options: opts,
+		Options:         opts,
+		PrintFlags:      common.NewPrintFlags(),
+		SelectorOptions: common.NewSelectorOptions(),
+		FilterOptions:   common.NewFilterOptions(filter.SecretVariables),
+		ContextOptions:  common.NewContextOptions(),
 	}
 }
 
@@ -145,19 +201,25 @@ func NewCmd(opts *common.Options) *cobra.Command {
 	cmd.SetErr(o.IOStreams.ErrOut)
 
 	// add flags
-	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "table", fmt.Sprintf("Output format. One of (%s)", common.StringSliceToFlagOptions(secretValidOutputFormats)))
+	o.PrintFlags.AddFlags(cmd)
+	o.SelectorOptions.AddFlags(cmd, "secrets")
+	o.FilterOptions.AddFlags(cmd, "secrets")
+	o.ContextOptions.AddFlags(cmd)
 	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If true, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
-	cmd.Flags().StringSliceVar(&o.SortBy, "sort-by", nil, fmt.Sprintf("Sort list of secrets by the specified fields. One of (%s)", common.StringSliceToFlagOptions(secret.SortOptionsList)))
+	cmd.Flags().StringSliceVar(&o.SortBy, "sort-by", nil, fmt.Sprintf("Sort list of secrets by the specified fields, prefix a field with '-' or suffix it with ':desc' to sort in descending order. One of (%s)", common.StringSliceToFlagOptions(secret.SortOptionsList)))
 	cmd.Flags().BoolVarP(&o.FilterOnlyExpired, "only-expired", "E", false, "If true, only show secrets with tickets that have expired")
 	cmd.Flags().BoolVarP(&o.FilterOnlyUnexpired, "only-unexpired", "U", false, "If true, only show secrets with tickets that have not expired")
+	cmd.Flags().BoolVar(&o.FilterOnlyInvalid, "only-invalid", false, "If true, only show secrets with tickets that can't be parsed")
 	cmd.Flags().StringVarP(&o.FilterByMaprCluster, "mapr-cluster", "c", "", "Only show secrets with tickets for the specified MapR cluster")
 	cmd.Flags().StringVarP(&o.FilterByMaprUser, "mapr-user", "u", "", "Only show secrets with tickets for the specified MapR user")
 	cmd.Flags().Uint32Var(&o.FilterByMaprUID, "mapr-uid", 0, "Only show secrets with tickets for the specified UID")
 	cmd.Flags().Uint32Var(&o.FilterByMaprGID, "mapr-gid", 0, "Only show secrets with tickets for the specified GID")
-	cmd.Flags().BoolVarP(&o.FilterByInUse, "in-use", "I", false, "If true, only show secrets that are in use by a persistent volume")
+	cmd.Flags().BoolVarP(&o.FilterByInUse, "in-use", "I", false, "If true, only show secrets that are in use by a persistent volume, an inline volume of a pod or a StorageClass")
 	cmd.Flags().Var(&o.FilterExpiresBefore, "expires-before", "Only show secrets with tickets that expire before the specified duration from now")
-	cmd.Flags().BoolVarP(&o.ShowInUse, "show-in-use", "i", false, "If true, add a column to the output indicating whether the secret is in use by a persistent volume")
-	cmd.MarkFlagsMutuallyExclusive("only-expired", "only-unexpired")
+	cmd.Flags().BoolVarP(&o.ShowInUse, "show-in-use", "i", false, "If true, add columns to the output indicating by how many persistent volumes, inline volumes of pods and StorageClasses the secret is in use")
+	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", false, "After listing the secrets, watch for changes")
+	cmd.Flags().BoolVar(&o.OutputWatchEvents, "output-watch-events", false, "Output watch event objects when --watch is used. Existing objects are output as initial ADDED events.")
+	cmd.MarkFlagsMutuallyExclusive("only-expired", "only-unexpired", "only-invalid")
 
 	// register completions for flags
 	if err := o.registerCompletions(cmd); err != nil {
@@ -169,6 +231,16 @@ func NewCmd(opts *common.Options) *cobra.Command {
 
 // Complete sets any default values for the command flags not handled automatically
 func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	// determine the contexts to list secrets in
+	if err := o.ContextOptions.Complete(o.Options); err != nil {
+		return err
+	}
+
+	// when listing across multiple contexts, the namespace is determined per context
+	if o.ContextOptions.IsMultiContext() {
+		return nil
+	}
+
 	// set namespace based on flags
 	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
 	o.KubernetesConfigFlags.Namespace = &ns
@@ -179,36 +251,86 @@ func (o *options) Complete(cmd *cobra.Command, args []string) error {
 // Validate ensures that all required arguments and flag values are provided
 func (o *options) Validate() error {
 	// validate output format
-	if o.OutputFormat != "table" && o.OutputFormat != "wide" {
-		return fmt.Errorf("invalid output format: %s. Must be one of: table|wide", o.OutputFormat)
+	if err := o.PrintFlags.Validate(); err != nil {
+		return err
+	}
+
+	// validate label and field selectors
+	if err := o.SelectorOptions.Validate(); err != nil {
+		return err
+	}
+
+	// validate filter expression
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
 	}
 
 	// validate sort options
-	if err := util.ValidateSortOptions(secret.SortOptionsList, o.SortBy); err != nil {
+	if err := util.ValidateDirectionalSortOptions(secret.SortOptionsList, o.SortBy); err != nil {
 		return err
 	}
 
+	// watching is only supported for a single context
+	if o.Watch && o.ContextOptions.IsMultiContext() {
+		return fmt.Errorf("--watch can't be used with multiple contexts")
+	}
+
 	return nil
 }
 
 // Run executes the command
 func (o *options) Run(cmd *cobra.Command, args []string) error {
-	client, err := util.ClientFromFlags(o.KubernetesConfigFlags)
+	if o.ContextOptions.IsMultiContext() {
+		tickets, err := o.listContexts(cmd)
+		if err != nil {
+			return err
+		}
+
+		return o.print(cmd, tickets)
+	}
+
+	client, err := o.Client()
 	if err != nil {
 		return err
 	}
 
+	lister := o.newLister(cmd, client, *o.KubernetesConfigFlags.Namespace)
+
+	// in watch mode, keep printing changes until interrupted
+	if o.Watch {
+		return o.runWatch(cmd, lister)
+	}
+
+	// run lister
+	tickets, err := lister.List()
+	if err != nil {
+		return err
+	}
+
+	return o.print(cmd, tickets)
+}
+
+// newLister creates a secret lister for the given client and namespace based on the flags
+func (o *options) newLister(cmd *cobra.Command, client kubernetes.Interface, namespace string) *secret.Lister {
 	// create list options and pass them to the lister
-	opts := []secret.ListerOption{}
+	opts := o.SecretListerOptions()
 
-	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
-		// convert sort options to SortOptions
-		sortOptions := make([]secret.SortOption, 0, len(o.SortBy))
-		for _, sortBy := range o.SortBy {
-			sortOptions = append(sortOptions, secret.SortOption(sortBy))
-		}
+	// only list the secrets matching the selectors
+	if o.SelectorOptions.LabelSelector != "" {
+		opts = append(opts, secret.WithLabelSelector(o.SelectorOptions.LabelSelector))
+	}
+
+	if o.SelectorOptions.FieldSelector != "" {
+		opts = append(opts, secret.WithFieldSelector(o.SelectorOptions.FieldSelector))
+	}
 
-		opts = append(opts, secret.WithSortBy(sortOptions))
+	// only keep the secrets matching the filter expression
+	if o.FilterOptions.Expression != nil {
+		opts = append(opts, secret.WithFilterByExpression(o.FilterOptions.Expression))
+	}
+
+	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
+		opts = append(opts, secret.WithSortBy(toSortOptions(o.SortBy)))
 	}
 
 	if cmd.Flags().Changed("only-expired") && o.FilterOnlyExpired {
@@ -219,6 +341,10 @@ func (o *options) Run(cmd *cobra.Command, args []string) error {
 		opts = append(opts, secret.WithFilterOnlyUnexpired())
 	}
 
+	if cmd.Flags().Changed("only-invalid") && o.FilterOnlyInvalid {
+		opts = append(opts, secret.WithFilterOnlyInvalid())
+	}
+
 	if cmd.Flags().Changed("mapr-cluster") {
 		opts = append(opts, secret.WithFilterByMaprCluster(o.FilterByMaprCluster))
 	}
@@ -238,9 +364,8 @@ func (o *options) Run(cmd *cobra.Command, args []string) error {
 	if cmd.Flags().Changed("in-use") && o.FilterByInUse {
 		opts = append(opts, secret.WithFilterByInUse())
 
-		// add volume lister, since we need to know which secrets are in use
-		volumeLister := volume.NewLister(client, util.SecretAll, metaV1.NamespaceAll)
-		opts = append(opts, secret.WithVolumeLister(volumeLister))
+		// add volume and StorageClass listers, since we need to know which secrets are in use
+		opts = append(opts, common.InUseListerOptions(client)...)
 	}
 
 	if cmd.Flags().Changed("expires-before") {
@@ -250,32 +375,90 @@ func (o *options) Run(cmd *cobra.Command, args []string) error {
 	if cmd.Flags().Changed("show-in-use") && o.ShowInUse {
 		opts = append(opts, secret.WithShowInUse())
 
-		// add volume lister, since we need to know which secrets are in use
-		volumeLister := volume.NewLister(client, util.SecretAll, metaV1.NamespaceAll)
-		opts = append(opts, secret.WithVolumeLister(volumeLister))
+		// add volume and StorageClass listers, since we need to know which secrets are in use
+		opts = append(opts, common.InUseListerOptions(client)...)
 	}
 
-	// create lister
-	lister := secret.NewLister(client, *o.KubernetesConfigFlags.Namespace, opts...)
+	return secret.NewLister(client, namespace, opts...)
+}
 
-	// run lister
-	tickets, err := lister.List()
+// listContexts lists the secrets in all requested contexts concurrently. Errors of single contexts
+// are printed instead of aborting the whole run.
+func (o *options) listContexts(cmd *cobra.Command) ([]types.MaprSecret, error) {
+	results := util.ForEachContext(o.ContextOptions.Contexts, func(name string) ([]types.MaprSecret, error) {
+		flags := util.ConfigFlagsForContext(o.KubernetesConfigFlags, name)
+
+		client, err := o.ClientFromFlags(flags)
+		if err != nil {
+			return nil, err
+		}
+
+		tickets, err := o.newLister(cmd, client, util.GetNamespace(flags, o.AllNamespaces)).List()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range tickets {
+			tickets[i].Context = name
+		}
+
+		return tickets, nil
+	})
+
+	tickets, err := common.MergeContextResults(o.IOStreams.ErrOut, results)
+	if err != nil {
+		return nil, err
+	}
+
+	// the results of each context are already sorted, so only sort across contexts if requested
+	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
+		secret.SortItems(tickets, toSortOptions(o.SortBy))
+	}
+
+	return tickets, nil
+}
+
+// print prints the secrets, either as a table or using one of the structured printers
+func (o *options) print(cmd *cobra.Command, tickets []types.MaprSecret) error {
+	if o.PrintFlags.IsTableFormat() {
+		return secret.Print(cmd, tickets)
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
 	if err != nil {
 		return err
 	}
 
-	// print output
-	if err := secret.Print(cmd, tickets); err != nil {
+	return secret.PrintObjects(cmd, printer, tickets)
+}
+
+// runWatch prints the secrets listed by the lister and keeps printing changes until the command
+// is interrupted
+func (o *options) runWatch(cmd *cobra.Command, lister *secret.Lister) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	initial, events, err := lister.Watch(ctx)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	// a nil printer makes PrintWatch use the table printer
+	var printer printers.ResourcePrinter
+
+	if !o.PrintFlags.IsTableFormat() {
+		if printer, err = o.PrintFlags.ToPrinter(); err != nil {
+			return err
+		}
+	}
+
+	return secret.PrintWatch(cmd, printer, initial, events, o.OutputWatchEvents)
 }
 
 // registerCompletions registers completions for the command flags
 func (o *options) registerCompletions(cmd *cobra.Command) error {
 	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return common.CompleteStringValues(secretValidOutputFormats, toComplete)
+		return o.PrintFlags.CompleteOutputFormats(toComplete)
 	})
 	if err != nil {
 		return err
@@ -290,3 +473,13 @@ func (o *options) registerCompletions(cmd *cobra.Command) error {
 
 	return nil
 }
+
+// toSortOptions converts the sort flag values to secret sort options
+func toSortOptions(sortBy []string) []secret.SortOption {
+	sortOptions := make([]secret.SortOption, 0, len(sortBy))
+	for _, s := range sortBy {
+		sortOptions = append(sortOptions, secret.SortOption(s))
+	}
+
+	return sortOptions
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package storageclass provides the storageclass command for the application.
+package storageclass
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/storageclass"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+)
+
+// command string constants for use in help and usage text
+const (
+	storageClassUse   = `storageclass`
+	storageClassShort = "List all StorageClasses using a MapR CSI provisioner and the secrets they reference"
+	storageClassLong  = `
+		List all StorageClasses using one of the MapR CSI provisioners together with
+		the secrets referenced by their parameters, e.g. the provisioner and the
+		node-publish secrets, and the status of the MapR tickets stored in them.
+
+		References containing templates like ${pvc.namespace} are resolved against the
+		persistent volume claims using the StorageClass, resulting in one row per
+		distinct secret. References that can't be resolved yet, as no claim uses the
+		StorageClass, are listed with their template only. References missing either the
+		secret name or the secret namespace parameter are reported as broken, as the CSI
+		sidecars fail to provision or mount volumes using them.
+		`
+	storageClassExample = `
+		# List all StorageClasses using a MapR CSI provisioner and the secrets they reference
+		%[1]s storageclass
+
+		# List only StorageClasses with incomplete references or referencing a missing, invalid or
+		# expired MapR ticket
+		%[1]s storageclass --only-broken
+
+		# List all StorageClasses, sorted by the expiration date of the MapR ticket
+		%[1]s storageclass --sort-by expiration
+
+		# List all StorageClasses including the referenced secrets as a JSON list
+		%[1]s storageclass --output json
+		`
+)
+
+type options struct {
+	*common.Options
+
+	// PrintFlags are the flags used to configure the output format
+	PrintFlags *common.PrintFlags
+
+	// SelectorOptions are the label and field selectors used to select the StorageClasses to list
+	SelectorOptions *common.SelectorOptions
+
+	// OnlyBroken indicates whether to only show StorageClasses referencing a missing, invalid or
+	// expired ticket
+	OnlyBroken bool
+
+	// SortBy is the list of fields to sort by
+	SortBy []string
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:         opts,
+		PrintFlags:      common.NewPrintFlags(),
+		SelectorOptions: common.NewSelectorOptions(),
+	}
+}
+
+// NewCmd creates a new storageclass command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Aliases: []string{"sc"},
+		Use:     storageClassUse,
+		Short:   storageClassShort,
+		Long:    common.CliLongDesc(storageClassLong),
+		Example: common.CliExample(storageClassExample, common.CliBinName),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	o.PrintFlags.AddFlags(cmd)
+	o.SelectorOptions.AddFlags(cmd, "StorageClasses")
+	cmd.Flags().BoolVar(&o.OnlyBroken, "only-broken", false, "If true, only show StorageClasses referencing a missing, invalid or expired MapR ticket")
+	cmd.Flags().StringSliceVar(&o.SortBy, "sort-by", []string{}, fmt.Sprintf("Sort list of StorageClasses by the specified fields. One or more of (%s)", common.StringSliceToFlagOptions(storageclass.SortOptionsList)))
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	// validate output format
+	if err := o.PrintFlags.Validate(); err != nil {
+		return err
+	}
+
+	// validate label and field selectors
+	if err := o.SelectorOptions.Validate(); err != nil {
+		return err
+	}
+
+	// ensure that the sort options are valid
+	if err := util.ValidateSortOptions(storageclass.SortOptionsList, o.SortBy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	// create secret lister used to look up the referenced tickets in all namespaces
+	secretLister := secret.NewLister(
+		client,
+		util.NamespaceAll,
+		o.SecretListerOptions()...,
+	)
+
+	// create list options and pass them to the lister
+	opts := []storageclass.ListerOption{
+		storageclass.WithSecretLister(secretLister),
+	}
+
+	if o.OnlyBroken {
+		opts = append(opts, storageclass.WithFilterOnlyBroken())
+	}
+
+	// only list the StorageClasses matching the selectors
+	if o.SelectorOptions.LabelSelector != "" {
+		opts = append(opts, storageclass.WithLabelSelector(o.SelectorOptions.LabelSelector))
+	}
+
+	if o.SelectorOptions.FieldSelector != "" {
+		opts = append(opts, storageclass.WithFieldSelector(o.SelectorOptions.FieldSelector))
+	}
+
+	// set sort options
+	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
+		sortOptions := make([]storageclass.SortOption, 0, len(o.SortBy))
+		for _, sortBy := range o.SortBy {
+			sortOptions = append(sortOptions, storageclass.SortOption(sortBy))
+		}
+
+		opts = append(opts, storageclass.WithSortBy(sortOptions))
+	}
+
+	// create lister
+	lister := storageclass.NewLister(
+		client,
+		opts...,
+	)
+
+	// run lister
+	storageClasses, err := lister.List()
+	if err != nil {
+		return err
+	}
+
+	// print output, either as a table or using one of the structured printers
+	if o.PrintFlags.IsTableFormat() {
+		return storageclass.Print(cmd, storageClasses)
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	return storageclass.PrintObjects(cmd, printer, storageClasses)
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return o.PrintFlags.CompleteOutputFormats(toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = cmd.RegisterFlagCompletionFunc("sort-by", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return common.CompleteStringSliceValues(storageclass.SortOptionsList, toComplete)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
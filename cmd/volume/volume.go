@@ -12,9 +12,13 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
 
 const (
@@ -35,12 +39,29 @@ const (
 		%[1]s volume --all-namespaces
 
 		# List all persistent volumes that use any MapR ticket secret in all namespaces, sorted by expiration date
-		%[1]s volume --all-namespaces --sort-by expiryTime
-		`
-)
+		%[1]s volume --all-namespaces --sort-by expiration
+
+		# List all persistent volumes that use any MapR ticket secret in all namespaces, grouped by secret and soonest to expire last
+		%[1]s volume --all-namespaces --sort-by secret.namespace,secret.name,expiration:desc
+
+		# List only persistent volumes whose MapR ticket secret is missing, unreadable, invalid or expired
+		%[1]s volume --all-namespaces --only-broken
+
+		# List all persistent volumes that use the specified MapR ticket secret as a YAML list
+		%[1]s volume my-secret --output yaml
+
+		# List all persistent volumes in all namespaces with their claims and ticket expiry using custom columns
+		%[1]s volume --all-namespaces -o custom-columns=NAME:.volume.name,CLAIM:.claim.name,EXPIRES:.ticket.expirationTime
+
+		# List all persistent volumes in all namespaces whose MapR ticket expires within 7 days
+		%[1]s volume --all-namespaces --filter "ticket.expiresIn < duration('168h')"
+
+		# List all persistent volumes that use any MapR ticket secret in all namespaces of all kubeconfig contexts
+		%[1]s volume --all-namespaces --all-contexts
 
-var (
-	volumeValidOutputFormats = []string{"table", "wide"}
+		# List all persistent volumes that use the specified MapR ticket secret in the contexts "prod" and "staging"
+		%[1]s volume my-secret --context prod,staging
+		`
 )
 
 type options struct {
@@ -52,20 +73,37 @@ type options struct {
 	// SecretName is the name of the secret to find persistent volumes for
 	SecretName string
 
-	// OutputFormat is the format to use for output
-	OutputFormat string
+	// PrintFlags are the flags used to configure the output format
+	PrintFlags *common.PrintFlags
+
+	// SelectorOptions are the label and field selectors used to select the persistent volumes to list
+	SelectorOptions *common.SelectorOptions
+
+	// FilterOptions are the options used to filter the listed persistent volumes using a CEL expression
+	FilterOptions *common.FilterOptions
+
+	// ContextOptions are the options used to list persistent volumes across multiple kubeconfig contexts
+	ContextOptions *common.ContextOptions
 
 	// AllNamespaces indicates whether to find persistent volumes for all secrets
 	// in all namespaces
 	AllNamespaces bool
 
+	// OnlyBroken indicates whether to only show persistent volumes whose MapR ticket is missing,
+	// unreadable, invalid or expired
+	OnlyBroken bool
+
 	// SortBy is the list of fields to sort by
 	SortBy []string
 }
 
 func newOptions(opts *common.Options) *options {
 	return &options{
-		Options: opts,
+		Options:         opts,
+		PrintFlags:      common.NewPrintFlags(),
+		SelectorOptions: common.NewSelectorOptions(),
+		FilterOptions:   common.NewFilterOptions(filter.VolumeVariables),
+		ContextOptions:  common.NewContextOptions(),
 	}
 }
 
@@ -90,12 +128,12 @@ func NewCmd(opts *common.Options) *cobra.Command {
 			o.KubernetesConfigFlags.Namespace = &namespace
 
 			// get client
-			client, err := util.ClientFromFlags(o.KubernetesConfigFlags)
+			client, err := o.Client()
 			if err != nil {
 				return nil, cobra.ShellCompDirectiveError
 			}
 
-			return common.CompleteTicketNames(client, namespace, args, toComplete)
+			return common.CompleteTicketNames(client, namespace, o.TicketKey, o.DetectTicketKeys, args, toComplete)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := o.Complete(cmd, args); err != nil {
@@ -120,9 +158,13 @@ func NewCmd(opts *common.Options) *cobra.Command {
 	cmd.SetErr(o.IOStreams.ErrOut)
 
 	// add flags
-	cmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "table", fmt.Sprintf("Output format. One of (%s)", common.StringSliceToFlagOptions(volumeValidOutputFormats)))
+	o.PrintFlags.AddFlags(cmd)
+	o.SelectorOptions.AddFlags(cmd, "persistent volumes")
+	o.FilterOptions.AddFlags(cmd, "persistent volumes")
+	o.ContextOptions.AddFlags(cmd)
 	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "List persistent volumes for all MapR ticket secrets in all namespaces")
-	cmd.Flags().StringSliceVar(&o.SortBy, "sort-by", []string{}, fmt.Sprintf("Sort list of persistent volumes by the specified fields. One or more of (%s)", common.StringSliceToFlagOptions(volume.SortOptionsList)))
+	cmd.Flags().BoolVar(&o.OnlyBroken, "only-broken", false, "If true, only show persistent volumes whose MapR ticket secret is missing, forbidden to read, contains no ticket, or whose ticket is invalid or expired")
+	cmd.Flags().StringSliceVar(&o.SortBy, "sort-by", []string{}, fmt.Sprintf("Sort list of persistent volumes by the specified fields, prefix a field with '-' or suffix it with ':desc' to sort in descending order. One or more of (%s)", common.StringSliceToFlagOptions(volume.SortOptionsList)))
 
 	// register completions for flags
 	if err := o.registerCompletions(cmd); err != nil {
@@ -146,6 +188,16 @@ func (o *options) Complete(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("too many arguments provided, either provide a secret name or nothing")
 	}
 
+	// determine the contexts to list persistent volumes in
+	if err := o.ContextOptions.Complete(o.Options); err != nil {
+		return err
+	}
+
+	// when listing across multiple contexts, the namespace is determined per context
+	if o.ContextOptions.IsMultiContext() {
+		return nil
+	}
+
 	// set namespace based on flags
 	ns := util.GetNamespace(o.KubernetesConfigFlags, o.AllNamespaces)
 	o.KubernetesConfigFlags.Namespace = &ns
@@ -155,12 +207,22 @@ func (o *options) Complete(cmd *cobra.Command, args []string) error {
 
 func (o *options) Validate() error {
 	// validate output format
-	if o.OutputFormat != "table" && o.OutputFormat != "wide" {
-		return fmt.Errorf("output format %s is not valid", o.OutputFormat)
+	if err := o.PrintFlags.Validate(); err != nil {
+		return err
+	}
+
+	// validate label and field selectors
+	if err := o.SelectorOptions.Validate(); err != nil {
+		return err
+	}
+
+	// validate filter expression
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
 	}
 
 	// ensure that the sort options are valid
-	if err := util.ValidateSortOptions(volume.SortOptionsList, o.SortBy); err != nil {
+	if err := util.ValidateDirectionalSortOptions(volume.SortOptionsList, o.SortBy); err != nil {
 		return err
 	}
 
@@ -168,57 +230,137 @@ func (o *options) Validate() error {
 }
 
 func (o *options) Run(cmd *cobra.Command, args []string) error {
-	client, err := util.ClientFromFlags(o.KubernetesConfigFlags)
+	var (
+		pvs []types.MaprVolume
+		err error
+	)
+
+	if o.ContextOptions.IsMultiContext() {
+		pvs, err = o.listContexts(cmd)
+	} else {
+		pvs, err = o.list(cmd, o.KubernetesConfigFlags, *o.KubernetesConfigFlags.Namespace)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// print output, either as a table or using one of the structured printers
+	if o.PrintFlags.IsTableFormat() {
+		return volume.Print(cmd, pvs)
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
 	if err != nil {
 		return err
 	}
 
+	return volume.PrintObjects(cmd, printer, pvs)
+}
+
+// list lists the persistent volumes using secrets in the given namespace of the cluster the flags
+// point to
+func (o *options) list(cmd *cobra.Command, flags *genericclioptions.ConfigFlags, namespace string) ([]types.MaprVolume, error) {
+	client, err := o.ClientFromFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+
 	// create secret lister
 	secretLister := secret.NewLister(
 		client,
 		util.NamespaceAll,
+		o.SecretListerOptions()...,
 	)
 
 	// create list options and pass them to the lister
 	opts := []volume.ListerOption{
 		volume.WithSecretLister(secretLister),
+		volume.WithTicketKey(o.TicketKey),
 	}
 
-	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
-		// convert sort options to SortOptions
-		sortOptions := make([]volume.SortOption, 0, len(o.SortBy))
-		for _, sortBy := range o.SortBy {
-			sortOptions = append(sortOptions, volume.SortOption(sortBy))
-		}
+	if o.DetectTicketKeys {
+		opts = append(opts, volume.WithDetectTicketKeys())
+	}
 
-		opts = append(opts, volume.WithSortBy(sortOptions))
+	// only list the persistent volumes matching the selectors
+	if o.SelectorOptions.LabelSelector != "" {
+		opts = append(opts, volume.WithLabelSelector(o.SelectorOptions.LabelSelector))
+	}
+
+	if o.SelectorOptions.FieldSelector != "" {
+		opts = append(opts, volume.WithFieldSelector(o.SelectorOptions.FieldSelector))
+	}
+
+	// only keep the persistent volumes with a broken ticket, determining why it is broken
+	if o.OnlyBroken {
+		opts = append(opts, volume.WithFilterOnlyBroken())
+	}
+
+	// only keep the persistent volumes matching the filter expression
+	if o.FilterOptions.Expression != nil {
+		opts = append(opts, volume.WithFilterByExpression(o.FilterOptions.Expression))
+	}
+
+	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
+		opts = append(opts, volume.WithSortBy(o.sortOptions()))
 	}
 
 	// create lister
 	lister := volume.NewLister(
 		client,
 		o.SecretName,
-		*o.KubernetesConfigFlags.Namespace,
+		namespace,
 		opts...,
 	)
 
-	// run the lister
-	pvs, err := lister.List()
+	return lister.List()
+}
+
+// listContexts lists the persistent volumes in all requested contexts concurrently. Errors of
+// single contexts are printed instead of aborting the whole run.
+func (o *options) listContexts(cmd *cobra.Command) ([]types.MaprVolume, error) {
+	results := util.ForEachContext(o.ContextOptions.Contexts, func(name string) ([]types.MaprVolume, error) {
+		flags := util.ConfigFlagsForContext(o.KubernetesConfigFlags, name)
+
+		pvs, err := o.list(cmd, flags, util.GetNamespace(flags, o.AllNamespaces))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range pvs {
+			pvs[i].Context = name
+		}
+
+		return pvs, nil
+	})
+
+	pvs, err := common.MergeContextResults(o.IOStreams.ErrOut, results)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// print the volumes
-	if err := volume.Print(cmd, pvs); err != nil {
-		return err
+	// the results of each context are already sorted, so only sort across contexts if requested
+	if cmd.Flags().Changed("sort-by") && o.SortBy != nil {
+		volume.SortItems(pvs, o.sortOptions())
 	}
 
-	return nil
+	return pvs, nil
+}
+
+// sortOptions converts the sort flag values to volume sort options
+func (o *options) sortOptions() []volume.SortOption {
+	sortOptions := make([]volume.SortOption, 0, len(o.SortBy))
+	for _, sortBy := range o.SortBy {
+		sortOptions = append(sortOptions, volume.SortOption(sortBy))
+	}
+
+	return sortOptions
 }
 
 func (o *options) registerCompletions(cmd *cobra.Command) error {
 	err := cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return common.CompleteStringValues(volumeValidOutputFormats, toComplete)
+		return o.PrintFlags.CompleteOutputFormats(toComplete)
 	})
 	if err != nil {
 		return err
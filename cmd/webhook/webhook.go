@@ -0,0 +1,233 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package webhook provides the webhook command for the application.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/cmd/common"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/webhook"
+)
+
+// command string constants for use in help and usage text
+const (
+	webhookUse   = `webhook`
+	webhookShort = "Run a validating admission webhook for secrets containing MapR tickets"
+	webhookLong  = `
+		Run a validating admission webhook rejecting secrets containing invalid or expired
+		MapR tickets, meant to be deployed in-cluster and registered using a
+		ValidatingWebhookConfiguration for the CREATE and UPDATE operations of secrets and
+		persistent volumes.
+
+		Secrets are rejected if the ticket can't be parsed, is expired or, if --allowed-cluster
+		or --allowed-user are given, was issued for any other cluster or user. Persistent
+		volumes provisioned by one of the MapR CSI provisioners are rejected if they reference
+		a secret that does not exist or does not contain a ticket. On update, only tickets
+		whose data changed are validated, so secrets holding an expired ticket can still be
+		labeled or annotated, and persistent volumes are only validated if their secret
+		reference changed and they are not being deleted.
+
+		Admission reviews are served via HTTPS on /validate using the certificate and key
+		given by --tls-cert-file and --tls-key-file. The files are reloaded once they change,
+		so rotated certificates are picked up without a restart.
+		`
+	webhookExample = `
+		# Run the webhook on port 8443 using the certificate mounted from a secret
+		%[1]s webhook --tls-cert-file /tls/tls.crt --tls-key-file /tls/tls.key
+
+		# Only allow tickets issued for the given cluster and users
+		%[1]s webhook --tls-cert-file /tls/tls.crt --tls-key-file /tls/tls.key \
+			--allowed-cluster demo.mapr.com --allowed-user user_a --allowed-user user_b
+		`
+)
+
+const (
+	// shutdownTimeout is the maximum time to wait for open connections on shutdown
+	shutdownTimeout = 5 * time.Second
+)
+
+type options struct {
+	*common.Options
+
+	// ListenAddress is the address the webhook server listens on
+	ListenAddress string
+
+	// TLSCertFile is the path of the TLS certificate served by the webhook
+	TLSCertFile string
+
+	// TLSKeyFile is the path of the private key of the TLS certificate
+	TLSKeyFile string
+
+	// AllowedClusters are the clusters tickets may be issued for, empty to allow all
+	AllowedClusters []string
+
+	// AllowedUsers are the users tickets may be issued for, empty to allow all
+	AllowedUsers []string
+}
+
+func newOptions(opts *common.Options) *options {
+	return &options{
+		Options:       opts,
+		ListenAddress: ":8443",
+	}
+}
+
+// NewCmd creates a new webhook command for the application.
+func NewCmd(opts *common.Options) *cobra.Command {
+	o := newOptions(opts)
+
+	cmd := &cobra.Command{
+		Use:          webhookUse,
+		Short:        webhookShort,
+		Long:         common.CliLongDesc(webhookLong),
+		Example:      common.CliExample(webhookExample, common.CliBinName),
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(cmd, args); err != nil {
+				return err
+			}
+
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			if err := o.Run(cmd, args); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	// set IOStreams for this command
+	cmd.SetIn(o.IOStreams.In)
+	cmd.SetOut(o.IOStreams.Out)
+	cmd.SetErr(o.IOStreams.ErrOut)
+
+	// add flags
+	cmd.Flags().StringVar(&o.ListenAddress, "listen", o.ListenAddress, "Address the webhook server listens on")
+	cmd.Flags().StringVar(&o.TLSCertFile, "tls-cert-file", "", "Path of the TLS certificate served by the webhook")
+	cmd.Flags().StringVar(&o.TLSKeyFile, "tls-key-file", "", "Path of the private key of the TLS certificate")
+	cmd.Flags().StringSliceVar(&o.AllowedClusters, "allowed-cluster", nil, "Only allow tickets issued for the given clusters. Can be specified multiple times")
+	cmd.Flags().StringSliceVar(&o.AllowedUsers, "allowed-user", nil, "Only allow tickets issued for the given users. Can be specified multiple times")
+
+	// register completions for flags
+	if err := o.registerCompletions(cmd); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// Complete sets any default values for the command flags not handled automatically
+func (o *options) Complete(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (o *options) Validate() error {
+	if o.ListenAddress == "" {
+		return fmt.Errorf("listen address must not be empty")
+	}
+
+	if o.TLSCertFile == "" || o.TLSKeyFile == "" {
+		return fmt.Errorf("--tls-cert-file and --tls-key-file are required, admission webhooks must be served via HTTPS")
+	}
+
+	if o.FromDump != "" {
+		return fmt.Errorf("--from-dump can't be used to run the webhook")
+	}
+
+	return nil
+}
+
+// Run executes the command logic
+func (o *options) Run(cmd *cobra.Command, args []string) error {
+	client, err := o.Client()
+	if err != nil {
+		return err
+	}
+
+	certificates, err := webhook.NewCertificateLoader(o.TLSCertFile, o.TLSKeyFile)
+	if err != nil {
+		return err
+	}
+
+	webhookOpts := []webhook.Option{
+		webhook.WithTicketKey(o.TicketKey),
+		webhook.WithAllowedClusters(o.AllowedClusters),
+		webhook.WithAllowedUsers(o.AllowedUsers),
+	}
+
+	if o.DetectTicketKeys {
+		webhookOpts = append(webhookOpts, webhook.WithDetectTicketKeys())
+	}
+
+	validator := webhook.NewValidator(client, webhookOpts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", validator.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              o.ListenAddress,
+		Handler:           mux,
+		TLSConfig:         certificates.TLSConfig(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to shut down webhook server", "error", err)
+		}
+	}()
+
+	slog.Info("serving admission webhook", "address", o.ListenAddress)
+
+	// the certificate is provided by the TLS config, so no files are passed here
+	if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// registerCompletions registers completions for the command flags
+func (o *options) registerCompletions(cmd *cobra.Command) error {
+	err := cmd.MarkFlagFilename("tls-cert-file", "crt", "pem")
+	if err != nil {
+		return err
+	}
+
+	err = cmd.MarkFlagFilename("tls-key-file", "key", "pem")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
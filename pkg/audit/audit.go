@@ -0,0 +1,430 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package audit implements a health report of the MapR tickets used in the cluster. The auditor
+// combines the secret, volume, claim and StorageClass listers and runs a fixed set of checks on
+// their results, each producing findings with a severity, e.g. for expired tickets still in use by
+// bound persistent volumes or persistent volumes referencing secrets that don't exist.
+//
+// The findings can be printed as a table or in machine readable formats, ie. JSON, JUnit XML and
+// SARIF, to be used as a gate in CI pipelines.
+package audit
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Severity is the severity of a finding
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+var (
+	// SeverityList is the list of all severities, from the lowest to the highest
+	SeverityList = []Severity{
+		SeverityInfo,
+		SeverityWarning,
+		SeverityCritical,
+	}
+)
+
+// Check identifies the check that produced a finding
+type Check string
+
+const (
+	CheckExpiredTicketInUse Check = "ExpiredTicketInUse"
+	CheckTicketExpiringSoon Check = "TicketExpiringSoon"
+	CheckMissingSecret      Check = "MissingSecret"
+	CheckUnusedSecret       Check = "UnusedSecret"
+	CheckRootTicket         Check = "RootTicket"
+	CheckClusterMismatch    Check = "ClusterMismatch"
+	CheckDuplicateTicket    Check = "DuplicateTicket"
+)
+
+var (
+	// CheckList is the list of all checks run by the auditor
+	CheckList = []Check{
+		CheckExpiredTicketInUse,
+		CheckTicketExpiringSoon,
+		CheckMissingSecret,
+		CheckUnusedSecret,
+		CheckRootTicket,
+		CheckClusterMismatch,
+		CheckDuplicateTicket,
+	}
+
+	// checkSeverities maps the checks to the severity of their findings
+	checkSeverities = map[Check]Severity{
+		CheckExpiredTicketInUse: SeverityCritical,
+		CheckTicketExpiringSoon: SeverityWarning,
+		CheckMissingSecret:      SeverityCritical,
+		CheckUnusedSecret:       SeverityInfo,
+		CheckRootTicket:         SeverityWarning,
+		CheckClusterMismatch:    SeverityCritical,
+		CheckDuplicateTicket:    SeverityInfo,
+	}
+
+	// checkDescriptions maps the checks to a short description of what they detect
+	checkDescriptions = map[Check]string{
+		CheckExpiredTicketInUse: "Expired MapR ticket still in use by a bound persistent volume",
+		CheckTicketExpiringSoon: "MapR ticket expires within the configured threshold",
+		CheckMissingSecret:      "Persistent volume references a secret that does not exist",
+		CheckUnusedSecret:       "Secret containing a MapR ticket is not used by any volume or StorageClass",
+		CheckRootTicket:         "MapR ticket is issued for the root user with UID 0",
+		CheckClusterMismatch:    "MapR ticket is issued for a different cluster than the volume or StorageClass uses",
+		CheckDuplicateTicket:    "Same MapR ticket is stored in secrets in multiple namespaces",
+	}
+
+	// DefaultExpiresWithin is the default threshold for tickets to be reported as expiring soon
+	DefaultExpiresWithin = 7 * 24 * time.Hour
+)
+
+// kinds of the objects findings refer to
+const (
+	KindSecret           = "Secret"
+	KindPersistentVolume = "PersistentVolume"
+	KindStorageClass     = "StorageClass"
+)
+
+// Finding is a single issue found by one of the checks
+type Finding struct {
+	Check     Check    `json:"check"`
+	Severity  Severity `json:"severity"`
+	Kind      string   `json:"kind"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+	Message   string   `json:"message"`
+}
+
+// secretLister is the interface that a secret lister must implement.
+type secretLister interface {
+	List() ([]types.MaprSecret, error)
+}
+
+// volumeLister is the interface that a volume lister must implement.
+type volumeLister interface {
+	List() ([]types.MaprVolume, error)
+}
+
+// claimLister is the interface that a volume claim lister must implement.
+type claimLister interface {
+	List() ([]types.MaprVolumeClaim, error)
+}
+
+// storageClassLister is the interface that a StorageClass lister must implement.
+type storageClassLister interface {
+	List() ([]types.MaprStorageClass, error)
+}
+
+// Auditor is the struct that is used to run the checks on the MapR tickets used in the cluster.
+type Auditor struct {
+	client kubernetes.Interface
+
+	secretLister       secretLister
+	volumeLister       volumeLister
+	claimLister        claimLister
+	storageClassLister storageClassLister
+	expiresWithin      time.Duration
+
+	findings []Finding
+}
+
+// NewAuditor creates a new auditor. It requires a Kubernetes client to look up the secrets
+// referenced by persistent volumes. The listers providing the audited objects are passed as
+// options, checks whose lister is not configured are skipped.
+func NewAuditor(client kubernetes.Interface, opts ...Option) *Auditor {
+	a := &Auditor{
+		client:        client,
+		expiresWithin: DefaultExpiresWithin,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Audit runs all checks and returns the findings, sorted by severity from the highest to the
+// lowest and then by check and object.
+func (a *Auditor) Audit() ([]Finding, error) {
+	a.findings = []Finding{}
+
+	if err := a.auditSecrets(); err != nil {
+		return nil, err
+	}
+
+	if err := a.auditVolumes(); err != nil {
+		return nil, err
+	}
+
+	if err := a.auditClaims(); err != nil {
+		return nil, err
+	}
+
+	if err := a.auditStorageClasses(); err != nil {
+		return nil, err
+	}
+
+	SortFindings(a.findings)
+
+	return a.findings, nil
+}
+
+// auditSecrets checks the listed secrets for tickets expiring soon, unused secrets, root tickets
+// and tickets duplicated across namespaces. The secret lister is expected to determine which
+// secrets are in use.
+func (a *Auditor) auditSecrets() error {
+	if a.secretLister == nil {
+		return nil
+	}
+
+	secrets, err := a.secretLister.List()
+	if err != nil {
+		return err
+	}
+
+	for i := range secrets {
+		s := &secrets[i]
+
+		if s.Ticket != nil && !s.IsExpired() && time.Until(s.GetExpirationTime()) < a.expiresWithin {
+			a.addSecretFinding(CheckTicketExpiringSoon, s,
+				"ticket expires in %s at %s", time.Until(s.GetExpirationTime()).Round(time.Minute), s.GetExpirationTime().Format(time.RFC3339))
+		}
+
+		if !s.IsInUse() {
+			a.addSecretFinding(CheckUnusedSecret, s, "ticket is not used by any persistent volume, inline volume or StorageClass")
+		}
+
+		if s.Ticket != nil && s.Ticket.UserCreds.GetUid() == 0 {
+			a.addSecretFinding(CheckRootTicket, s, "ticket of user %q is issued for UID 0", s.GetUser())
+		}
+	}
+
+	a.auditDuplicateTickets(secrets)
+
+	return nil
+}
+
+// auditDuplicateTickets adds a finding for each secret whose ticket is also stored in secrets in
+// other namespaces. Tickets are compared by the fingerprint of their raw data.
+func (a *Auditor) auditDuplicateTickets(secrets []types.MaprSecret) {
+	secretsByFingerprint := make(map[[sha256.Size]byte][]*types.MaprSecret)
+
+	for i := range secrets {
+		s := &secrets[i]
+
+		if s.Ticket == nil || s.Secret == nil {
+			continue
+		}
+
+		fingerprint := sha256.Sum256(s.Secret.Data[s.GetKey()])
+		secretsByFingerprint[fingerprint] = append(secretsByFingerprint[fingerprint], s)
+	}
+
+	for _, duplicates := range secretsByFingerprint {
+		namespaces := make(map[string]struct{}, len(duplicates))
+		for _, s := range duplicates {
+			namespaces[s.GetSecretNamespace()] = struct{}{}
+		}
+
+		if len(namespaces) < 2 {
+			continue
+		}
+
+		for _, s := range duplicates {
+			others := make([]string, 0, len(duplicates)-1)
+
+			for _, other := range duplicates {
+				if other != s {
+					others = append(others, other.GetSecretNamespace()+"/"+other.GetSecretName())
+				}
+			}
+
+			slices.Sort(others)
+
+			a.addSecretFinding(CheckDuplicateTicket, s, "ticket is also stored in %s", strings.Join(others, ", "))
+		}
+	}
+}
+
+// auditVolumes checks the listed persistent volumes for references to secrets that don't exist
+// and for tickets issued for a different cluster than the volume is stored on. The volume lister
+// is expected to collect the tickets used by the volumes.
+func (a *Auditor) auditVolumes() error {
+	if a.volumeLister == nil {
+		return nil
+	}
+
+	volumes, err := a.volumeLister.List()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range volumes {
+		if v.Ticket == nil {
+			a.auditMissingSecret(v.Volume)
+			continue
+		}
+
+		if v.Ticket.Ticket != nil && v.Volume.GetCluster() != "" && v.Volume.GetCluster() != v.Ticket.GetCluster() {
+			a.addFinding(CheckClusterMismatch, KindPersistentVolume, "", v.Volume.GetName(),
+				"volume is stored on cluster %q, but ticket of secret %s/%s is issued for cluster %q",
+				v.Volume.GetCluster(), v.Ticket.GetSecretNamespace(), v.Ticket.GetSecretName(), v.Ticket.GetCluster())
+		}
+	}
+
+	return nil
+}
+
+// auditMissingSecret adds a finding if the secret referenced by the persistent volume does not
+// exist. Secrets that exist but don't contain a ticket are not reported by this check.
+func (a *Auditor) auditMissingSecret(v *types.PersistentVolume) {
+	namespace, name := v.GetSecretNamespace(), v.GetSecretName()
+
+	_, err := a.client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if err == nil {
+		return
+	}
+
+	if !apiErrors.IsNotFound(err) {
+		slog.Debug("failed to get secret referenced by persistent volume", "volume", v.GetName(), "namespace", namespace, "name", name, "error", err)
+		return
+	}
+
+	a.addFinding(CheckMissingSecret, KindPersistentVolume, "", v.GetName(),
+		"volume references secret %s/%s that does not exist", namespace, name)
+}
+
+// auditClaims checks the listed bound claims for persistent volumes still using expired tickets.
+// The claim lister is expected to collect the tickets used by the volumes.
+func (a *Auditor) auditClaims() error {
+	if a.claimLister == nil {
+		return nil
+	}
+
+	claims, err := a.claimLister.List()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range claims {
+		if !c.Ticket.IsExpired() {
+			continue
+		}
+
+		a.addFinding(CheckExpiredTicketInUse, KindPersistentVolume, "", c.Volume.GetName(),
+			"volume bound to claim %s/%s uses ticket of secret %s/%s that expired at %s",
+			c.Claim.GetNamespace(), c.Claim.GetName(), c.Ticket.GetSecretNamespace(), c.Ticket.GetSecretName(),
+			c.Ticket.GetExpirationTime().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// auditStorageClasses checks the listed StorageClasses for tickets issued for a different cluster
+// than the StorageClass provisions volumes on. The StorageClass lister is expected to collect the
+// referenced tickets.
+func (a *Auditor) auditStorageClasses() error {
+	if a.storageClassLister == nil {
+		return nil
+	}
+
+	storageClasses, err := a.storageClassLister.List()
+	if err != nil {
+		return err
+	}
+
+	for _, sc := range storageClasses {
+		cluster := sc.StorageClass.GetCluster()
+
+		if sc.Ticket == nil || sc.Ticket.Ticket == nil || cluster == "" || cluster == sc.Ticket.GetCluster() {
+			continue
+		}
+
+		a.addFinding(CheckClusterMismatch, KindStorageClass, "", sc.StorageClass.GetName(),
+			"StorageClass provisions volumes on cluster %q, but %s ticket of secret %s/%s is issued for cluster %q",
+			cluster, sc.Reference.Type, sc.Namespace, sc.Name, sc.Ticket.GetCluster())
+	}
+
+	return nil
+}
+
+// addSecretFinding adds a finding of the given check for the secret
+func (a *Auditor) addSecretFinding(check Check, s *types.MaprSecret, format string, args ...any) {
+	a.addFinding(check, KindSecret, s.GetSecretNamespace(), s.GetSecretName(), format, args...)
+}
+
+// addFinding adds a finding of the given check for the object, using the severity of the check
+func (a *Auditor) addFinding(check Check, kind, namespace, name, format string, args ...any) {
+	a.findings = append(a.findings, Finding{
+		Check:     check,
+		Severity:  check.Severity(),
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// Severity returns the severity of the findings of the check
+func (c Check) Severity() Severity {
+	return checkSeverities[c]
+}
+
+// Description returns a short description of what the check detects
+func (c Check) Description() string {
+	return checkDescriptions[c]
+}
+
+// Rank returns the rank of the severity, higher values are more severe. Unknown severities have a
+// rank of -1.
+func (s Severity) Rank() int {
+	return slices.Index(SeverityList, s)
+}
+
+// CountAtLeast returns the number of findings with the given severity or a higher one
+func CountAtLeast(findings []Finding, severity Severity) int {
+	count := 0
+
+	for _, f := range findings {
+		if f.Severity.Rank() >= severity.Rank() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// SortFindings sorts the findings by severity from the highest to the lowest, then by check, kind,
+// namespace and name
+func SortFindings(findings []Finding) {
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return cmp.Or(
+			cmp.Compare(b.Severity.Rank(), a.Severity.Rank()),
+			cmp.Compare(slices.Index(CheckList, a.Check), slices.Index(CheckList, b.Check)),
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+}
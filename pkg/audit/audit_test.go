@@ -0,0 +1,318 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/audit"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+type fakeSecretLister []types.MaprSecret
+
+func (l fakeSecretLister) List() ([]types.MaprSecret, error) {
+	return l, nil
+}
+
+type fakeVolumeLister []types.MaprVolume
+
+func (l fakeVolumeLister) List() ([]types.MaprVolume, error) {
+	return l, nil
+}
+
+type fakeClaimLister []types.MaprVolumeClaim
+
+func (l fakeClaimLister) List() ([]types.MaprVolumeClaim, error) {
+	return l, nil
+}
+
+type fakeStorageClassLister []types.MaprStorageClass
+
+func (l fakeStorageClassLister) List() ([]types.MaprStorageClass, error) {
+	return l, nil
+}
+
+type expectedFinding struct {
+	check     Check
+	kind      string
+	namespace string
+	name      string
+}
+
+func TestAuditor_Audit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		opts     func(t *testing.T) []Option
+		expected []expectedFinding
+	}{
+		{
+			name:     "no listers",
+			opts:     func(t *testing.T) []Option { return nil },
+			expected: []expectedFinding{},
+		},
+		{
+			name: "healthy secret in use",
+			opts: func(t *testing.T) []Option {
+				return []Option{
+					WithSecretLister(fakeSecretLister{
+						inUse(newMaprSecret(t, "team-a", "ticket", "demo.mapr.com", 1000, now.Add(30*24*time.Hour))),
+					}),
+				}
+			},
+			expected: []expectedFinding{},
+		},
+		{
+			name: "secret expiring soon, unused and issued for root",
+			opts: func(t *testing.T) []Option {
+				return []Option{
+					WithSecretLister(fakeSecretLister{
+						newMaprSecret(t, "team-a", "ticket", "demo.mapr.com", 0, now.Add(time.Hour)),
+					}),
+				}
+			},
+			expected: []expectedFinding{
+				{check: CheckTicketExpiringSoon, kind: KindSecret, namespace: "team-a", name: "ticket"},
+				{check: CheckRootTicket, kind: KindSecret, namespace: "team-a", name: "ticket"},
+				{check: CheckUnusedSecret, kind: KindSecret, namespace: "team-a", name: "ticket"},
+			},
+		},
+		{
+			name: "custom expiry threshold",
+			opts: func(t *testing.T) []Option {
+				return []Option{
+					WithExpiresWithin(time.Hour),
+					WithSecretLister(fakeSecretLister{
+						inUse(newMaprSecret(t, "team-a", "ticket", "demo.mapr.com", 1000, now.Add(2*time.Hour))),
+					}),
+				}
+			},
+			expected: []expectedFinding{},
+		},
+		{
+			name: "duplicate tickets across namespaces",
+			opts: func(t *testing.T) []Option {
+				ticketA := inUse(newMaprSecret(t, "team-a", "ticket", "demo.mapr.com", 1000, now.Add(30*24*time.Hour)))
+				ticketB := ticketA
+				ticketB.Secret = &types.Secret{
+					ObjectMeta: metaV1.ObjectMeta{Namespace: "team-b", Name: "ticket"},
+					Data:       ticketA.Secret.Data,
+				}
+				ticketC := ticketA
+				ticketC.Secret = &types.Secret{
+					ObjectMeta: metaV1.ObjectMeta{Namespace: "team-a", Name: "copy"},
+					Data:       ticketA.Secret.Data,
+				}
+
+				return []Option{
+					WithSecretLister(fakeSecretLister{ticketA, ticketB, ticketC}),
+				}
+			},
+			expected: []expectedFinding{
+				{check: CheckDuplicateTicket, kind: KindSecret, namespace: "team-a", name: "copy"},
+				{check: CheckDuplicateTicket, kind: KindSecret, namespace: "team-a", name: "ticket"},
+				{check: CheckDuplicateTicket, kind: KindSecret, namespace: "team-b", name: "ticket"},
+			},
+		},
+		{
+			name: "duplicate tickets in a single namespace",
+			opts: func(t *testing.T) []Option {
+				ticketA := inUse(newMaprSecret(t, "team-a", "ticket", "demo.mapr.com", 1000, now.Add(30*24*time.Hour)))
+				ticketB := ticketA
+				ticketB.Secret = &types.Secret{
+					ObjectMeta: metaV1.ObjectMeta{Namespace: "team-a", Name: "copy"},
+					Data:       ticketA.Secret.Data,
+				}
+
+				return []Option{
+					WithSecretLister(fakeSecretLister{ticketA, ticketB}),
+				}
+			},
+			expected: []expectedFinding{},
+		},
+		{
+			name: "volumes with missing secret and cluster mismatch",
+			objects: []runtime.Object{
+				&coreV1.Secret{ObjectMeta: metaV1.ObjectMeta{Namespace: "team-a", Name: "no-ticket"}},
+			},
+			opts: func(t *testing.T) []Option {
+				s := newMaprSecret(t, "team-a", "ticket", "other.mapr.com", 1000, now.Add(30*24*time.Hour))
+
+				return []Option{
+					WithVolumeLister(fakeVolumeLister{
+						{Volume: newVolume("pv-ok", "team-a", "ticket", "other.mapr.com"), Ticket: &s},
+						{Volume: newVolume("pv-mismatch", "team-a", "ticket", "demo.mapr.com"), Ticket: &s},
+						{Volume: newVolume("pv-missing", "team-a", "missing", "demo.mapr.com")},
+						{Volume: newVolume("pv-no-ticket", "team-a", "no-ticket", "demo.mapr.com")},
+					}),
+				}
+			},
+			expected: []expectedFinding{
+				{check: CheckMissingSecret, kind: KindPersistentVolume, name: "pv-missing"},
+				{check: CheckClusterMismatch, kind: KindPersistentVolume, name: "pv-mismatch"},
+			},
+		},
+		{
+			name: "bound claims with expired ticket",
+			opts: func(t *testing.T) []Option {
+				expired := newMaprSecret(t, "team-a", "expired", "demo.mapr.com", 1000, now.Add(-time.Hour))
+				valid := newMaprSecret(t, "team-a", "valid", "demo.mapr.com", 1000, now.Add(time.Hour))
+
+				return []Option{
+					WithClaimLister(fakeClaimLister{
+						{Claim: newClaim("team-a", "data"), Volume: newVolume("pv-expired", "team-a", "expired", ""), Ticket: &expired},
+						{Claim: newClaim("team-a", "logs"), Volume: newVolume("pv-valid", "team-a", "valid", ""), Ticket: &valid},
+						{Claim: newClaim("team-a", "cache"), Volume: newVolume("pv-unknown", "team-a", "unknown", "")},
+					}),
+				}
+			},
+			expected: []expectedFinding{
+				{check: CheckExpiredTicketInUse, kind: KindPersistentVolume, name: "pv-expired"},
+			},
+		},
+		{
+			name: "storage class with cluster mismatch",
+			opts: func(t *testing.T) []Option {
+				s := newMaprSecret(t, "mapr", "ticket", "other.mapr.com", 1000, now.Add(time.Hour))
+
+				return []Option{
+					WithStorageClassLister(fakeStorageClassLister{
+						{StorageClass: newStorageClass("mismatch", "demo.mapr.com"), Namespace: "mapr", Name: "ticket", Ticket: &s},
+						{StorageClass: newStorageClass("matching", "other.mapr.com"), Namespace: "mapr", Name: "ticket", Ticket: &s},
+						{StorageClass: newStorageClass("no-cluster", ""), Namespace: "mapr", Name: "ticket", Ticket: &s},
+						{StorageClass: newStorageClass("no-ticket", "demo.mapr.com"), Namespace: "mapr", Name: "missing"},
+					}),
+				}
+			},
+			expected: []expectedFinding{
+				{check: CheckClusterMismatch, kind: KindStorageClass, name: "mismatch"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(test.objects...)
+			auditor := NewAuditor(client, test.opts(t)...)
+
+			findings, err := auditor.Audit()
+			assert.NoError(t, err)
+
+			got := make([]expectedFinding, 0, len(findings))
+			for _, f := range findings {
+				assert.Equal(t, f.Check.Severity(), f.Severity)
+				assert.NotEmpty(t, f.Message)
+
+				got = append(got, expectedFinding{
+					check:     f.Check,
+					kind:      f.Kind,
+					namespace: f.Namespace,
+					name:      f.Name,
+				})
+			}
+
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestCountAtLeast(t *testing.T) {
+	t.Parallel()
+
+	findings := []Finding{
+		{Severity: SeverityCritical},
+		{Severity: SeverityWarning},
+		{Severity: SeverityInfo},
+		{Severity: SeverityInfo},
+	}
+
+	assert.Equal(t, 4, CountAtLeast(findings, SeverityInfo))
+	assert.Equal(t, 2, CountAtLeast(findings, SeverityWarning))
+	assert.Equal(t, 1, CountAtLeast(findings, SeverityCritical))
+}
+
+func newMaprSecret(t *testing.T, namespace, name, cluster string, uid uint32, expiry time.Time) types.MaprSecret {
+	t.Helper()
+
+	maprTicket := ticket.NewMaprTicket()
+	maprTicket.Cluster = cluster
+	maprTicket.UserCreds.Uid = ptr.To(uid)
+	maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(expiry.Unix()))
+
+	secret, err := ticket.NewSecret(namespace, name, maprTicket)
+	assert.NoError(t, err)
+
+	return *types.NewMaprSecret((*types.Secret)(secret), ticket.SecretMaprTicketKey)
+}
+
+func inUse(s types.MaprSecret) types.MaprSecret {
+	s.NumPVC = 1
+	return s
+}
+
+func newVolume(name, secretNamespace, secretName, cluster string) *types.PersistentVolume {
+	return &types.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+		},
+		Spec: coreV1.PersistentVolumeSpec{
+			PersistentVolumeSource: coreV1.PersistentVolumeSource{
+				CSI: &coreV1.CSIPersistentVolumeSource{
+					Driver: types.MaprCSIProvisionerKDF,
+					VolumeAttributes: map[string]string{
+						"cluster": cluster,
+					},
+					NodePublishSecretRef: &coreV1.SecretReference{
+						Namespace: secretNamespace,
+						Name:      secretName,
+					},
+				},
+			},
+		},
+	}
+}
+
+func newClaim(namespace, name string) *types.PersistentVolumeClaim {
+	return &types.PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Status: coreV1.PersistentVolumeClaimStatus{
+			Phase: coreV1.ClaimBound,
+		},
+	}
+}
+
+func newStorageClass(name, cluster string) *types.StorageClass {
+	return &types.StorageClass{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+		},
+		Provisioner: types.MaprCSIProvisionerKDF,
+		Parameters: map[string]string{
+			"cluster": cluster,
+		},
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import "time"
+
+// Option is a function that can be used to configure the auditor.
+type Option func(*Auditor)
+
+// WithSecretLister configures the auditor to check the secrets returned by the given secret
+// lister. The lister should determine which secrets are in use, otherwise all secrets are
+// reported as unused.
+func WithSecretLister(secretLister secretLister) Option {
+	return func(a *Auditor) {
+		a.secretLister = secretLister
+	}
+}
+
+// WithVolumeLister configures the auditor to check the persistent volumes returned by the given
+// volume lister
+func WithVolumeLister(volumeLister volumeLister) Option {
+	return func(a *Auditor) {
+		a.volumeLister = volumeLister
+	}
+}
+
+// WithClaimLister configures the auditor to check the bound claims returned by the given volume
+// claim lister
+func WithClaimLister(claimLister claimLister) Option {
+	return func(a *Auditor) {
+		a.claimLister = claimLister
+	}
+}
+
+// WithStorageClassLister configures the auditor to check the StorageClasses returned by the given
+// StorageClass lister
+func WithStorageClassLister(storageClassLister storageClassLister) Option {
+	return func(a *Auditor) {
+		a.storageClassLister = storageClassLister
+	}
+}
+
+// WithExpiresWithin configures the threshold for tickets to be reported as expiring soon
+func WithExpiresWithin(expiresWithin time.Duration) Option {
+	return func(a *Auditor) {
+		a.expiresWithin = expiresWithin
+	}
+}
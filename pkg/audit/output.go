@@ -0,0 +1,347 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+const (
+	// toolName is the name of the tool reported in SARIF logs
+	toolName = "kubectl-mapr-ticket"
+
+	// toolInformationURI is the URI of the tool reported in SARIF logs
+	toolInformationURI = "https://github.com/nobbs/kubectl-mapr-ticket"
+
+	// sarifVersion and sarifSchema identify the version of the SARIF format
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+var (
+	tableColumnDefinitions = []metaV1.TableColumnDefinition{
+		{
+			Name:        "Severity",
+			Type:        "string",
+			Description: "Severity of the finding",
+			Priority:    0,
+		},
+		{
+			Name:        "Check",
+			Type:        "string",
+			Description: "Check that produced the finding",
+			Priority:    0,
+		},
+		{
+			Name:        "Kind",
+			Type:        "string",
+			Description: "Kind of the object the finding refers to",
+			Priority:    0,
+		},
+		{
+			Name:        "Namespace",
+			Type:        "string",
+			Description: "Namespace of the object the finding refers to",
+			Priority:    0,
+		},
+		{
+			Name:        "Name",
+			Type:        "string",
+			Format:      "name",
+			Description: "Name of the object the finding refers to",
+			Priority:    0,
+		},
+		{
+			Name:        "Message",
+			Type:        "string",
+			Description: "Description of the finding",
+			Priority:    0,
+		},
+	}
+
+	// sarifLevels maps the severities to the levels of SARIF results
+	sarifLevels = map[Severity]string{
+		SeverityInfo:     "note",
+		SeverityWarning:  "warning",
+		SeverityCritical: "error",
+	}
+)
+
+// Report is the structure of the JSON output, containing the number of findings per severity
+// and the findings themselves
+type Report struct {
+	Summary  map[Severity]int `json:"summary"`
+	Findings []Finding        `json:"findings"`
+}
+
+// Print prints the findings to the given output stream in a tabular format known by kubectl.
+func Print(cmd *cobra.Command, findings []Finding) error {
+	table := &metaV1.Table{
+		ColumnDefinitions: tableColumnDefinitions,
+		Rows:              generateRows(findings),
+	}
+
+	printer := printers.NewTablePrinter(printers.PrintOptions{})
+
+	return printer.PrintObj(table, cmd.OutOrStdout())
+}
+
+// PrintJSON prints the findings as a JSON report
+func PrintJSON(w io.Writer, findings []Finding) error {
+	report := Report{
+		Summary:  make(map[Severity]int, len(SeverityList)),
+		Findings: findings,
+	}
+
+	for _, severity := range SeverityList {
+		report.Summary[severity] = 0
+	}
+
+	for _, f := range findings {
+		report.Summary[f.Severity]++
+	}
+
+	return writeJSON(w, report)
+}
+
+// generateRows generates the table rows for the given findings.
+func generateRows(findings []Finding) []metaV1.TableRow {
+	rows := make([]metaV1.TableRow, 0, len(findings))
+
+	for _, f := range findings {
+		rows = append(rows, metaV1.TableRow{
+			Cells: []any{
+				string(f.Severity),
+				string(f.Check),
+				f.Kind,
+				f.Namespace,
+				f.Name,
+				f.Message,
+			},
+		})
+	}
+
+	return rows
+}
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite contains the test cases of a single check
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single finding, or a passed test case if a check has no findings
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure describes the finding of a failed test case
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// PrintJUnit prints the findings as a JUnit XML report. Each check is reported as a test suite
+// with one failed test case per finding, or a single passed test case if there are no findings.
+func PrintJUnit(w io.Writer, findings []Finding) error {
+	report := junitTestSuites{
+		Name: toolName,
+	}
+
+	for _, check := range CheckList {
+		suite := junitTestSuite{
+			Name: string(check),
+		}
+
+		for _, f := range findings {
+			if f.Check != check {
+				continue
+			}
+
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      objectName(&f),
+				ClassName: string(check),
+				Failure: &junitFailure{
+					Message: f.Message,
+					Type:    string(f.Severity),
+					Text:    check.Description(),
+				},
+			})
+			suite.Failures++
+		}
+
+		if len(suite.TestCases) == 0 {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      check.Description(),
+				ClassName: string(check),
+			})
+		}
+
+		suite.Tests = len(suite.TestCases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.TestSuites = append(report.TestSuites, suite)
+	}
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, out)
+
+	return err
+}
+
+// sarifLog is the root object of a SARIF log
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRun describes a single run of the tool and its results
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Version        string      `json:"version,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+// sarifRule describes one of the checks
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+// sarifResult is a single finding
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifLocation refers to the Kubernetes object of a finding using a logical location, as
+// findings don't refer to source files
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// PrintSARIF prints the findings as a SARIF log, with one rule per check. The version is reported
+// as the version of the tool.
+func PrintSARIF(w io.Writer, findings []Finding, version string) error {
+	driver := sarifDriver{
+		Name:           toolName,
+		InformationURI: toolInformationURI,
+		Version:        version,
+		Rules:          make([]sarifRule, 0, len(CheckList)),
+	}
+
+	for _, check := range CheckList {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   string(check),
+			ShortDescription:     sarifMessage{Text: check.Description()},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevels[check.Severity()]},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+
+	for _, f := range findings {
+		results = append(results, sarifResult{
+			RuleID:  string(f.Check),
+			Level:   sarifLevels[f.Severity],
+			Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{
+				{
+					LogicalLocations: []sarifLogicalLocation{
+						{
+							Name:               f.Name,
+							FullyQualifiedName: objectName(&f),
+							Kind:               "resource",
+						},
+					},
+				},
+			},
+		})
+	}
+
+	return writeJSON(w, sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{
+			{
+				Tool:    sarifTool{Driver: driver},
+				Results: results,
+			},
+		},
+	})
+}
+
+// objectName returns the name of the object the finding refers to in the form kind/name or
+// kind/namespace/name
+func objectName(f *Finding) string {
+	if f.Namespace == "" {
+		return f.Kind + "/" + f.Name
+	}
+
+	return f.Kind + "/" + f.Namespace + "/" + f.Name
+}
+
+// writeJSON writes the value as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
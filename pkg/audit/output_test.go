@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package audit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/audit"
+)
+
+var testFindings = []Finding{
+	{
+		Check:    CheckExpiredTicketInUse,
+		Severity: SeverityCritical,
+		Kind:     KindPersistentVolume,
+		Name:     "pv-expired",
+		Message:  "volume uses an expired ticket",
+	},
+	{
+		Check:     CheckUnusedSecret,
+		Severity:  SeverityInfo,
+		Kind:      KindSecret,
+		Namespace: "team-a",
+		Name:      "ticket",
+		Message:   "ticket is not used",
+	},
+}
+
+func TestPrintJSON(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	assert.NoError(t, PrintJSON(&out, testFindings))
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+
+	assert.Equal(t, map[Severity]int{SeverityInfo: 1, SeverityWarning: 0, SeverityCritical: 1}, report.Summary)
+	assert.Equal(t, testFindings, report.Findings)
+}
+
+func TestPrintJUnit(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	assert.NoError(t, PrintJUnit(&out, testFindings))
+
+	var report struct {
+		Tests      int `xml:"tests,attr"`
+		Failures   int `xml:"failures,attr"`
+		TestSuites []struct {
+			Name     string `xml:"name,attr"`
+			Failures int    `xml:"failures,attr"`
+		} `xml:"testsuite"`
+	}
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &report))
+
+	// one test suite per check, with a passed test case for each check without findings
+	assert.Len(t, report.TestSuites, len(CheckList))
+	assert.Equal(t, len(CheckList), report.Tests)
+	assert.Equal(t, 2, report.Failures)
+}
+
+func TestPrintSARIF(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	assert.NoError(t, PrintSARIF(&out, testFindings, "v0.0.0"))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Version string           `json:"version"`
+					Rules   []map[string]any `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID string `json:"ruleId"`
+				Level  string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &log))
+
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Len(t, log.Runs, 1)
+	assert.Equal(t, "v0.0.0", log.Runs[0].Tool.Driver.Version)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(CheckList))
+	assert.Len(t, log.Runs[0].Results, 2)
+	assert.Equal(t, string(CheckExpiredTicketInUse), log.Runs[0].Results[0].RuleID)
+	assert.Equal(t, "error", log.Runs[0].Results[0].Level)
+	assert.Equal(t, "note", log.Runs[0].Results[1].Level)
+}
@@ -15,16 +15,19 @@ package claim
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
 
 	coreV1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
 type secretLister interface {
-	List() ([]types.MaprSecret, error)
+	ListReferenced(refs []k8sTypes.NamespacedName) ([]types.MaprSecret, error)
 }
 
 // Lister is the struct that is used to list volume claims refering to MapR-backed persistent
@@ -33,10 +36,17 @@ type Lister struct {
 	client    kubernetes.Interface
 	namespace string
 
-	secretLister secretLister
-	sortBy       []SortOption
+	secretLister       secretLister
+	sortBy             []SortOption
+	labelSelector      string
+	fieldSelector      string
+	filterByExpression *filter.Expression
 
 	volumeClaims []types.MaprVolumeClaim
+
+	// err holds the error of collecting the tickets, as the claims would otherwise be reported
+	// without a ticket
+	err error
 }
 
 // NewLister creates a new volume claim lister. It requires a Kubernetes client and a namespace
@@ -57,6 +67,8 @@ func NewLister(client kubernetes.Interface, namespace string, opts ...ListerOpti
 
 // List returns a list of volume claims that are provisioned by one of the MapR CSI provisioners.
 func (l *Lister) List() ([]types.MaprVolumeClaim, error) {
+	l.err = nil
+
 	if err := l.getClaims(); err != nil {
 		return nil, err
 	}
@@ -65,14 +77,28 @@ func (l *Lister) List() ([]types.MaprVolumeClaim, error) {
 		collectVolumes().
 		filterClaimsMaprCSI().
 		collectTickets().
+		filterClaimsByExpression().
 		sort()
 
+	if l.err != nil {
+		return nil, l.err
+	}
+
 	return l.volumeClaims, nil
 }
 
+// listOptions returns the options used to list the persistent volume claims, applying the label and field
+// selectors of the Lister
+func (l *Lister) listOptions() metaV1.ListOptions {
+	return metaV1.ListOptions{
+		LabelSelector: l.labelSelector,
+		FieldSelector: l.fieldSelector,
+	}
+}
+
 // getClaims returns a list of all PVCs in the cluster
 func (l *Lister) getClaims() error {
-	claims, err := l.client.CoreV1().PersistentVolumeClaims(l.namespace).List(context.TODO(), metaV1.ListOptions{})
+	claims, err := l.client.CoreV1().PersistentVolumeClaims(l.namespace).List(context.TODO(), l.listOptions())
 	if err != nil {
 		return err
 	}
@@ -124,28 +150,50 @@ func (l *Lister) filterClaimsMaprCSI() *Lister {
 	return l
 }
 
-// collectVolumes collects the PV for each PVC.
-func (l *Lister) collectVolumes() *Lister {
-	// Get all PVs in the cluster
-	pvs, err := l.client.CoreV1().PersistentVolumes().List(context.TODO(), metaV1.ListOptions{})
-	if err != nil {
+// filterClaimsByExpression filters volume claims to those matching the filter expression, if one
+// was provided to the Lister. It runs after the volumes and tickets have been collected, so the
+// expression can refer to them.
+func (l *Lister) filterClaimsByExpression() *Lister {
+	// if the filter is not enabled, we can skip this step
+	if l.filterByExpression == nil {
 		return l
 	}
 
-	// Lookup the PV for each PVC
-	lookupPV := func(pvc *types.PersistentVolumeClaim) (*coreV1.PersistentVolume, bool) {
-		for _, pv := range pvs.Items {
-			if pv.Spec.CSI != nil && pv.Name == pvc.Spec.VolumeName {
-				return &pv, true
-			}
+	var filtered []types.MaprVolumeClaim
+
+	for i := range l.volumeClaims {
+		if l.filterByExpression.Matches(filter.ClaimActivation(&l.volumeClaims[i])) {
+			filtered = append(filtered, l.volumeClaims[i])
 		}
+	}
+
+	l.volumeClaims = filtered
 
-		return nil, false
+	return l
+}
+
+// collectVolumes collects the PV for each PVC. All PVs are listed at once, as the label and field
+// selectors of the PVCs don't apply to their PVs, and matched to the PVCs by name.
+func (l *Lister) collectVolumes() *Lister {
+	// return early if there are no volume claims
+	if len(l.volumeClaims) == 0 {
+		return l
+	}
+
+	list, err := l.client.CoreV1().PersistentVolumes().List(context.TODO(), metaV1.ListOptions{})
+	if err != nil {
+		l.err = fmt.Errorf("failed to list persistent volumes bound to persistent volume claims: %w", err)
+		return l
+	}
+
+	pvs := make(map[string]*coreV1.PersistentVolume, len(list.Items))
+	for i := range list.Items {
+		pvs[list.Items[i].Name] = &list.Items[i]
 	}
 
 	filtered := make([]types.MaprVolumeClaim, 0, len(l.volumeClaims))
 	for _, volumeClaim := range l.volumeClaims {
-		if pv, ok := lookupPV(volumeClaim.Claim); ok {
+		if pv, ok := pvs[volumeClaim.Claim.Spec.VolumeName]; ok && pv.Spec.CSI != nil {
 			volumeClaim.Volume = (*types.PersistentVolume)(pv)
 			filtered = append(filtered, volumeClaim)
 		}
@@ -168,9 +216,19 @@ func (l *Lister) collectTickets() *Lister {
 		return l
 	}
 
-	// collect all tickets via the secret lister
-	tickets, err := l.secretLister.List()
+	// collect all tickets via the secret lister, getting the referenced secrets one by one if they
+	// can't be listed
+	refs := make([]k8sTypes.NamespacedName, 0, len(l.volumeClaims))
+	for i := range l.volumeClaims {
+		refs = append(refs, k8sTypes.NamespacedName{
+			Namespace: l.volumeClaims[i].Volume.GetSecretNamespace(),
+			Name:      l.volumeClaims[i].Volume.GetSecretName(),
+		})
+	}
+
+	tickets, err := l.secretLister.ListReferenced(refs)
 	if err != nil {
+		l.err = fmt.Errorf("failed to list secrets referenced by persistent volume claims: %w", err)
 		return l
 	}
 
@@ -6,6 +6,7 @@
 package claim_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -16,9 +17,12 @@ import (
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
 
 	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	clientTesting "k8s.io/client-go/testing"
 )
 
 const (
@@ -164,6 +168,114 @@ type listerFields struct {
 	opts      []ListerOption
 }
 
+func TestLister_WithSelectors(t *testing.T) {
+	t.Parallel()
+
+	newClient := func() *fake.Clientset {
+		return fake.NewSimpleClientset(
+			newClaim("default", "claim-1", withVolumeName("volume-1"), withPhase(coreV1.ClaimBound), withLabels(map[string]string{"app": "spark"})),
+			newClaim("default", "claim-2", withVolumeName("volume-2"), withPhase(coreV1.ClaimBound), withLabels(map[string]string{"app": "hive"})),
+			newCSIVolume("volume-1", CSIProvisionerMapr, withClaimRef("default", "claim-1")),
+			newCSIVolume("volume-2", CSIProvisionerMapr, withClaimRef("default", "claim-2")),
+		)
+	}
+
+	tests := []struct {
+		name              string
+		opts              []ListerOption
+		expected          []expecetedClaim
+		wantLabelSelector string
+		wantFieldSelector string
+	}{
+		{
+			name: "no selectors",
+			expected: []expecetedClaim{
+				expectClaim("claim-1", "default"),
+				expectClaim("claim-2", "default"),
+			},
+		},
+		{
+			name: "label selector resolves volumes of selected claims",
+			opts: []ListerOption{
+				WithLabelSelector("app=spark"),
+			},
+			expected: []expecetedClaim{
+				expectClaim("claim-1", "default"),
+			},
+			wantLabelSelector: "app=spark",
+		},
+		{
+			name: "field selector is passed to the API",
+			opts: []ListerOption{
+				WithFieldSelector("metadata.name=claim-2"),
+			},
+			expected: []expecetedClaim{
+				expectClaim("claim-1", "default"),
+				expectClaim("claim-2", "default"),
+			},
+			wantFieldSelector: "metadata.name=claim-2",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newClient()
+			l := NewLister(client, "default", test.opts...)
+
+			actual, err := l.List()
+			assert.NoError(t, err)
+			assertClaims(t, test.expected, actual)
+
+			for _, claim := range actual {
+				assert.NotNil(t, claim.Volume)
+			}
+
+			// the selectors must only be applied to the claims, not to their volumes, which are
+			// listed once instead of being retrieved one by one
+			for _, action := range client.Actions() {
+				list, ok := action.(clientTesting.ListAction)
+				if !ok {
+					assert.NotEqual(t, "persistentvolumes", action.GetResource().Resource)
+					continue
+				}
+
+				restrictions := list.GetListRestrictions()
+
+				if action.GetResource().Resource == "persistentvolumeclaims" {
+					assert.Equal(t, test.wantLabelSelector, restrictions.Labels.String())
+					assert.Equal(t, test.wantFieldSelector, restrictions.Fields.String())
+				} else {
+					assert.True(t, restrictions.Labels.Empty())
+					assert.True(t, restrictions.Fields.Empty())
+				}
+			}
+		})
+	}
+}
+
+func TestLister_VolumeListError(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newClaim("default", "claim-1", withVolumeName("volume-1"), withPhase(coreV1.ClaimBound), withLabels(map[string]string{"app": "spark"})),
+		newCSIVolume("volume-1", CSIProvisionerMapr, withClaimRef("default", "claim-1")),
+	)
+
+	// fail listing the volumes, so the claims can't be resolved to MapR-backed volumes
+	client.PrependReactor("list", "persistentvolumes", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apiErrors.NewInternalError(errors.New("etcd unavailable"))
+	})
+
+	l := NewLister(client, "default", WithLabelSelector("app=spark"))
+
+	actual, err := l.List()
+	assert.True(t, apiErrors.IsInternalError(err))
+	assert.Empty(t, actual)
+}
+
 type expecetedClaim struct {
 	name      string
 	namespace string
@@ -192,6 +304,7 @@ type claimOptions struct {
 	namespace  string
 	volumeName string
 	phase      coreV1.PersistentVolumeClaimPhase
+	labels     map[string]string
 }
 
 type claimOption func(*claimOptions)
@@ -208,6 +321,12 @@ func withPhase(phase coreV1.PersistentVolumeClaimPhase) claimOption {
 	}
 }
 
+func withLabels(labels map[string]string) claimOption {
+	return func(c *claimOptions) {
+		c.labels = labels
+	}
+}
+
 func newClaim(namespace, name string, opts ...claimOption) *coreV1.PersistentVolumeClaim {
 	c := &claimOptions{
 		name:      name,
@@ -222,6 +341,7 @@ func newClaim(namespace, name string, opts ...claimOption) *coreV1.PersistentVol
 		ObjectMeta: metaV1.ObjectMeta{
 			Name:      c.name,
 			Namespace: c.namespace,
+			Labels:    c.labels,
 		},
 		Spec: coreV1.PersistentVolumeClaimSpec{
 			VolumeName: c.volumeName,
@@ -5,6 +5,8 @@
 
 package claim
 
+import "github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
+
 // ListerOption is a function that can be used to configure the volume claim lister.
 type ListerOption func(*Lister)
 
@@ -21,3 +23,25 @@ func WithSortBy(sortBy []SortOption) ListerOption {
 		l.sortBy = sortBy
 	}
 }
+
+// WithLabelSelector sets the label selector used by the Lister to select the persistent volume claims to list
+func WithLabelSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.labelSelector = selector
+	}
+}
+
+// WithFieldSelector sets the field selector used by the Lister to select the persistent volume claims to list
+func WithFieldSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.fieldSelector = selector
+	}
+}
+
+// WithFilterByExpression configures the volume claim lister to only return the volume claims
+// matching the given filter expression.
+func WithFilterByExpression(expression *filter.Expression) ListerOption {
+	return func(l *Lister) {
+		l.filterByExpression = expression
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	coreV1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/printers"
 )
@@ -80,6 +81,14 @@ func Print(cmd *cobra.Command, volumeClaims []types.MaprVolumeClaim) error {
 	// generate the table
 	table := generableTable(volumeClaims)
 
+	// add the context column when listing across multiple contexts
+	contexts := make([]string, 0, len(volumeClaims))
+	for i := range volumeClaims {
+		contexts = append(contexts, volumeClaims[i].Context)
+	}
+
+	util.AddContextColumn(table, contexts)
+
 	// print the table
 	printer := printers.NewTablePrinter(printers.PrintOptions{
 		WithNamespace: allNamespaces,
@@ -94,6 +103,17 @@ func Print(cmd *cobra.Command, volumeClaims []types.MaprVolumeClaim) error {
 	return nil
 }
 
+// PrintObjects prints the volume claims as a structured list using the given printer, e.g. a JSON, YAML,
+// JSONPath, Go template or custom columns printer.
+func PrintObjects(cmd *cobra.Command, printer printers.ResourcePrinter, volumeClaims []types.MaprVolumeClaim) error {
+	list, err := generateList(volumeClaims)
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(list, cmd.OutOrStdout())
+}
+
 // generableTable generates a table from the given volume claims.
 func generableTable(volumeClaims []types.MaprVolumeClaim) *metaV1.Table {
 	rows := generateRows(volumeClaims)
@@ -104,6 +124,17 @@ func generableTable(volumeClaims []types.MaprVolumeClaim) *metaV1.Table {
 	}
 }
 
+// generateList generates a list of structured items from the given volume claims.
+func generateList(volumeClaims []types.MaprVolumeClaim) (*unstructured.UnstructuredList, error) {
+	items := make([]*types.MaprVolumeClaimItem, 0, len(volumeClaims))
+
+	for i := range volumeClaims {
+		items = append(items, volumeClaims[i].Item())
+	}
+
+	return types.NewList(items)
+}
+
 // generateRows generates the rows for the given volume claims.
 func generateRows(volumeClaims []types.MaprVolumeClaim) []metaV1.TableRow {
 	rows := make([]metaV1.TableRow, 0, len(volumeClaims))
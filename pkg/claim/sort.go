@@ -6,9 +6,11 @@
 package claim
 
 import (
-	"sort"
+	"cmp"
+	"slices"
 
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
 )
 
 // SortOption is the type of a sort option, basically a wrapper around a string to provide
@@ -54,93 +56,104 @@ func (s SortOption) String() string {
 	return string(s)
 }
 
-func sortByName(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Claim.GetName() < claims[j].Claim.GetName()
-	})
+// compareByNamespace compares the volume claims by namespace
+func compareByNamespace(a, b *types.MaprVolumeClaim) int {
+	return cmp.Compare(a.Claim.GetNamespace(), b.Claim.GetNamespace())
 }
 
-func sortByNamespace(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Claim.GetNamespace() < claims[j].Claim.GetNamespace()
-	})
+// compareByName compares the volume claims by name
+func compareByName(a, b *types.MaprVolumeClaim) int {
+	return cmp.Compare(a.Claim.GetName(), b.Claim.GetName())
 }
 
-func sortBySecretNamespace(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Volume.GetSecretNamespace() < claims[j].Volume.GetSecretNamespace()
-	})
+// compareBySecretNamespace compares the volume claims by namespace of the secret they use
+func compareBySecretNamespace(a, b *types.MaprVolumeClaim) int {
+	return cmp.Compare(a.Volume.GetSecretNamespace(), b.Volume.GetSecretNamespace())
 }
 
-func sortBySecretName(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Volume.GetSecretName() < claims[j].Volume.GetSecretName()
-	})
+// compareBySecretName compares the volume claims by name of the secret they use
+func compareBySecretName(a, b *types.MaprVolumeClaim) int {
+	return cmp.Compare(a.Volume.GetSecretName(), b.Volume.GetSecretName())
 }
 
-func sortByVolumeName(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Volume.GetName() < claims[j].Volume.GetName()
-	})
+// compareByVolumeName compares the volume claims by name of the volume they are bound to
+func compareByVolumeName(a, b *types.MaprVolumeClaim) int {
+	return cmp.Compare(a.Volume.GetName(), b.Volume.GetName())
 }
 
-func sortByVolumePath(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Volume.GetVolumePath() < claims[j].Volume.GetVolumePath()
-	})
+// compareByVolumePath compares the volume claims by MapR volume path
+func compareByVolumePath(a, b *types.MaprVolumeClaim) int {
+	return cmp.Compare(a.Volume.GetVolumePath(), b.Volume.GetVolumePath())
 }
 
-func sortByVolumeHandle(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Volume.GetVolumeHandle() < claims[j].Volume.GetVolumeHandle()
-	})
+// compareByVolumeHandle compares the volume claims by CSI volume handle
+func compareByVolumeHandle(a, b *types.MaprVolumeClaim) int {
+	return cmp.Compare(a.Volume.GetVolumeHandle(), b.Volume.GetVolumeHandle())
 }
 
-func sortByExpiration(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Ticket.GetExpirationTime().Before(claims[j].Ticket.GetExpirationTime())
-	})
+// compareByExpiration compares the volume claims by expiry time of the ticket
+func compareByExpiration(a, b *types.MaprVolumeClaim) int {
+	return a.Ticket.GetExpirationTime().Compare(b.Ticket.GetExpirationTime())
 }
 
-func sortByAge(claims []types.MaprVolumeClaim) {
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Claim.CreationTimestamp.Before(&claims[j].Claim.CreationTimestamp)
-	})
+// compareByAge compares the volume claims by creation timestamp
+func compareByAge(a, b *types.MaprVolumeClaim) int {
+	return a.Claim.CreationTimestamp.Time.Compare(b.Claim.CreationTimestamp.Time)
 }
 
-// sort sorts the items by the specified sort options, in reverse order of the
-// order in which they are specified. This makes for a more natural sort result
-// when using multiple sort options.
+// comparators maps the sort options to the functions comparing the volume claims by the option
+var comparators = map[SortOption]func(a, b *types.MaprVolumeClaim) int{
+	SortByNamespace:       compareByNamespace,
+	SortByName:            compareByName,
+	SortBySecretNamespace: compareBySecretNamespace,
+	SortBySecretName:      compareBySecretName,
+	SortByVolumeName:      compareByVolumeName,
+	SortByVolumePath:      compareByVolumePath,
+	SortByVolumeHandle:    compareByVolumeHandle,
+	SortByExpiration:      compareByExpiration,
+	SortByAge:             compareByAge,
+}
+
+// sort sorts the items by the sort options of the lister.
 func (l *Lister) sort() *Lister {
-	// reverse the order of the sort options
-	order := make([]SortOption, len(l.sortBy))
-	for i, j := 0, len(l.sortBy)-1; i < len(l.sortBy); i, j = i+1, j-1 {
-		order[i] = l.sortBy[j]
+	SortItems(l.volumeClaims, l.sortBy)
+
+	return l
+}
+
+// SortItems sorts the given persistent volume claims by the specified sort options. Items that are equal by
+// the first option are sorted by the second one, and so on. Options prefixed with "-" or suffixed
+// with ":desc" sort in descending order, see util.ParseSortOption. The sort is stable, so items
+// equal by all options keep their order.
+func SortItems(items []types.MaprVolumeClaim, sortBy []SortOption) {
+	type sortKey struct {
+		compare    func(a, b *types.MaprVolumeClaim) int
+		descending bool
 	}
 
-	// sort the items by the specified sort options
-	for _, sortOption := range order {
-		switch sortOption {
-		case SortByNamespace:
-			sortByNamespace(l.volumeClaims)
-		case SortByName:
-			sortByName(l.volumeClaims)
-		case SortBySecretNamespace:
-			sortBySecretNamespace(l.volumeClaims)
-		case SortBySecretName:
-			sortBySecretName(l.volumeClaims)
-		case SortByVolumeName:
-			sortByVolumeName(l.volumeClaims)
-		case SortByVolumePath:
-			sortByVolumePath(l.volumeClaims)
-		case SortByVolumeHandle:
-			sortByVolumeHandle(l.volumeClaims)
-		case SortByExpiration:
-			sortByExpiration(l.volumeClaims)
-		case SortByAge:
-			sortByAge(l.volumeClaims)
+	keys := make([]sortKey, 0, len(sortBy))
+
+	for _, sortOption := range sortBy {
+		field, descending := util.ParseSortOption(sortOption.String())
+
+		if compare, ok := comparators[SortOption(field)]; ok {
+			keys = append(keys, sortKey{compare: compare, descending: descending})
 		}
 	}
 
-	return l
+	slices.SortStableFunc(items, func(a, b types.MaprVolumeClaim) int {
+		for _, key := range keys {
+			result := key.compare(&a, &b)
+
+			if key.descending {
+				result = -result
+			}
+
+			if result != 0 {
+				return result
+			}
+		}
+
+		return 0
+	})
 }
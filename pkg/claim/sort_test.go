@@ -4,3 +4,81 @@
 // SPDX-License-Identifier: MIT
 
 package claim_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/claim"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSortItems(t *testing.T) {
+	t.Parallel()
+
+	// newItem returns a claim bound to the given volume
+	newItem := func(namespace, name, volumeName string) types.MaprVolumeClaim {
+		return types.MaprVolumeClaim{
+			Claim:  &types.PersistentVolumeClaim{ObjectMeta: metaV1.ObjectMeta{Namespace: namespace, Name: name}},
+			Volume: &types.PersistentVolume{ObjectMeta: metaV1.ObjectMeta{Name: volumeName}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		sortBy []SortOption
+		want   []string
+	}{
+		{
+			name:   "default",
+			sortBy: DefaultSortBy,
+			want:   []string{"a/x", "a/y", "b/x", "b/y"},
+		},
+		{
+			name:   "descending prefix",
+			sortBy: []SortOption{"-namespace", SortByName},
+			want:   []string{"b/x", "b/y", "a/x", "a/y"},
+		},
+		{
+			name:   "descending suffix",
+			sortBy: []SortOption{SortByNamespace, "name:desc"},
+			want:   []string{"a/y", "a/x", "b/y", "b/x"},
+		},
+		{
+			name:   "volume name descending",
+			sortBy: []SortOption{"volume.name:desc"},
+			want:   []string{"b/y", "a/x", "b/x", "a/y"},
+		},
+		{
+			name:   "stable for equal items",
+			sortBy: []SortOption{SortByExpiration},
+			want:   []string{"b/y", "a/x", "b/x", "a/y"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			items := []types.MaprVolumeClaim{
+				newItem("b", "y", "pv-4"),
+				newItem("a", "x", "pv-3"),
+				newItem("b", "x", "pv-2"),
+				newItem("a", "y", "pv-1"),
+			}
+
+			SortItems(items, test.sortBy)
+
+			got := make([]string, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.Claim.GetNamespace()+"/"+item.Claim.GetName())
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
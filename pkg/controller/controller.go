@@ -0,0 +1,276 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package controller implements a controller that periodically evaluates the MapR tickets stored
+// in secrets and surfaces tickets nearing their expiry to the users of the cluster.
+//
+// On each reconciliation, the secrets containing a ticket are annotated with the expiration time
+// and status of the ticket. For expired tickets and tickets expiring soon, Kubernetes Events are
+// emitted for the secrets as well as for the persistent volume claims using them, so they show up
+// in kubectl describe without requiring this plugin. Events are emitted on every reconciliation,
+// as the API server only keeps them for a limited time.
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/claim"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// DefaultExpiresWithin is the default threshold for tickets to be considered as expiring soon
+	DefaultExpiresWithin = 7 * 24 * time.Hour
+
+	// ReasonTicketExpiringSoon is the reason of events emitted for tickets expiring soon
+	ReasonTicketExpiringSoon = "TicketExpiringSoon"
+
+	// ReasonTicketExpired is the reason of events emitted for expired tickets
+	ReasonTicketExpired = "TicketExpired"
+)
+
+// Status is the status of a ticket as stored in the ticket.AnnotationStatus annotation
+type Status string
+
+const (
+	StatusValid        Status = "Valid"
+	StatusExpiringSoon Status = "ExpiringSoon"
+	StatusExpired      Status = "Expired"
+)
+
+// Controller periodically evaluates the tickets stored in secrets, annotates the secrets with the
+// status of their ticket and emits events for expired tickets and tickets expiring soon.
+type Controller struct {
+	client    kubernetes.Interface
+	namespace string
+	recorder  record.EventRecorder
+
+	// secretOpts are additional options passed to the secret lister on each reconciliation
+	secretOpts []secret.ListerOption
+
+	expiresWithin time.Duration
+}
+
+// NewController returns a new controller for the ticket secrets and persistent volume claims in the
+// given namespace, use util.NamespaceAll to watch all namespaces. Events are emitted using the
+// given recorder. It also accepts a list of options that can be used to configure the controller.
+func NewController(client kubernetes.Interface, namespace string, recorder record.EventRecorder, opts ...Option) *Controller {
+	c := &Controller{
+		client:        client,
+		namespace:     namespace,
+		recorder:      recorder,
+		expiresWithin: DefaultExpiresWithin,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Reconcile evaluates all ticket secrets and the persistent volume claims using them once. Failing
+// to annotate a secret doesn't stop the reconciliation, all such errors are returned together.
+func (c *Controller) Reconcile() error {
+	secrets, err := secret.NewLister(c.client, c.namespace, c.secretOpts...).List()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	var errs []error
+
+	for _, s := range c.worstTicketPerSecret(secrets) {
+		if err := c.reconcileSecret(s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// reuse the secrets listed above instead of listing them again for the claims
+	claims, err := claim.NewLister(c.client, c.namespace, claim.WithSecretLister(staticSecretLister(secrets))).List()
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("failed to list persistent volume claims: %w", err))...)
+	}
+
+	for i := range claims {
+		c.reconcileClaim(&claims[i])
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run reconciles immediately and then periodically with the given interval until the context is
+// cancelled. Errors during a reconciliation are logged and don't stop the controller.
+func (c *Controller) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Reconcile(); err != nil {
+			slog.Error("failed to reconcile tickets", "error", err)
+		} else {
+			slog.Debug("reconciled tickets")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// worstTicketPerSecret returns a single ticket per secret, the one expiring first, in the order of
+// the given list. Secrets holding several tickets under different keys are only annotated once, so
+// the annotations reflect the ticket that needs attention first. Invalid tickets are skipped.
+func (c *Controller) worstTicketPerSecret(secrets []types.MaprSecret) []*types.MaprSecret {
+	var order []string
+
+	worst := make(map[string]*types.MaprSecret)
+
+	for i := range secrets {
+		s := &secrets[i]
+		if s.IsInvalid() {
+			continue
+		}
+
+		id := s.GetSecretNamespace() + "/" + s.GetSecretName()
+
+		current, ok := worst[id]
+		if !ok {
+			order = append(order, id)
+		}
+
+		if !ok || s.GetExpirationTime().Before(current.GetExpirationTime()) {
+			worst[id] = s
+		}
+	}
+
+	result := make([]*types.MaprSecret, 0, len(order))
+	for _, id := range order {
+		result = append(result, worst[id])
+	}
+
+	return result
+}
+
+// reconcileSecret annotates the secret with the expiration time and status of the ticket and emits
+// an event if the ticket is expired or expiring soon
+func (c *Controller) reconcileSecret(s *types.MaprSecret) error {
+	obj := (*coreV1.Secret)(s.Secret)
+	status := c.status(s.Ticket)
+
+	switch status {
+	case StatusExpired:
+		c.recorder.Eventf(obj, coreV1.EventTypeWarning, ReasonTicketExpired,
+			"MapR ticket stored under key %s for user %s of cluster %s expired at %s",
+			s.GetKey(), s.GetUser(), s.GetCluster(), formatTime(s.GetExpirationTime()))
+	case StatusExpiringSoon:
+		c.recorder.Eventf(obj, coreV1.EventTypeWarning, ReasonTicketExpiringSoon,
+			"MapR ticket stored under key %s for user %s of cluster %s expires at %s",
+			s.GetKey(), s.GetUser(), s.GetCluster(), formatTime(s.GetExpirationTime()))
+	}
+
+	annotations := map[string]string{
+		ticket.AnnotationExpiry: formatTime(s.GetExpirationTime()),
+		ticket.AnnotationStatus: string(status),
+	}
+
+	// only patch the secret if the annotations actually changed, to avoid needless writes
+	if isAnnotated(obj, annotations) {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.CoreV1().Secrets(obj.Namespace).Patch(
+		context.TODO(),
+		obj.Name,
+		k8sTypes.MergePatchType,
+		patch,
+		metaV1.PatchOptions{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to annotate secret %s/%s: %w", obj.Namespace, obj.Name, err)
+	}
+
+	return nil
+}
+
+// reconcileClaim emits an event for the persistent volume claim if the ticket used by its volume is
+// expired or expiring soon
+func (c *Controller) reconcileClaim(pvc *types.MaprVolumeClaim) {
+	if pvc.Ticket == nil || pvc.Ticket.IsInvalid() {
+		return
+	}
+
+	obj := (*coreV1.PersistentVolumeClaim)(pvc.Claim)
+
+	switch c.status(pvc.Ticket.Ticket) {
+	case StatusExpired:
+		c.recorder.Eventf(obj, coreV1.EventTypeWarning, ReasonTicketExpired,
+			"MapR ticket of secret %s/%s used by persistent volume %s expired at %s",
+			pvc.Ticket.GetSecretNamespace(), pvc.Ticket.GetSecretName(), pvc.Volume.GetName(), formatTime(pvc.Ticket.GetExpirationTime()))
+	case StatusExpiringSoon:
+		c.recorder.Eventf(obj, coreV1.EventTypeWarning, ReasonTicketExpiringSoon,
+			"MapR ticket of secret %s/%s used by persistent volume %s expires at %s",
+			pvc.Ticket.GetSecretNamespace(), pvc.Ticket.GetSecretName(), pvc.Volume.GetName(), formatTime(pvc.Ticket.GetExpirationTime()))
+	}
+}
+
+// status returns the status of the ticket based on the configured threshold
+func (c *Controller) status(t *ticket.Ticket) Status {
+	switch {
+	case t.IsExpired():
+		return StatusExpired
+	case t.ExpiresBefore(c.expiresWithin):
+		return StatusExpiringSoon
+	default:
+		return StatusValid
+	}
+}
+
+// isAnnotated returns true if the secret already has all the given annotations
+func isAnnotated(s *coreV1.Secret, annotations map[string]string) bool {
+	for key, value := range annotations {
+		if current, ok := s.Annotations[key]; !ok || current != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// formatTime formats the time the same way as the expiry annotation set on newly created secrets
+func formatTime(t time.Time) string {
+	return t.UTC().Format(ticket.DefaultTimeFormat)
+}
+
+// staticSecretLister is a secret lister returning an already listed set of secrets, regardless of
+// the secrets referenced
+type staticSecretLister []types.MaprSecret
+
+func (l staticSecretLister) ListReferenced(_ []k8sTypes.NamespacedName) ([]types.MaprSecret, error) {
+	return l, nil
+}
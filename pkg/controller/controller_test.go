@@ -0,0 +1,158 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package controller_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/controller"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientTesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+)
+
+func TestController_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Truncate(time.Second)
+
+	expired := newSecret(t, "team-a", "expired", now.Add(-time.Hour))
+	expiring := newSecret(t, "team-a", "expiring", now.Add(24*time.Hour))
+	valid := newSecret(t, "team-b", "valid", now.Add(30*24*time.Hour))
+
+	client := fake.NewSimpleClientset(
+		expired,
+		expiring,
+		valid,
+		newVolume("pv-expiring", "team-a", "expiring"),
+		newClaim("team-a", "data", "pv-expiring"),
+		newVolume("pv-valid", "team-b", "valid"),
+		newClaim("team-b", "logs", "pv-valid"),
+	)
+
+	recorder := record.NewFakeRecorder(10)
+	controller := NewController(client, util.NamespaceAll, recorder, WithExpiresWithin(7*24*time.Hour))
+
+	assert.NoError(t, controller.Reconcile())
+
+	// check the annotations of the secrets
+	expectedStatus := map[*coreV1.Secret]Status{
+		expired:  StatusExpired,
+		expiring: StatusExpiringSoon,
+		valid:    StatusValid,
+	}
+
+	for s, status := range expectedStatus {
+		got, err := client.CoreV1().Secrets(s.Namespace).Get(context.TODO(), s.Name, metaV1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, string(status), got.Annotations[ticket.AnnotationStatus], s.Name)
+		assert.Equal(t, s.Annotations[ticket.AnnotationExpiry], got.Annotations[ticket.AnnotationExpiry], s.Name)
+	}
+
+	// check the emitted events
+	assert.Equal(t, []string{
+		"Warning TicketExpired MapR ticket stored under key CONTAINER_TICKET for user mapr of cluster demo.mapr.com expired at " + now.Add(-time.Hour).UTC().Format(ticket.DefaultTimeFormat),
+		"Warning TicketExpiringSoon MapR ticket of secret team-a/expiring used by persistent volume pv-expiring expires at " + now.Add(24*time.Hour).UTC().Format(ticket.DefaultTimeFormat),
+		"Warning TicketExpiringSoon MapR ticket stored under key CONTAINER_TICKET for user mapr of cluster demo.mapr.com expires at " + now.Add(24*time.Hour).UTC().Format(ticket.DefaultTimeFormat),
+	}, drainEvents(recorder))
+}
+
+func TestController_Reconcile_SkipsUnchangedSecrets(t *testing.T) {
+	t.Parallel()
+
+	s := newSecret(t, "default", "ticket", time.Now().Add(30*24*time.Hour))
+	client := fake.NewSimpleClientset(s)
+
+	patches := 0
+	client.PrependReactor("patch", "secrets", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		patches++
+		return false, nil, nil
+	})
+
+	controller := NewController(client, "default", record.NewFakeRecorder(10))
+
+	// the first reconciliation adds the status annotation, the second one has nothing to do
+	assert.NoError(t, controller.Reconcile())
+	assert.NoError(t, controller.Reconcile())
+	assert.Equal(t, 1, patches)
+}
+
+func newSecret(t *testing.T, namespace, name string, expiry time.Time) *coreV1.Secret {
+	t.Helper()
+
+	maprTicket := ticket.NewMaprTicket()
+	maprTicket.Cluster = "demo.mapr.com"
+	maprTicket.UserCreds.UserName = ptr.To("mapr")
+	maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(expiry.Unix()))
+
+	secret, err := ticket.NewSecret(namespace, name, maprTicket)
+	assert.NoError(t, err)
+
+	return secret
+}
+
+func newVolume(name, secretNamespace, secretName string) *coreV1.PersistentVolume {
+	return &coreV1.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+		},
+		Spec: coreV1.PersistentVolumeSpec{
+			PersistentVolumeSource: coreV1.PersistentVolumeSource{
+				CSI: &coreV1.CSIPersistentVolumeSource{
+					Driver: types.MaprCSIProvisionerKDF,
+					NodePublishSecretRef: &coreV1.SecretReference{
+						Namespace: secretNamespace,
+						Name:      secretName,
+					},
+				},
+			},
+		},
+	}
+}
+
+func newClaim(namespace, name, volumeName string) *coreV1.PersistentVolumeClaim {
+	return &coreV1.PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: coreV1.PersistentVolumeClaimSpec{
+			VolumeName: volumeName,
+		},
+		Status: coreV1.PersistentVolumeClaimStatus{
+			Phase: coreV1.ClaimBound,
+		},
+	}
+}
+
+// drainEvents returns the events recorded so far, sorted to not depend on the listing order
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, strings.TrimSpace(event))
+		default:
+			sort.Strings(events)
+			return events
+		}
+	}
+}
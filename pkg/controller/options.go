@@ -0,0 +1,30 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package controller
+
+import (
+	"time"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+)
+
+// Option is a function that can be used to configure the controller.
+type Option func(*Controller)
+
+// WithSecretListerOptions configures the options passed to the secret lister on each
+// reconciliation, e.g. the data key the tickets are stored under
+func WithSecretListerOptions(opts ...secret.ListerOption) Option {
+	return func(c *Controller) {
+		c.secretOpts = opts
+	}
+}
+
+// WithExpiresWithin configures the threshold for tickets to be considered as expiring soon
+func WithExpiresWithin(d time.Duration) Option {
+	return func(c *Controller) {
+		c.expiresWithin = d
+	}
+}
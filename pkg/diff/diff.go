@@ -0,0 +1,132 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package diff implements comparing two MapR tickets field by field.
+//
+// Tickets are compared based on a summary of the fields that typically matter when a rotated
+// ticket breaks mounts, ie. the cluster, user credentials, creation and expiration time, maximum
+// renewal duration and a fingerprint of the encrypted ticket payload. The differences can be
+// printed as a human readable diff or as a JSON patch transforming the summary of the first ticket
+// into the summary of the second one.
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"time"
+
+	"github.com/xhit/go-str2duration/v2"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+)
+
+// Field names of the summary, used in the human readable diff and as JSON patch paths
+const (
+	FieldCluster            = "cluster"
+	FieldUser               = "user"
+	FieldUID                = "uid"
+	FieldGIDs               = "gids"
+	FieldCreationTime       = "creationTime"
+	FieldExpiryTime         = "expiryTime"
+	FieldMaxRenewalDuration = "maxRenewalDuration"
+	FieldFingerprint        = "fingerprint"
+)
+
+// Summary contains the compared fields of a ticket
+type Summary struct {
+	Cluster            string   `json:"cluster"`
+	User               string   `json:"user"`
+	UID                uint32   `json:"uid"`
+	GIDs               []uint32 `json:"gids"`
+	CreationTime       string   `json:"creationTime"`
+	ExpiryTime         string   `json:"expiryTime"`
+	MaxRenewalDuration string   `json:"maxRenewalDuration"`
+	Fingerprint        string   `json:"fingerprint"`
+}
+
+// FieldDiff is the comparison of a single field of two tickets
+type FieldDiff struct {
+	Field   string
+	From    any
+	To      any
+	Changed bool
+}
+
+// NewSummary returns the summary of the given ticket. Times are formatted using
+// ticket.DefaultTimeFormat in UTC, the fingerprint is the SHA-256 hash of the encrypted ticket.
+func NewSummary(t *ticket.Ticket) Summary {
+	creds := t.GetUserCreds()
+
+	gids := creds.GetGids()
+	if gids == nil {
+		gids = []uint32{}
+	}
+
+	return Summary{
+		Cluster:            t.GetCluster(),
+		User:               t.GetUser(),
+		UID:                creds.GetUid(),
+		GIDs:               gids,
+		CreationTime:       formatTime(t.CreationTime()),
+		ExpiryTime:         formatTime(t.ExpirationTime()),
+		MaxRenewalDuration: str2duration.String(time.Duration(t.GetMaxRenewalDurationSec()) * time.Second),
+		Fingerprint:        Fingerprint(t),
+	}
+}
+
+// Fingerprint returns the SHA-256 hash of the encrypted payload of the ticket, which changes
+// whenever the ticket is regenerated, even if all other fields stay the same
+func Fingerprint(t *ticket.Ticket) string {
+	sum := sha256.Sum256(t.GetEncryptedTicket())
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Compare compares the summaries field by field and returns the comparison of all fields, in the
+// order of the fields of the summary
+func Compare(a, b Summary) []FieldDiff {
+	fields := []struct {
+		name     string
+		from, to any
+	}{
+		{FieldCluster, a.Cluster, b.Cluster},
+		{FieldUser, a.User, b.User},
+		{FieldUID, a.UID, b.UID},
+		{FieldGIDs, a.GIDs, b.GIDs},
+		{FieldCreationTime, a.CreationTime, b.CreationTime},
+		{FieldExpiryTime, a.ExpiryTime, b.ExpiryTime},
+		{FieldMaxRenewalDuration, a.MaxRenewalDuration, b.MaxRenewalDuration},
+		{FieldFingerprint, a.Fingerprint, b.Fingerprint},
+	}
+
+	diffs := make([]FieldDiff, 0, len(fields))
+
+	for _, f := range fields {
+		diffs = append(diffs, FieldDiff{
+			Field:   f.name,
+			From:    f.from,
+			To:      f.to,
+			Changed: !reflect.DeepEqual(f.from, f.to),
+		})
+	}
+
+	return diffs
+}
+
+// HasChanges returns true if any of the fields changed
+func HasChanges(diffs []FieldDiff) bool {
+	for _, d := range diffs {
+		if d.Changed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatTime formats the time the same way as the expiry annotation of secrets
+func formatTime(t time.Time) string {
+	return t.UTC().Format(ticket.DefaultTimeFormat)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package diff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/diff"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+
+	"k8s.io/utils/ptr"
+)
+
+func TestNewSummary(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	summary := NewSummary(newTicket("demo.mapr.com", "mapr", 5000, []uint32{5000, 5001}, created, created.Add(14*24*time.Hour), "payload"))
+
+	assert.Equal(t, Summary{
+		Cluster:            "demo.mapr.com",
+		User:               "mapr",
+		UID:                5000,
+		GIDs:               []uint32{5000, 5001},
+		CreationTime:       "2024-01-01T00:00:00Z",
+		ExpiryTime:         "2024-01-15T00:00:00Z",
+		MaxRenewalDuration: "4w2d",
+		Fingerprint:        "sha256:239f59ed55e737c77147cf55ad0c1b030b6d7ee748a7426952f9b852d5a935e5",
+	}, summary)
+
+	// missing groups are reported as an empty list instead of null
+	summary = NewSummary(newTicket("demo.mapr.com", "mapr", 5000, nil, created, created, "payload"))
+	assert.Equal(t, []uint32{}, summary.GIDs)
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	a := NewSummary(newTicket("demo.mapr.com", "mapr", 5000, []uint32{5000}, created, created.Add(24*time.Hour), "first"))
+	b := NewSummary(newTicket("demo.mapr.com", "mapr", 5000, []uint32{5000, 5001}, created, created.Add(48*time.Hour), "second"))
+
+	diffs := Compare(a, b)
+	assert.True(t, HasChanges(diffs))
+
+	changed := []string{}
+	for _, d := range diffs {
+		if d.Changed {
+			changed = append(changed, d.Field)
+		}
+	}
+
+	assert.Equal(t, []string{FieldGIDs, FieldExpiryTime, FieldFingerprint}, changed)
+
+	// a ticket does not differ from itself
+	assert.False(t, HasChanges(Compare(a, a)))
+}
+
+func newTicket(cluster, user string, uid uint32, gids []uint32, created, expiry time.Time, payload string) *ticket.Ticket {
+	t := ticket.NewMaprTicket()
+	t.Cluster = cluster
+	t.UserCreds.UserName = ptr.To(user)
+	t.UserCreds.Uid = ptr.To(uid)
+	t.UserCreds.Gids = gids
+	t.TicketAndKey.CreationTimeSec = ptr.To(uint64(created.Unix()))
+	t.TicketAndKey.ExpiryTime = ptr.To(uint64(expiry.Unix()))
+	t.TicketAndKey.MaxRenewalDurationSec = ptr.To(uint64(30 * 24 * 60 * 60))
+	t.TicketAndKey.EncryptedTicket = []byte(payload)
+
+	return t
+}
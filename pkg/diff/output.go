@@ -0,0 +1,113 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ANSI escape sequences used to color the human readable diff
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+)
+
+// PatchOperation is a single operation of a JSON patch as defined by RFC 6902
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// PrintDiff prints a human readable diff of the fields to the given writer, in the style of a
+// unified diff. The names describe where the tickets were read from. Unchanged fields are printed
+// as context. If color is true, the headers and changed fields are colored using ANSI escape
+// sequences.
+func PrintDiff(w io.Writer, nameA, nameB string, diffs []FieldDiff, color bool) error {
+	width := 0
+	for _, d := range diffs {
+		width = max(width, len(d.Field))
+	}
+
+	p := &diffPrinter{w: w, color: color}
+
+	p.line(colorBold, "--- %s", nameA)
+	p.line(colorBold, "+++ %s", nameB)
+
+	for _, d := range diffs {
+		if !d.Changed {
+			p.line("", "  %-*s  %s", width, d.Field, formatValue(d.From))
+			continue
+		}
+
+		p.line(colorRed, "- %-*s  %s", width, d.Field, formatValue(d.From))
+		p.line(colorGreen, "+ %-*s  %s", width, d.Field, formatValue(d.To))
+	}
+
+	return p.err
+}
+
+// PrintJSONPatch prints a JSON patch replacing the changed fields, transforming the summary of the
+// first ticket into the summary of the second one. If nothing changed, an empty patch is printed.
+func PrintJSONPatch(w io.Writer, diffs []FieldDiff) error {
+	patch := []PatchOperation{}
+
+	for _, d := range diffs {
+		if d.Changed {
+			patch = append(patch, PatchOperation{
+				Op:    "replace",
+				Path:  "/" + d.Field,
+				Value: d.To,
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(patch)
+}
+
+// diffPrinter writes lines of a diff, remembering the first error
+type diffPrinter struct {
+	w     io.Writer
+	color bool
+	err   error
+}
+
+// line prints a single line, wrapped in the given color if coloring is enabled
+func (p *diffPrinter) line(color, format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+
+	text := fmt.Sprintf(format, args...)
+	if p.color && color != "" {
+		text = color + text + colorReset
+	}
+
+	_, p.err = fmt.Fprintln(p.w, text)
+}
+
+// formatValue formats a field value for the human readable diff
+func formatValue(v any) string {
+	gids, ok := v.([]uint32)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+
+	parts := make([]string, 0, len(gids))
+	for _, gid := range gids {
+		parts = append(parts, fmt.Sprint(gid))
+	}
+
+	return "[" + strings.Join(parts, ",") + "]"
+}
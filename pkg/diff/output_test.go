@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package diff_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/diff"
+)
+
+var testDiffs = []FieldDiff{
+	{Field: FieldCluster, From: "demo.mapr.com", To: "demo.mapr.com"},
+	{Field: FieldGIDs, From: []uint32{5000}, To: []uint32{5000, 5001}, Changed: true},
+	{Field: FieldExpiryTime, From: "2024-01-02T00:00:00Z", To: "2024-01-03T00:00:00Z", Changed: true},
+}
+
+func TestPrintDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		color    bool
+		expected string
+	}{
+		{
+			name: "without color",
+			expected: `--- team-a/old
++++ ./new-ticket
+  cluster     demo.mapr.com
+- gids        [5000]
++ gids        [5000,5001]
+- expiryTime  2024-01-02T00:00:00Z
++ expiryTime  2024-01-03T00:00:00Z
+`,
+		},
+		{
+			name:  "with color",
+			color: true,
+			expected: "\x1b[1m--- team-a/old\x1b[0m\n" +
+				"\x1b[1m+++ ./new-ticket\x1b[0m\n" +
+				"  cluster     demo.mapr.com\n" +
+				"\x1b[31m- gids        [5000]\x1b[0m\n" +
+				"\x1b[32m+ gids        [5000,5001]\x1b[0m\n" +
+				"\x1b[31m- expiryTime  2024-01-02T00:00:00Z\x1b[0m\n" +
+				"\x1b[32m+ expiryTime  2024-01-03T00:00:00Z\x1b[0m\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			assert.NoError(t, PrintDiff(&out, "team-a/old", "./new-ticket", testDiffs, test.color))
+			assert.Equal(t, test.expected, out.String())
+		})
+	}
+}
+
+func TestPrintJSONPatch(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	assert.NoError(t, PrintJSONPatch(&out, testDiffs))
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/gids", "value": [5000, 5001]},
+		{"op": "replace", "path": "/expiryTime", "value": "2024-01-03T00:00:00Z"}
+	]`, out.String())
+
+	// nothing changed, so the patch is empty
+	out.Reset()
+	assert.NoError(t, PrintJSONPatch(&out, testDiffs[:1]))
+	assert.JSONEq(t, `[]`, out.String())
+}
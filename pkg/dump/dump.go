@@ -0,0 +1,260 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package dump loads Kubernetes objects from cluster dumps, e.g. the output of
+// `kubectl get secrets,pv,pvc -A -o yaml` or a must-gather archive, into a fake clientset. This
+// allows all listers to operate on a dump without access to the cluster.
+//
+// A dump can either be a single YAML or JSON file, a directory that is searched recursively for
+// YAML and JSON files, or a gzipped tar archive containing such files. Files may contain multiple
+// YAML documents and List objects. Objects of kinds unknown to the client, e.g. custom resources,
+// and documents that are not Kubernetes objects at all are skipped.
+package dump
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilYaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// NewClient loads all objects of the dump at the given path into a fake clientset. Objects that
+// are contained in the dump more than once are only added once.
+func NewClient(path string) (kubernetes.Interface, error) {
+	objects, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	client := fake.NewSimpleClientset()
+
+	for _, obj := range objects {
+		if err := client.Tracker().Add(obj); err != nil {
+			if apiErrors.IsAlreadyExists(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to add object from dump %q: %w", path, err)
+		}
+	}
+
+	return client, nil
+}
+
+// Load returns all Kubernetes objects contained in the dump at the given path. List objects are
+// flattened into their items.
+func Load(path string) ([]runtime.Object, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case info.IsDir():
+		return loadDir(path)
+	case isArchive(path):
+		return loadArchive(path)
+	default:
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return decode(path, body)
+	}
+}
+
+// loadDir loads all YAML and JSON files in the directory and its subdirectories
+func loadDir(dir string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !isManifest(path) {
+			return nil
+		}
+
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		decoded, err := decode(path, body)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, decoded...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// loadArchive loads all YAML and JSON files contained in the gzipped tar archive
+func loadArchive(path string) ([]runtime.Object, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %q: %w", path, err)
+	}
+	defer gzipReader.Close()
+
+	var (
+		objects   []runtime.Object
+		tarReader = tar.NewReader(gzipReader)
+	)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive %q: %w", path, err)
+		}
+
+		if header.Typeflag != tar.TypeReg || !isManifest(header.Name) {
+			continue
+		}
+
+		body, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q from archive %q: %w", header.Name, path, err)
+		}
+
+		decoded, err := decode(path+":"+header.Name, body)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, decoded...)
+	}
+
+	return objects, nil
+}
+
+// decode decodes all Kubernetes objects contained in the YAML documents or JSON of the body. The
+// source is only used for error and log messages.
+func decode(source string, body []byte) ([]runtime.Object, error) {
+	var (
+		objects []runtime.Object
+		reader  = utilYaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(body)))
+	)
+
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", source, err)
+		}
+
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		decoded, err := decodeObject(source, document)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, decoded...)
+	}
+
+	return objects, nil
+}
+
+// decodeObject decodes a single object, flattening List objects into their items. Objects of
+// unknown kinds and documents without a kind are skipped.
+func decodeObject(source string, document []byte) ([]runtime.Object, error) {
+	obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode(document, nil, nil)
+	if err != nil {
+		if runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) || runtime.IsMissingVersion(err) {
+			slog.Debug("skipping unsupported object in dump", "source", source, "error", err)
+
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to decode object in %q: %w", source, err)
+	}
+
+	if !meta.IsListType(obj) {
+		return []runtime.Object{obj}, nil
+	}
+
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract items of %s in %q: %w", gvk.Kind, source, err)
+	}
+
+	var objects []runtime.Object
+
+	for _, item := range items {
+		// items of generic lists are not decoded yet
+		if raw, ok := item.(*runtime.Unknown); ok {
+			decoded, err := decodeObject(source, raw.Raw)
+			if err != nil {
+				return nil, err
+			}
+
+			objects = append(objects, decoded...)
+
+			continue
+		}
+
+		objects = append(objects, item)
+	}
+
+	return objects, nil
+}
+
+// isManifest returns true if the file at the path is a YAML or JSON file
+func isManifest(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// isArchive returns true if the file at the path is a gzipped tar archive
+func isArchive(path string) bool {
+	path = strings.ToLower(path)
+
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package dump_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/dump"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	unknownKindDocument = `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+`
+
+	noKindDocument = `some: config
+of: another tool
+`
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	list := newList(t, newSecret(t, "team-a", "mapr-ticket"), newVolume("pv-1"))
+	claim := toYAML(t, newClaim("team-a", "data"))
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "list.yaml"), list)
+	writeFile(t, filepath.Join(dir, "multi.yaml"), claim+"---\n"+unknownKindDocument+"---\n"+noKindDocument)
+	writeFile(t, filepath.Join(dir, "nested", "namespaces", "team-b", "secret.json"), toJSON(t, newSecret(t, "team-b", "mapr-ticket")))
+	writeFile(t, filepath.Join(dir, "nested", "README.md"), "# must-gather")
+
+	archive := filepath.Join(t.TempDir(), "must-gather.tar.gz")
+	writeArchive(t, archive, map[string]string{
+		"must-gather/list.yaml":  list,
+		"must-gather/claim.yml":  claim,
+		"must-gather/notes.txt":  "not a manifest",
+		"must-gather/other.yaml": unknownKindDocument,
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "single file with list",
+			path: filepath.Join(dir, "list.yaml"),
+			want: []string{"Secret team-a/mapr-ticket", "PersistentVolume /pv-1"},
+		},
+		{
+			name: "single file with multiple documents",
+			path: filepath.Join(dir, "multi.yaml"),
+			want: []string{"PersistentVolumeClaim team-a/data"},
+		},
+		{
+			name: "directory",
+			path: dir,
+			want: []string{
+				"Secret team-a/mapr-ticket",
+				"PersistentVolume /pv-1",
+				"PersistentVolumeClaim team-a/data",
+				"Secret team-b/mapr-ticket",
+			},
+		},
+		{
+			name: "archive",
+			path: archive,
+			want: []string{
+				"Secret team-a/mapr-ticket",
+				"PersistentVolume /pv-1",
+				"PersistentVolumeClaim team-a/data",
+			},
+		},
+		{
+			name:    "missing path",
+			path:    filepath.Join(dir, "missing.yaml"),
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			objects, err := Load(test.path)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, test.want, describe(objects))
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	list := newList(t, newSecret(t, "team-a", "mapr-ticket"), newVolume("pv-1"))
+
+	// the same objects contained twice must only be added once
+	writeFile(t, filepath.Join(dir, "first.yaml"), list)
+	writeFile(t, filepath.Join(dir, "second.yaml"), list)
+
+	client, err := NewClient(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tickets, err := secret.NewLister(client, util.NamespaceAll).List()
+	assert.NoError(t, err)
+
+	if !assert.Len(t, tickets, 1) {
+		return
+	}
+
+	assert.Equal(t, "team-a", tickets[0].GetSecretNamespace())
+	assert.Equal(t, "mapr-ticket", tickets[0].GetSecretName())
+	assert.Equal(t, "demo.mapr.com", tickets[0].GetCluster())
+}
+
+func newSecret(t *testing.T, namespace, name string) *coreV1.Secret {
+	t.Helper()
+
+	maprTicket := ticket.NewMaprTicket()
+	maprTicket.Cluster = "demo.mapr.com"
+	maprTicket.UserCreds.UserName = ptr.To("mapr")
+	maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(time.Now().Add(24 * time.Hour).Unix()))
+	maprTicket.TicketAndKey.CreationTimeSec = ptr.To(uint64(time.Now().Unix()))
+
+	secret, err := ticket.NewSecret(namespace, name, maprTicket)
+	assert.NoError(t, err)
+
+	secret.TypeMeta = metaV1.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+
+	return secret
+}
+
+func newVolume(name string) *coreV1.PersistentVolume {
+	return &coreV1.PersistentVolume{
+		TypeMeta:   metaV1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+		ObjectMeta: metaV1.ObjectMeta{Name: name},
+	}
+}
+
+func newClaim(namespace, name string) *coreV1.PersistentVolumeClaim {
+	return &coreV1.PersistentVolumeClaim{
+		TypeMeta:   metaV1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+		ObjectMeta: metaV1.ObjectMeta{Namespace: namespace, Name: name},
+	}
+}
+
+// newList returns the YAML of a v1 List containing the given objects, as written by kubectl get
+func newList(t *testing.T, objects ...runtime.Object) string {
+	t.Helper()
+
+	list := &coreV1.List{
+		TypeMeta: metaV1.TypeMeta{APIVersion: "v1", Kind: "List"},
+	}
+
+	for _, obj := range objects {
+		list.Items = append(list.Items, runtime.RawExtension{Raw: []byte(toJSON(t, obj))})
+	}
+
+	return toYAML(t, list)
+}
+
+func toJSON(t *testing.T, obj any) string {
+	t.Helper()
+
+	body, err := json.Marshal(obj)
+	assert.NoError(t, err)
+
+	return string(body)
+}
+
+func toYAML(t *testing.T, obj any) string {
+	t.Helper()
+
+	body, err := yaml.Marshal(obj)
+	assert.NoError(t, err)
+
+	return string(body)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func writeArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for name, content := range files {
+		assert.NoError(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o600,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+
+		_, err := tarWriter.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, gzipWriter.Close())
+}
+
+// describe returns a short description of each object, consisting of its kind, namespace and name
+func describe(objects []runtime.Object) []string {
+	descriptions := make([]string, 0, len(objects))
+
+	for _, obj := range objects {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+
+		descriptions = append(descriptions, obj.GetObjectKind().GroupVersionKind().Kind+" "+accessor.GetNamespace()+"/"+accessor.GetName())
+	}
+
+	return descriptions
+}
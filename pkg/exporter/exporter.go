@@ -0,0 +1,243 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package exporter implements a Prometheus exporter for MapR tickets stored in secrets. The
+// exporter periodically lists all ticket secrets using the secret lister and exposes the expiry
+// and creation time of each ticket, as well as the number of persistent volumes using it, as
+// Prometheus metrics.
+//
+// The ticket metrics of each refresh are collected into a snapshot that replaces the previous one
+// atomically, so scrapes never observe a partially refreshed state.
+package exporter
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// metricsNamespace is the prefix of all metrics exposed by the exporter
+	metricsNamespace = "mapr_ticket"
+)
+
+var (
+	// ticketLabels are the labels attached to all per ticket metrics
+	ticketLabels = []string{"namespace", "secret", "key", "cluster", "user"}
+
+	// secretLabels are the labels attached to metrics of secrets whose ticket can't be parsed
+	secretLabels = []string{"namespace", "secret", "key"}
+)
+
+// ticketCollector is a Prometheus collector serving the ticket metrics of the last refresh
+type ticketCollector struct {
+	expiry   *prometheus.Desc
+	creation *prometheus.Desc
+	expired  *prometheus.Desc
+	pvCount  *prometheus.Desc
+	invalid  *prometheus.Desc
+
+	// snapshot holds the metrics of the last refresh, it is replaced as a whole on each refresh
+	snapshot atomic.Pointer[[]prometheus.Metric]
+}
+
+// newTicketCollector returns a new collector without any ticket metrics
+func newTicketCollector() *ticketCollector {
+	return &ticketCollector{
+		expiry: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "expiry_timestamp_seconds"),
+			"Expiration time of the MapR ticket as Unix timestamp.",
+			ticketLabels, nil,
+		),
+		creation: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "creation_timestamp_seconds"),
+			"Creation time of the MapR ticket as Unix timestamp.",
+			ticketLabels, nil,
+		),
+		expired: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "expired"),
+			"Whether the MapR ticket has expired (1) or not (0).",
+			ticketLabels, nil,
+		),
+		pvCount: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "pv_count"),
+			"Number of persistent volumes using the MapR ticket.",
+			ticketLabels, nil,
+		),
+		invalid: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "invalid"),
+			"Secrets containing a MapR ticket that can't be parsed.",
+			secretLabels, nil,
+		),
+	}
+}
+
+// Describe sends the descriptors of all ticket metrics to the channel
+func (c *ticketCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.expiry
+	ch <- c.creation
+	ch <- c.expired
+	ch <- c.pvCount
+	ch <- c.invalid
+}
+
+// Collect sends the ticket metrics of the last refresh to the channel
+func (c *ticketCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.snapshot.Load()
+	if snapshot == nil {
+		return
+	}
+
+	for _, m := range *snapshot {
+		ch <- m
+	}
+}
+
+// update replaces the ticket metrics with the metrics of the given tickets
+func (c *ticketCollector) update(tickets []types.MaprSecret) {
+	metrics := make([]prometheus.Metric, 0, 4*len(tickets))
+
+	for _, t := range tickets {
+		namespace, name := t.Secret.GetNamespace(), t.Secret.GetName()
+
+		if t.IsInvalid() {
+			metrics = append(metrics, prometheus.MustNewConstMetric(c.invalid, prometheus.GaugeValue, 1, namespace, name, t.Key))
+			continue
+		}
+
+		labels := []string{namespace, name, t.Key, t.Ticket.GetCluster(), t.Ticket.GetUser()}
+
+		expired := 0.0
+		if t.Ticket.IsExpired() {
+			expired = 1.0
+		}
+
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(c.expiry, prometheus.GaugeValue, float64(t.Ticket.ExpirationTime().Unix()), labels...),
+			prometheus.MustNewConstMetric(c.creation, prometheus.GaugeValue, float64(t.Ticket.CreationTime().Unix()), labels...),
+			prometheus.MustNewConstMetric(c.expired, prometheus.GaugeValue, expired, labels...),
+			prometheus.MustNewConstMetric(c.pvCount, prometheus.GaugeValue, float64(t.NumPVC), labels...),
+		)
+	}
+
+	c.snapshot.Store(&metrics)
+}
+
+// Exporter periodically collects MapR ticket secrets and exposes them as Prometheus metrics
+type Exporter struct {
+	client    kubernetes.Interface
+	namespace string
+
+	// secretOpts are additional options passed to the secret lister on each refresh
+	secretOpts []secret.ListerOption
+
+	registry *prometheus.Registry
+
+	tickets       *ticketCollector
+	lastRefresh   prometheus.Gauge
+	refreshErrors prometheus.Counter
+}
+
+// NewExporter returns a new exporter for the ticket secrets in the given namespace, use
+// util.NamespaceAll to export the tickets of all namespaces. The given secret lister options are
+// used to configure how tickets are read from the secrets, e.g. the data key they are stored under.
+func NewExporter(client kubernetes.Interface, namespace string, secretOpts ...secret.ListerOption) *Exporter {
+	e := &Exporter{
+		client:     client,
+		namespace:  namespace,
+		secretOpts: secretOpts,
+		registry:   prometheus.NewRegistry(),
+
+		tickets: newTicketCollector(),
+		lastRefresh: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "last_refresh_timestamp_seconds",
+			Help:      "Time of the last successful refresh of the ticket metrics as Unix timestamp.",
+		}),
+		refreshErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "refresh_errors_total",
+			Help:      "Number of failed refreshes of the ticket metrics.",
+		}),
+	}
+
+	e.registry.MustRegister(
+		e.tickets,
+		e.lastRefresh,
+		e.refreshErrors,
+	)
+
+	return e
+}
+
+// Registry returns the registry containing all metrics of the exporter
+func (e *Exporter) Registry() *prometheus.Registry {
+	return e.registry
+}
+
+// Handler returns the HTTP handler serving the metrics of the exporter
+func (e *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{
+		Registry: e.registry,
+	})
+}
+
+// Refresh lists all ticket secrets and replaces the current ticket metrics with the result. If
+// listing fails, the previous metrics are kept and the error counter is increased.
+func (e *Exporter) Refresh() error {
+	volumeLister := volume.NewLister(e.client, util.SecretAll, util.NamespaceAll)
+	opts := append([]secret.ListerOption{
+		secret.WithShowInUse(),
+		secret.WithVolumeLister(volumeLister),
+	}, e.secretOpts...)
+
+	lister := secret.NewLister(e.client, e.namespace, opts...)
+
+	tickets, err := lister.List()
+	if err != nil {
+		e.refreshErrors.Inc()
+		return err
+	}
+
+	// replace all ticket metrics, so deleted secrets don't linger around
+	e.tickets.update(tickets)
+
+	e.lastRefresh.SetToCurrentTime()
+
+	return nil
+}
+
+// Run refreshes the metrics immediately and then periodically with the given interval until the
+// context is cancelled. Errors during a refresh are logged and don't stop the exporter.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Refresh(); err != nil {
+			slog.Error("failed to refresh ticket metrics", "error", err)
+		} else {
+			slog.Debug("refreshed ticket metrics")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package exporter_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/exporter"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestExporter_Refresh(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	created := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+	client := fake.NewSimpleClientset(
+		newSecret(t, "team-a", "ticket-a", "demo.mapr.com", "user_a", created, expiry),
+		newSecret(t, "team-b", "ticket-b", "demo.mapr.com", "user_b", created, created),
+		newVolume("pv-1", "team-a", "ticket-a"),
+		newVolume("pv-2", "team-a", "ticket-a"),
+	)
+
+	e := NewExporter(client, util.NamespaceAll)
+
+	err := e.Refresh()
+	assert.NoError(t, err)
+
+	expected := fmt.Sprintf(`
+# HELP mapr_ticket_expiry_timestamp_seconds Expiration time of the MapR ticket as Unix timestamp.
+# TYPE mapr_ticket_expiry_timestamp_seconds gauge
+mapr_ticket_expiry_timestamp_seconds{cluster="demo.mapr.com",key="CONTAINER_TICKET",namespace="team-a",secret="ticket-a",user="user_a"} %[1]d
+mapr_ticket_expiry_timestamp_seconds{cluster="demo.mapr.com",key="CONTAINER_TICKET",namespace="team-b",secret="ticket-b",user="user_b"} %[2]d
+# HELP mapr_ticket_expired Whether the MapR ticket has expired (1) or not (0).
+# TYPE mapr_ticket_expired gauge
+mapr_ticket_expired{cluster="demo.mapr.com",key="CONTAINER_TICKET",namespace="team-a",secret="ticket-a",user="user_a"} 0
+mapr_ticket_expired{cluster="demo.mapr.com",key="CONTAINER_TICKET",namespace="team-b",secret="ticket-b",user="user_b"} 1
+# HELP mapr_ticket_pv_count Number of persistent volumes using the MapR ticket.
+# TYPE mapr_ticket_pv_count gauge
+mapr_ticket_pv_count{cluster="demo.mapr.com",key="CONTAINER_TICKET",namespace="team-a",secret="ticket-a",user="user_a"} 2
+mapr_ticket_pv_count{cluster="demo.mapr.com",key="CONTAINER_TICKET",namespace="team-b",secret="ticket-b",user="user_b"} 0
+`, expiry.Unix(), created.Unix())
+
+	err = testutil.GatherAndCompare(e.Registry(), strings.NewReader(expected),
+		"mapr_ticket_expiry_timestamp_seconds",
+		"mapr_ticket_expired",
+		"mapr_ticket_pv_count",
+	)
+	assert.NoError(t, err)
+
+	// deleted secrets must no longer be exported after the next refresh
+	err = client.CoreV1().Secrets("team-b").Delete(context.TODO(), "ticket-b", metaV1.DeleteOptions{})
+	assert.NoError(t, err)
+
+	err = e.Refresh()
+	assert.NoError(t, err)
+
+	count, err := testutil.GatherAndCount(e.Registry(), "mapr_ticket_expiry_timestamp_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestExporter_Refresh_ConcurrentScrape(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	client := fake.NewSimpleClientset(
+		newSecret(t, "default", "ticket", "demo.mapr.com", "mapr", now, now.Add(time.Hour)),
+	)
+
+	e := NewExporter(client, "default")
+	assert.NoError(t, e.Refresh())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		for ctx.Err() == nil {
+			_ = e.Refresh()
+		}
+	}()
+
+	// scrapes during a refresh must always see the series of the ticket
+	for i := 0; i < 100; i++ {
+		count, err := testutil.GatherAndCount(e.Registry(), "mapr_ticket_expiry_timestamp_seconds")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	}
+}
+
+func TestExporter_Handler(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	client := fake.NewSimpleClientset(
+		newSecret(t, "default", "ticket", "demo.mapr.com", "mapr", now, now.Add(time.Hour)),
+	)
+
+	e := NewExporter(client, "default")
+	assert.NoError(t, e.Refresh())
+
+	recorder := httptest.NewRecorder()
+	e.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `mapr_ticket_pv_count{cluster="demo.mapr.com",key="CONTAINER_TICKET",namespace="default",secret="ticket",user="mapr"} 0`)
+	assert.Contains(t, recorder.Body.String(), "mapr_ticket_last_refresh_timestamp_seconds")
+}
+
+func TestExporter_Refresh_InvalidTicket(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "broken",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				ticket.SecretMaprTicketKey: []byte("invalid ticket data"),
+			},
+		},
+	)
+
+	e := NewExporter(client, "default")
+	assert.NoError(t, e.Refresh())
+
+	expected := `
+# HELP mapr_ticket_invalid Secrets containing a MapR ticket that can't be parsed.
+# TYPE mapr_ticket_invalid gauge
+mapr_ticket_invalid{key="CONTAINER_TICKET",namespace="default",secret="broken"} 1
+`
+
+	err := testutil.GatherAndCompare(e.Registry(), strings.NewReader(expected), "mapr_ticket_invalid")
+	assert.NoError(t, err)
+
+	// invalid tickets must not show up in the per ticket metrics
+	count, err := testutil.GatherAndCount(e.Registry(), "mapr_ticket_expiry_timestamp_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestExporter_Refresh_MultipleTickets(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Truncate(time.Second)
+
+	// both tickets of the secret are for the same cluster and user, so only the key tells them apart
+	tickets := newSecret(t, "default", "tickets", "demo.mapr.com", "mapr", now, now.Add(time.Hour))
+	other := newSecret(t, "default", "other", "demo.mapr.com", "mapr", now, now.Add(2*time.Hour))
+	tickets.Data["SECOND_TICKET"] = other.Data[ticket.SecretMaprTicketKey]
+
+	e := NewExporter(fake.NewSimpleClientset(tickets), "default", secret.WithDetectTicketKeys())
+	assert.NoError(t, e.Refresh())
+
+	expected := fmt.Sprintf(`
+# HELP mapr_ticket_expiry_timestamp_seconds Expiration time of the MapR ticket as Unix timestamp.
+# TYPE mapr_ticket_expiry_timestamp_seconds gauge
+mapr_ticket_expiry_timestamp_seconds{cluster="demo.mapr.com",key="CONTAINER_TICKET",namespace="default",secret="tickets",user="mapr"} %[1]d
+mapr_ticket_expiry_timestamp_seconds{cluster="demo.mapr.com",key="SECOND_TICKET",namespace="default",secret="tickets",user="mapr"} %[2]d
+`, now.Add(time.Hour).Unix(), now.Add(2*time.Hour).Unix())
+
+	err := testutil.GatherAndCompare(e.Registry(), strings.NewReader(expected), "mapr_ticket_expiry_timestamp_seconds")
+	assert.NoError(t, err)
+}
+
+func newSecret(t *testing.T, namespace, name, cluster, user string, created, expiry time.Time) *coreV1.Secret {
+	t.Helper()
+
+	maprTicket := ticket.NewMaprTicket()
+	maprTicket.Cluster = cluster
+	maprTicket.UserCreds.UserName = ptr.To(user)
+	maprTicket.TicketAndKey.CreationTimeSec = ptr.To(uint64(created.Unix()))
+	maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(expiry.Unix()))
+
+	secret, err := ticket.NewSecret(namespace, name, maprTicket)
+	assert.NoError(t, err)
+
+	return secret
+}
+
+func newVolume(name, secretNamespace, secretName string) *coreV1.PersistentVolume {
+	return &coreV1.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+		},
+		Spec: coreV1.PersistentVolumeSpec{
+			PersistentVolumeSource: coreV1.PersistentVolumeSource{
+				CSI: &coreV1.CSIPersistentVolumeSource{
+					Driver: types.MaprCSIProvisionerKDF,
+					NodePublishSecretRef: &coreV1.SecretReference{
+						Namespace: secretNamespace,
+						Name:      secretName,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package filter implements filtering of the listed secrets, volumes and claims using expressions
+// of the Common Expression Language (CEL), see https://github.com/google/cel-spec.
+//
+// The expressions are evaluated against a set of variables describing the listed item, each of
+// them a map of its fields:
+//
+//   - ticket: cluster, user, uid, gids, creationTime, expirationTime, expiresIn and expired
+//   - secret: namespace, name, key, labels, annotations, invalid, error, numPVs,
+//     numInlineVolumes and numStorageClasses
+//   - volume: name, driver, path, handle and labels
+//   - claim: namespace, name and labels
+//
+// Only the variables matching the kind of the listed items are available, see SecretVariables,
+// VolumeVariables and ClaimVariables. Fields that are unknown for an item, e.g. the fields of the
+// ticket if the secret does not contain a parsable ticket, are missing from the maps. Items for
+// which the expression can't be evaluated are treated as not matching.
+package filter
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+)
+
+// names of the variables available in the filter expressions
+const (
+	VariableTicket = "ticket"
+	VariableSecret = "secret"
+	VariableVolume = "volume"
+	VariableClaim  = "claim"
+)
+
+var (
+	// SecretVariables are the variables available when filtering secrets
+	SecretVariables = []string{VariableTicket, VariableSecret}
+
+	// VolumeVariables are the variables available when filtering persistent volumes
+	VolumeVariables = []string{VariableTicket, VariableSecret, VariableVolume, VariableClaim}
+
+	// ClaimVariables are the variables available when filtering persistent volume claims
+	ClaimVariables = []string{VariableTicket, VariableSecret, VariableVolume, VariableClaim}
+)
+
+// Expression is a compiled filter expression
+type Expression struct {
+	source  string
+	program cel.Program
+}
+
+// Compile parses and type checks the filter expression using the given variables and returns the
+// compiled expression. An error is returned if the expression is invalid, uses unknown variables
+// or does not evaluate to a boolean.
+func Compile(expression string, variables []string) (*Expression, error) {
+	opts := make([]cel.EnvOption, 0, len(variables))
+	for _, variable := range variables {
+		opts = append(opts, cel.Variable(variable, cel.MapType(cel.StringType, cel.DynType)))
+	}
+
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid filter expression %q: %w", expression, issues.Err())
+	}
+
+	// fields of the variables are dynamically typed, so only reject types that can't be a boolean
+	if !ast.OutputType().IsExactType(cel.BoolType) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("invalid filter expression %q: must evaluate to a bool, not %s", expression, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression %q: %w", expression, err)
+	}
+
+	return &Expression{
+		source:  expression,
+		program: program,
+	}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Matches evaluates the expression using the given variables and returns whether the result is
+// true. Evaluation errors, e.g. due to missing fields, are logged and treated as not matching.
+func (e *Expression) Matches(variables map[string]any) bool {
+	out, _, err := e.program.Eval(variables)
+	if err != nil {
+		slog.Debug("failed to evaluate filter expression", "expression", e.source, "error", err)
+		return false
+	}
+
+	matches, ok := out.Value().(bool)
+	if !ok {
+		slog.Debug("filter expression did not evaluate to a bool", "expression", e.source, "result", out.Value())
+		return false
+	}
+
+	return matches
+}
+
+// SecretActivation returns the variables used to evaluate filter expressions for the secret
+func SecretActivation(s *types.MaprSecret) map[string]any {
+	return map[string]any{
+		VariableTicket: ticketVariables(s),
+		VariableSecret: secretVariables(s.GetSecretNamespace(), s.GetSecretName(), s),
+	}
+}
+
+// VolumeActivation returns the variables used to evaluate filter expressions for the persistent
+// volume
+func VolumeActivation(v *types.MaprVolume) map[string]any {
+	return map[string]any{
+		VariableTicket: ticketVariables(v.Ticket),
+		VariableSecret: secretVariables(v.Volume.GetSecretNamespace(), v.Volume.GetSecretName(), v.Ticket),
+		VariableVolume: volumeVariables(v.Volume),
+		VariableClaim: map[string]any{
+			"namespace": v.Volume.GetClaimNamespace(),
+			"name":      v.Volume.GetClaimName(),
+			"labels":    map[string]string{},
+		},
+	}
+}
+
+// ClaimActivation returns the variables used to evaluate filter expressions for the persistent
+// volume claim
+func ClaimActivation(c *types.MaprVolumeClaim) map[string]any {
+	claim := map[string]any{
+		"namespace": c.Claim.GetNamespace(),
+		"name":      c.Claim.GetName(),
+		"labels":    map[string]string{},
+	}
+
+	if c.Claim != nil {
+		claim["labels"] = nonNilMap(c.Claim.Labels)
+	}
+
+	return map[string]any{
+		VariableTicket: ticketVariables(c.Ticket),
+		VariableSecret: secretVariables(c.Volume.GetSecretNamespace(), c.Volume.GetSecretName(), c.Ticket),
+		VariableVolume: volumeVariables(c.Volume),
+		VariableClaim:  claim,
+	}
+}
+
+// ticketVariables returns the fields of the ticket of the secret, or an empty map if there is no
+// parsable ticket
+func ticketVariables(s *types.MaprSecret) map[string]any {
+	if s == nil || s.Ticket == nil {
+		return map[string]any{}
+	}
+
+	gids := make([]int64, 0, len(s.Ticket.UserCreds.GetGids()))
+	for _, gid := range s.Ticket.UserCreds.GetGids() {
+		gids = append(gids, int64(gid))
+	}
+
+	return map[string]any{
+		"cluster":        s.GetCluster(),
+		"user":           s.GetUser(),
+		"uid":            int64(s.Ticket.UserCreds.GetUid()),
+		"gids":           gids,
+		"creationTime":   s.GetCreationTime(),
+		"expirationTime": s.GetExpirationTime(),
+		"expiresIn":      time.Until(s.GetExpirationTime()),
+		"expired":        s.IsExpired(),
+	}
+}
+
+// secretVariables returns the fields of the secret with the given namespace and name. The fields
+// besides the namespace and name are only set if the secret was found.
+func secretVariables(namespace, name string, s *types.MaprSecret) map[string]any {
+	vars := map[string]any{
+		"namespace": namespace,
+		"name":      name,
+	}
+
+	if s == nil || s.Secret == nil {
+		return vars
+	}
+
+	vars["key"] = s.GetKey()
+	vars["labels"] = nonNilMap(s.Secret.Labels)
+	vars["annotations"] = nonNilMap(s.Secret.Annotations)
+	vars["invalid"] = s.IsInvalid()
+	vars["error"] = s.GetParseError()
+	vars["numPVs"] = int64(s.NumPVC)
+	vars["numInlineVolumes"] = int64(s.NumInline)
+	vars["numStorageClasses"] = int64(s.NumStorageClasses)
+
+	return vars
+}
+
+// volumeVariables returns the fields of the persistent volume, or an empty map if there is no
+// volume
+func volumeVariables(v *types.PersistentVolume) map[string]any {
+	if v == nil {
+		return map[string]any{}
+	}
+
+	vars := map[string]any{
+		"name":   v.GetName(),
+		"driver": "",
+		"path":   v.GetVolumePath(),
+		"handle": v.GetVolumeHandle(),
+		"labels": nonNilMap(v.Labels),
+	}
+
+	if v.Spec.CSI != nil {
+		vars["driver"] = v.Spec.CSI.Driver
+	}
+
+	return vars
+}
+
+// nonNilMap returns the given map, or an empty map if it is nil
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+
+	return m
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package filter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestCompile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		expression string
+		variables  []string
+		wantErr    bool
+	}{
+		{
+			name:       "valid expression",
+			expression: "ticket.user == 'mapr' && secret.namespace.startsWith('team-')",
+			variables:  SecretVariables,
+		},
+		{
+			name:       "dynamically typed field",
+			expression: "ticket.expired",
+			variables:  SecretVariables,
+		},
+		{
+			name:       "syntax error",
+			expression: "ticket.user ==",
+			variables:  SecretVariables,
+			wantErr:    true,
+		},
+		{
+			name:       "unknown variable",
+			expression: "volume.name == 'pv-1'",
+			variables:  SecretVariables,
+			wantErr:    true,
+		},
+		{
+			name:       "known variable",
+			expression: "volume.name == 'pv-1'",
+			variables:  VolumeVariables,
+		},
+		{
+			name:       "non boolean result",
+			expression: "'mapr'",
+			variables:  SecretVariables,
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Compile(test.expression, test.variables)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expression, got.String())
+		})
+	}
+}
+
+func TestExpression_MatchesSecret(t *testing.T) {
+	t.Parallel()
+
+	valid := newMaprSecret("team-a", "mapr-ticket", "mapr", 48*time.Hour)
+	valid.Secret.Labels = map[string]string{"app": "spark"}
+	valid.NumPVC = 2
+
+	expired := newMaprSecret("other", "mapr-ticket", "alice", -time.Hour)
+
+	invalid := &types.MaprSecret{
+		Secret:     &types.Secret{ObjectMeta: metaV1.ObjectMeta{Namespace: "team-b", Name: "broken"}},
+		Key:        ticket.SecretMaprTicketKey,
+		ParseError: errors.New("failed to parse ticket"),
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		want       []bool
+	}{
+		{
+			name:       "user in list",
+			expression: "ticket.user in ['mapr', 'alice']",
+			want:       []bool{true, true, false},
+		},
+		{
+			name:       "expires within duration",
+			expression: "ticket.expiresIn < duration('72h')",
+			want:       []bool{true, true, false},
+		},
+		{
+			name:       "expires within duration and namespace prefix",
+			expression: "ticket.expiresIn < duration('72h') && secret.namespace.startsWith('team-')",
+			want:       []bool{true, false, false},
+		},
+		{
+			name:       "expired",
+			expression: "ticket.expired",
+			want:       []bool{false, true, false},
+		},
+		{
+			name:       "expiration and creation time",
+			expression: "ticket.expirationTime > ticket.creationTime",
+			want:       []bool{true, false, false},
+		},
+		{
+			name:       "labels",
+			expression: "secret.labels.app == 'spark'",
+			want:       []bool{true, false, false},
+		},
+		{
+			name:       "in use",
+			expression: "secret.numPVs > 1",
+			want:       []bool{true, false, false},
+		},
+		{
+			name:       "uid and gids",
+			expression: "ticket.uid == 5000 && 5001 in ticket.gids",
+			want:       []bool{true, true, false},
+		},
+		{
+			name:       "invalid tickets",
+			expression: "secret.invalid || !has(ticket.user)",
+			want:       []bool{false, false, true},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			expression, err := Compile(test.expression, SecretVariables)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			got := []bool{}
+			for _, item := range []*types.MaprSecret{valid, expired, invalid} {
+				got = append(got, expression.Matches(SecretActivation(item)))
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestExpression_MatchesVolumeAndClaim(t *testing.T) {
+	t.Parallel()
+
+	volume := &types.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:   "pv-1",
+			Labels: map[string]string{"tier": "gold"},
+		},
+		Spec: coreV1.PersistentVolumeSpec{
+			PersistentVolumeSource: coreV1.PersistentVolumeSource{
+				CSI: &coreV1.CSIPersistentVolumeSource{
+					Driver:       types.MaprCSIProvisionerKDF,
+					VolumeHandle: "handle-1",
+					VolumeAttributes: map[string]string{
+						"volumePath": "/data",
+					},
+					NodePublishSecretRef: &coreV1.SecretReference{Namespace: "team-a", Name: "mapr-ticket"},
+				},
+			},
+			ClaimRef: &coreV1.ObjectReference{Namespace: "team-a", Name: "data"},
+		},
+	}
+
+	claim := &types.PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: "team-a",
+			Name:      "data",
+			Labels:    map[string]string{"app": "spark"},
+		},
+	}
+
+	maprSecret := newMaprSecret("team-a", "mapr-ticket", "mapr", 48*time.Hour)
+
+	tests := []struct {
+		name       string
+		expression string
+		volume     *types.MaprVolume
+		claim      *types.MaprVolumeClaim
+		want       bool
+	}{
+		{
+			name:       "volume fields",
+			expression: "volume.name == 'pv-1' && volume.path == '/data' && volume.labels.tier == 'gold' && volume.driver == 'com.mapr.csi-kdf'",
+			volume:     &types.MaprVolume{Volume: volume, Ticket: maprSecret},
+			claim:      &types.MaprVolumeClaim{Claim: claim, Volume: volume, Ticket: maprSecret},
+			want:       true,
+		},
+		{
+			name:       "claim and secret reference",
+			expression: "claim.namespace == 'team-a' && claim.name == 'data' && secret.name == 'mapr-ticket'",
+			volume:     &types.MaprVolume{Volume: volume, Ticket: maprSecret},
+			claim:      &types.MaprVolumeClaim{Claim: claim, Volume: volume, Ticket: maprSecret},
+			want:       true,
+		},
+		{
+			name:       "ticket fields",
+			expression: "ticket.user == 'mapr' && !ticket.expired",
+			volume:     &types.MaprVolume{Volume: volume, Ticket: maprSecret},
+			claim:      &types.MaprVolumeClaim{Claim: claim, Volume: volume, Ticket: maprSecret},
+			want:       true,
+		},
+		{
+			name:       "missing ticket",
+			expression: "ticket.user == 'mapr'",
+			volume:     &types.MaprVolume{Volume: volume},
+			claim:      &types.MaprVolumeClaim{Claim: claim, Volume: volume},
+			want:       false,
+		},
+		{
+			name:       "secret reference without ticket",
+			expression: "secret.namespace == 'team-a' && !has(secret.key)",
+			volume:     &types.MaprVolume{Volume: volume},
+			claim:      &types.MaprVolumeClaim{Claim: claim, Volume: volume},
+			want:       true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			volumeExpression, err := Compile(test.expression, VolumeVariables)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, volumeExpression.Matches(VolumeActivation(test.volume)))
+
+			claimExpression, err := Compile(test.expression, ClaimVariables)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, claimExpression.Matches(ClaimActivation(test.claim)))
+		})
+	}
+
+	t.Run("claim labels", func(t *testing.T) {
+		t.Parallel()
+
+		expression, err := Compile("claim.labels.app == 'spark'", ClaimVariables)
+		assert.NoError(t, err)
+		assert.True(t, expression.Matches(ClaimActivation(&types.MaprVolumeClaim{Claim: claim, Volume: volume})))
+	})
+}
+
+func newMaprSecret(namespace, name, user string, expiresIn time.Duration) *types.MaprSecret {
+	maprTicket := ticket.NewMaprTicket()
+	maprTicket.Cluster = "demo.mapr.com"
+	maprTicket.UserCreds.UserName = ptr.To(user)
+	maprTicket.UserCreds.Uid = ptr.To(uint32(5000))
+	maprTicket.UserCreds.Gids = []uint32{5000, 5001}
+	maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(time.Now().Add(expiresIn).Unix()))
+	maprTicket.TicketAndKey.CreationTimeSec = ptr.To(uint64(time.Now().Add(-time.Hour).Unix()))
+
+	return &types.MaprSecret{
+		Secret: &types.Secret{ObjectMeta: metaV1.ObjectMeta{Namespace: namespace, Name: name}},
+		Ticket: maprTicket,
+		Key:    ticket.SecretMaprTicketKey,
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package label implements labeling existing secrets containing MapR tickets with the label
+// ticket.LabelTicket. Secrets created or rotated using this plugin are labeled automatically, but
+// secrets created by other means have to be labeled once, so the secret lister can retrieve only
+// the labeled secrets instead of the data of all secrets.
+package label
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Labeler is the struct that is used to find and label secrets containing MapR tickets.
+type Labeler struct {
+	client    kubernetes.Interface
+	namespace string
+
+	ticketKey        string
+	detectTicketKeys bool
+	chunkSize        int64
+	dryRun           []string
+}
+
+// NewLabeler creates a new labeler. It requires a Kubernetes client and a namespace to operate on.
+// It also accepts a list of options that can be used to configure the labeler.
+func NewLabeler(client kubernetes.Interface, namespace string, opts ...Option) *Labeler {
+	l := &Labeler{
+		client:    client,
+		namespace: namespace,
+		ticketKey: ticket.SecretMaprTicketKey,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Unlabeled returns the secrets containing a MapR ticket that are not labeled yet. If the secret
+// name is equal to util.SecretAll, all secrets in the namespace are considered, otherwise only the
+// secret with the given name. An error is returned if that secret does not contain a ticket.
+func (l *Labeler) Unlabeled(secretName string) ([]coreV1.Secret, error) {
+	if secretName != util.SecretAll {
+		secret, err := l.client.CoreV1().Secrets(l.namespace).Get(context.TODO(), secretName, metaV1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ticket.SecretTicketKeys(secret, l.ticketKey, l.detectTicketKeys)) == 0 {
+			return nil, ticket.NewErrSecretDoesNotContainMaprTicket(secret.Namespace, secret.Name)
+		}
+
+		if IsLabeled(secret) {
+			return []coreV1.Secret{}, nil
+		}
+
+		return []coreV1.Secret{*secret}, nil
+	}
+
+	// let the API server skip the secrets that are labeled already
+	opts := metaV1.ListOptions{
+		LabelSelector: ticket.LabelTicket + "!=" + ticket.LabelTicketValue,
+		Limit:         l.chunkSize,
+	}
+
+	unlabeled := []coreV1.Secret{}
+
+	for {
+		secrets, err := l.client.CoreV1().Secrets(l.namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range secrets.Items {
+			secret := &secrets.Items[i]
+
+			if !IsLabeled(secret) && len(ticket.SecretTicketKeys(secret, l.ticketKey, l.detectTicketKeys)) > 0 {
+				unlabeled = append(unlabeled, *secret.DeepCopy())
+			}
+		}
+
+		if secrets.Continue == "" {
+			break
+		}
+
+		opts.Continue = secrets.Continue
+	}
+
+	return unlabeled, nil
+}
+
+// Label labels the secret as containing a MapR ticket using a merge patch, so other changes of the
+// secret are not overwritten. It returns the labeled secret as returned by the API server.
+func (l *Labeler) Label(secret *coreV1.Secret) (*coreV1.Secret, error) {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]string{
+				ticket.LabelTicket: ticket.LabelTicketValue,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return l.client.CoreV1().Secrets(secret.Namespace).Patch(
+		context.TODO(),
+		secret.Name,
+		k8sTypes.MergePatchType,
+		patch,
+		metaV1.PatchOptions{DryRun: l.dryRun},
+	)
+}
+
+// IsLabeled returns true if the secret is labeled as containing a MapR ticket
+func IsLabeled(secret *coreV1.Secret) bool {
+	return secret.Labels[ticket.LabelTicket] == ticket.LabelTicketValue
+}
+
+// Mark returns a copy of the secret labeled as containing a MapR ticket, without sending it to the
+// API server
+func Mark(secret *coreV1.Secret) *coreV1.Secret {
+	marked := secret.DeepCopy()
+
+	if marked.Labels == nil {
+		marked.Labels = map[string]string{}
+	}
+
+	marked.Labels[ticket.LabelTicket] = ticket.LabelTicketValue
+
+	return marked
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package label_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/label"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+	"github.com/nobbs/mapr-ticket-parser/pkg/parse"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLabeler_Unlabeled(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newSecret("team-a", "unlabeled", ticket.SecretMaprTicketKey, nil),
+		newSecret("team-a", "labeled", ticket.SecretMaprTicketKey, map[string]string{ticket.LabelTicket: ticket.LabelTicketValue}),
+		newSecret("team-a", "other-key", "ticket", nil),
+		newSecret("team-a", "no-ticket", "", nil),
+		newSecret("team-b", "unlabeled", ticket.SecretMaprTicketKey, map[string]string{"team": "b"}),
+	)
+
+	tests := []struct {
+		name       string
+		namespace  string
+		secretName string
+		opts       []Option
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name:       "all secrets in namespace",
+			namespace:  "team-a",
+			secretName: util.SecretAll,
+			want:       []string{"team-a/unlabeled"},
+		},
+		{
+			name:       "all secrets in all namespaces",
+			namespace:  util.NamespaceAll,
+			secretName: util.SecretAll,
+			want:       []string{"team-a/unlabeled", "team-b/unlabeled"},
+		},
+		{
+			name:       "all secrets with detected ticket keys",
+			namespace:  "team-a",
+			secretName: util.SecretAll,
+			opts:       []Option{WithDetectTicketKeys()},
+			want:       []string{"team-a/unlabeled", "team-a/other-key"},
+		},
+		{
+			name:       "single unlabeled secret",
+			namespace:  "team-a",
+			secretName: "unlabeled",
+			want:       []string{"team-a/unlabeled"},
+		},
+		{
+			name:       "single labeled secret",
+			namespace:  "team-a",
+			secretName: "labeled",
+			want:       []string{},
+		},
+		{
+			name:       "single secret with custom ticket key",
+			namespace:  "team-a",
+			secretName: "other-key",
+			opts:       []Option{WithTicketKey("ticket")},
+			want:       []string{"team-a/other-key"},
+		},
+		{
+			name:       "single secret without ticket",
+			namespace:  "team-a",
+			secretName: "no-ticket",
+			wantErr:    true,
+		},
+		{
+			name:       "missing secret",
+			namespace:  "team-a",
+			secretName: "missing",
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NewLabeler(client, test.namespace, test.opts...).Unlabeled(test.secretName)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, test.want, names(got))
+		})
+	}
+}
+
+func TestLabeler_Label(t *testing.T) {
+	t.Parallel()
+
+	secret := newSecret("team-a", "unlabeled", ticket.SecretMaprTicketKey, map[string]string{"team": "a"})
+	client := fake.NewSimpleClientset(secret)
+
+	labeled, err := NewLabeler(client, "team-a").Label(secret)
+	assert.NoError(t, err)
+	assert.True(t, IsLabeled(labeled))
+
+	// the existing labels and data of the secret must be kept
+	got, err := client.CoreV1().Secrets("team-a").Get(context.TODO(), "unlabeled", metaV1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "a", ticket.LabelTicket: ticket.LabelTicketValue}, got.Labels)
+	assert.Equal(t, secret.Data, got.Data)
+}
+
+func TestMark(t *testing.T) {
+	t.Parallel()
+
+	secret := newSecret("team-a", "unlabeled", ticket.SecretMaprTicketKey, nil)
+
+	marked := Mark(secret)
+
+	assert.True(t, IsLabeled(marked))
+	assert.False(t, IsLabeled(secret))
+}
+
+// newSecret returns a secret holding a MapR ticket under the given key, or no data if the key is
+// empty
+func newSecret(namespace, name, key string, labels map[string]string) *coreV1.Secret {
+	secret := &coreV1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+	}
+
+	if key != "" {
+		maprTicket := ticket.NewMaprTicket()
+		maprTicket.Cluster = "demo.mapr.com"
+
+		data, err := parse.Marshal(maprTicket.AsMaprTicket())
+		if err != nil {
+			panic(err)
+		}
+
+		secret.Data = map[string][]byte{key: data}
+	}
+
+	return secret
+}
+
+// names returns the namespace and name of each secret
+func names(secrets []coreV1.Secret) []string {
+	out := make([]string, 0, len(secrets))
+
+	for _, secret := range secrets {
+		out = append(out, secret.Namespace+"/"+secret.Name)
+	}
+
+	return out
+}
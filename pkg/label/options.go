@@ -0,0 +1,41 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package label
+
+// Option is a function that can be used to configure the labeler.
+type Option func(*Labeler)
+
+// WithTicketKey configures the labeler to look for MapR tickets stored under the given data key
+// of the secrets
+func WithTicketKey(key string) Option {
+	return func(l *Labeler) {
+		l.ticketKey = key
+	}
+}
+
+// WithDetectTicketKeys configures the labeler to try to parse every data key of the secrets as a
+// MapR ticket
+func WithDetectTicketKeys() Option {
+	return func(l *Labeler) {
+		l.detectTicketKeys = true
+	}
+}
+
+// WithChunkSize configures the labeler to list the secrets in chunks of the given size instead of
+// all at once. A chunk size of 0 disables chunking.
+func WithChunkSize(chunkSize int64) Option {
+	return func(l *Labeler) {
+		l.chunkSize = chunkSize
+	}
+}
+
+// WithDryRun configures the labeler to send the patches with the given dry run option, e.g. to
+// perform a server side dry run
+func WithDryRun(dryRun []string) Option {
+	return func(l *Labeler) {
+		l.dryRun = dryRun
+	}
+}
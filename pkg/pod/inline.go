@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package pod
+
+import (
+	"context"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// InlineVolumeLister scans the pod specs in the cluster for inline CSI volumes using one of the
+// MapR CSI drivers. Such volumes reference the ticket secret directly, without any persistent
+// volume being involved.
+type InlineVolumeLister struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewInlineVolumeLister returns a new inline volume lister for the pods in the given namespace,
+// use util.NamespaceAll to scan the pods of all namespaces.
+func NewInlineVolumeLister(client kubernetes.Interface, namespace string) *InlineVolumeLister {
+	return &InlineVolumeLister{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+// List returns all inline CSI volumes using one of the MapR CSI drivers.
+func (l *InlineVolumeLister) List() ([]types.MaprInlineVolume, error) {
+	pods, err := l.client.CoreV1().Pods(l.namespace).List(context.TODO(), metaV1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var volumes []types.MaprInlineVolume
+
+	for i := range pods.Items {
+		volumes = append(volumes, (*types.Pod)(&pods.Items[i]).GetMaprInlineVolumes()...)
+	}
+
+	return volumes, nil
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package pod implements the pod lister. It is responsible for listing all pods in the cluster
+// that mount persistent volume claims bound to MapR-backed persistent volumes, or that use one of
+// the MapR CSI drivers through inline CSI volumes.
+//
+// The lister follows each pod to the claims it mounts, using the volume claim lister to resolve
+// the claims to their persistent volumes and MapR tickets. It also resolves the workload owning
+// each pod, ie. the Deployment or CronJob instead of the intermediate ReplicaSet or Job, so it is
+// easy to tell which workloads are affected by an expiring ticket.
+package pod
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/claim"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// kinds of intermediate controllers that are resolved to their own controller
+	kindReplicaSet = "ReplicaSet"
+	kindJob        = "Job"
+)
+
+// claimLister is the interface that a volume claim lister must implement.
+type claimLister interface {
+	List() ([]types.MaprVolumeClaim, error)
+}
+
+// secretLister is the interface that a secret lister must implement.
+type secretLister interface {
+	ListReferenced(refs []k8sTypes.NamespacedName) ([]types.MaprSecret, error)
+}
+
+// Lister is the struct that is used to list pods mounting MapR-backed volumes in the cluster.
+type Lister struct {
+	client    kubernetes.Interface
+	namespace string
+
+	claimLister   claimLister
+	secretLister  secretLister
+	sortBy        []SortOption
+	labelSelector string
+	fieldSelector string
+
+	pods []types.MaprPod
+
+	// err holds the error of collecting the tickets of inline volumes, as the pods would otherwise
+	// be reported without a ticket
+	err error
+}
+
+// NewLister creates a new pod lister. It requires a Kubernetes client and a namespace to operate
+// on. It also accepts a list of options that can be used to configure the lister. If no volume
+// claim lister is configured, a default one without ticket lookup is used.
+func NewLister(client kubernetes.Interface, namespace string, opts ...ListerOption) *Lister {
+	l := &Lister{
+		client:    client,
+		namespace: namespace,
+		sortBy:    DefaultSortBy,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.claimLister == nil {
+		l.claimLister = claim.NewLister(client, namespace)
+	}
+
+	return l
+}
+
+// List returns a list of pods mounting at least one MapR-backed volume, either through a
+// persistent volume claim or as an inline CSI volume, with one entry per pod and volume.
+func (l *Lister) List() ([]types.MaprPod, error) {
+	l.err = nil
+
+	if err := l.getPodsWithVolumes(); err != nil {
+		return nil, err
+	}
+
+	l.collectInlineTickets().
+		collectControllers().
+		sort()
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return l.pods, nil
+}
+
+// listOptions returns the options used to list the pods, applying the label and field
+// selectors of the Lister
+func (l *Lister) listOptions() metaV1.ListOptions {
+	return metaV1.ListOptions{
+		LabelSelector: l.labelSelector,
+		FieldSelector: l.fieldSelector,
+	}
+}
+
+// getPodsWithVolumes lists all pods and matches the claims they mount against the MapR-backed
+// volume claims returned by the volume claim lister. Inline CSI volumes using one of the MapR CSI
+// drivers are collected as well.
+func (l *Lister) getPodsWithVolumes() error {
+	claims, err := l.claimLister.List()
+	if err != nil {
+		return err
+	}
+
+	pods, err := l.client.CoreV1().Pods(l.namespace).List(context.TODO(), l.listOptions())
+	if err != nil {
+		return err
+	}
+
+	// index the claims by namespace and name for faster lookup
+	claimsByKey := make(map[string]*types.MaprVolumeClaim, len(claims))
+	for i := range claims {
+		claimsByKey[claimKey(claims[i].Claim.GetNamespace(), claims[i].Claim.GetName())] = &claims[i]
+	}
+
+	l.pods = make([]types.MaprPod, 0, len(pods.Items))
+
+	for i := range pods.Items {
+		pod := (*types.Pod)(&pods.Items[i])
+
+		for _, claimName := range pod.GetClaimNames() {
+			volumeClaim, ok := claimsByKey[claimKey(pod.GetNamespace(), claimName)]
+			if !ok {
+				continue
+			}
+
+			l.pods = append(l.pods, types.MaprPod{
+				Pod:        pod,
+				Controller: pod.GetController(),
+				Claim:      volumeClaim,
+			})
+		}
+
+		inlineVolumes := pod.GetMaprInlineVolumes()
+		for j := range inlineVolumes {
+			l.pods = append(l.pods, types.MaprPod{
+				Pod:        pod,
+				Controller: pod.GetController(),
+				Inline:     &inlineVolumes[j],
+			})
+		}
+	}
+
+	return nil
+}
+
+// collectInlineTickets collects the MapR tickets referenced by inline volumes, if available.
+func (l *Lister) collectInlineTickets() *Lister {
+	// return early if there is no secret lister
+	if l.secretLister == nil {
+		return l
+	}
+
+	// return early if there are no inline volumes
+	var refs []k8sTypes.NamespacedName
+	for _, pod := range l.pods {
+		if pod.Inline != nil {
+			refs = append(refs, k8sTypes.NamespacedName{
+				Namespace: pod.Inline.GetSecretNamespace(),
+				Name:      pod.Inline.GetSecretName(),
+			})
+		}
+	}
+
+	if len(refs) == 0 {
+		return l
+	}
+
+	// collect the tickets via the secret lister, getting the referenced secrets one by one if they
+	// can't be listed
+	tickets, err := l.secretLister.ListReferenced(refs)
+	if err != nil {
+		l.err = fmt.Errorf("failed to list secrets referenced by inline volumes: %w", err)
+		return l
+	}
+
+	for i := range l.pods {
+		inline := l.pods[i].Inline
+		if inline == nil {
+			continue
+		}
+
+		for j := range tickets {
+			if tickets[j].Secret.Namespace == inline.GetSecretNamespace() &&
+				tickets[j].Secret.Name == inline.GetSecretName() {
+				inline.Ticket = &tickets[j]
+				break
+			}
+		}
+	}
+
+	return l
+}
+
+// collectControllers replaces intermediate controllers, ie. ReplicaSets and Jobs, with the
+// controller owning them, e.g. a Deployment or CronJob. If the intermediate controllers can't be
+// listed, the direct controller of the pod is kept.
+func (l *Lister) collectControllers() *Lister {
+	var resolveReplicaSets, resolveJobs bool
+
+	for _, pod := range l.pods {
+		switch pod.Controller.GetKind() {
+		case kindReplicaSet:
+			resolveReplicaSets = true
+		case kindJob:
+			resolveJobs = true
+		}
+	}
+
+	owners := make(map[string]*types.ControllerReference)
+
+	if resolveReplicaSets {
+		replicaSets, err := l.client.AppsV1().ReplicaSets(l.namespace).List(context.TODO(), metaV1.ListOptions{})
+		if err != nil {
+			slog.Debug("failed to list replica sets, keeping them as pod controllers", "error", err)
+		} else {
+			for i := range replicaSets.Items {
+				rs := &replicaSets.Items[i]
+				addOwner(owners, kindReplicaSet, rs.Namespace, rs.Name, metaV1.GetControllerOf(rs))
+			}
+		}
+	}
+
+	if resolveJobs {
+		jobs, err := l.client.BatchV1().Jobs(l.namespace).List(context.TODO(), metaV1.ListOptions{})
+		if err != nil {
+			slog.Debug("failed to list jobs, keeping them as pod controllers", "error", err)
+		} else {
+			for i := range jobs.Items {
+				job := &jobs.Items[i]
+				addOwner(owners, kindJob, job.Namespace, job.Name, metaV1.GetControllerOf(job))
+			}
+		}
+	}
+
+	for i := range l.pods {
+		pod := &l.pods[i]
+
+		if pod.Controller == nil {
+			continue
+		}
+
+		if owner, ok := owners[ownerKey(pod.Controller.Kind, pod.Pod.GetNamespace(), pod.Controller.Name)]; ok {
+			pod.Controller = owner
+		}
+	}
+
+	return l
+}
+
+// addOwner adds the controller of the given intermediate controller to the owners map, if it has
+// one
+func addOwner(owners map[string]*types.ControllerReference, kind, namespace, name string, owner *metaV1.OwnerReference) {
+	if owner == nil {
+		return
+	}
+
+	owners[ownerKey(kind, namespace, name)] = &types.ControllerReference{
+		Kind: owner.Kind,
+		Name: owner.Name,
+	}
+}
+
+// claimKey returns the key used to look up a claim by namespace and name
+func claimKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// ownerKey returns the key used to look up the owner of an intermediate controller
+func ownerKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
@@ -0,0 +1,407 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package pod_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/pod"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestLister_Default(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fields   listerFields
+		expected []expectedPod
+		wantErr  bool
+	}{
+		{
+			name: "no pods",
+			fields: listerFields{
+				client:    fake.NewSimpleClientset(),
+				namespace: "default",
+			},
+			expected: []expectedPod{},
+			wantErr:  false,
+		},
+		{
+			name: "pod without claims",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					newBoundClaim("default", "claim-1", "volume-1"),
+					newVolume("volume-1", types.MaprCSIProvisionerKDF),
+					newPod("default", "pod-1"),
+				),
+				namespace: "default",
+			},
+			expected: []expectedPod{},
+			wantErr:  false,
+		},
+		{
+			name: "pods with mapr and other claims",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					newBoundClaim("default", "claim-1", "volume-1"),
+					newBoundClaim("default", "claim-2", "volume-2"),
+					newVolume("volume-1", types.MaprCSIProvisionerKDF),
+					newVolume("volume-2", "random-provisioner"),
+					newPod("default", "pod-2", withClaims("claim-1")),
+					newPod("default", "pod-1", withClaims("claim-2")),
+					newPod("default", "pod-3", withClaims("claim-1", "claim-2"), withNodeName("node-1")),
+				),
+				namespace: "default",
+			},
+			expected: []expectedPod{
+				expectPod("default", "pod-2", "claim-1", ""),
+				expectPod("default", "pod-3", "claim-1", ""),
+			},
+			wantErr: false,
+		},
+		{
+			name: "pod mounting the same claim name in another namespace",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					newBoundClaim("default", "claim-1", "volume-1"),
+					newVolume("volume-1", types.MaprCSIProvisionerNFSKDF),
+					newPod("other", "pod-1", withClaims("claim-1")),
+				),
+				namespace: util.NamespaceAll,
+			},
+			expected: []expectedPod{},
+			wantErr:  false,
+		},
+		{
+			name: "pod mounting two mapr claims",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					newBoundClaim("default", "claim-1", "volume-1"),
+					newBoundClaim("default", "claim-2", "volume-2"),
+					newVolume("volume-1", types.MaprCSIProvisionerKDF),
+					newVolume("volume-2", types.MaprCSIProvisionerNFSKDF),
+					newPod("default", "pod-1", withClaims("claim-1", "claim-2")),
+				),
+				namespace: "default",
+			},
+			expected: []expectedPod{
+				expectPod("default", "pod-1", "claim-1", ""),
+				expectPod("default", "pod-1", "claim-2", ""),
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLister(test.fields.client, test.fields.namespace, test.fields.opts...)
+
+			actual, err := l.List()
+
+			assertPods(t, test.expected, actual)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestLister_InlineVolumes(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newBoundClaim("default", "claim-1", "volume-1"),
+		newVolume("volume-1", types.MaprCSIProvisionerKDF),
+		newPod("default", "pod-1", withClaims("claim-1"), withInlineVolume("data", types.MaprCSIProvisionerNFSKDF, "ticket")),
+		newPod("default", "pod-2", withInlineVolume("data", "random-driver", "ticket")),
+	)
+
+	l := NewLister(client, "default")
+
+	actual, err := l.List()
+
+	assert.NoError(t, err)
+	assertPods(t, []expectedPod{
+		expectPod("default", "pod-1", "claim-1", ""),
+		expectPod("default", "pod-1", "", ""),
+	}, actual)
+
+	if assert.Len(t, actual, 2) {
+		assert.NotNil(t, actual[1].Inline)
+		assert.Equal(t, "data", actual[1].GetVolumeName())
+		assert.Equal(t, "default", actual[1].GetSecretNamespace())
+		assert.Equal(t, "ticket", actual[1].GetSecretName())
+	}
+}
+
+func TestInlineVolumeLister_List(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newPod("default", "pod-1", withInlineVolume("data", types.MaprCSIProvisionerKDF, "ticket-1")),
+		newPod("other", "pod-2", withInlineVolume("data", types.MaprCSIProvisionerNFSKDF, "ticket-2"), withInlineVolume("logs", types.MaprCSIProvisionerKDF, "ticket-3")),
+		newPod("other", "pod-3", withInlineVolume("data", "random-driver", "ticket-4")),
+	)
+
+	volumes, err := NewInlineVolumeLister(client, util.NamespaceAll).List()
+
+	assert.NoError(t, err)
+	assert.Len(t, volumes, 3)
+
+	volumes, err = NewInlineVolumeLister(client, "other").List()
+
+	assert.NoError(t, err)
+	assert.Len(t, volumes, 2)
+
+	for _, v := range volumes {
+		assert.True(t, v.UsesSecret("other", util.SecretAll))
+		assert.False(t, v.UsesSecret("default", util.SecretAll))
+	}
+}
+
+func TestLister_Controllers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fields   listerFields
+		expected []expectedPod
+		wantErr  bool
+	}{
+		{
+			name: "replica set owned by a deployment",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					newBoundClaim("default", "claim-1", "volume-1"),
+					newVolume("volume-1", types.MaprCSIProvisionerKDF),
+					newReplicaSet("default", "app-5d8f7", controllerRefs("Deployment", "app")),
+					newPod("default", "app-5d8f7-abcde", withClaims("claim-1"), withController("ReplicaSet", "app-5d8f7")),
+				),
+				namespace: "default",
+			},
+			expected: []expectedPod{
+				expectPod("default", "app-5d8f7-abcde", "claim-1", "Deployment/app"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "bare replica set and stateful set",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					newBoundClaim("default", "claim-1", "volume-1"),
+					newVolume("volume-1", types.MaprCSIProvisionerKDF),
+					newReplicaSet("default", "bare", nil),
+					newPod("default", "bare-abcde", withClaims("claim-1"), withController("ReplicaSet", "bare")),
+					newPod("default", "db-0", withClaims("claim-1"), withController("StatefulSet", "db")),
+				),
+				namespace: "default",
+			},
+			expected: []expectedPod{
+				expectPod("default", "bare-abcde", "claim-1", "ReplicaSet/bare"),
+				expectPod("default", "db-0", "claim-1", "StatefulSet/db"),
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLister(test.fields.client, test.fields.namespace, test.fields.opts...)
+
+			actual, err := l.List()
+
+			assertPods(t, test.expected, actual)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestLister_WithSortBy(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newBoundClaim("default", "claim-1", "volume-1"),
+		newVolume("volume-1", types.MaprCSIProvisionerKDF),
+		newPod("default", "pod-1", withClaims("claim-1"), withNodeName("node-b")),
+		newPod("default", "pod-2", withClaims("claim-1"), withNodeName("node-a")),
+	)
+
+	l := NewLister(client, "default", WithSortBy([]SortOption{SortByNode}))
+
+	actual, err := l.List()
+
+	assert.NoError(t, err)
+	assertPods(t, []expectedPod{
+		expectPod("default", "pod-2", "claim-1", ""),
+		expectPod("default", "pod-1", "claim-1", ""),
+	}, actual)
+}
+
+type listerFields struct {
+	client    kubernetes.Interface
+	namespace string
+	opts      []ListerOption
+}
+
+type expectedPod struct {
+	namespace  string
+	name       string
+	claim      string
+	controller string
+}
+
+func expectPod(namespace, name, claim, controller string) expectedPod {
+	return expectedPod{
+		namespace:  namespace,
+		name:       name,
+		claim:      claim,
+		controller: controller,
+	}
+}
+
+func assertPods(t *testing.T, expected []expectedPod, actual []types.MaprPod) {
+	t.Helper()
+
+	assert.Len(t, actual, len(expected))
+
+	for i, pod := range actual {
+		if i >= len(expected) {
+			break
+		}
+
+		assert.Equal(t, expected[i].namespace, pod.Pod.GetNamespace())
+		assert.Equal(t, expected[i].name, pod.Pod.GetName())
+		assert.Equal(t, expected[i].claim, pod.GetClaimName())
+		assert.Equal(t, expected[i].controller, pod.Controller.String())
+	}
+}
+
+type podOption func(*coreV1.Pod)
+
+func withClaims(claimNames ...string) podOption {
+	return func(p *coreV1.Pod) {
+		for _, claimName := range claimNames {
+			p.Spec.Volumes = append(p.Spec.Volumes, coreV1.Volume{
+				Name: claimName,
+				VolumeSource: coreV1.VolumeSource{
+					PersistentVolumeClaim: &coreV1.PersistentVolumeClaimVolumeSource{
+						ClaimName: claimName,
+					},
+				},
+			})
+		}
+	}
+}
+
+func withInlineVolume(name, driver, secretName string) podOption {
+	return func(p *coreV1.Pod) {
+		p.Spec.Volumes = append(p.Spec.Volumes, coreV1.Volume{
+			Name: name,
+			VolumeSource: coreV1.VolumeSource{
+				CSI: &coreV1.CSIVolumeSource{
+					Driver:               driver,
+					NodePublishSecretRef: &coreV1.LocalObjectReference{Name: secretName},
+				},
+			},
+		})
+	}
+}
+
+func withNodeName(nodeName string) podOption {
+	return func(p *coreV1.Pod) {
+		p.Spec.NodeName = nodeName
+	}
+}
+
+func withController(kind, name string) podOption {
+	return func(p *coreV1.Pod) {
+		p.OwnerReferences = controllerRefs(kind, name)
+	}
+}
+
+func controllerRefs(kind, name string) []metaV1.OwnerReference {
+	return []metaV1.OwnerReference{
+		{
+			Kind:       kind,
+			Name:       name,
+			Controller: ptr.To(true),
+		},
+	}
+}
+
+func newPod(namespace, name string, opts ...podOption) *coreV1.Pod {
+	p := &coreV1.Pod{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+func newReplicaSet(namespace, name string, owners []metaV1.OwnerReference) *appsV1.ReplicaSet {
+	return &appsV1.ReplicaSet{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			OwnerReferences: owners,
+		},
+	}
+}
+
+func newBoundClaim(namespace, name, volumeName string) *coreV1.PersistentVolumeClaim {
+	return &coreV1.PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: coreV1.PersistentVolumeClaimSpec{
+			VolumeName: volumeName,
+		},
+		Status: coreV1.PersistentVolumeClaimStatus{
+			Phase: coreV1.ClaimBound,
+		},
+	}
+}
+
+func newVolume(name, driver string) *coreV1.PersistentVolume {
+	return &coreV1.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+		},
+		Spec: coreV1.PersistentVolumeSpec{
+			PersistentVolumeSource: coreV1.PersistentVolumeSource{
+				CSI: &coreV1.CSIPersistentVolumeSource{
+					Driver: driver,
+					NodePublishSecretRef: &coreV1.SecretReference{
+						Namespace: "default",
+						Name:      "ticket",
+					},
+				},
+			},
+		},
+	}
+}
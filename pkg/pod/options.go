@@ -0,0 +1,46 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package pod
+
+// ListerOption is a function that can be used to configure the pod lister.
+type ListerOption func(*Lister)
+
+// WithClaimLister configures the pod lister to use the given volume claim lister to resolve the
+// claims mounted by the pods.
+func WithClaimLister(claimLister claimLister) ListerOption {
+	return func(l *Lister) {
+		l.claimLister = claimLister
+	}
+}
+
+// WithSecretLister configures the pod lister to use the given secret lister to look up the
+// tickets referenced by inline CSI volumes.
+func WithSecretLister(secretLister secretLister) ListerOption {
+	return func(l *Lister) {
+		l.secretLister = secretLister
+	}
+}
+
+// WithSortBy configures the pod lister to sort the pods by the given sort options.
+func WithSortBy(sortBy []SortOption) ListerOption {
+	return func(l *Lister) {
+		l.sortBy = sortBy
+	}
+}
+
+// WithLabelSelector sets the label selector used by the Lister to select the pods to list
+func WithLabelSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.labelSelector = selector
+	}
+}
+
+// WithFieldSelector sets the field selector used by the Lister to select the pods to list
+func WithFieldSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.fieldSelector = selector
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package pod
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+var (
+	tableColumnDefinitions = []metaV1.TableColumnDefinition{
+		{
+			Name:        "Name",
+			Type:        "string",
+			Format:      "name",
+			Description: "Name of the pod",
+			Priority:    0,
+		},
+		{
+			Name:        "Status",
+			Type:        "string",
+			Description: "Phase of the pod",
+			Priority:    1,
+		},
+		{
+			Name:        "Node",
+			Type:        "string",
+			Description: "Name of the node the pod is scheduled on",
+			Priority:    0,
+		},
+		{
+			Name:        "Controller",
+			Type:        "string",
+			Description: "Workload controlling the pod",
+			Priority:    0,
+		},
+		{
+			Name:        "Claim",
+			Type:        "string",
+			Description: "Name of the persistent volume claim mounted by the pod, or <inline> for inline volumes",
+			Priority:    0,
+		},
+		{
+			Name:        "Volume Name",
+			Type:        "string",
+			Description: "Name of the persistent volume bound to the claim, or of the inline volume",
+			Priority:    1,
+		},
+		{
+			Name:        "Secret Namespace",
+			Type:        "string",
+			Description: "Namespace of the secret containing the MapR ticket",
+			Priority:    1,
+		},
+		{
+			Name:        "Secret",
+			Type:        "string",
+			Description: "Name of the secret containing the MapR ticket",
+			Priority:    1,
+		},
+		{
+			Name:        "Ticket Status",
+			Type:        "string",
+			Description: "Status of the MapR ticket",
+			Priority:    0,
+		},
+		{
+			Name:        "Age",
+			Type:        "string",
+			Format:      "date-time",
+			Description: "Creation time of the pod",
+			Priority:    0,
+		},
+	}
+)
+
+// Print prints the pods to the given output stream in a tabular format known by kubectl.
+func Print(cmd *cobra.Command, pods []types.MaprPod) error {
+	format := cmd.Flag("output").Value.String()
+	allNamespaces := cmd.Flag("all-namespaces").Changed && cmd.Flag("all-namespaces").Value.String() == "true"
+
+	// generate the table
+	table := generateTable(pods)
+
+	// print the table
+	printer := printers.NewTablePrinter(printers.PrintOptions{
+		WithNamespace: allNamespaces,
+		Wide:          format == "wide",
+	})
+
+	err := printer.PrintObj(table, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PrintObjects prints the pods as a structured list using the given printer, e.g. a JSON, YAML,
+// JSONPath, Go template or custom columns printer.
+func PrintObjects(cmd *cobra.Command, printer printers.ResourcePrinter, pods []types.MaprPod) error {
+	list, err := generateList(pods)
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(list, cmd.OutOrStdout())
+}
+
+// generateTable generates a table from the given pods.
+func generateTable(pods []types.MaprPod) *metaV1.Table {
+	rows := generateRows(pods)
+
+	return &metaV1.Table{
+		ColumnDefinitions: tableColumnDefinitions,
+		Rows:              rows,
+	}
+}
+
+// generateList generates a list of structured items from the given pods.
+func generateList(pods []types.MaprPod) (*unstructured.UnstructuredList, error) {
+	items := make([]*types.MaprPodItem, 0, len(pods))
+
+	for i := range pods {
+		items = append(items, pods[i].Item())
+	}
+
+	return types.NewList(items)
+}
+
+// generateRows generates the rows for the given pods.
+func generateRows(pods []types.MaprPod) []metaV1.TableRow {
+	rows := make([]metaV1.TableRow, 0, len(pods))
+
+	for _, pod := range pods {
+		rows = append(rows, *generateRow(&pod))
+	}
+
+	return rows
+}
+
+// generateRow generates a row for the given pod.
+func generateRow(pod *types.MaprPod) *metaV1.TableRow {
+	row := &metaV1.TableRow{
+		Object: runtime.RawExtension{
+			Object: (*coreV1.Pod)(pod.Pod),
+		},
+	}
+
+	controller := pod.Controller.String()
+	if controller == "" {
+		controller = "<none>"
+	}
+
+	claim := pod.GetClaimName()
+	if pod.Inline != nil {
+		claim = "<inline>"
+	}
+
+	row.Cells = []any{
+		pod.Pod.GetName(),
+		string(pod.Pod.Status.Phase),
+		pod.Pod.GetNodeName(),
+		controller,
+		claim,
+		pod.GetVolumeName(),
+		pod.GetSecretNamespace(),
+		pod.GetSecretName(),
+		pod.GetTicket().GetStatusString(),
+		util.ShortHumanDurationUntilNow(pod.Pod.CreationTimestamp.Time),
+	}
+
+	return row
+}
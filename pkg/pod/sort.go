@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package pod
+
+import (
+	"sort"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+)
+
+// SortOption is the type of a sort option, basically a wrapper around a string to provide
+// type safety.
+type SortOption string
+
+// All valid sort options are defined here
+const (
+	SortByNamespace  SortOption = "namespace"
+	SortByName       SortOption = "name"
+	SortByNode       SortOption = "node"
+	SortByController SortOption = "controller"
+	SortByClaimName  SortOption = "claim.name"
+	SortBySecretName SortOption = "secret.name"
+	SortByExpiration SortOption = "expiration"
+	SortByAge        SortOption = "age"
+)
+
+var (
+	// SortOptionsList is the list of valid sort options
+	SortOptionsList = []string{
+		SortByNamespace.String(),
+		SortByName.String(),
+		SortByNode.String(),
+		SortByController.String(),
+		SortByClaimName.String(),
+		SortBySecretName.String(),
+		SortByExpiration.String(),
+		SortByAge.String(),
+	}
+
+	// DefaultSortBy is the default sort order
+	DefaultSortBy = []SortOption{
+		SortByNamespace,
+		SortByName,
+	}
+)
+
+// String returns the string representation of the sort option.
+func (s SortOption) String() string {
+	return string(s)
+}
+
+func sortByName(pods []types.MaprPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Pod.GetName() < pods[j].Pod.GetName()
+	})
+}
+
+func sortByNamespace(pods []types.MaprPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Pod.GetNamespace() < pods[j].Pod.GetNamespace()
+	})
+}
+
+func sortByNode(pods []types.MaprPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Pod.GetNodeName() < pods[j].Pod.GetNodeName()
+	})
+}
+
+func sortByController(pods []types.MaprPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Controller.String() < pods[j].Controller.String()
+	})
+}
+
+func sortByClaimName(pods []types.MaprPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].GetClaimName() < pods[j].GetClaimName()
+	})
+}
+
+func sortBySecretName(pods []types.MaprPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].GetSecretName() < pods[j].GetSecretName()
+	})
+}
+
+func sortByExpiration(pods []types.MaprPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].GetTicket().GetExpirationTime().Before(pods[j].GetTicket().GetExpirationTime())
+	})
+}
+
+func sortByAge(pods []types.MaprPod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Pod.CreationTimestamp.Before(&pods[j].Pod.CreationTimestamp)
+	})
+}
+
+// sort sorts the items by the specified sort options, in reverse order of the
+// order in which they are specified. This makes for a more natural sort result
+// when using multiple sort options.
+func (l *Lister) sort() *Lister {
+	// reverse the order of the sort options
+	order := make([]SortOption, len(l.sortBy))
+	for i, j := 0, len(l.sortBy)-1; i < len(l.sortBy); i, j = i+1, j-1 {
+		order[i] = l.sortBy[j]
+	}
+
+	// sort the items by the specified sort options
+	for _, sortOption := range order {
+		switch sortOption {
+		case SortByNamespace:
+			sortByNamespace(l.pods)
+		case SortByName:
+			sortByName(l.pods)
+		case SortByNode:
+			sortByNode(l.pods)
+		case SortByController:
+			sortByController(l.pods)
+		case SortByClaimName:
+			sortByClaimName(l.pods)
+		case SortBySecretName:
+			sortBySecretName(l.pods)
+		case SortByExpiration:
+			sortByExpiration(l.pods)
+		case SortByAge:
+			sortByAge(l.pods)
+		}
+	}
+
+	return l
+}
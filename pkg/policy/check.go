@@ -0,0 +1,219 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/xhit/go-str2duration/v2"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// RuleInvalidTicket is the rule reported for tickets that can't be parsed, as none of the
+	// rules of a policy can be evaluated for them
+	RuleInvalidTicket = "invalid-ticket"
+)
+
+// Violation is a ticket failing a rule of the policy
+type Violation struct {
+	Rule      string `json:"rule"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	Message   string `json:"message"`
+}
+
+// Checker evaluates MapR tickets against a policy
+type Checker struct {
+	client kubernetes.Interface
+	policy *Policy
+
+	// namespaceLabels caches the labels of the namespaces by name
+	namespaceLabels map[string]labels.Set
+}
+
+// NewChecker returns a new checker for the given policy. The Kubernetes client is used to look up
+// the labels of namespaces if the policy contains namespace selectors.
+func NewChecker(client kubernetes.Interface, policy *Policy) *Checker {
+	return &Checker{
+		client:          client,
+		policy:          policy,
+		namespaceLabels: make(map[string]labels.Set),
+	}
+}
+
+// Check evaluates every ticket against all rules of the policy and returns the violations, in the
+// order of the tickets and rules
+func (c *Checker) Check(secrets []types.MaprSecret) ([]Violation, error) {
+	violations := []Violation{}
+
+	for i := range secrets {
+		s := &secrets[i]
+
+		if s.IsInvalid() {
+			violations = append(violations, newViolation(RuleInvalidTicket, s, "ticket can't be parsed: %s", s.GetParseError()))
+			continue
+		}
+
+		for j := range c.policy.Rules {
+			rule := &c.policy.Rules[j]
+
+			if !rule.matches(s) {
+				continue
+			}
+
+			messages, err := c.evaluate(rule, s)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, message := range messages {
+				violations = append(violations, newViolation(rule.Name, s, "%s", message))
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// matches returns true if the rule applies to the ticket
+func (r *Rule) matches(s *types.MaprSecret) bool {
+	if r.Match == nil {
+		return true
+	}
+
+	if len(r.Match.Clusters) > 0 && !slices.Contains(r.Match.Clusters, s.GetCluster()) {
+		return false
+	}
+
+	if len(r.Match.Users) > 0 && !slices.Contains(r.Match.Users, s.GetUser()) {
+		return false
+	}
+
+	if len(r.Match.Namespaces) > 0 && !slices.Contains(r.Match.Namespaces, s.GetSecretNamespace()) {
+		return false
+	}
+
+	return true
+}
+
+// evaluate returns a message for each constraint of the rule the ticket fails
+func (c *Checker) evaluate(r *Rule, s *types.MaprSecret) ([]string, error) {
+	var messages []string
+
+	creds := s.Ticket.GetUserCreds()
+	uid := creds.GetUid()
+
+	if len(r.AllowedUIDs) > 0 && !slices.Contains(r.AllowedUIDs, uid) {
+		messages = append(messages, fmt.Sprintf("ticket is issued for UID %d, which is not allowed", uid))
+	}
+
+	if slices.Contains(r.DeniedUIDs, uid) {
+		messages = append(messages, fmt.Sprintf("ticket is issued for UID %d, which is denied", uid))
+	}
+
+	for _, gid := range creds.GetGids() {
+		if slices.Contains(r.DeniedGIDs, gid) {
+			messages = append(messages, fmt.Sprintf("ticket is issued for GID %d, which is denied", gid))
+		}
+	}
+
+	if len(r.AllowedUsers) > 0 && !slices.Contains(r.AllowedUsers, s.GetUser()) {
+		messages = append(messages, fmt.Sprintf("ticket is issued for user %q, which is not allowed", s.GetUser()))
+	}
+
+	if slices.Contains(r.DeniedUsers, s.GetUser()) {
+		messages = append(messages, fmt.Sprintf("ticket is issued for user %q, which is denied", s.GetUser()))
+	}
+
+	if len(r.AllowedClusters) > 0 && !slices.Contains(r.AllowedClusters, s.GetCluster()) {
+		messages = append(messages, fmt.Sprintf("ticket is issued for cluster %q, which is not allowed", s.GetCluster()))
+	}
+
+	hasExpiry := s.Ticket.GetExpiryTime() != 0
+
+	if r.RequireExpiry && !hasExpiry {
+		messages = append(messages, "ticket has no expiration time")
+	}
+
+	if r.MaxLifetime != nil && hasExpiry {
+		if lifetime := s.GetExpirationTime().Sub(s.GetCreationTime()); lifetime > r.MaxLifetime.Duration() {
+			messages = append(messages, fmt.Sprintf("ticket is valid for %s, more than the maximum of %s", formatDuration(lifetime), formatDuration(r.MaxLifetime.Duration())))
+		}
+	}
+
+	if r.MaxAge != nil {
+		if age := time.Since(s.GetCreationTime()); age > r.MaxAge.Duration() {
+			messages = append(messages, fmt.Sprintf("ticket was created %s ago, more than the maximum of %s", formatDuration(age), formatDuration(r.MaxAge.Duration())))
+		}
+	}
+
+	if r.MinValidity != nil && hasExpiry && s.Ticket.ExpiresBefore(r.MinValidity.Duration()) {
+		messages = append(messages, fmt.Sprintf("ticket expires at %s, less than %s from now", s.GetExpirationTime().UTC().Format(ticket.DefaultTimeFormat), formatDuration(r.MinValidity.Duration())))
+	}
+
+	if r.namespaceSelector != nil {
+		namespaceLabels, err := c.getNamespaceLabels(s.GetSecretNamespace())
+		if err != nil {
+			return nil, err
+		}
+
+		if !r.namespaceSelector.Matches(namespaceLabels) {
+			messages = append(messages, fmt.Sprintf("namespace %s does not match the selector %s", s.GetSecretNamespace(), r.namespaceSelector.String()))
+		}
+	}
+
+	return messages, nil
+}
+
+// getNamespaceLabels returns the labels of the namespace. A namespace that does not exist has no
+// labels.
+func (c *Checker) getNamespaceLabels(name string) (labels.Set, error) {
+	if namespaceLabels, ok := c.namespaceLabels[name]; ok {
+		return namespaceLabels, nil
+	}
+
+	namespace, err := c.client.CoreV1().Namespaces().Get(context.TODO(), name, metaV1.GetOptions{})
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", name, err)
+	}
+
+	namespaceLabels := labels.Set{}
+	if err == nil {
+		namespaceLabels = namespace.Labels
+	}
+
+	c.namespaceLabels[name] = namespaceLabels
+
+	return namespaceLabels, nil
+}
+
+// newViolation returns a new violation of the rule by the ticket
+func newViolation(rule string, s *types.MaprSecret, format string, args ...any) Violation {
+	return Violation{
+		Rule:      rule,
+		Namespace: s.GetSecretNamespace(),
+		Name:      s.GetSecretName(),
+		Key:       s.GetKey(),
+		Message:   fmt.Sprintf(format, args...),
+	}
+}
+
+// formatDuration formats the duration using days and weeks, rounded to seconds
+func formatDuration(d time.Duration) string {
+	return str2duration.String(d.Round(time.Second))
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package policy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/policy"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+type ticketSpec struct {
+	namespace string
+	name      string
+	cluster   string
+	user      string
+	uid       uint32
+	gids      []uint32
+	created   time.Time
+	expiry    time.Time
+}
+
+func TestChecker_Check(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	secrets := []types.MaprSecret{
+		newMaprSecret(t, ticketSpec{namespace: "team-x", name: "root", cluster: "x.mapr.com", user: "root", uid: 0, created: now.Add(-time.Hour), expiry: now.Add(30 * 24 * time.Hour)}),
+		newMaprSecret(t, ticketSpec{namespace: "team-x", name: "long", cluster: "x.mapr.com", user: "user_x", uid: 1000, gids: []uint32{1000, 0}, created: now.Add(-time.Hour), expiry: now.Add(365 * 24 * time.Hour)}),
+		newMaprSecret(t, ticketSpec{namespace: "team-y", name: "wrong-namespace", cluster: "x.mapr.com", user: "user_x", uid: 1000, created: now.Add(-time.Hour), expiry: now.Add(24 * time.Hour)}),
+		newMaprSecret(t, ticketSpec{namespace: "team-y", name: "no-expiry", cluster: "y.mapr.com", user: "user_y", uid: 1001, created: now.Add(-time.Hour)}),
+		{
+			Secret:     &types.Secret{ObjectMeta: metaV1.ObjectMeta{Namespace: "team-y", Name: "broken"}},
+			Key:        ticket.SecretMaprTicketKey,
+			ParseError: assert.AnError,
+		},
+	}
+
+	client := fake.NewSimpleClientset(
+		&coreV1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "team-x", Labels: map[string]string{"team": "x"}}},
+		&coreV1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "team-y", Labels: map[string]string{"team": "y"}}},
+	)
+
+	tests := []struct {
+		name     string
+		policy   string
+		expected []Violation
+	}{
+		{
+			name: "root tickets",
+			policy: `
+rules:
+  - name: no-root
+    deniedUIDs: [0]
+  - name: no-root-group
+    deniedGIDs: [0]
+`,
+			expected: []Violation{
+				{Rule: "no-root", Namespace: "team-x", Name: "root", Key: ticket.SecretMaprTicketKey, Message: "ticket is issued for UID 0, which is denied"},
+				{Rule: "no-root-group", Namespace: "team-x", Name: "long", Key: ticket.SecretMaprTicketKey, Message: "ticket is issued for GID 0, which is denied"},
+			},
+		},
+		{
+			name: "ticket span and expiry",
+			policy: `
+rules:
+  - name: max-span
+    maxLifetime: 90d
+  - name: expiry
+    requireExpiry: true
+`,
+			expected: []Violation{
+				{Rule: "max-span", Namespace: "team-x", Name: "long", Key: ticket.SecretMaprTicketKey, Message: "ticket is valid for 52w1d1h, more than the maximum of 12w6d"},
+				{Rule: "expiry", Namespace: "team-y", Name: "no-expiry", Key: ticket.SecretMaprTicketKey, Message: "ticket has no expiration time"},
+			},
+		},
+		{
+			name: "cluster only in labeled namespaces",
+			policy: `
+rules:
+  - name: cluster-x-in-team-x
+    match:
+      clusters: [x.mapr.com]
+    namespaceSelector:
+      matchLabels:
+        team: x
+`,
+			expected: []Violation{
+				{Rule: "cluster-x-in-team-x", Namespace: "team-y", Name: "wrong-namespace", Key: ticket.SecretMaprTicketKey, Message: "namespace team-y does not match the selector team=x"},
+			},
+		},
+		{
+			name: "allowed users and clusters of a namespace",
+			policy: `
+rules:
+  - name: team-y
+    match:
+      namespaces: [team-y]
+    allowedUsers: [user_y]
+    allowedClusters: [y.mapr.com]
+`,
+			expected: []Violation{
+				{Rule: "team-y", Namespace: "team-y", Name: "wrong-namespace", Key: ticket.SecretMaprTicketKey, Message: `ticket is issued for user "user_x", which is not allowed`},
+				{Rule: "team-y", Namespace: "team-y", Name: "wrong-namespace", Key: ticket.SecretMaprTicketKey, Message: `ticket is issued for cluster "x.mapr.com", which is not allowed`},
+			},
+		},
+		{
+			name: "min validity",
+			policy: `
+rules:
+  - name: valid-for-a-week
+    match:
+      users: [user_x]
+    minValidity: 7d
+`,
+			expected: []Violation{
+				{Rule: "valid-for-a-week", Namespace: "team-y", Name: "wrong-namespace", Key: ticket.SecretMaprTicketKey, Message: "ticket expires at " + time.Unix(now.Add(24*time.Hour).Unix(), 0).UTC().Format(ticket.DefaultTimeFormat) + ", less than 1w from now"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			policy, err := Parse([]byte(test.policy))
+			assert.NoError(t, err)
+
+			violations, err := NewChecker(client, policy).Check(secrets)
+			assert.NoError(t, err)
+
+			// the invalid ticket is reported regardless of the rules of the policy
+			expected := append(test.expected, Violation{
+				Rule:      RuleInvalidTicket,
+				Namespace: "team-y",
+				Name:      "broken",
+				Key:       ticket.SecretMaprTicketKey,
+				Message:   "ticket can't be parsed: " + assert.AnError.Error(),
+			})
+
+			assert.ElementsMatch(t, expected, violations)
+		})
+	}
+}
+
+func newMaprSecret(t *testing.T, spec ticketSpec) types.MaprSecret {
+	t.Helper()
+
+	maprTicket := ticket.NewMaprTicket()
+	maprTicket.Cluster = spec.cluster
+	maprTicket.UserCreds.UserName = ptr.To(spec.user)
+	maprTicket.UserCreds.Uid = ptr.To(spec.uid)
+	maprTicket.UserCreds.Gids = spec.gids
+	maprTicket.TicketAndKey.CreationTimeSec = ptr.To(uint64(spec.created.Unix()))
+
+	if !spec.expiry.IsZero() {
+		maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(spec.expiry.Unix()))
+	}
+
+	secret, err := ticket.NewSecret(spec.namespace, spec.name, maprTicket)
+	assert.NoError(t, err)
+
+	s := types.NewMaprSecret((*types.Secret)(secret), ticket.SecretMaprTicketKey)
+	s.Key = ticket.SecretMaprTicketKey
+
+	return *s
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package policy
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+var (
+	tableColumnDefinitions = []metaV1.TableColumnDefinition{
+		{
+			Name:        "Rule",
+			Type:        "string",
+			Description: "Name of the violated rule",
+			Priority:    0,
+		},
+		{
+			Name:        "Namespace",
+			Type:        "string",
+			Description: "Namespace of the secret",
+			Priority:    0,
+		},
+		{
+			Name:        "Name",
+			Type:        "string",
+			Format:      "name",
+			Description: "Name of the secret",
+			Priority:    0,
+		},
+		{
+			Name:        "Key",
+			Type:        "string",
+			Description: "Data key of the secret containing the MapR ticket",
+			Priority:    0,
+		},
+		{
+			Name:        "Message",
+			Type:        "string",
+			Description: "Description of the violation",
+			Priority:    0,
+		},
+	}
+)
+
+// Print prints the violations to the given output stream in a tabular format known by kubectl.
+func Print(cmd *cobra.Command, violations []Violation) error {
+	table := &metaV1.Table{
+		ColumnDefinitions: tableColumnDefinitions,
+		Rows:              generateRows(violations),
+	}
+
+	printer := printers.NewTablePrinter(printers.PrintOptions{})
+
+	return printer.PrintObj(table, cmd.OutOrStdout())
+}
+
+// PrintJSON prints the violations as a JSON array
+func PrintJSON(w io.Writer, violations []Violation) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(violations)
+}
+
+// generateRows generates the table rows for the given violations.
+func generateRows(violations []Violation) []metaV1.TableRow {
+	rows := make([]metaV1.TableRow, 0, len(violations))
+
+	for _, v := range violations {
+		rows = append(rows, metaV1.TableRow{
+			Cells: []any{
+				v.Rule,
+				v.Namespace,
+				v.Name,
+				v.Key,
+				v.Message,
+			},
+		})
+	}
+
+	return rows
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package policy implements declarative compliance rules for MapR tickets stored in secrets.
+//
+// A policy is a YAML document containing a list of rules. Each rule may be limited to tickets of
+// certain clusters, users or namespaces using match and consists of one or more constraints on the
+// user credentials, cluster, creation and expiration time of the tickets or the labels of the
+// namespaces they are stored in. A ticket violates a rule if it matches the rule and fails any of
+// its constraints, for example:
+//
+//	rules:
+//	  - name: no-root-tickets
+//	    deniedUIDs: [0]
+//	  - name: max-ticket-span
+//	    maxLifetime: 90d
+//	  - name: cluster-x-only-in-team-x
+//	    match:
+//	      clusters: [x.mapr.com]
+//	    namespaceSelector:
+//	      matchLabels:
+//	        team: x
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/xhit/go-str2duration/v2"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// Policy is a set of rules MapR tickets have to comply with
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule is a single compliance rule. All constraints that are set have to be fulfilled by the
+// tickets the rule matches.
+type Rule struct {
+	// Name identifies the rule in the reported violations
+	Name string `json:"name"`
+
+	// Description optionally describes the intent of the rule
+	Description string `json:"description,omitempty"`
+
+	// Match limits the rule to certain tickets, the rule applies to all tickets if unset
+	Match *Match `json:"match,omitempty"`
+
+	// AllowedUIDs are the only UIDs tickets may be issued for
+	AllowedUIDs []uint32 `json:"allowedUIDs,omitempty"`
+
+	// DeniedUIDs are UIDs tickets must not be issued for, e.g. 0 for root
+	DeniedUIDs []uint32 `json:"deniedUIDs,omitempty"`
+
+	// DeniedGIDs are GIDs tickets must not be issued for
+	DeniedGIDs []uint32 `json:"deniedGIDs,omitempty"`
+
+	// AllowedUsers are the only user names tickets may be issued for
+	AllowedUsers []string `json:"allowedUsers,omitempty"`
+
+	// DeniedUsers are user names tickets must not be issued for
+	DeniedUsers []string `json:"deniedUsers,omitempty"`
+
+	// AllowedClusters are the only clusters tickets may be issued for
+	AllowedClusters []string `json:"allowedClusters,omitempty"`
+
+	// RequireExpiry rejects tickets without an expiration time
+	RequireExpiry bool `json:"requireExpiry,omitempty"`
+
+	// MaxLifetime is the maximum duration between the creation and expiration of tickets
+	MaxLifetime *Duration `json:"maxLifetime,omitempty"`
+
+	// MaxAge is the maximum duration since the creation of tickets
+	MaxAge *Duration `json:"maxAge,omitempty"`
+
+	// MinValidity is the minimum duration tickets have to be valid for from now
+	MinValidity *Duration `json:"minValidity,omitempty"`
+
+	// NamespaceSelector selects the namespaces tickets may be stored in by their labels
+	NamespaceSelector *metaV1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// namespaceSelector is the parsed NamespaceSelector
+	namespaceSelector labels.Selector
+}
+
+// Match limits a rule to certain tickets. All fields that are set have to match.
+type Match struct {
+	// Clusters the ticket has to be issued for
+	Clusters []string `json:"clusters,omitempty"`
+
+	// Users the ticket has to be issued for
+	Users []string `json:"users,omitempty"`
+
+	// Namespaces the ticket has to be stored in
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// Duration is a time.Duration that is read from strings like "90d" or "12h", additionally
+// supporting days and weeks
+type Duration time.Duration
+
+// UnmarshalJSON implements the json.Unmarshaler interface for Duration
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"90d\": %w", err)
+	}
+
+	v, err := str2duration.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for Duration
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(str2duration.String(time.Duration(d)))
+}
+
+// Duration returns the underlying time.Duration value
+func (d *Duration) Duration() time.Duration {
+	return time.Duration(*d)
+}
+
+// Load reads and parses the policy from the given file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses and validates the policy from the given YAML document. Unknown fields are
+// rejected, so misspelled constraints are not silently ignored.
+func Parse(data []byte) (*Policy, error) {
+	policy := &Policy{}
+
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// validate ensures that the rules of the policy are named uniquely and have constraints, and
+// parses their namespace selectors
+func (p *Policy) validate() error {
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("policy does not contain any rules")
+	}
+
+	names := make(map[string]bool, len(p.Rules))
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+
+		if names[rule.Name] {
+			return fmt.Errorf("rule %q is defined more than once", rule.Name)
+		}
+
+		names[rule.Name] = true
+
+		if !rule.hasConstraints() {
+			return fmt.Errorf("rule %q has no constraints", rule.Name)
+		}
+
+		if rule.NamespaceSelector != nil {
+			selector, err := metaV1.LabelSelectorAsSelector(rule.NamespaceSelector)
+			if err != nil {
+				return fmt.Errorf("rule %q has an invalid namespace selector: %w", rule.Name, err)
+			}
+
+			rule.namespaceSelector = selector
+		}
+	}
+
+	return nil
+}
+
+// hasConstraints returns true if at least one constraint of the rule is set
+func (r *Rule) hasConstraints() bool {
+	return len(r.AllowedUIDs) > 0 ||
+		len(r.DeniedUIDs) > 0 ||
+		len(r.DeniedGIDs) > 0 ||
+		len(r.AllowedUsers) > 0 ||
+		len(r.DeniedUsers) > 0 ||
+		len(r.AllowedClusters) > 0 ||
+		r.RequireExpiry ||
+		r.MaxLifetime != nil ||
+		r.MaxAge != nil ||
+		r.MinValidity != nil ||
+		r.NamespaceSelector != nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package policy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/policy"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		policy   string
+		expected string
+	}{
+		{
+			name: "valid policy",
+			policy: `
+rules:
+  - name: no-root-tickets
+    description: Tickets must not be issued for root
+    deniedUIDs: [0]
+  - name: max-ticket-span
+    maxLifetime: 90d
+  - name: cluster-x-only-in-team-x
+    match:
+      clusters: [x.mapr.com]
+    namespaceSelector:
+      matchLabels:
+        team: x
+`,
+		},
+		{
+			name:     "no rules",
+			policy:   `rules: []`,
+			expected: "invalid policy: policy does not contain any rules",
+		},
+		{
+			name: "unknown field",
+			policy: `
+rules:
+  - name: no-root-tickets
+    deniedUID: [0]
+`,
+			expected: `failed to parse policy: error unmarshaling JSON: while decoding JSON: json: unknown field "deniedUID"`,
+		},
+		{
+			name: "missing name",
+			policy: `
+rules:
+  - requireExpiry: true
+`,
+			expected: "invalid policy: rule 1 has no name",
+		},
+		{
+			name: "duplicate name",
+			policy: `
+rules:
+  - name: expiry
+    requireExpiry: true
+  - name: expiry
+    maxLifetime: 90d
+`,
+			expected: `invalid policy: rule "expiry" is defined more than once`,
+		},
+		{
+			name: "no constraints",
+			policy: `
+rules:
+  - name: empty
+    match:
+      clusters: [x.mapr.com]
+`,
+			expected: `invalid policy: rule "empty" has no constraints`,
+		},
+		{
+			name: "invalid duration",
+			policy: `
+rules:
+  - name: max-ticket-span
+    maxLifetime: 90 days
+`,
+			expected: `failed to parse policy: error unmarshaling JSON: while decoding JSON: time: unknown unit " days" in duration "90 days"`,
+		},
+		{
+			name: "invalid namespace selector",
+			policy: `
+rules:
+  - name: selector
+    namespaceSelector:
+      matchExpressions:
+        - key: team
+          operator: Unknown
+`,
+			expected: `invalid policy: rule "selector" has an invalid namespace selector: "Unknown" is not a valid label selector operator`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			policy, err := Parse([]byte(test.policy))
+
+			if test.expected != "" {
+				assert.EqualError(t, err, test.expected)
+				assert.Nil(t, policy)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, policy.Rules, 3)
+			assert.Equal(t, []uint32{0}, policy.Rules[0].DeniedUIDs)
+			assert.Equal(t, 90*24*time.Hour, policy.Rules[1].MaxLifetime.Duration())
+			assert.Equal(t, []string{"x.mapr.com"}, policy.Rules[2].Match.Clusters)
+		})
+	}
+}
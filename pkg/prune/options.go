@@ -0,0 +1,34 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package prune
+
+import "time"
+
+// Option is a function that can be used to configure the pruner.
+type Option func(*Pruner)
+
+// WithOnlyExpired configures the pruner to only plan secrets whose tickets have all expired
+func WithOnlyExpired() Option {
+	return func(p *Pruner) {
+		p.onlyExpired = true
+	}
+}
+
+// WithOlderThan configures the pruner to only plan secrets that were created more than the given
+// duration ago
+func WithOlderThan(olderThan time.Duration) Option {
+	return func(p *Pruner) {
+		p.olderThan = olderThan
+	}
+}
+
+// WithDryRun configures the pruner to send the deletions with the given dry run option, e.g. to
+// perform a server side dry run
+func WithDryRun(dryRun []string) Option {
+	return func(p *Pruner) {
+		p.dryRun = dryRun
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package prune
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+var (
+	tableColumnDefinitions = []metaV1.TableColumnDefinition{
+		{
+			Name:        "Namespace",
+			Type:        "string",
+			Description: "Namespace of the secret",
+			Priority:    0,
+		},
+		{
+			Name:        "Name",
+			Type:        "string",
+			Format:      "name",
+			Description: "Name of the secret",
+			Priority:    0,
+		},
+		{
+			Name:        "Keys",
+			Type:        "string",
+			Description: "Data keys of the secret containing MapR tickets",
+			Priority:    0,
+		},
+		{
+			Name:        "Status",
+			Type:        "string",
+			Description: "Status of the tickets",
+			Priority:    0,
+		},
+		{
+			Name:        "Age",
+			Type:        "string",
+			Format:      "date-time",
+			Description: "Creation time of the secret",
+			Priority:    0,
+		},
+	}
+)
+
+// Print prints the secrets planned to be deleted to the given output stream in a tabular format
+// known by kubectl.
+func Print(cmd *cobra.Command, candidates []Candidate) error {
+	table := &metaV1.Table{
+		ColumnDefinitions: tableColumnDefinitions,
+		Rows:              generateRows(candidates),
+	}
+
+	printer := printers.NewTablePrinter(printers.PrintOptions{})
+
+	return printer.PrintObj(table, cmd.OutOrStdout())
+}
+
+// generateRows generates the rows for the given candidates.
+func generateRows(candidates []Candidate) []metaV1.TableRow {
+	rows := make([]metaV1.TableRow, 0, len(candidates))
+
+	for i := range candidates {
+		rows = append(rows, *generateRow(&candidates[i]))
+	}
+
+	return rows
+}
+
+// generateRow generates a row for the given candidate, joining the keys and statuses of secrets
+// holding several tickets.
+func generateRow(c *Candidate) *metaV1.TableRow {
+	keys := make([]string, 0, len(c.Tickets))
+	statuses := make([]string, 0, len(c.Tickets))
+
+	for i := range c.Tickets {
+		keys = append(keys, c.Tickets[i].GetKey())
+		statuses = append(statuses, c.Tickets[i].GetStatusString())
+	}
+
+	return &metaV1.TableRow{
+		Object: runtime.RawExtension{
+			Object: c.Secret,
+		},
+		Cells: []any{
+			c.Secret.Namespace,
+			c.Secret.Name,
+			strings.Join(keys, ","),
+			strings.Join(statuses, ", "),
+			util.ShortHumanDurationUntilNow(c.Secret.CreationTimestamp.Time),
+		},
+	}
+}
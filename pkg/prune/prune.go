@@ -0,0 +1,295 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package prune implements finding and deleting secrets containing MapR tickets that are not used
+// by any persistent volume, inline volume of a pod or StorageClass. Secrets referenced by inline
+// volumes in the pod templates of workloads are kept as well, as workloads scaled to zero or
+// CronJobs between runs have no pods using them.
+//
+// Pruning is split into planning and deleting, so the plan can be reviewed first. Before deleting,
+// a backup manifest of the planned secrets can be written, which allows restoring them using
+// kubectl apply.
+package prune
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// secretLister is the interface that a secret lister must implement.
+type secretLister interface {
+	List() ([]types.MaprSecret, error)
+}
+
+// Candidate is a secret that is planned to be deleted, together with the tickets it contains
+type Candidate struct {
+	Secret  *coreV1.Secret
+	Tickets []types.MaprSecret
+}
+
+// Pruner is the struct that is used to plan and delete unused secrets containing MapR tickets.
+type Pruner struct {
+	client       kubernetes.Interface
+	secretLister secretLister
+
+	onlyExpired bool
+	olderThan   time.Duration
+	dryRun      []string
+}
+
+// NewPruner creates a new pruner. It requires a Kubernetes client to delete the secrets and a
+// secret lister that determines which secrets are in use. It also accepts a list of options that
+// can be used to configure the pruner.
+func NewPruner(client kubernetes.Interface, secretLister secretLister, opts ...Option) *Pruner {
+	p := &Pruner{
+		client:       client,
+		secretLister: secretLister,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Plan returns the secrets that are not used by any persistent volume, inline volume, pod template
+// of a workload or StorageClass and match the configured filters, in the order returned by the
+// secret lister. A secret holding several tickets is only planned if none of them is in use. No
+// secrets are planned if the secrets, the volumes or the workloads using them could not be listed,
+// as their usage is unknown.
+func (p *Pruner) Plan() ([]Candidate, error) {
+	secrets, err := p.secretLister.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine which secrets are in use, not pruning any secrets: %w", err)
+	}
+
+	// group the tickets by secret, keeping the order of the lister
+	var candidates []*Candidate
+
+	bySecret := make(map[string]*Candidate)
+
+	for i := range secrets {
+		s := &secrets[i]
+		id := s.GetSecretNamespace() + "/" + s.GetSecretName()
+
+		candidate, ok := bySecret[id]
+		if !ok {
+			candidate = &Candidate{Secret: (*coreV1.Secret)(s.Secret)}
+			bySecret[id] = candidate
+			candidates = append(candidates, candidate)
+		}
+
+		candidate.Tickets = append(candidate.Tickets, *s)
+	}
+
+	planned := []Candidate{}
+
+	for _, candidate := range candidates {
+		if p.isPrunable(candidate) {
+			planned = append(planned, *candidate)
+		}
+	}
+
+	if len(planned) == 0 {
+		return planned, nil
+	}
+
+	// keep the secrets referenced by workloads without pods
+	referenced, err := p.templateReferences(planned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine which secrets are used by workloads, not pruning any secrets: %w", err)
+	}
+
+	unreferenced := []Candidate{}
+
+	for _, candidate := range planned {
+		if !referenced[candidate.Secret.Namespace+"/"+candidate.Secret.Name] {
+			unreferenced = append(unreferenced, candidate)
+		}
+	}
+
+	return unreferenced, nil
+}
+
+// templateReferences returns the secrets referenced by MapR inline volumes in the pod templates of
+// the Deployments, StatefulSets, DaemonSets, ReplicaSets, Jobs and CronJobs, as namespace/name.
+// Only the namespace of the candidates is searched if they all share one, otherwise the workloads
+// of all namespaces are listed.
+func (p *Pruner) templateReferences(candidates []Candidate) (map[string]bool, error) {
+	namespace := candidates[0].Secret.Namespace
+	for i := range candidates {
+		if candidates[i].Secret.Namespace != namespace {
+			namespace = util.NamespaceAll
+			break
+		}
+	}
+
+	referenced := make(map[string]bool)
+
+	addTemplate := func(namespace string, spec coreV1.PodSpec) {
+		pod := &types.Pod{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: namespace},
+			Spec:       spec,
+		}
+
+		for _, volume := range pod.GetMaprInlineVolumes() {
+			referenced[volume.GetSecretNamespace()+"/"+volume.GetSecretName()] = true
+		}
+	}
+
+	ctx, opts := context.TODO(), metaV1.ListOptions{}
+
+	deployments, err := p.client.AppsV1().Deployments(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range deployments.Items {
+		addTemplate(deployments.Items[i].Namespace, deployments.Items[i].Spec.Template.Spec)
+	}
+
+	statefulSets, err := p.client.AppsV1().StatefulSets(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range statefulSets.Items {
+		addTemplate(statefulSets.Items[i].Namespace, statefulSets.Items[i].Spec.Template.Spec)
+	}
+
+	daemonSets, err := p.client.AppsV1().DaemonSets(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range daemonSets.Items {
+		addTemplate(daemonSets.Items[i].Namespace, daemonSets.Items[i].Spec.Template.Spec)
+	}
+
+	replicaSets, err := p.client.AppsV1().ReplicaSets(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range replicaSets.Items {
+		addTemplate(replicaSets.Items[i].Namespace, replicaSets.Items[i].Spec.Template.Spec)
+	}
+
+	jobs, err := p.client.BatchV1().Jobs(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range jobs.Items {
+		addTemplate(jobs.Items[i].Namespace, jobs.Items[i].Spec.Template.Spec)
+	}
+
+	cronJobs, err := p.client.BatchV1().CronJobs(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range cronJobs.Items {
+		addTemplate(cronJobs.Items[i].Namespace, cronJobs.Items[i].Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	return referenced, nil
+}
+
+// isPrunable returns true if none of the tickets of the candidate is in use and the candidate
+// matches the configured filters
+func (p *Pruner) isPrunable(c *Candidate) bool {
+	if p.olderThan > 0 && time.Since(c.Secret.CreationTimestamp.Time) < p.olderThan {
+		return false
+	}
+
+	for i := range c.Tickets {
+		if c.Tickets[i].IsInUse() {
+			return false
+		}
+
+		if p.onlyExpired && !c.Tickets[i].IsExpired() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Delete deletes the secret of the candidate. The deletion is only performed if the secret was not
+// changed since it was planned, so a secret that started to be used in the meantime is not deleted
+// based on an outdated plan.
+func (p *Pruner) Delete(c *Candidate) error {
+	uid, resourceVersion := c.Secret.UID, c.Secret.ResourceVersion
+
+	opts := metaV1.DeleteOptions{
+		DryRun: p.dryRun,
+	}
+
+	if uid != "" || resourceVersion != "" {
+		opts.Preconditions = &metaV1.Preconditions{
+			UID:             &uid,
+			ResourceVersion: &resourceVersion,
+		}
+	}
+
+	return p.client.CoreV1().Secrets(c.Secret.Namespace).Delete(context.TODO(), c.Secret.Name, opts)
+}
+
+// WriteBackup writes a manifest of the secrets of the candidates to the given writer, as a List
+// that can be restored using kubectl apply. Server populated fields like the UID and resource
+// version are removed.
+func WriteBackup(w io.Writer, candidates []Candidate) error {
+	list := &coreV1.List{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "List",
+		},
+	}
+
+	for i := range candidates {
+		secret := candidates[i].Secret.DeepCopy()
+
+		secret.TypeMeta = metaV1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		}
+		secret.ObjectMeta = metaV1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   secret.Namespace,
+			Labels:      secret.Labels,
+			Annotations: secret.Annotations,
+		}
+
+		raw, err := json.Marshal(secret)
+		if err != nil {
+			return err
+		}
+
+		list.Items = append(list.Items, runtime.RawExtension{Raw: raw})
+	}
+
+	out, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+
+	return err
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package prune_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/pod"
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/prune"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/storageclass"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
+
+	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientTesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+type fakeSecretLister []types.MaprSecret
+
+func (l fakeSecretLister) List() ([]types.MaprSecret, error) {
+	return l, nil
+}
+
+func TestPruner_Plan(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	secrets := fakeSecretLister{
+		newMaprSecret(t, "team-a", "expired-old", ticket.SecretMaprTicketKey, now.Add(-time.Hour), now.Add(-30*24*time.Hour), 0),
+		newMaprSecret(t, "team-a", "expired-new", ticket.SecretMaprTicketKey, now.Add(-time.Hour), now.Add(-time.Hour), 0),
+		newMaprSecret(t, "team-a", "valid-old", ticket.SecretMaprTicketKey, now.Add(time.Hour), now.Add(-30*24*time.Hour), 0),
+		newMaprSecret(t, "team-a", "in-use", ticket.SecretMaprTicketKey, now.Add(-time.Hour), now.Add(-30*24*time.Hour), 1),
+		newMaprSecret(t, "team-b", "partially-used", "first", now.Add(-time.Hour), now.Add(-30*24*time.Hour), 0),
+		newMaprSecret(t, "team-b", "partially-used", "second", now.Add(-time.Hour), now.Add(-30*24*time.Hour), 1),
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name:     "unused secrets",
+			expected: []string{"team-a/expired-old", "team-a/expired-new", "team-a/valid-old"},
+		},
+		{
+			name:     "only expired",
+			opts:     []Option{WithOnlyExpired()},
+			expected: []string{"team-a/expired-old", "team-a/expired-new"},
+		},
+		{
+			name:     "older than",
+			opts:     []Option{WithOlderThan(7 * 24 * time.Hour)},
+			expected: []string{"team-a/expired-old", "team-a/valid-old"},
+		},
+		{
+			name:     "only expired and older than",
+			opts:     []Option{WithOnlyExpired(), WithOlderThan(7 * 24 * time.Hour)},
+			expected: []string{"team-a/expired-old"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pruner := NewPruner(fake.NewSimpleClientset(), secrets, test.opts...)
+
+			candidates, err := pruner.Plan()
+			assert.NoError(t, err)
+
+			got := make([]string, 0, len(candidates))
+			for _, c := range candidates {
+				got = append(got, c.Secret.Namespace+"/"+c.Secret.Name)
+			}
+
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestPruner_PlanUsageUnknown(t *testing.T) {
+	t.Parallel()
+
+	s := newMaprSecret(t, "team-a", "mounted", ticket.SecretMaprTicketKey, time.Now().Add(time.Hour), time.Now(), 0)
+	client := fake.NewSimpleClientset((*coreV1.Secret)(s.Secret))
+
+	// listing pods cluster-wide is forbidden, so inline volumes using the secret can't be found
+	client.PrependReactor("list", "pods", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apiErrors.NewForbidden(coreV1.Resource("pods"), "", errors.New("access denied"))
+	})
+
+	lister := secret.NewLister(client, "team-a",
+		secret.WithShowInUse(),
+		secret.WithVolumeLister(volume.NewLister(client, util.SecretAll, util.NamespaceAll)),
+		secret.WithInlineVolumeLister(pod.NewInlineVolumeLister(client, util.NamespaceAll)),
+		secret.WithStorageClassLister(storageclass.NewLister(client)),
+	)
+
+	candidates, err := NewPruner(client, lister).Plan()
+	assert.True(t, apiErrors.IsForbidden(err))
+	assert.Empty(t, candidates)
+}
+
+func TestPruner_PlanWorkloadTemplates(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	secrets := fakeSecretLister{
+		newMaprSecret(t, "team-a", "unused", ticket.SecretMaprTicketKey, now.Add(time.Hour), now, 0),
+		newMaprSecret(t, "team-a", "scaled-down", ticket.SecretMaprTicketKey, now.Add(time.Hour), now, 0),
+		newMaprSecret(t, "team-a", "between-runs", ticket.SecretMaprTicketKey, now.Add(time.Hour), now, 0),
+	}
+
+	podSpec := func(secretName string) coreV1.PodSpec {
+		return coreV1.PodSpec{
+			Volumes: []coreV1.Volume{
+				{
+					Name: "data",
+					VolumeSource: coreV1.VolumeSource{
+						CSI: &coreV1.CSIVolumeSource{
+							Driver:               types.MaprCSIProvisionerKDF,
+							NodePublishSecretRef: &coreV1.LocalObjectReference{Name: secretName},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	client := fake.NewSimpleClientset(
+		// a Deployment scaled to zero has no pods mounting the secret
+		&appsV1.Deployment{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "team-a", Name: "app"},
+			Spec: appsV1.DeploymentSpec{
+				Replicas: ptr.To(int32(0)),
+				Template: coreV1.PodTemplateSpec{Spec: podSpec("scaled-down")},
+			},
+		},
+		// a CronJob only has pods while a job is running
+		&batchV1.CronJob{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "team-a", Name: "report"},
+			Spec: batchV1.CronJobSpec{
+				JobTemplate: batchV1.JobTemplateSpec{
+					Spec: batchV1.JobSpec{
+						Template: coreV1.PodTemplateSpec{Spec: podSpec("between-runs")},
+					},
+				},
+			},
+		},
+		// secrets are referenced relative to the namespace of the workload
+		&appsV1.Deployment{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "team-b", Name: "app"},
+			Spec: appsV1.DeploymentSpec{
+				Template: coreV1.PodTemplateSpec{Spec: podSpec("unused")},
+			},
+		},
+	)
+
+	candidates, err := NewPruner(client, secrets).Plan()
+	assert.NoError(t, err)
+
+	if assert.Len(t, candidates, 1) {
+		assert.Equal(t, "unused", candidates[0].Secret.Name)
+	}
+}
+
+func TestPruner_PlanWorkloadsUnknown(t *testing.T) {
+	t.Parallel()
+
+	secrets := fakeSecretLister{
+		newMaprSecret(t, "team-a", "unused", ticket.SecretMaprTicketKey, time.Now().Add(time.Hour), time.Now(), 0),
+	}
+
+	// listing Deployments is forbidden, so the secrets used by their pod templates can't be found
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "deployments", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apiErrors.NewForbidden(appsV1.Resource("deployments"), "", errors.New("access denied"))
+	})
+
+	candidates, err := NewPruner(client, secrets).Plan()
+	assert.True(t, apiErrors.IsForbidden(err))
+	assert.Empty(t, candidates)
+}
+
+func TestPruner_Delete(t *testing.T) {
+	t.Parallel()
+
+	s := newMaprSecret(t, "team-a", "stale", ticket.SecretMaprTicketKey, time.Now().Add(-time.Hour), time.Now(), 0)
+	client := fake.NewSimpleClientset((*coreV1.Secret)(s.Secret))
+
+	pruner := NewPruner(client, fakeSecretLister{s})
+
+	candidates, err := pruner.Plan()
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 1)
+
+	assert.NoError(t, pruner.Delete(&candidates[0]))
+
+	_, err = client.CoreV1().Secrets("team-a").Get(context.TODO(), "stale", metaV1.GetOptions{})
+	assert.True(t, apiErrors.IsNotFound(err))
+}
+
+func TestWriteBackup(t *testing.T) {
+	t.Parallel()
+
+	s := newMaprSecret(t, "team-a", "stale", ticket.SecretMaprTicketKey, time.Now().Add(-time.Hour), time.Now(), 0)
+	s.Secret.UID = "1234"
+	s.Secret.ResourceVersion = "42"
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteBackup(&out, []Candidate{{Secret: (*coreV1.Secret)(s.Secret)}}))
+
+	var list struct {
+		Kind  string          `json:"kind"`
+		Items []coreV1.Secret `json:"items"`
+	}
+	assert.NoError(t, yaml.Unmarshal(out.Bytes(), &list))
+
+	assert.Equal(t, "List", list.Kind)
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, "Secret", list.Items[0].Kind)
+	assert.Equal(t, "team-a", list.Items[0].Namespace)
+	assert.Equal(t, "stale", list.Items[0].Name)
+	assert.Empty(t, list.Items[0].UID)
+	assert.Empty(t, list.Items[0].ResourceVersion)
+	assert.Equal(t, s.Secret.Data, list.Items[0].Data)
+}
+
+func newMaprSecret(t *testing.T, namespace, name, key string, expiry, created time.Time, numPVC uint32) types.MaprSecret {
+	t.Helper()
+
+	maprTicket := ticket.NewMaprTicket()
+	maprTicket.Cluster = "demo.mapr.com"
+	maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(expiry.Unix()))
+
+	secret, err := ticket.NewSecretWithKey(namespace, name, key, maprTicket)
+	assert.NoError(t, err)
+
+	secret.CreationTimestamp = metaV1.NewTime(created)
+
+	s := types.NewMaprSecret((*types.Secret)(secret), ticket.SecretMaprTicketKey)
+	s.Key = key
+	s.NumPVC = numPVC
+
+	return *s
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package rotate
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+var (
+	affectedColumnDefinitions = []metaV1.TableColumnDefinition{
+		{
+			Name:        "Kind",
+			Type:        "string",
+			Description: "Kind of the affected object",
+			Priority:    0,
+		},
+		{
+			Name:        "Namespace",
+			Type:        "string",
+			Description: "Namespace of the affected object",
+			Priority:    0,
+		},
+		{
+			Name:        "Name",
+			Type:        "string",
+			Format:      "name",
+			Description: "Name of the affected object",
+			Priority:    0,
+		},
+	}
+)
+
+// ticketField is a single field of a ticket shown in the diff
+type ticketField struct {
+	name  string
+	value string
+}
+
+// PrintDiff prints the differences between the current and the replacement ticket field by field.
+// Unchanged fields are prefixed with a space, changed fields are printed twice, prefixed with "-"
+// for the current and "+" for the new value.
+func PrintDiff(w io.Writer, r *Rotation) error {
+	current := ticketFields(r.Current)
+	replacement := ticketFields(r.Replacement)
+
+	if _, err := fmt.Fprintf(w, "--- %s/%s (current)\n+++ %s/%s (new)\n", r.Secret.Namespace, r.Secret.Name, r.Secret.Namespace, r.Secret.Name); err != nil {
+		return err
+	}
+
+	for i := range current {
+		var err error
+
+		if current[i].value == replacement[i].value {
+			_, err = fmt.Fprintf(w, "  %s: %s\n", current[i].name, current[i].value)
+		} else {
+			_, err = fmt.Fprintf(w, "- %s: %s\n+ %s: %s\n", current[i].name, current[i].value, replacement[i].name, replacement[i].value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PrintAffected prints the persistent volumes, claims and pods affected by the rotation in a human
+// readable table format
+func PrintAffected(w io.Writer, r *Rotation) error {
+	if len(r.Volumes) == 0 && len(r.Claims) == 0 && len(r.Pods) == 0 {
+		_, err := fmt.Fprintf(w, "No persistent volumes, claims or pods are using secret %s/%s\n", r.Secret.Namespace, r.Secret.Name)
+		return err
+	}
+
+	rows := make([]metaV1.TableRow, 0, len(r.Volumes)+len(r.Claims)+len(r.Pods))
+
+	for _, v := range r.Volumes {
+		rows = append(rows, metaV1.TableRow{
+			Cells: []any{"PersistentVolume", "", v.Volume.GetName()},
+		})
+	}
+
+	for _, c := range r.Claims {
+		rows = append(rows, metaV1.TableRow{
+			Cells: []any{"PersistentVolumeClaim", c.Claim.GetNamespace(), c.Claim.GetName()},
+		})
+	}
+
+	for _, p := range r.Pods {
+		rows = append(rows, metaV1.TableRow{
+			Cells: []any{"Pod", p.Pod.GetNamespace(), p.Pod.GetName()},
+		})
+	}
+
+	table := &metaV1.Table{
+		ColumnDefinitions: affectedColumnDefinitions,
+		Rows:              rows,
+	}
+
+	return printers.NewTablePrinter(printers.PrintOptions{}).PrintObj(table, w)
+}
+
+// ticketFields returns the fields of the ticket that are compared in the diff
+func ticketFields(t *ticket.Ticket) []ticketField {
+	return []ticketField{
+		{name: "cluster", value: t.GetCluster()},
+		{name: "user", value: t.GetUser()},
+		{name: "uid", value: fmt.Sprint(t.UserCreds.GetUid())},
+		{name: "gids", value: fmt.Sprint(t.UserCreds.GetGids())},
+		{name: "creationTime", value: t.CreationTime().UTC().Format(ticket.DefaultTimeFormat)},
+		{name: "expirationTime", value: t.ExpirationTime().UTC().Format(ticket.DefaultTimeFormat)},
+		{name: "maxRenewalDuration", value: (time.Duration(t.GetMaxRenewalDurationSec()) * time.Second).String()},
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package rotate implements the replacement of a MapR ticket stored in an existing secret with a
+// renewed ticket. The new ticket is validated against the current one before the secret is
+// updated, and the persistent volumes, claims and pods depending on the secret can be collected to
+// report which workloads are affected by the rotation.
+package rotate
+
+import (
+	"fmt"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/claim"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/pod"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Rotation describes the replacement of the ticket stored in a secret with a new ticket
+type Rotation struct {
+	// Secret is the secret as currently stored in the cluster
+	Secret *coreV1.Secret
+
+	// Key is the data key of the secret the ticket is stored under
+	Key string
+
+	// Updated is a copy of the secret with the ticket replaced and the metadata updated
+	Updated *coreV1.Secret
+
+	// Current is the ticket currently stored in the secret
+	Current *ticket.Ticket
+
+	// Replacement is the new ticket that replaces the current one
+	Replacement *ticket.Ticket
+
+	// Volumes are the persistent volumes using the secret
+	Volumes []types.MaprVolume
+
+	// Claims are the persistent volume claims bound to volumes using the secret
+	Claims []types.MaprVolumeClaim
+
+	// Pods are the pods mounting one of the claims or an inline volume using the secret, with one
+	// entry per pod
+	Pods []types.MaprPod
+}
+
+// NewRotation validates that the replacement ticket can be used in place of the ticket currently
+// stored under the given key of the secret and returns the resulting rotation. The replacement must
+// be for the same cluster and user, must not be expired and must expire later than the current
+// ticket. The replacement is read from the given bytes, which may hold a plain or base64 encoded
+// ticket or a secret manifest storing it under the same key or as its only ticket, and the encoded
+// ticket is stored in the updated secret as is.
+func NewRotation(secret *coreV1.Secret, key string, replacementBytes []byte) (*Rotation, error) {
+	current, err := ticket.NewMaprTicketFromSecretKey(secret, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current ticket of secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	replacement, encoded, err := ticket.DecodeMaprTicket(replacementBytes, key, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse new ticket: %w", err)
+	}
+
+	if err := validateReplacement(current, replacement); err != nil {
+		return nil, err
+	}
+
+	updated := secret.DeepCopy()
+	updated.Data[key] = encoded
+	ticket.SetSecretMetadata(updated, replacement)
+
+	return &Rotation{
+		Secret:      secret,
+		Key:         key,
+		Updated:     updated,
+		Current:     current,
+		Replacement: replacement,
+	}, nil
+}
+
+// CollectAffected collects the persistent volumes using the secret, the claims bound to them and
+// the pods mounting either one of these claims or an inline volume using the secret, as these pods
+// may need to be restarted to pick up the new ticket.
+func (r *Rotation) CollectAffected(client kubernetes.Interface) error {
+	namespace, name := r.Secret.Namespace, r.Secret.Name
+
+	volumes, err := volume.NewLister(client, name, namespace).List()
+	if err != nil {
+		return err
+	}
+
+	claims, err := claim.NewLister(client, util.NamespaceAll).List()
+	if err != nil {
+		return err
+	}
+
+	affectedClaims := make([]types.MaprVolumeClaim, 0, len(claims))
+	for i := range claims {
+		if claims[i].Volume.UsesSecret(namespace, name) {
+			affectedClaims = append(affectedClaims, claims[i])
+		}
+	}
+
+	// only match the pods against the affected claims, the pod lister resolves inline volumes
+	// itself
+	pods, err := pod.NewLister(client, util.NamespaceAll, pod.WithClaimLister(staticClaimLister(affectedClaims))).List()
+	if err != nil {
+		return err
+	}
+
+	affectedPods := make([]types.MaprPod, 0, len(pods))
+	seen := make(map[string]bool, len(pods))
+
+	for i := range pods {
+		key := pods[i].Pod.GetNamespace() + "/" + pods[i].Pod.GetName()
+		if seen[key] || (pods[i].Claim == nil && !pods[i].Inline.UsesSecret(namespace, name)) {
+			continue
+		}
+
+		seen[key] = true
+		affectedPods = append(affectedPods, pods[i])
+	}
+
+	r.Volumes = volumes
+	r.Claims = affectedClaims
+	r.Pods = affectedPods
+
+	return nil
+}
+
+// staticClaimLister is a volume claim lister returning an already listed set of claims
+type staticClaimLister []types.MaprVolumeClaim
+
+func (l staticClaimLister) List() ([]types.MaprVolumeClaim, error) {
+	return l, nil
+}
+
+// validateReplacement returns an error if the replacement ticket can't be used in place of the
+// current ticket
+func validateReplacement(current, replacement *ticket.Ticket) error {
+	if current.GetCluster() != replacement.GetCluster() {
+		return fmt.Errorf("cluster of new ticket %q does not match cluster of current ticket %q", replacement.GetCluster(), current.GetCluster())
+	}
+
+	if current.GetUser() != replacement.GetUser() {
+		return fmt.Errorf("user of new ticket %q does not match user of current ticket %q", replacement.GetUser(), current.GetUser())
+	}
+
+	if replacement.IsExpired() {
+		return fmt.Errorf("new ticket expired at %s", replacement.ExpirationTime().Format(ticket.DefaultTimeFormat))
+	}
+
+	if !replacement.ExpirationTime().After(current.ExpirationTime()) {
+		return fmt.Errorf("new ticket expires at %s, which is not later than the current ticket expiring at %s",
+			replacement.ExpirationTime().Format(ticket.DefaultTimeFormat),
+			current.ExpirationTime().Format(ticket.DefaultTimeFormat))
+	}
+
+	return nil
+}
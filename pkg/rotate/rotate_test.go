@@ -0,0 +1,356 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package rotate_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/rotate"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/mapr-ticket-parser/pkg/parse"
+
+	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientTesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestNewRotation(t *testing.T) {
+	t.Parallel()
+
+	current := newTicket("demo.mapr.com", "mapr", 24*time.Hour)
+
+	tests := []struct {
+		name        string
+		replacement *ticket.Ticket
+		wantErr     string
+	}{
+		{
+			name:        "valid replacement",
+			replacement: newTicket("demo.mapr.com", "mapr", 48*time.Hour),
+		},
+		{
+			name:        "different cluster",
+			replacement: newTicket("other.mapr.com", "mapr", 48*time.Hour),
+			wantErr:     `cluster of new ticket "other.mapr.com" does not match cluster of current ticket "demo.mapr.com"`,
+		},
+		{
+			name:        "different user",
+			replacement: newTicket("demo.mapr.com", "other", 48*time.Hour),
+			wantErr:     `user of new ticket "other" does not match user of current ticket "mapr"`,
+		},
+		{
+			name:        "expired replacement",
+			replacement: newTicket("demo.mapr.com", "mapr", -1*time.Hour),
+			wantErr:     "new ticket expired at",
+		},
+		{
+			name:        "replacement expires earlier",
+			replacement: newTicket("demo.mapr.com", "mapr", 12*time.Hour),
+			wantErr:     "which is not later than the current ticket",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			secret := newSecret(t, "default", "mapr-secret", current)
+			secret.Labels["app"] = "test"
+
+			replacementBytes := encodeTicket(t, test.replacement)
+
+			got, err := NewRotation(secret, ticket.SecretMaprTicketKey, replacementBytes)
+
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			// the original secret must not be modified
+			parsed, err := ticket.NewMaprTicketFromSecret(got.Secret)
+			assert.NoError(t, err)
+			assert.True(t, parse.Equal(current.AsMaprTicket(), parsed.AsMaprTicket()))
+
+			// the updated secret must contain the replacement ticket and keep unrelated labels
+			parsed, err = ticket.NewMaprTicketFromSecret(got.Updated)
+			assert.NoError(t, err)
+			assert.True(t, parse.Equal(test.replacement.AsMaprTicket(), parsed.AsMaprTicket()))
+			assert.Equal(t, replacementBytes, got.Updated.Data[ticket.SecretMaprTicketKey])
+			assert.Equal(t, "test", got.Updated.Labels["app"])
+			assert.Equal(t, test.replacement.ExpirationTime().UTC().Format(ticket.DefaultTimeFormat), got.Updated.Annotations[ticket.AnnotationExpiry])
+		})
+	}
+}
+
+func TestNewRotation_InvalidCurrentTicket(t *testing.T) {
+	t.Parallel()
+
+	secret := &coreV1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "mapr-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			ticket.SecretMaprTicketKey: []byte("invalid ticket"),
+		},
+	}
+
+	_, err := NewRotation(secret, ticket.SecretMaprTicketKey, encodeTicket(t, newTicket("demo.mapr.com", "mapr", time.Hour)))
+
+	assert.ErrorContains(t, err, "failed to parse current ticket of secret default/mapr-secret")
+}
+
+func TestRotation_CollectAffected(t *testing.T) {
+	t.Parallel()
+
+	secret := newSecret(t, "default", "mapr-secret", newTicket("demo.mapr.com", "mapr", 24*time.Hour))
+
+	client := fake.NewSimpleClientset(
+		secret,
+		newVolume("pv-a", "default", "mapr-secret", "claim-a"),
+		newVolume("pv-b", "default", "other-secret", "claim-b"),
+		newVolume("pv-c", "default", "mapr-secret", ""),
+		newClaim("claim-a", "pv-a"),
+		newClaim("claim-b", "pv-b"),
+		newPod("app", "pod-a", withClaim("claim-a"), withClaim("claim-a")),
+		newPod("app", "pod-b", withClaim("claim-b")),
+		newPod("default", "pod-c", withInlineVolume("mapr-secret")),
+		newPod("default", "pod-d", withInlineVolume("other-secret")),
+	)
+
+	rotation, err := NewRotation(secret, ticket.SecretMaprTicketKey, encodeTicket(t, newTicket("demo.mapr.com", "mapr", 48*time.Hour)))
+	assert.NoError(t, err)
+
+	err = rotation.CollectAffected(client)
+	assert.NoError(t, err)
+
+	if assert.Len(t, rotation.Volumes, 2) {
+		assert.Equal(t, "pv-a", rotation.Volumes[0].Volume.GetName())
+		assert.Equal(t, "pv-c", rotation.Volumes[1].Volume.GetName())
+	}
+
+	if assert.Len(t, rotation.Claims, 1) {
+		assert.Equal(t, "claim-a", rotation.Claims[0].Claim.GetName())
+		assert.Equal(t, "pv-a", rotation.Claims[0].Volume.GetName())
+	}
+
+	// pods mounting the claim more than once are only reported once
+	if assert.Len(t, rotation.Pods, 2) {
+		assert.Equal(t, "pod-a", rotation.Pods[0].Pod.GetName())
+		assert.Equal(t, "pod-c", rotation.Pods[1].Pod.GetName())
+	}
+
+	out := &bytes.Buffer{}
+	err = PrintAffected(out, rotation)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "KIND                    NAMESPACE   NAME\nPersistentVolume                    pv-a\nPersistentVolume                    pv-c\nPersistentVolumeClaim   app         claim-a\nPod                     app         pod-a\nPod                     default     pod-c\n", out.String())
+}
+
+func TestRotation_CollectAffected_ClaimsForbidden(t *testing.T) {
+	t.Parallel()
+
+	secret := newSecret(t, "default", "mapr-secret", newTicket("demo.mapr.com", "mapr", 24*time.Hour))
+
+	client := fake.NewSimpleClientset(secret, newVolume("pv-a", "default", "mapr-secret", "claim-a"), newClaim("claim-a", "pv-a"))
+	client.PrependReactor("list", "persistentvolumeclaims", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apiErrors.NewForbidden(coreV1.Resource("persistentvolumeclaims"), "", errors.New("access denied"))
+	})
+
+	rotation, err := NewRotation(secret, ticket.SecretMaprTicketKey, encodeTicket(t, newTicket("demo.mapr.com", "mapr", 48*time.Hour)))
+	assert.NoError(t, err)
+
+	err = rotation.CollectAffected(client)
+	assert.True(t, apiErrors.IsForbidden(err))
+	assert.Empty(t, rotation.Claims)
+	assert.Empty(t, rotation.Pods)
+}
+
+func TestRotation_CollectAffected_Forbidden(t *testing.T) {
+	t.Parallel()
+
+	secret := newSecret(t, "default", "mapr-secret", newTicket("demo.mapr.com", "mapr", 24*time.Hour))
+
+	client := fake.NewSimpleClientset(secret, newVolume("pv-a", "default", "mapr-secret", "claim-a"))
+	client.PrependReactor("list", "persistentvolumes", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apiErrors.NewForbidden(coreV1.Resource("persistentvolumes"), "", errors.New("access denied"))
+	})
+
+	rotation, err := NewRotation(secret, ticket.SecretMaprTicketKey, encodeTicket(t, newTicket("demo.mapr.com", "mapr", 48*time.Hour)))
+	assert.NoError(t, err)
+
+	err = rotation.CollectAffected(client)
+	assert.True(t, apiErrors.IsForbidden(err))
+	assert.Empty(t, rotation.Volumes)
+	assert.Empty(t, rotation.Claims)
+}
+
+func TestPrintDiff(t *testing.T) {
+	t.Parallel()
+
+	current := newTicket("demo.mapr.com", "mapr", 24*time.Hour)
+	replacement := newTicket("demo.mapr.com", "mapr", 48*time.Hour)
+
+	rotation, err := NewRotation(newSecret(t, "default", "mapr-secret", current), ticket.SecretMaprTicketKey, encodeTicket(t, replacement))
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	err = PrintDiff(out, rotation)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "--- default/mapr-secret (current)\n+++ default/mapr-secret (new)\n")
+	assert.Contains(t, out.String(), "  cluster: demo.mapr.com\n")
+	assert.Contains(t, out.String(), "- expirationTime: "+current.ExpirationTime().UTC().Format(ticket.DefaultTimeFormat)+"\n")
+	assert.Contains(t, out.String(), "+ expirationTime: "+replacement.ExpirationTime().UTC().Format(ticket.DefaultTimeFormat)+"\n")
+}
+
+func TestPrintAffected_None(t *testing.T) {
+	t.Parallel()
+
+	rotation := &Rotation{
+		Secret: &coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "mapr-secret",
+				Namespace: "default",
+			},
+		},
+		Volumes: []types.MaprVolume{},
+	}
+
+	out := &bytes.Buffer{}
+	err := PrintAffected(out, rotation)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "No persistent volumes, claims or pods are using secret default/mapr-secret\n", out.String())
+}
+
+func newTicket(cluster, user string, expiresIn time.Duration) *ticket.Ticket {
+	t := ticket.NewMaprTicket()
+	t.Cluster = cluster
+	t.UserCreds.UserName = ptr.To(user)
+	t.TicketAndKey.ExpiryTime = ptr.To(uint64(time.Now().Add(expiresIn).Unix()))
+	t.TicketAndKey.CreationTimeSec = ptr.To(uint64(time.Now().Unix()))
+
+	return t
+}
+
+func encodeTicket(t *testing.T, maprTicket *ticket.Ticket) []byte {
+	t.Helper()
+
+	out, err := parse.Marshal(maprTicket.AsMaprTicket())
+	assert.NoError(t, err)
+
+	return out
+}
+
+func newSecret(t *testing.T, namespace, name string, maprTicket *ticket.Ticket) *coreV1.Secret {
+	t.Helper()
+
+	secret, err := ticket.NewSecret(namespace, name, maprTicket)
+	assert.NoError(t, err)
+
+	return secret
+}
+
+func newVolume(name, secretNamespace, secretName, claimName string) *coreV1.PersistentVolume {
+	pv := &coreV1.PersistentVolume{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+		},
+		Spec: coreV1.PersistentVolumeSpec{
+			PersistentVolumeSource: coreV1.PersistentVolumeSource{
+				CSI: &coreV1.CSIPersistentVolumeSource{
+					Driver: types.MaprCSIProvisionerKDF,
+					NodePublishSecretRef: &coreV1.SecretReference{
+						Namespace: secretNamespace,
+						Name:      secretName,
+					},
+				},
+			},
+		},
+		Status: coreV1.PersistentVolumeStatus{
+			Phase: coreV1.VolumeAvailable,
+		},
+	}
+
+	if claimName != "" {
+		pv.Spec.ClaimRef = &coreV1.ObjectReference{Namespace: "app", Name: claimName}
+		pv.Status.Phase = coreV1.VolumeBound
+	}
+
+	return pv
+}
+
+func newClaim(name, volumeName string) *coreV1.PersistentVolumeClaim {
+	return &coreV1.PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: "app",
+		},
+		Spec: coreV1.PersistentVolumeClaimSpec{
+			VolumeName: volumeName,
+		},
+		Status: coreV1.PersistentVolumeClaimStatus{
+			Phase: coreV1.ClaimBound,
+		},
+	}
+}
+
+type podOption func(*coreV1.Pod)
+
+func withClaim(claimName string) podOption {
+	return func(p *coreV1.Pod) {
+		p.Spec.Volumes = append(p.Spec.Volumes, coreV1.Volume{
+			Name: fmt.Sprintf("volume-%d", len(p.Spec.Volumes)),
+			VolumeSource: coreV1.VolumeSource{
+				PersistentVolumeClaim: &coreV1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			},
+		})
+	}
+}
+
+func withInlineVolume(secretName string) podOption {
+	return func(p *coreV1.Pod) {
+		p.Spec.Volumes = append(p.Spec.Volumes, coreV1.Volume{
+			Name: fmt.Sprintf("volume-%d", len(p.Spec.Volumes)),
+			VolumeSource: coreV1.VolumeSource{
+				CSI: &coreV1.CSIVolumeSource{
+					Driver:               types.MaprCSIProvisionerKDF,
+					NodePublishSecretRef: &coreV1.LocalObjectReference{Name: secretName},
+				},
+			},
+		})
+	}
+}
+
+func newPod(namespace, name string, opts ...podOption) *coreV1.Pod {
+	p := &coreV1.Pod{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
@@ -14,13 +14,22 @@ package secret
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
 	"time"
 
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
 
 	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -28,24 +37,47 @@ type volumeLister interface {
 	List() ([]types.MaprVolume, error)
 }
 
+type inlineVolumeLister interface {
+	List() ([]types.MaprInlineVolume, error)
+}
+
+type storageClassLister interface {
+	List() ([]types.MaprStorageClass, error)
+}
+
 // Lister is the struct that is used to list secrets containing MapR tickets in the cluster.
 type Lister struct {
 	client    kubernetes.Interface
 	namespace string
 
 	volumeLister        volumeLister
+	inlineVolumeLister  inlineVolumeLister
+	storageClassLister  storageClassLister
+	ticketKey           string
+	detectTicketKeys    bool
 	filterOnlyExpired   bool
 	filterOnlyUnexpired bool
+	filterOnlyInvalid   bool
 	filterByMaprCluster *string
 	filterByMaprUser    *string
 	filterByUID         *uint32
 	filterByGID         *uint32
 	filterByInUse       bool
 	filterExpiresBefore time.Duration
+	filterByExpression  *filter.Expression
 	showInUse           bool
 	sortBy              []SortOption
+	labelSelector       string
+	fieldSelector       string
+	chunkSize           int64
+	labeledOnly         bool
+	parseWorkers        int
 
 	tickets []types.MaprSecret
+
+	// err holds the errors of the collectors, as the tickets can't be reported as unused if the
+	// volumes using them could not be listed
+	err error
 }
 
 // NewLister creates a new secret lister. It requires a Kubernetes client and a namespace
@@ -60,6 +92,8 @@ func NewLister(client kubernetes.Interface, namespace string, opts ...ListerOpti
 		client:              client,
 		namespace:           namespace,
 		sortBy:              DefaultSortBy,
+		ticketKey:           ticket.SecretMaprTicketKey,
+		parseWorkers:        runtime.GOMAXPROCS(0),
 		filterOnlyExpired:   defaultFilterOnlyExpired,
 		filterOnlyUnexpired: defaultFilterOnlyUnexpired,
 	}
@@ -74,12 +108,79 @@ func NewLister(client kubernetes.Interface, namespace string, opts ...ListerOpti
 // List returns a list of secrets containing MapR tickets in the cluster, enriched with additional
 // information and filtered according to the specified options.
 func (l *Lister) List() ([]types.MaprSecret, error) {
+	l.err = nil
+
 	if err := l.getSecretsWithTickets(); err != nil {
 		return nil, err
 	}
 
 	// run all filters and sorts
-	l.filterTicketsOnlyExpired().
+	l.applyFilters().
+		Sort()
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return l.tickets, nil
+}
+
+// ListReferenced returns the secrets containing MapR tickets like List, but falls back to getting
+// the given secrets one by one if listing the secrets is forbidden, e.g. for users with only
+// namespace scoped permissions listing the secrets of all namespaces. Referenced secrets that can't
+// be retrieved are left out.
+func (l *Lister) ListReferenced(refs []k8sTypes.NamespacedName) ([]types.MaprSecret, error) {
+	l.err = nil
+
+	if err := l.getSecretsWithTickets(); err != nil {
+		if !apiErrors.IsForbidden(err) {
+			return nil, err
+		}
+
+		slog.Debug("not allowed to list secrets, getting the referenced secrets instead", "namespace", l.namespace, "error", err)
+		l.getReferencedSecrets(refs)
+	}
+
+	// run all filters and sorts
+	l.applyFilters().
+		Sort()
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return l.tickets, nil
+}
+
+// getReferencedSecrets retrieves the given secrets one by one and parses their tickets, skipping
+// secrets that don't exist or can't be read
+func (l *Lister) getReferencedSecrets(refs []k8sTypes.NamespacedName) {
+	l.tickets = nil
+	seen := make(map[k8sTypes.NamespacedName]bool, len(refs))
+
+	for _, ref := range refs {
+		if seen[ref] || (l.namespace != util.NamespaceAll && ref.Namespace != l.namespace) {
+			continue
+		}
+
+		seen[ref] = true
+
+		secret, err := l.client.CoreV1().Secrets(ref.Namespace).Get(context.TODO(), ref.Name, metaV1.GetOptions{})
+		if err != nil {
+			slog.Debug("failed to get referenced secret", "namespace", ref.Namespace, "name", ref.Name, "error", err)
+			continue
+		}
+
+		l.tickets = append(l.tickets, l.parseTicketsFromSecret(secret)...)
+	}
+
+	logInvalidTickets(l.tickets)
+}
+
+// applyFilters runs all filters and collectors on the current list of tickets
+func (l *Lister) applyFilters() *Lister {
+	return l.filterTicketsOnlyInvalid().
+		filterTicketsOnlyExpired().
 		filterTicketsOnlyUnexpired().
 		filterTicketsByMaprCluster().
 		filterTicketsByMaprUser().
@@ -87,63 +188,191 @@ func (l *Lister) List() ([]types.MaprSecret, error) {
 		filterTicketsByGID().
 		filterTicketsExpiresBefore().
 		collectPVsUsingTickets().
+		collectInlineVolumesUsingTickets().
+		collectStorageClassesUsingTickets().
 		filterTicketsInUse().
-		Sort()
+		filterTicketsByExpression()
+}
 
-	return l.tickets, nil
+// listOptions returns the options used to list the secrets, applying the label and field
+// selectors of the Lister
+func (l *Lister) listOptions() metaV1.ListOptions {
+	return metaV1.ListOptions{
+		LabelSelector: l.labelSelector,
+		FieldSelector: l.fieldSelector,
+	}
 }
 
-// getSecretsWithTickets retrieves the list of ticket secrets
+// getSecretsWithTickets retrieves the list of ticket secrets. If only labeled secrets should be
+// listed, only the secrets marked as containing a ticket are retrieved, falling back to retrieving
+// all secrets if there are none.
 func (l *Lister) getSecretsWithTickets() error {
-	secrets, err := l.client.CoreV1().Secrets(l.namespace).List(context.TODO(), metaV1.ListOptions{})
-	if err != nil {
+	l.tickets = nil
+
+	if l.labeledOnly {
+		found, err := l.listSecrets(l.labeledListOptions())
+		if err != nil {
+			return err
+		}
+
+		if found > 0 {
+			logInvalidTickets(l.tickets)
+			return nil
+		}
+
+		slog.Info("found no secrets labeled as containing a MapR ticket, listing all secrets instead", "selector", ticket.TicketSelector)
+	}
+
+	if _, err := l.listSecrets(l.listOptions()); err != nil {
 		return err
 	}
 
-	// convert secrets to items, parse all tickets
-	l.tickets = parseTicketsFromSecrets(secrets.Items)
+	logInvalidTickets(l.tickets)
 
 	return nil
 }
 
-// rejectSecretsWithoutTicket filters secrets to only those that contain a MapR ticket key
-func rejectSecretsWithoutTicket(secrets []coreV1.Secret) []coreV1.Secret {
-	var filtered []coreV1.Secret
+// labeledListOptions returns the options used to list only the secrets marked as containing a
+// ticket, in addition to the label and field selectors of the Lister
+func (l *Lister) labeledListOptions() metaV1.ListOptions {
+	opts := l.listOptions()
 
-	for i := range secrets {
-		secret := secrets[i]
+	if opts.LabelSelector == "" {
+		opts.LabelSelector = ticket.TicketSelector
+	} else {
+		opts.LabelSelector += "," + ticket.TicketSelector
+	}
+
+	return opts
+}
 
-		if ticket.SecretContainsMaprTicket(&secret) {
-			filtered = append(filtered, secret)
+// listSecrets retrieves the secrets using the given list options, parses their tickets and adds
+// them to the tickets of the Lister. It returns the number of secrets retrieved, including those
+// without a ticket. If a chunk size is set, the secrets are listed in chunks of that size and the
+// tickets of each chunk are parsed before the next chunk is retrieved, so secrets without a ticket
+// don't need to be kept in memory.
+func (l *Lister) listSecrets(opts metaV1.ListOptions) (int, error) {
+	opts.Limit = l.chunkSize
+	found := 0
+
+	for {
+		secrets, err := l.client.CoreV1().Secrets(l.namespace).List(context.TODO(), opts)
+		if err != nil {
+			return found, err
 		}
+
+		// convert secrets to items, parse all tickets
+		found += len(secrets.Items)
+		l.tickets = append(l.tickets, l.parseTicketsFromSecrets(secrets.Items)...)
+
+		if secrets.Continue == "" {
+			break
+		}
+
+		opts.Continue = secrets.Continue
 	}
 
-	return filtered
+	return found, nil
 }
 
-// parseTicketsFromSecrets parses secrets to items, ignoring secrets that don't contain a MapR ticket
-func parseTicketsFromSecrets(secrets []coreV1.Secret) []types.MaprSecret {
+// parseTicketsFromSecrets parses secrets to items, ignoring secrets that don't contain a MapR ticket.
+// Secrets whose ticket can't be parsed are kept, with the parse error stored in the item. Secrets
+// holding several tickets result in one item per ticket. The secrets are parsed concurrently by a
+// bounded number of workers, the order of the items matches the order of the secrets.
+func (l *Lister) parseTicketsFromSecrets(secrets []coreV1.Secret) []types.MaprSecret {
+	parsed := make([][]types.MaprSecret, len(secrets))
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < min(l.parseWorkers, len(secrets)); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				parsed[i] = l.parseTicketsFromSecret(&secrets[i])
+			}
+		}()
+	}
+
+	for i := range secrets {
+		indices <- i
+	}
+
+	close(indices)
+	wg.Wait()
+
 	items := make([]types.MaprSecret, 0, len(secrets))
+	for _, secretItems := range parsed {
+		items = append(items, secretItems...)
+	}
+
+	return items
+}
 
-	filtered := rejectSecretsWithoutTicket(secrets)
+// parseTicketsFromSecret parses the tickets stored in the secret, returning one item per ticket
+func (l *Lister) parseTicketsFromSecret(s *coreV1.Secret) []types.MaprSecret {
+	keys := ticket.SecretTicketKeys(s, l.ticketKey, l.detectTicketKeys)
+	if len(keys) == 0 {
+		return nil
+	}
 
-	for i := range filtered {
-		s := filtered[i]
+	// copy the secret, so the items don't keep the whole list of secrets in memory
+	secret := (*types.Secret)(s.DeepCopy())
+	items := make([]types.MaprSecret, 0, len(keys))
 
-		ticket, err := ticket.NewMaprTicketFromSecret(&s)
-		if err != nil {
-			continue
-		}
+	for _, key := range keys {
+		ticket, err := ticket.NewMaprTicketFromSecretKey(s, key)
 
 		items = append(items, types.MaprSecret{
-			Secret: (*types.Secret)(&s),
-			Ticket: ticket,
+			Secret:     secret,
+			Key:        key,
+			Ticket:     ticket,
+			ParseError: err,
 		})
 	}
 
 	return items
 }
 
+// logInvalidTickets logs the secrets whose ticket could not be parsed
+func logInvalidTickets(items []types.MaprSecret) {
+	count := 0
+
+	for _, item := range items {
+		if item.IsInvalid() {
+			count++
+			slog.Debug("failed to parse MapR ticket", "namespace", item.Secret.Namespace, "secret", item.Secret.Name, "key", item.Key, "error", item.ParseError)
+		}
+	}
+
+	if count > 0 {
+		slog.Warn("found secrets with invalid MapR tickets", "count", count)
+	}
+}
+
+// filterTicketsOnlyInvalid filters tickets to only those that could not be parsed
+func (l *Lister) filterTicketsOnlyInvalid() *Lister {
+	// if the filter is not enabled, we can skip this step
+	if !l.filterOnlyInvalid {
+		return l
+	}
+
+	var filtered []types.MaprSecret
+
+	for _, item := range l.tickets {
+		if item.IsInvalid() {
+			filtered = append(filtered, item)
+		}
+	}
+
+	l.tickets = filtered
+
+	return l
+}
+
 // filterTicketsOnlyExpired filters tickets to only those that are expired
 func (l *Lister) filterTicketsOnlyExpired() *Lister {
 	// if the filter is not enabled, we can skip this step
@@ -154,7 +383,7 @@ func (l *Lister) filterTicketsOnlyExpired() *Lister {
 	var filtered []types.MaprSecret
 
 	for _, item := range l.tickets {
-		if item.Ticket.IsExpired() {
+		if item.IsExpired() {
 			filtered = append(filtered, item)
 		}
 	}
@@ -174,7 +403,7 @@ func (l *Lister) filterTicketsOnlyUnexpired() *Lister {
 	var filtered []types.MaprSecret
 
 	for _, item := range l.tickets {
-		if !item.Ticket.IsExpired() {
+		if item.Ticket != nil && !item.Ticket.IsExpired() {
 			filtered = append(filtered, item)
 		}
 	}
@@ -194,7 +423,7 @@ func (l *Lister) filterTicketsByMaprCluster() *Lister {
 	var filtered []types.MaprSecret
 
 	for _, item := range l.tickets {
-		if item.Ticket.Cluster == *l.filterByMaprCluster {
+		if item.GetCluster() == *l.filterByMaprCluster {
 			filtered = append(filtered, item)
 		}
 	}
@@ -214,7 +443,7 @@ func (l *Lister) filterTicketsByMaprUser() *Lister {
 	var filtered []types.MaprSecret
 
 	for _, item := range l.tickets {
-		if item.Ticket.UserCreds.GetUserName() == *l.filterByMaprUser {
+		if item.GetUser() == *l.filterByMaprUser {
 			filtered = append(filtered, item)
 		}
 	}
@@ -234,7 +463,7 @@ func (l *Lister) filterTicketsByUID() *Lister {
 	var filtered []types.MaprSecret
 
 	for _, item := range l.tickets {
-		if *item.Ticket.UserCreds.Uid == *l.filterByUID {
+		if item.Ticket != nil && item.Ticket.UserCreds.GetUid() == *l.filterByUID {
 			filtered = append(filtered, item)
 		}
 	}
@@ -254,6 +483,10 @@ func (l *Lister) filterTicketsByGID() *Lister {
 	var filtered []types.MaprSecret
 
 	for _, item := range l.tickets {
+		if item.Ticket == nil {
+			continue
+		}
+
 		for _, gid := range item.Ticket.UserCreds.Gids {
 			if gid == *l.filterByGID {
 				filtered = append(filtered, item)
@@ -278,7 +511,7 @@ func (l *Lister) filterTicketsExpiresBefore() *Lister {
 	var filtered []types.MaprSecret
 
 	for _, item := range l.tickets {
-		if item.Ticket.ExpiresBefore(l.filterExpiresBefore) {
+		if item.Ticket != nil && item.Ticket.ExpiresBefore(l.filterExpiresBefore) {
 			filtered = append(filtered, item)
 		}
 	}
@@ -288,7 +521,8 @@ func (l *Lister) filterTicketsExpiresBefore() *Lister {
 	return l
 }
 
-// filterTicketsInUse filters tickets to only those that are in use by a persistent volume
+// filterTicketsInUse filters tickets to only those that are in use by a persistent volume, an
+// inline volume or a StorageClass
 func (l *Lister) filterTicketsInUse() *Lister {
 	// if the filter is not enabled, we can skip this step
 	if !l.filterByInUse {
@@ -298,7 +532,7 @@ func (l *Lister) filterTicketsInUse() *Lister {
 	var filtered []types.MaprSecret
 
 	for _, item := range l.tickets {
-		if item.NumPVC > 0 {
+		if item.IsInUse() {
 			filtered = append(filtered, item)
 		}
 	}
@@ -308,6 +542,27 @@ func (l *Lister) filterTicketsInUse() *Lister {
 	return l
 }
 
+// filterTicketsByExpression filters tickets to only those matching the filter expression. It runs
+// after the collectors, so the expression can refer to the number of volumes using the tickets.
+func (l *Lister) filterTicketsByExpression() *Lister {
+	// if the filter is not enabled, we can skip this step
+	if l.filterByExpression == nil {
+		return l
+	}
+
+	var filtered []types.MaprSecret
+
+	for i := range l.tickets {
+		if l.filterByExpression.Matches(filter.SecretActivation(&l.tickets[i])) {
+			filtered = append(filtered, l.tickets[i])
+		}
+	}
+
+	l.tickets = filtered
+
+	return l
+}
+
 // collectPVsUsingTickets enriches the ticket items with the number of PVCs using the ticket
 func (l *Lister) collectPVsUsingTickets() *Lister {
 	// if we don't have a volume lister, we need to skip this step
@@ -323,6 +578,7 @@ func (l *Lister) collectPVsUsingTickets() *Lister {
 	// get all persistent volumes
 	pvs, err := l.volumeLister.List()
 	if err != nil {
+		l.err = errors.Join(l.err, fmt.Errorf("failed to list persistent volumes using tickets: %w", err))
 		return l
 	}
 
@@ -337,3 +593,72 @@ func (l *Lister) collectPVsUsingTickets() *Lister {
 
 	return l
 }
+
+// collectInlineVolumesUsingTickets enriches the ticket items with the number of inline CSI volumes
+// in pod specs using the ticket
+func (l *Lister) collectInlineVolumesUsingTickets() *Lister {
+	// if we don't have an inline volume lister, we need to skip this step
+	if l.inlineVolumeLister == nil {
+		return l
+	}
+
+	// if we don't need to show in use, or filter by in use, we can skip this step
+	if !l.showInUse && !l.filterByInUse {
+		return l
+	}
+
+	// get all inline volumes
+	volumes, err := l.inlineVolumeLister.List()
+	if err != nil {
+		l.err = errors.Join(l.err, fmt.Errorf("failed to list inline volumes using tickets: %w", err))
+		return l
+	}
+
+	// check for each ticket if it is in use by an inline volume
+	for i := range l.tickets {
+		for j := range volumes {
+			if volumes[j].UsesSecret(l.tickets[i].Secret.Namespace, l.tickets[i].Secret.Name) {
+				l.tickets[i].NumInline++
+			}
+		}
+	}
+
+	return l
+}
+
+// collectStorageClassesUsingTickets enriches the ticket items with the number of StorageClasses
+// referencing the ticket in their parameters
+func (l *Lister) collectStorageClassesUsingTickets() *Lister {
+	// if we don't have a StorageClass lister, we need to skip this step
+	if l.storageClassLister == nil {
+		return l
+	}
+
+	// if we don't need to show in use, or filter by in use, we can skip this step
+	if !l.showInUse && !l.filterByInUse {
+		return l
+	}
+
+	// get all StorageClasses
+	storageClasses, err := l.storageClassLister.List()
+	if err != nil {
+		l.err = errors.Join(l.err, fmt.Errorf("failed to list StorageClasses using tickets: %w", err))
+		return l
+	}
+
+	// check for each ticket by how many distinct StorageClasses it is referenced
+	for i := range l.tickets {
+		seen := make(map[string]bool)
+
+		for j := range storageClasses {
+			name := storageClasses[j].StorageClass.GetName()
+
+			if !seen[name] && storageClasses[j].UsesSecret(l.tickets[i].Secret.Namespace, l.tickets[i].Secret.Name) {
+				seen[name] = true
+				l.tickets[i].NumStorageClasses++
+			}
+		}
+	}
+
+	return l
+}
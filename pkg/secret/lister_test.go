@@ -8,20 +8,31 @@ package secret_test
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/pod"
 	. "github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/storageclass"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
 	"github.com/nobbs/mapr-ticket-parser/pkg/parse"
 
 	coreV1 "k8s.io/api/core/v1"
+	storageV1 "k8s.io/api/storage/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
 )
 
 func TestLister_Default(t *testing.T) {
@@ -70,7 +81,9 @@ func TestLister_Default(t *testing.T) {
 				}),
 				namespace: "default",
 			},
-			want:    []expectedSecret{},
+			want: []expectedSecret{
+				newExpectedSecret("default", "test-secret"),
+			},
 			wantErr: false,
 		},
 		{
@@ -725,6 +738,105 @@ func TestLister_WithFilterOnlyUnexpired(t *testing.T) {
 	}
 }
 
+func TestLister_WithFilterOnlyInvalid(t *testing.T) {
+	t.Parallel()
+
+	invalidSecret := func(namespace, name string) *coreV1.Secret {
+		return &coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Data: map[string][]byte{
+				ticket.SecretMaprTicketKey: []byte("invalid ticket data"),
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		fields  listerFields
+		want    []expectedSecret
+		wantErr bool
+	}{
+		{
+			name: "one valid secret with ticket in default namespace",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					secretFromTicketJSON(
+						t,
+						"default",
+						"test-secret-1",
+						[]byte(`{"ticket":{"cluster":"test-cluster"}}`),
+					),
+				),
+				namespace: "default",
+				opts: []ListerOption{
+					WithFilterOnlyInvalid(),
+				},
+			},
+			want:    []expectedSecret{},
+			wantErr: false,
+		},
+		{
+			name: "two secrets in default namespace, one valid, one invalid",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					secretFromTicketJSON(
+						t,
+						"default",
+						"test-secret-1",
+						[]byte(`{"ticket":{"cluster":"test-cluster"}}`),
+					),
+					invalidSecret("default", "invalid-secret-1"),
+				),
+				namespace: "default",
+				opts: []ListerOption{
+					WithFilterOnlyInvalid(),
+				},
+			},
+			want: []expectedSecret{
+				newExpectedSecret("default", "invalid-secret-1"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid secret is not matched by ticket filters",
+			fields: listerFields{
+				client: fake.NewSimpleClientset(
+					invalidSecret("default", "invalid-secret-1"),
+				),
+				namespace: "default",
+				opts: []ListerOption{
+					WithFilterOnlyUnexpired(),
+				},
+			},
+			want:    []expectedSecret{},
+			wantErr: false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLister(test.fields.client, test.fields.namespace, test.fields.opts...)
+
+			got, err := l.List()
+
+			assertTicketSecret(t, got, test.want)
+			assert.Equal(t, test.wantErr, err != nil)
+
+			for _, item := range got {
+				assert.True(t, item.IsInvalid())
+				assert.Equal(t, "Invalid", item.GetStatusString())
+				assert.NotEmpty(t, item.GetParseError())
+			}
+		})
+	}
+}
+
 func TestLister_WithFilterExpiresBefore(t *testing.T) {
 	ticketWithExpiryTime := func(t *testing.T, expiryTime time.Time) []byte {
 		unix := uint64(expiryTime.Unix())
@@ -947,6 +1059,493 @@ func TestLister_WithMultipleFilters(t *testing.T) {
 	}
 }
 
+func TestLister_WithFilterByInUse(t *testing.T) {
+	t.Parallel()
+
+	inUseListers := func(client kubernetes.Interface) []ListerOption {
+		return []ListerOption{
+			WithFilterByInUse(),
+			WithVolumeLister(volume.NewLister(client, util.SecretAll, util.NamespaceAll)),
+			WithInlineVolumeLister(pod.NewInlineVolumeLister(client, util.NamespaceAll)),
+			WithStorageClassLister(storageclass.NewLister(client)),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    []expectedSecret
+		wantPVs []uint32
+		wantIn  []uint32
+		wantSCs []uint32
+	}{
+		{
+			name: "unused secret",
+			objects: []runtime.Object{
+				secretFromTicketJSON(t, "default", "test-secret-1", []byte(`{"ticket":{"cluster":"test-cluster"}}`)),
+			},
+			want: []expectedSecret{},
+		},
+		{
+			name: "secrets used by a persistent volume and an inline volume",
+			objects: []runtime.Object{
+				secretFromTicketJSON(t, "default", "test-secret-1", []byte(`{"ticket":{"cluster":"test-cluster"}}`)),
+				secretFromTicketJSON(t, "default", "test-secret-2", []byte(`{"ticket":{"cluster":"test-cluster"}}`)),
+				secretFromTicketJSON(t, "default", "test-secret-3", []byte(`{"ticket":{"cluster":"test-cluster"}}`)),
+				&coreV1.PersistentVolume{
+					ObjectMeta: metaV1.ObjectMeta{Name: "volume-1"},
+					Spec: coreV1.PersistentVolumeSpec{
+						PersistentVolumeSource: coreV1.PersistentVolumeSource{
+							CSI: &coreV1.CSIPersistentVolumeSource{
+								Driver:               types.MaprCSIProvisionerKDF,
+								NodePublishSecretRef: &coreV1.SecretReference{Namespace: "default", Name: "test-secret-1"},
+							},
+						},
+					},
+				},
+				newInlineVolumePod("default", "pod-1", types.MaprCSIProvisionerNFSKDF, "test-secret-2"),
+				newInlineVolumePod("other", "pod-2", types.MaprCSIProvisionerKDF, "test-secret-3"),
+				newInlineVolumePod("default", "pod-3", "random-driver", "test-secret-3"),
+			},
+			want: []expectedSecret{
+				newExpectedSecret("default", "test-secret-1"),
+				newExpectedSecret("default", "test-secret-2"),
+			},
+			wantPVs: []uint32{1, 0},
+			wantIn:  []uint32{0, 1},
+			wantSCs: []uint32{0, 0},
+		},
+		{
+			name: "secrets referenced by storage classes",
+			objects: []runtime.Object{
+				secretFromTicketJSON(t, "default", "test-secret-1", []byte(`{"ticket":{"cluster":"test-cluster"}}`)),
+				secretFromTicketJSON(t, "default", "test-secret-2", []byte(`{"ticket":{"cluster":"test-cluster"}}`)),
+				secretFromTicketJSON(t, "default", "test-secret-3", []byte(`{"ticket":{"cluster":"test-cluster"}}`)),
+				&storageV1.StorageClass{
+					ObjectMeta:  metaV1.ObjectMeta{Name: "fixed"},
+					Provisioner: types.MaprCSIProvisionerKDF,
+					Parameters: map[string]string{
+						"csi.storage.k8s.io/provisioner-secret-name":       "test-secret-1",
+						"csi.storage.k8s.io/provisioner-secret-namespace":  "default",
+						"csi.storage.k8s.io/node-publish-secret-name":      "test-secret-1",
+						"csi.storage.k8s.io/node-publish-secret-namespace": "default",
+					},
+				},
+				&storageV1.StorageClass{
+					ObjectMeta:  metaV1.ObjectMeta{Name: "templated"},
+					Provisioner: types.MaprCSIProvisionerNFSKDF,
+					Parameters: map[string]string{
+						"csi.storage.k8s.io/node-publish-secret-name":      "test-secret-2",
+						"csi.storage.k8s.io/node-publish-secret-namespace": "${pvc.namespace}",
+					},
+				},
+				&storageV1.StorageClass{
+					ObjectMeta:  metaV1.ObjectMeta{Name: "incomplete"},
+					Provisioner: types.MaprCSIProvisionerKDF,
+					Parameters: map[string]string{
+						"csi.storage.k8s.io/node-publish-secret-name": "test-secret-3",
+					},
+				},
+				&storageV1.StorageClass{
+					ObjectMeta:  metaV1.ObjectMeta{Name: "other"},
+					Provisioner: "random-provisioner",
+					Parameters: map[string]string{
+						"csi.storage.k8s.io/node-publish-secret-name":      "test-secret-3",
+						"csi.storage.k8s.io/node-publish-secret-namespace": "default",
+					},
+				},
+			},
+			want: []expectedSecret{
+				newExpectedSecret("default", "test-secret-1"),
+				newExpectedSecret("default", "test-secret-2"),
+			},
+			wantPVs: []uint32{0, 0},
+			wantIn:  []uint32{0, 0},
+			wantSCs: []uint32{1, 1},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(test.objects...)
+			l := NewLister(client, "default", inUseListers(client)...)
+
+			got, err := l.List()
+
+			assert.NoError(t, err)
+			assertTicketSecret(t, got, test.want)
+
+			for i := range got {
+				assert.Equal(t, test.wantPVs[i], got[i].NumPVC)
+				assert.Equal(t, test.wantIn[i], got[i].NumInline)
+				assert.Equal(t, test.wantSCs[i], got[i].NumStorageClasses)
+			}
+		})
+	}
+}
+
+func TestLister_WithTicketKey(t *testing.T) {
+	t.Parallel()
+
+	// secretWithKeys returns a secret holding a ticket under each of the given keys
+	secretWithKeys := func(name string, keys ...string) *coreV1.Secret {
+		secret := secretFromTicketJSON(t, "default", name, []byte(`{"ticket":{"cluster":"test-cluster"}}`))
+		ticketBytes := secret.Data[ticket.SecretMaprTicketKey]
+
+		secret.Data = map[string][]byte{"other": []byte("not a ticket")}
+		for _, key := range keys {
+			secret.Data[key] = ticketBytes
+		}
+
+		return secret
+	}
+
+	client := fake.NewSimpleClientset(
+		secretWithKeys("test-secret-1", ticket.SecretMaprTicketKey),
+		secretWithKeys("test-secret-2", "maprticket"),
+		secretWithKeys("test-secret-3", "maprticket", "user-a"),
+	)
+
+	tests := []struct {
+		name     string
+		opts     []ListerOption
+		want     []expectedSecret
+		wantKeys []string
+	}{
+		{
+			name:     "default key",
+			opts:     nil,
+			want:     []expectedSecret{newExpectedSecret("default", "test-secret-1")},
+			wantKeys: []string{ticket.SecretMaprTicketKey},
+		},
+		{
+			name: "custom key",
+			opts: []ListerOption{WithTicketKey("maprticket")},
+			want: []expectedSecret{
+				newExpectedSecret("default", "test-secret-2"),
+				newExpectedSecret("default", "test-secret-3"),
+			},
+			wantKeys: []string{"maprticket", "maprticket"},
+		},
+		{
+			name: "detect ticket keys",
+			opts: []ListerOption{WithDetectTicketKeys(), WithSortBy([]SortOption{SortByName})},
+			want: []expectedSecret{
+				newExpectedSecret("default", "test-secret-1"),
+				newExpectedSecret("default", "test-secret-2"),
+				newExpectedSecret("default", "test-secret-3"),
+				newExpectedSecret("default", "test-secret-3"),
+			},
+			wantKeys: []string{ticket.SecretMaprTicketKey, "maprticket", "maprticket", "user-a"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLister(client, "default", test.opts...)
+
+			got, err := l.List()
+
+			assert.NoError(t, err)
+			assertTicketSecret(t, got, test.want)
+
+			keys := make([]string, 0, len(got))
+			for i := range got {
+				keys = append(keys, got[i].Key)
+				assert.False(t, got[i].IsInvalid())
+			}
+
+			assert.ElementsMatch(t, test.wantKeys, keys)
+		})
+	}
+}
+
+func TestLister_WithLabelSelector(t *testing.T) {
+	t.Parallel()
+
+	// secretWithLabels returns a secret holding a ticket with the given labels
+	secretWithLabels := func(name string, labels map[string]string) *coreV1.Secret {
+		secret := secretFromTicketJSON(t, "default", name, []byte(`{"ticket":{"cluster":"test-cluster"}}`))
+		secret.Labels = labels
+
+		return secret
+	}
+
+	client := fake.NewSimpleClientset(
+		secretWithLabels("test-secret-1", map[string]string{"team": "a"}),
+		secretWithLabels("test-secret-2", map[string]string{"team": "b"}),
+		secretWithLabels("test-secret-3", nil),
+	)
+
+	tests := []struct {
+		name string
+		opts []ListerOption
+		want []expectedSecret
+	}{
+		{
+			name: "no selector",
+			opts: nil,
+			want: []expectedSecret{
+				newExpectedSecret("default", "test-secret-1"),
+				newExpectedSecret("default", "test-secret-2"),
+				newExpectedSecret("default", "test-secret-3"),
+			},
+		},
+		{
+			name: "equality selector",
+			opts: []ListerOption{WithLabelSelector("team=a")},
+			want: []expectedSecret{newExpectedSecret("default", "test-secret-1")},
+		},
+		{
+			name: "set based selector",
+			opts: []ListerOption{WithLabelSelector("team in (a,b)")},
+			want: []expectedSecret{
+				newExpectedSecret("default", "test-secret-1"),
+				newExpectedSecret("default", "test-secret-2"),
+			},
+		},
+		{
+			name: "no matching secret",
+			opts: []ListerOption{WithLabelSelector("team=c")},
+			want: []expectedSecret{},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLister(client, "default", test.opts...)
+
+			got, err := l.List()
+
+			assert.NoError(t, err)
+			assertTicketSecret(t, got, test.want)
+		})
+	}
+}
+
+func TestLister_WithFilterByExpression(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		secretFromTicketJSON(t, "team-a", "test-secret-1", []byte(`{"ticket":{"cluster":"test-cluster","userCreds":{"userName":"a"}}}`)),
+		secretFromTicketJSON(t, "team-b", "test-secret-2", []byte(`{"ticket":{"cluster":"test-cluster","userCreds":{"userName":"b"}}}`)),
+		secretFromTicketJSON(t, "other", "test-secret-3", []byte(`{"ticket":{"cluster":"test-cluster","userCreds":{"userName":"a"}}}`)),
+	)
+
+	tests := []struct {
+		name       string
+		expression string
+		want       []expectedSecret
+	}{
+		{
+			name:       "user in list",
+			expression: "ticket.user in ['a', 'b']",
+			want: []expectedSecret{
+				newExpectedSecret("other", "test-secret-3"),
+				newExpectedSecret("team-a", "test-secret-1"),
+				newExpectedSecret("team-b", "test-secret-2"),
+			},
+		},
+		{
+			name:       "user and namespace prefix",
+			expression: "ticket.user == 'a' && secret.namespace.startsWith('team-')",
+			want:       []expectedSecret{newExpectedSecret("team-a", "test-secret-1")},
+		},
+		{
+			name:       "no match",
+			expression: "ticket.cluster == 'other-cluster'",
+			want:       []expectedSecret{},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			expression, err := filter.Compile(test.expression, filter.SecretVariables)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			l := NewLister(client, util.NamespaceAll, WithFilterByExpression(expression))
+
+			got, err := l.List()
+
+			assert.NoError(t, err)
+			assertTicketSecret(t, got, test.want)
+		})
+	}
+}
+
+func TestLister_WithLabeledOnly(t *testing.T) {
+	t.Parallel()
+
+	// secretWithLabels returns a secret holding a ticket with the given labels
+	secretWithLabels := func(name string, labels map[string]string) *coreV1.Secret {
+		secret := secretFromTicketJSON(t, "default", name, []byte(`{"ticket":{"cluster":"test-cluster"}}`))
+		secret.Labels = labels
+
+		return secret
+	}
+
+	labeled := map[string]string{ticket.LabelTicket: ticket.LabelTicketValue}
+
+	tests := []struct {
+		name   string
+		client kubernetes.Interface
+		opts   []ListerOption
+		want   []expectedSecret
+	}{
+		{
+			name: "only labeled secrets",
+			client: fake.NewSimpleClientset(
+				secretWithLabels("test-secret-1", labeled),
+				secretWithLabels("test-secret-2", nil),
+			),
+			want: []expectedSecret{newExpectedSecret("default", "test-secret-1")},
+		},
+		{
+			name: "fall back to all secrets if none are labeled",
+			client: fake.NewSimpleClientset(
+				secretWithLabels("test-secret-1", nil),
+				secretWithLabels("test-secret-2", map[string]string{"team": "a"}),
+			),
+			want: []expectedSecret{
+				newExpectedSecret("default", "test-secret-1"),
+				newExpectedSecret("default", "test-secret-2"),
+			},
+		},
+		{
+			name: "combined with label selector",
+			client: fake.NewSimpleClientset(
+				secretWithLabels("test-secret-1", map[string]string{ticket.LabelTicket: ticket.LabelTicketValue, "team": "a"}),
+				secretWithLabels("test-secret-2", labeled),
+				secretWithLabels("test-secret-3", map[string]string{"team": "a"}),
+			),
+			opts: []ListerOption{WithLabelSelector("team=a")},
+			want: []expectedSecret{newExpectedSecret("default", "test-secret-1")},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLister(test.client, "default", append(test.opts, WithLabeledOnly())...)
+
+			got, err := l.List()
+
+			assert.NoError(t, err)
+			assertTicketSecret(t, got, test.want)
+		})
+	}
+}
+
+func TestLister_WithChunkSize(t *testing.T) {
+	t.Parallel()
+
+	// five secrets with tickets, interleaved with secrets without tickets
+	secrets := make([]coreV1.Secret, 0, 8)
+	for i := 0; i < 8; i++ {
+		secret := secretFromTicketJSON(t, "default", fmt.Sprintf("test-secret-%d", i), []byte(`{"ticket":{"cluster":"test-cluster"}}`))
+		if i%3 == 2 {
+			secret.Data = map[string][]byte{"other": []byte("not a ticket")}
+		}
+
+		secrets = append(secrets, *secret)
+	}
+
+	want := []expectedSecret{
+		newExpectedSecret("default", "test-secret-0"),
+		newExpectedSecret("default", "test-secret-1"),
+		newExpectedSecret("default", "test-secret-3"),
+		newExpectedSecret("default", "test-secret-4"),
+		newExpectedSecret("default", "test-secret-6"),
+		newExpectedSecret("default", "test-secret-7"),
+	}
+
+	tests := []struct {
+		name       string
+		opts       []ListerOption
+		wantLimits []string
+	}{
+		{
+			name:       "no chunking",
+			opts:       nil,
+			wantLimits: []string{""},
+		},
+		{
+			name:       "chunks smaller than the list",
+			opts:       []ListerOption{WithChunkSize(3)},
+			wantLimits: []string{"3", "3", "3"},
+		},
+		{
+			name:       "chunk larger than the list",
+			opts:       []ListerOption{WithChunkSize(500)},
+			wantLimits: []string{"500"},
+		},
+		{
+			name:       "single parse worker",
+			opts:       []ListerOption{WithChunkSize(3), WithParseWorkers(1)},
+			wantLimits: []string{"3", "3", "3"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var limits []string
+
+			// serve the secrets in chunks using limit and continue like the API server does
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				limits = append(limits, query.Get("limit"))
+
+				start, _ := strconv.Atoi(query.Get("continue"))
+				end := len(secrets)
+
+				list := &coreV1.SecretList{}
+
+				if limit, err := strconv.Atoi(query.Get("limit")); err == nil && start+limit < end {
+					end = start + limit
+					list.Continue = strconv.Itoa(end)
+				}
+
+				list.Items = secrets[start:end]
+
+				w.Header().Set("Content-Type", "application/json")
+				assert.NoError(t, json.NewEncoder(w).Encode(list))
+			}))
+			defer server.Close()
+
+			client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+			assert.NoError(t, err)
+
+			opts := append([]ListerOption{WithSortBy([]SortOption{SortByName})}, test.opts...)
+			l := NewLister(client, util.NamespaceAll, opts...)
+
+			got, err := l.List()
+
+			assert.NoError(t, err)
+			assertTicketSecret(t, got, want)
+			assert.Equal(t, test.wantLimits, limits)
+		})
+	}
+}
+
 func TestLister_WithSortByName(t *testing.T) {
 	t.Parallel()
 
@@ -1545,3 +2144,25 @@ func secretFromTicketJSON(t *testing.T, namespace, name string, in []byte) *core
 		},
 	}
 }
+
+func newInlineVolumePod(namespace, name, driver, secretName string) *coreV1.Pod {
+	return &coreV1.Pod{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: coreV1.PodSpec{
+			Volumes: []coreV1.Volume{
+				{
+					Name: "data",
+					VolumeSource: coreV1.VolumeSource{
+						CSI: &coreV1.CSIVolumeSource{
+							Driver:               driver,
+							NodePublishSecretRef: &coreV1.LocalObjectReference{Name: secretName},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -5,7 +5,11 @@
 
 package secret
 
-import "time"
+import (
+	"time"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
+)
 
 // ListerOption is a function that can be used to configure the secret lister.
 type ListerOption func(*Lister)
@@ -63,6 +67,14 @@ func WithFilterOnlyUnexpired() ListerOption {
 	}
 }
 
+// WithFilterOnlyInvalid configures the secret lister to only list secrets whose ticket can't be
+// parsed.
+func WithFilterOnlyInvalid() ListerOption {
+	return func(l *Lister) {
+		l.filterOnlyInvalid = true
+	}
+}
+
 // WithFilterByInUse configures the secret lister to only list tickets that are in use by a
 // persistent volume.
 func WithFilterByInUse() ListerOption {
@@ -79,6 +91,14 @@ func WithFilterExpiresBefore(expiresBefore time.Duration) ListerOption {
 	}
 }
 
+// WithFilterByExpression sets the filter to only return tickets matching the given filter
+// expression
+func WithFilterByExpression(expression *filter.Expression) ListerOption {
+	return func(l *Lister) {
+		l.filterByExpression = expression
+	}
+}
+
 // WithShowInUse configures the secret lister to show by how many persistent volumes a ticket is in
 // use.
 func WithShowInUse() ListerOption {
@@ -94,3 +114,76 @@ func WithVolumeLister(volumeLister volumeLister) ListerOption {
 		l.volumeLister = volumeLister
 	}
 }
+
+// WithInlineVolumeLister configures the secret lister to make use of the given inline volume lister
+// to collect information about the inline CSI volumes in pod specs that are using tickets.
+func WithInlineVolumeLister(inlineVolumeLister inlineVolumeLister) ListerOption {
+	return func(l *Lister) {
+		l.inlineVolumeLister = inlineVolumeLister
+	}
+}
+
+// WithStorageClassLister configures the secret lister to make use of the given StorageClass lister
+// to collect information about the StorageClasses that are referencing tickets.
+func WithStorageClassLister(storageClassLister storageClassLister) ListerOption {
+	return func(l *Lister) {
+		l.storageClassLister = storageClassLister
+	}
+}
+
+// WithTicketKey configures the secret lister to read MapR tickets from the given data key of the
+// secrets instead of the default key.
+func WithTicketKey(key string) ListerOption {
+	return func(l *Lister) {
+		l.ticketKey = key
+	}
+}
+
+// WithDetectTicketKeys configures the secret lister to try to parse every data key of the secrets
+// as a MapR ticket. Secrets holding several tickets are listed once per ticket.
+func WithDetectTicketKeys() ListerOption {
+	return func(l *Lister) {
+		l.detectTicketKeys = true
+	}
+}
+
+// WithLabelSelector sets the label selector used by the Lister to select the secrets to list
+func WithLabelSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.labelSelector = selector
+	}
+}
+
+// WithFieldSelector sets the field selector used by the Lister to select the secrets to list
+func WithFieldSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.fieldSelector = selector
+	}
+}
+
+// WithChunkSize configures the secret lister to list the secrets in chunks of the given size
+// instead of all at once. A chunk size of 0 disables chunking.
+func WithChunkSize(chunkSize int64) ListerOption {
+	return func(l *Lister) {
+		l.chunkSize = chunkSize
+	}
+}
+
+// WithParseWorkers configures the number of workers parsing the tickets of the secrets
+// concurrently. It defaults to the number of usable CPUs.
+func WithParseWorkers(workers int) ListerOption {
+	return func(l *Lister) {
+		if workers > 0 {
+			l.parseWorkers = workers
+		}
+	}
+}
+
+// WithLabeledOnly configures the secret lister to only retrieve the secrets labeled as containing
+// a MapR ticket, see ticket.LabelTicket, instead of the data of all secrets. If no labeled secrets
+// exist, all secrets are retrieved instead.
+func WithLabeledOnly() ListerOption {
+	return func(l *Lister) {
+		l.labeledOnly = true
+	}
+}
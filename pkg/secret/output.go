@@ -14,10 +14,16 @@ import (
 
 	coreV1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/printers"
 )
 
+// keyTableColumnName is the name of the column showing the data key of the secret the ticket is
+// stored under
+const keyTableColumnName = "Key"
+
 var (
 	tableColumns = []metaV1.TableColumnDefinition{
 		{
@@ -27,6 +33,12 @@ var (
 			Description: "Name of the secret containing the MapR ticket",
 			Priority:    0,
 		},
+		{
+			Name:        keyTableColumnName,
+			Type:        "string",
+			Description: "Data key of the secret the ticket is stored under",
+			Priority:    1,
+		},
 		{
 			Name:        "MapR Cluster",
 			Type:        "string",
@@ -64,6 +76,12 @@ var (
 			Description: "Status of the ticket",
 			Priority:    0,
 		},
+		{
+			Name:        "Error",
+			Type:        "string",
+			Description: "Error encountered while parsing the ticket",
+			Priority:    1,
+		},
 		{
 			Name:        "Span",
 			Type:        "string",
@@ -91,6 +109,20 @@ var (
 		Description: "Number of persistent volumes using the ticket",
 		Priority:    0,
 	}
+
+	showInlineTableColumn = metaV1.TableColumnDefinition{
+		Name:        "#Inline",
+		Type:        "integer",
+		Description: "Number of inline volumes in pod specs using the ticket",
+		Priority:    0,
+	}
+
+	showStorageClassesTableColumn = metaV1.TableColumnDefinition{
+		Name:        "#SCs",
+		Type:        "integer",
+		Description: "Number of StorageClasses referencing the ticket",
+		Priority:    0,
+	}
 )
 
 // Print prints the secrets containing MapR tickets in a human-readable format to the given output
@@ -99,6 +131,7 @@ func Print(cmd *cobra.Command, secrets []types.MaprSecret) error {
 	format := cmd.Flag("output").Value.String()
 	allNamespaces := cmd.Flag("all-namespaces").Changed && cmd.Flag("all-namespaces").Value.String() == "true"
 	withInUse := cmd.Flag("show-in-use").Changed && cmd.Flag("show-in-use").Value.String() == "true"
+	withKeys := detectTicketKeys(cmd)
 
 	// generate table for output
 	table := generateTable(secrets)
@@ -108,6 +141,19 @@ func Print(cmd *cobra.Command, secrets []types.MaprSecret) error {
 		enrichTableWithInUse(table, secrets)
 	}
 
+	// show the key column by default, if secrets may be listed once per ticket
+	if withKeys {
+		enrichTableWithKeys(table)
+	}
+
+	// add the context column when listing across multiple contexts
+	contexts := make([]string, 0, len(secrets))
+	for i := range secrets {
+		contexts = append(contexts, secrets[i].Context)
+	}
+
+	util.AddContextColumn(table, contexts)
+
 	// print table
 	printer := printers.NewTablePrinter(printers.PrintOptions{
 		WithNamespace: allNamespaces,
@@ -122,6 +168,19 @@ func Print(cmd *cobra.Command, secrets []types.MaprSecret) error {
 	return nil
 }
 
+// PrintObjects prints the secrets containing MapR tickets as a structured list using the given
+// printer, e.g. a JSON, YAML, JSONPath, Go template or custom columns printer.
+func PrintObjects(cmd *cobra.Command, printer printers.ResourcePrinter, secrets []types.MaprSecret) error {
+	withInUse := cmd.Flag("show-in-use").Changed && cmd.Flag("show-in-use").Value.String() == "true"
+
+	list, err := generateList(secrets, withInUse)
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(list, cmd.OutOrStdout())
+}
+
 // generateTable generates a table from the secrets containing MapR tickets
 func generateTable(secrets []types.MaprSecret) *metaV1.Table {
 	rows := generateRows(secrets)
@@ -132,6 +191,17 @@ func generateTable(secrets []types.MaprSecret) *metaV1.Table {
 	}
 }
 
+// generateList generates a list of structured items from the secrets containing MapR tickets
+func generateList(secrets []types.MaprSecret, withInUse bool) (*unstructured.UnstructuredList, error) {
+	items := make([]*types.MaprSecretItem, 0, len(secrets))
+
+	for i := range secrets {
+		items = append(items, secrets[i].Item(withInUse))
+	}
+
+	return types.NewList(items)
+}
+
 // generateRows generates the rows for the table from the secrets containing
 // MapR tickets
 func generateRows(secrets []types.MaprSecret) []metaV1.TableRow {
@@ -153,14 +223,36 @@ func generateRow(secrets *types.MaprSecret) *metaV1.TableRow {
 		},
 	}
 
+	// secrets with invalid tickets only show the name, status and parse error
+	if secrets.IsInvalid() {
+		row.Cells = []any{
+			secrets.Secret.GetName(),
+			secrets.GetKey(),
+			"",
+			"",
+			"",
+			"",
+			"",
+			secrets.GetStatusString(),
+			secrets.GetParseError(),
+			"",
+			"",
+			"",
+		}
+
+		return row
+	}
+
 	row.Cells = []any{
 		secrets.Secret.GetName(),
+		secrets.GetKey(),
 		secrets.Ticket.Cluster,
 		secrets.Ticket.UserCreds.GetUserName(),
 		secrets.Ticket.UserCreds.GetUid(),
 		secrets.Ticket.UserCreds.GetGids(),
 		secrets.Ticket.ExpirationTime().Format(ticket.DefaultTimeFormat),
 		secrets.GetStatusString(),
+		"",
 		util.ShortHumanDuration(secrets.Ticket.ExpirationTime().Sub(secrets.Ticket.CreationTime())),
 		secrets.Ticket.CreationTime().Format(ticket.DefaultTimeFormat),
 		util.ShortHumanDurationUntilNow(secrets.Ticket.CreationTime()),
@@ -169,22 +261,149 @@ func generateRow(secrets *types.MaprSecret) *metaV1.TableRow {
 	return row
 }
 
-// enrichTableWithInUse enriches the table with a column indicating whether the
-// ticket is in use by a persistent volume or not
+// enrichTableWithInUse enriches the table with columns indicating by how many
+// persistent volumes, inline volumes and StorageClasses the ticket is in use
 func enrichTableWithInUse(table *metaV1.Table, secrets []types.MaprSecret) {
 	insertPos := len(tableColumns) - 1
 
 	table.ColumnDefinitions = append(
-		table.ColumnDefinitions[:insertPos],
+		table.ColumnDefinitions[:insertPos:insertPos],
 		showInUseTableColumn,
+		showInlineTableColumn,
+		showStorageClassesTableColumn,
 		table.ColumnDefinitions[insertPos],
 	)
 
 	for i := range table.Rows {
 		table.Rows[i].Cells = append(
-			table.Rows[i].Cells[:insertPos],
+			table.Rows[i].Cells[:insertPos:insertPos],
 			secrets[i].NumPVC,
+			secrets[i].NumInline,
+			secrets[i].NumStorageClasses,
 			table.Rows[i].Cells[insertPos],
 		)
 	}
 }
+
+// enrichTableWithKeys shows the column with the data key of the secret the ticket is stored under
+// in the default output, not only in the wide output
+func enrichTableWithKeys(table *metaV1.Table) {
+	columns := make([]metaV1.TableColumnDefinition, len(table.ColumnDefinitions))
+	copy(columns, table.ColumnDefinitions)
+
+	for i := range columns {
+		if columns[i].Name == keyTableColumnName {
+			columns[i].Priority = 0
+		}
+	}
+
+	table.ColumnDefinitions = columns
+}
+
+// detectTicketKeys returns true if ticket key detection is enabled for the command, in which case
+// a secret may be listed once per ticket it holds
+func detectTicketKeys(cmd *cobra.Command) bool {
+	flag := cmd.Flag("detect-ticket-keys")
+
+	return flag != nil && flag.Value.String() == "true"
+}
+
+// PrintWatch prints the initial list of secrets and afterwards every event received from the
+// events channel until it is closed. If printer is nil, the secrets are printed as a human
+// readable table, otherwise each secret is printed as a separate structured item using the
+// printer. If withEvents is true, the type of each event is included in the output.
+func PrintWatch(cmd *cobra.Command, printer printers.ResourcePrinter, initial []types.MaprSecret, events <-chan WatchEvent, withEvents bool) error {
+	format := cmd.Flag("output").Value.String()
+	allNamespaces := cmd.Flag("all-namespaces").Changed && cmd.Flag("all-namespaces").Value.String() == "true"
+	withInUse := cmd.Flag("show-in-use").Changed && cmd.Flag("show-in-use").Value.String() == "true"
+	withKeys := detectTicketKeys(cmd)
+
+	// use a single table printer for the whole watch, so the headers are only printed once
+	if printer == nil {
+		printer = printers.NewTablePrinter(printers.PrintOptions{
+			WithNamespace: allNamespaces,
+			Wide:          format == "wide",
+		})
+	}
+
+	_, isTable := printer.(*printers.HumanReadablePrinter)
+
+	printSecrets := func(eventType watch.EventType, secrets []types.MaprSecret) error {
+		obj, err := generateWatchObject(secrets, isTable, withInUse, withKeys)
+		if err != nil {
+			return err
+		}
+
+		if withEvents {
+			obj = wrapWatchEvent(eventType, obj, isTable)
+		}
+
+		return printer.PrintObj(obj, cmd.OutOrStdout())
+	}
+
+	// structured printers print each item on its own, tables print the initial list at once
+	if isTable {
+		if err := printSecrets(watch.Added, initial); err != nil {
+			return err
+		}
+	} else {
+		for i := range initial {
+			if err := printSecrets(watch.Added, initial[i:i+1]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for event := range events {
+		if err := printSecrets(event.Type, []types.MaprSecret{event.Secret}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateWatchObject generates the object printed for the given secrets during a watch, either a
+// table or a single structured item
+func generateWatchObject(secrets []types.MaprSecret, isTable, withInUse, withKeys bool) (runtime.Object, error) {
+	if isTable {
+		table := generateTable(secrets)
+
+		if withInUse {
+			enrichTableWithInUse(table, secrets)
+		}
+
+		if withKeys {
+			enrichTableWithKeys(table)
+		}
+
+		return table, nil
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secrets[0].Item(withInUse))
+	if err != nil {
+		return nil, err
+	}
+
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// wrapWatchEvent wraps the object into a watch event of the given type
+func wrapWatchEvent(eventType watch.EventType, obj runtime.Object, isTable bool) runtime.Object {
+	// the table printer adds an event column for metaV1.WatchEvent objects
+	if isTable {
+		return &metaV1.WatchEvent{
+			Type:   string(eventType),
+			Object: runtime.RawExtension{Object: obj},
+		}
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "meta.k8s.io/v1",
+			"kind":       "WatchEvent",
+			"type":       string(eventType),
+			"object":     obj.(*unstructured.Unstructured).Object,
+		},
+	}
+}
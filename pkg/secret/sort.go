@@ -6,9 +6,11 @@
 package secret
 
 import (
-	"sort"
+	"cmp"
+	"slices"
 
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
 )
 
 // SortOption is the type of a sort option, basically a wrapper around a string to provide
@@ -50,85 +52,93 @@ func (s SortOption) String() string {
 	return string(s)
 }
 
-// sortByName sorts the items by secret name
-func sortByName(items []types.MaprSecret) {
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].GetSecretName() < items[j].GetSecretName()
-	})
+// compareByName compares the items by secret name
+func compareByName(a, b *types.MaprSecret) int {
+	return cmp.Compare(a.GetSecretName(), b.GetSecretName())
 }
 
-// sortByNamespace sorts the items by secret namespace
-func sortByNamespace(items []types.MaprSecret) {
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].GetSecretNamespace() < items[j].GetSecretNamespace()
-	})
+// compareByNamespace compares the items by secret namespace
+func compareByNamespace(a, b *types.MaprSecret) int {
+	return cmp.Compare(a.GetSecretNamespace(), b.GetSecretNamespace())
 }
 
-// sortByMaprCluster sorts the items by MapR cluster that the ticket is for
-func sortByMaprCluster(items []types.MaprSecret) {
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].GetCluster() < items[j].GetCluster()
-	})
+// compareByMaprCluster compares the items by MapR cluster that the ticket is for
+func compareByMaprCluster(a, b *types.MaprSecret) int {
+	return cmp.Compare(a.GetCluster(), b.GetCluster())
 }
 
-// sortByMaprUser sorts the items by MapR user that the ticket is for
-func sortByMaprUser(items []types.MaprSecret) {
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].GetUser() < items[j].GetUser()
-	})
+// compareByMaprUser compares the items by MapR user that the ticket is for
+func compareByMaprUser(a, b *types.MaprSecret) int {
+	return cmp.Compare(a.GetUser(), b.GetUser())
 }
 
-// sortByAge sorts the items by creation timestamp of the ticket
-func sortByAge(items []types.MaprSecret) {
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].GetCreationTime().Before(items[j].GetCreationTime())
-	})
+// compareByAge compares the items by creation timestamp of the ticket
+func compareByAge(a, b *types.MaprSecret) int {
+	return a.GetCreationTime().Compare(b.GetCreationTime())
 }
 
-// sortByExpiration sorts the items by expiry time of the ticket
-func sortByExpiration(items []types.MaprSecret) {
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].GetExpirationTime().Before(items[j].GetExpirationTime())
-	})
+// compareByExpiration compares the items by expiry time of the ticket
+func compareByExpiration(a, b *types.MaprSecret) int {
+	return a.GetExpirationTime().Compare(b.GetExpirationTime())
 }
 
-// sortByNumPVCs sorts the items by the number of persistent volumes that are
-// using the secret
-func sortByNumPVCs(items []types.MaprSecret) {
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].NumPVC < items[j].NumPVC
-	})
+// compareByNumPVCs compares the items by the number of persistent volumes that are using the
+// secret
+func compareByNumPVCs(a, b *types.MaprSecret) int {
+	return cmp.Compare(a.NumPVC, b.NumPVC)
+}
+
+// comparators maps the sort options to the functions comparing the items by the option
+var comparators = map[SortOption]func(a, b *types.MaprSecret) int{
+	SortByName:        compareByName,
+	SortByNamespace:   compareByNamespace,
+	SortByMaprCluster: compareByMaprCluster,
+	SortByMaprUser:    compareByMaprUser,
+	SortByAge:         compareByAge,
+	SortByExpiration:  compareByExpiration,
+	SortByNumPVCs:     compareByNumPVCs,
 }
 
-// Sort sorts the items by the specified sort options, in reverse order of the
-// order in which they are specified. This makes for a more natural sort result
-// when using multiple sort options.
+// Sort sorts the items by the sort options of the lister.
 func (l *Lister) Sort() *Lister {
-	// reverse the order of the sort options
-	order := make([]SortOption, len(l.sortBy))
-	for i, j := 0, len(l.sortBy)-1; i < len(l.sortBy); i, j = i+1, j-1 {
-		order[i] = l.sortBy[j]
+	SortItems(l.tickets, l.sortBy)
+
+	return l
+}
+
+// SortItems sorts the given secrets by the specified sort options. Items that are equal by the
+// first option are sorted by the second one, and so on. Options prefixed with "-" or suffixed with
+// ":desc" sort in descending order, see util.ParseSortOption. The sort is stable, so items equal
+// by all options keep their order.
+func SortItems(items []types.MaprSecret, sortBy []SortOption) {
+	type sortKey struct {
+		compare    func(a, b *types.MaprSecret) int
+		descending bool
 	}
 
-	// sort the items by each sort option
-	for _, sortOption := range order {
-		switch sortOption {
-		case SortByName:
-			sortByName(l.tickets)
-		case SortByNamespace:
-			sortByNamespace(l.tickets)
-		case SortByMaprCluster:
-			sortByMaprCluster(l.tickets)
-		case SortByMaprUser:
-			sortByMaprUser(l.tickets)
-		case SortByAge:
-			sortByAge(l.tickets)
-		case SortByExpiration:
-			sortByExpiration(l.tickets)
-		case SortByNumPVCs:
-			sortByNumPVCs(l.tickets)
+	keys := make([]sortKey, 0, len(sortBy))
+
+	for _, sortOption := range sortBy {
+		field, descending := util.ParseSortOption(sortOption.String())
+
+		if compare, ok := comparators[SortOption(field)]; ok {
+			keys = append(keys, sortKey{compare: compare, descending: descending})
 		}
 	}
 
-	return l
+	slices.SortStableFunc(items, func(a, b types.MaprSecret) int {
+		for _, key := range keys {
+			result := key.compare(&a, &b)
+
+			if key.descending {
+				result = -result
+			}
+
+			if result != 0 {
+				return result
+			}
+		}
+
+		return 0
+	})
 }
@@ -4,3 +4,81 @@
 // SPDX-License-Identifier: MIT
 
 package secret_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSortItems(t *testing.T) {
+	t.Parallel()
+
+	// newItem returns a secret item in the given namespace used by the given number of volumes
+	newItem := func(namespace, name string, numPVC uint32) types.MaprSecret {
+		return types.MaprSecret{
+			Secret: &types.Secret{ObjectMeta: metaV1.ObjectMeta{Namespace: namespace, Name: name}},
+			NumPVC: numPVC,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		sortBy []SortOption
+		want   []string
+	}{
+		{
+			name:   "default",
+			sortBy: DefaultSortBy,
+			want:   []string{"a/x", "a/y", "b/x", "b/y"},
+		},
+		{
+			name:   "descending prefix",
+			sortBy: []SortOption{"-namespace", SortByName},
+			want:   []string{"b/x", "b/y", "a/x", "a/y"},
+		},
+		{
+			name:   "descending suffix",
+			sortBy: []SortOption{SortByNamespace, "name:desc"},
+			want:   []string{"a/y", "a/x", "b/y", "b/x"},
+		},
+		{
+			name:   "most volumes first",
+			sortBy: []SortOption{"-npvcs"},
+			want:   []string{"b/x", "a/y", "a/x", "b/y"},
+		},
+		{
+			name:   "stable for equal items",
+			sortBy: []SortOption{SortByMaprCluster},
+			want:   []string{"b/y", "a/x", "b/x", "a/y"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			items := []types.MaprSecret{
+				newItem("b", "y", 0),
+				newItem("a", "x", 1),
+				newItem("b", "x", 3),
+				newItem("a", "y", 2),
+			}
+
+			SortItems(items, test.sortBy)
+
+			got := make([]string, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.GetSecretNamespace()+"/"+item.GetSecretName())
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	// watchExpiryCheckInterval is the interval in which watched tickets are checked for having
+	// expired since the last event
+	watchExpiryCheckInterval = time.Second
+
+	// watchUsageRefreshInterval is the interval in which the volumes and StorageClasses used to
+	// determine which watched tickets are in use are listed again
+	watchUsageRefreshInterval = 30 * time.Second
+)
+
+// WatchEvent is a change of a secret containing a MapR ticket observed while watching
+type WatchEvent struct {
+	// Type is the type of the change, ie. watch.Added, watch.Modified or watch.Deleted
+	Type watch.EventType
+
+	// Secret is the state of the secret after the change, or the last known state if it was
+	// deleted
+	Secret types.MaprSecret
+}
+
+// watchedSecret is the last state of a secret that was reported to the caller of Watch
+type watchedSecret struct {
+	secret          types.MaprSecret
+	resourceVersion string
+	expired         bool
+}
+
+// cachedLister serves the items retrieved by the last refresh of the wrapped lister, so the
+// volumes and StorageClasses are not listed again for every secret event while watching
+type cachedLister[T any] struct {
+	lister interface{ List() ([]T, error) }
+	items  []T
+}
+
+// List returns the items retrieved by the last refresh
+func (c *cachedLister[T]) List() ([]T, error) {
+	return c.items, nil
+}
+
+// refresh lists the items of the wrapped lister again, keeping the previous items on error
+func (c *cachedLister[T]) refresh() error {
+	items, err := c.lister.List()
+	if err != nil {
+		return err
+	}
+
+	c.items = items
+
+	return nil
+}
+
+// secretChange is a raw notification received from the secret informer
+type secretChange struct {
+	secret  *coreV1.Secret
+	deleted bool
+}
+
+// Watch lists the secrets containing MapR tickets like List and keeps watching them afterwards
+// using a shared informer. It returns the initial filtered and sorted list of tickets and a
+// channel that receives an event whenever a ticket secret matching the filters is added, changed
+// or removed. Tickets that expire while being watched are re-emitted as modified, or as deleted if
+// they no longer match the filters. The channel is closed once the context is cancelled.
+//
+// The volumes and StorageClasses used to determine which tickets are in use are listed once and
+// refreshed periodically, instead of on every event.
+//
+// The lister must not be used for anything else after calling Watch.
+func (l *Lister) Watch(ctx context.Context) ([]types.MaprSecret, <-chan WatchEvent, error) {
+	watchOpts, err := l.watchListOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshUsage := l.cacheUsage()
+	if err := refreshUsage(); err != nil {
+		return nil, nil, err
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		l.client,
+		0,
+		informers.WithNamespace(l.namespace),
+		informers.WithTweakListOptions(func(opts *metaV1.ListOptions) {
+			opts.LabelSelector = watchOpts.LabelSelector
+			opts.FieldSelector = watchOpts.FieldSelector
+		}),
+	)
+	informer := factory.Core().V1().Secrets().Informer()
+
+	changes := make(chan secretChange)
+	send := func(change secretChange) {
+		select {
+		case changes <- change:
+		case <-ctx.Done():
+		}
+	}
+
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if secret, ok := obj.(*coreV1.Secret); ok {
+				send(secretChange{secret: secret})
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if secret, ok := obj.(*coreV1.Secret); ok {
+				send(secretChange{secret: secret})
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			if secret, ok := obj.(*coreV1.Secret); ok {
+				send(secretChange{secret: secret, deleted: true})
+			}
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		factory.Shutdown()
+		return nil, nil, fmt.Errorf("failed to sync secret informer")
+	}
+
+	// build the initial list from the informer cache
+	objs := informer.GetStore().List()
+	secrets := make([]coreV1.Secret, 0, len(objs))
+
+	for _, obj := range objs {
+		if secret, ok := obj.(*coreV1.Secret); ok {
+			secrets = append(secrets, *secret)
+		}
+	}
+
+	l.tickets = l.parseTicketsFromSecrets(secrets)
+	logInvalidTickets(l.tickets)
+	l.applyFilters().Sort()
+
+	initial := make([]types.MaprSecret, len(l.tickets))
+	copy(initial, l.tickets)
+
+	known := make(map[string]watchedSecret, len(initial))
+	for _, item := range initial {
+		known[watchKey(item.Secret.Namespace, item.Secret.Name, item.Key)] = newWatchedSecret(item)
+	}
+
+	events := make(chan WatchEvent)
+
+	go func() {
+		defer close(events)
+		defer factory.Shutdown()
+
+		l.watchLoop(ctx, known, changes, events, refreshUsage)
+	}()
+
+	return initial, events, nil
+}
+
+// watchListOptions returns the options used to watch the secrets. If only labeled secrets should
+// be listed, only the secrets marked as containing a ticket are watched, unless there are none.
+func (l *Lister) watchListOptions() (metaV1.ListOptions, error) {
+	if !l.labeledOnly {
+		return l.listOptions(), nil
+	}
+
+	opts := l.labeledListOptions()
+	opts.Limit = 1
+
+	secrets, err := l.client.CoreV1().Secrets(l.namespace).List(context.TODO(), opts)
+	if err != nil {
+		return metaV1.ListOptions{}, err
+	}
+
+	if len(secrets.Items) == 0 {
+		slog.Info("found no secrets labeled as containing a MapR ticket, watching all secrets instead", "selector", ticket.TicketSelector)
+		return l.listOptions(), nil
+	}
+
+	return l.labeledListOptions(), nil
+}
+
+// cacheUsage replaces the listers used to determine which tickets are in use with listers serving
+// the items of the last refresh. It returns the function refreshing all of them.
+func (l *Lister) cacheUsage() func() error {
+	var refreshers []func() error
+
+	// if we don't need to show in use, or filter by in use, there is nothing to cache
+	if l.showInUse || l.filterByInUse {
+		if l.volumeLister != nil {
+			cached := &cachedLister[types.MaprVolume]{lister: l.volumeLister}
+			l.volumeLister = cached
+			refreshers = append(refreshers, cached.refresh)
+		}
+
+		if l.inlineVolumeLister != nil {
+			cached := &cachedLister[types.MaprInlineVolume]{lister: l.inlineVolumeLister}
+			l.inlineVolumeLister = cached
+			refreshers = append(refreshers, cached.refresh)
+		}
+
+		if l.storageClassLister != nil {
+			cached := &cachedLister[types.MaprStorageClass]{lister: l.storageClassLister}
+			l.storageClassLister = cached
+			refreshers = append(refreshers, cached.refresh)
+		}
+	}
+
+	return func() error {
+		var errs error
+
+		for _, refresh := range refreshers {
+			errs = errors.Join(errs, refresh())
+		}
+
+		return errs
+	}
+}
+
+// watchLoop processes the changes received from the informer, periodically checks the known
+// tickets for expiry and refreshes the volumes and StorageClasses using tickets, until the context
+// is cancelled
+func (l *Lister) watchLoop(ctx context.Context, known map[string]watchedSecret, changes <-chan secretChange, events chan<- WatchEvent, refreshUsage func() error) {
+	ticker := time.NewTicker(watchExpiryCheckInterval)
+	defer ticker.Stop()
+
+	usageTicker := time.NewTicker(watchUsageRefreshInterval)
+	defer usageTicker.Stop()
+
+	emit := func(event WatchEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-changes:
+			for _, event := range l.processChange(known, change) {
+				if !emit(event) {
+					return
+				}
+			}
+		case <-ticker.C:
+			for _, event := range l.processExpiry(known) {
+				if !emit(event) {
+					return
+				}
+			}
+		case <-usageTicker.C:
+			if err := refreshUsage(); err != nil {
+				slog.Warn("failed to refresh volumes using MapR tickets, keeping the previous state", "error", err)
+			}
+		}
+	}
+}
+
+// processChange updates the known tickets with the given change and returns the resulting events.
+// A secret holding several tickets may result in one event per ticket.
+func (l *Lister) processChange(known map[string]watchedSecret, change secretChange) []WatchEvent {
+	// deleted secrets are only reported for the tickets that were reported before
+	if change.deleted {
+		return forgetSecret(known, change.secret.Namespace, change.secret.Name, nil)
+	}
+
+	var events []WatchEvent
+
+	current := l.evaluate(*change.secret)
+	matching := make(map[string]bool, len(current))
+
+	for _, ticket := range current {
+		key := watchKey(ticket.Secret.Namespace, ticket.Secret.Name, ticket.Key)
+		previous, wasKnown := known[key]
+		matching[key] = true
+
+		switch {
+		case !wasKnown:
+			known[key] = newWatchedSecret(ticket)
+			events = append(events, WatchEvent{Type: watch.Added, Secret: ticket})
+		// skip updates we have already seen, e.g. the initial events of the informer or resyncs
+		case previous.resourceVersion == change.secret.ResourceVersion && previous.expired == ticket.IsExpired():
+		default:
+			known[key] = newWatchedSecret(ticket)
+			events = append(events, WatchEvent{Type: watch.Modified, Secret: ticket})
+		}
+	}
+
+	// tickets that no longer match the filters, e.g. because they were removed from the secret,
+	// are reported as deleted
+	return append(events, forgetSecret(known, change.secret.Namespace, change.secret.Name, matching)...)
+}
+
+// processExpiry checks all known tickets for having expired since they were last reported and
+// returns the resulting events
+func (l *Lister) processExpiry(known map[string]watchedSecret) []WatchEvent {
+	var (
+		events  []WatchEvent
+		checked = make(map[string]bool)
+	)
+
+	for _, previous := range known {
+		if previous.expired || !previous.secret.IsExpired() {
+			continue
+		}
+
+		// re-evaluate each secret only once, even if several of its tickets expired
+		secretKey := watchKey(previous.secret.Secret.Namespace, previous.secret.Secret.Name, "")
+		if checked[secretKey] {
+			continue
+		}
+
+		checked[secretKey] = true
+
+		events = append(events, l.processChange(known, secretChange{secret: (*coreV1.Secret)(previous.secret.Secret)})...)
+	}
+
+	return events
+}
+
+// evaluate parses the tickets of the given secret and runs all filters of the lister on them. It
+// returns the tickets matching the filters, if any.
+func (l *Lister) evaluate(secret coreV1.Secret) []types.MaprSecret {
+	l.tickets = l.parseTicketsFromSecrets([]coreV1.Secret{secret})
+	l.applyFilters()
+
+	return l.tickets
+}
+
+// forgetSecret removes all known tickets of the given secret that are not contained in keep and
+// returns a deleted event for each of them
+func forgetSecret(known map[string]watchedSecret, namespace, name string, keep map[string]bool) []WatchEvent {
+	var events []WatchEvent
+
+	for key, previous := range known {
+		if previous.secret.Secret.Namespace != namespace || previous.secret.Secret.Name != name || keep[key] {
+			continue
+		}
+
+		delete(known, key)
+		events = append(events, WatchEvent{Type: watch.Deleted, Secret: previous.secret})
+	}
+
+	return events
+}
+
+// newWatchedSecret returns the watch state of the given ticket secret
+func newWatchedSecret(secret types.MaprSecret) watchedSecret {
+	return watchedSecret{
+		secret:          secret,
+		resourceVersion: secret.Secret.ResourceVersion,
+		expired:         secret.IsExpired(),
+	}
+}
+
+// watchKey returns the key used to identify a ticket of a secret while watching
+func watchKey(namespace, name, key string) string {
+	return namespace + "/" + name + "/" + key
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package secret_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/pod"
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/storageclass"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	clientTesting "k8s.io/client-go/testing"
+)
+
+func TestLister_Watch(t *testing.T) {
+	t.Parallel()
+
+	ticketWithExpiryTime := func(expiryTime time.Time) []byte {
+		return []byte(fmt.Sprintf(`{"cluster":"demo.mapr.com","ticket":{"expiryTime":%d}}`, expiryTime.Unix()))
+	}
+
+	client, watchStarted := newWatchClient(
+		secretFromTicketJSON(t, "default", "secret-a", ticketWithExpiryTime(time.Now().Add(time.Hour))),
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "no-ticket",
+				Namespace: "default",
+			},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	initial, events, err := NewLister(client, "default").Watch(ctx)
+
+	assert.NoError(t, err)
+	assertTicketSecret(t, initial, []expectedSecret{newExpectedSecret("default", "secret-a")})
+
+	<-watchStarted
+
+	secrets := client.CoreV1().Secrets("default")
+
+	// added secrets containing tickets are reported
+	_, err = secrets.Create(ctx, secretFromTicketJSON(t, "default", "secret-b", ticketWithExpiryTime(time.Now().Add(2*time.Second))), metaV1.CreateOptions{})
+	assert.NoError(t, err)
+	assertWatchEvent(t, events, watch.Added, "secret-b")
+
+	// secrets without tickets are ignored, so the next event is the modification of secret-a
+	_, err = secrets.Create(ctx, &coreV1.Secret{ObjectMeta: metaV1.ObjectMeta{Name: "other", Namespace: "default"}}, metaV1.CreateOptions{})
+	assert.NoError(t, err)
+
+	updated := secretFromTicketJSON(t, "default", "secret-a", ticketWithExpiryTime(time.Now().Add(2*time.Hour)))
+	updated.ResourceVersion = "2"
+	_, err = secrets.Update(ctx, updated, metaV1.UpdateOptions{})
+	assert.NoError(t, err)
+	assertWatchEvent(t, events, watch.Modified, "secret-a")
+
+	// tickets that expire while being watched are re-emitted
+	event := assertWatchEvent(t, events, watch.Modified, "secret-b")
+	assert.True(t, event.Secret.Ticket.IsExpired())
+
+	// deleted secrets are reported with their last known state
+	err = secrets.Delete(ctx, "secret-a", metaV1.DeleteOptions{})
+	assert.NoError(t, err)
+	assertWatchEvent(t, events, watch.Deleted, "secret-a")
+
+	// the channel is closed once the context is cancelled
+	cancel()
+
+	for range events {
+	}
+}
+
+func TestLister_Watch_WithFilterOnlyUnexpired(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newWatchClient(
+		secretFromTicketJSON(t, "default", "secret-a", []byte(fmt.Sprintf(`{"ticket":{"expiryTime":%d}}`, time.Now().Add(2*time.Second).Unix()))),
+		secretFromTicketJSON(t, "default", "secret-b", []byte(fmt.Sprintf(`{"ticket":{"expiryTime":%d}}`, time.Now().Add(-time.Hour).Unix()))),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	initial, events, err := NewLister(client, "default", WithFilterOnlyUnexpired()).Watch(ctx)
+
+	assert.NoError(t, err)
+	assertTicketSecret(t, initial, []expectedSecret{newExpectedSecret("default", "secret-a")})
+
+	// tickets no longer matching the filters after expiring are reported as deleted
+	assertWatchEvent(t, events, watch.Deleted, "secret-a")
+}
+
+func TestLister_Watch_WithShowInUse(t *testing.T) {
+	t.Parallel()
+
+	client, watchStarted := newWatchClient(
+		secretFromTicketJSON(t, "default", "secret-a", []byte(`{"cluster":"demo.mapr.com"}`)),
+		&coreV1.PersistentVolume{
+			ObjectMeta: metaV1.ObjectMeta{Name: "volume-1"},
+			Spec: coreV1.PersistentVolumeSpec{
+				PersistentVolumeSource: coreV1.PersistentVolumeSource{
+					CSI: &coreV1.CSIPersistentVolumeSource{
+						Driver:               types.MaprCSIProvisionerKDF,
+						NodePublishSecretRef: &coreV1.SecretReference{Namespace: "default", Name: "secret-b"},
+					},
+				},
+			},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	l := NewLister(client, "default",
+		WithShowInUse(),
+		WithVolumeLister(volume.NewLister(client, util.SecretAll, util.NamespaceAll)),
+		WithInlineVolumeLister(pod.NewInlineVolumeLister(client, util.NamespaceAll)),
+		WithStorageClassLister(storageclass.NewLister(client)),
+	)
+
+	initial, events, err := l.Watch(ctx)
+
+	assert.NoError(t, err)
+	assertTicketSecret(t, initial, []expectedSecret{newExpectedSecret("default", "secret-a")})
+
+	<-watchStarted
+
+	// the usage of added secrets is determined from the volumes listed when the watch started
+	_, err = client.CoreV1().Secrets("default").Create(ctx, secretFromTicketJSON(t, "default", "secret-b", []byte(`{"cluster":"demo.mapr.com"}`)), metaV1.CreateOptions{})
+	assert.NoError(t, err)
+
+	event := assertWatchEvent(t, events, watch.Added, "secret-b")
+	assert.Equal(t, uint32(1), event.Secret.NumPVC)
+
+	lists := make(map[string]int)
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "list" {
+			lists[action.GetResource().Resource]++
+		}
+	}
+
+	assert.Equal(t, 1, lists["persistentvolumes"])
+	assert.Equal(t, 1, lists["pods"])
+	assert.Equal(t, 1, lists["storageclasses"])
+}
+
+// newWatchClient returns a fake client containing the given objects and a channel that is closed
+// once the first watch on secrets has been started, as the fake client drops events that happen
+// before that
+func newWatchClient(objects ...runtime.Object) (*fake.Clientset, <-chan struct{}) {
+	client := fake.NewSimpleClientset(objects...)
+	watchStarted := make(chan struct{})
+	once := sync.Once{}
+
+	client.PrependWatchReactor("secrets", func(action clientTesting.Action) (bool, watch.Interface, error) {
+		w, err := client.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return false, nil, err
+		}
+
+		once.Do(func() { close(watchStarted) })
+
+		return true, w, nil
+	})
+
+	return client, watchStarted
+}
+
+func assertWatchEvent(t *testing.T, events <-chan WatchEvent, eventType watch.EventType, name string) WatchEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		assert.Equal(t, eventType, event.Type)
+		assert.Equal(t, name, event.Secret.Secret.Name)
+
+		return event
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for %s event of secret %s", eventType, name)
+	}
+
+	return WatchEvent{}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package storageclass implements the StorageClass lister. It is responsible for listing all
+// StorageClasses in the cluster that use one of the MapR CSI provisioners, together with the
+// secrets referenced by their parameters.
+//
+// Dynamic provisioning passes the provisioner and node-publish secrets to the CSI driver based on
+// the StorageClass parameters. These references may contain templates like ${pvc.namespace} that
+// are resolved for each claim, so the lister resolves them against the claims using the
+// StorageClass. The resolved secrets are then looked up using the secret lister to report missing
+// or expired tickets.
+package storageclass
+
+import (
+	"context"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// secretLister is the interface that a secret lister must implement.
+type secretLister interface {
+	List() ([]types.MaprSecret, error)
+}
+
+// Lister is the struct that is used to list StorageClasses using one of the MapR CSI provisioners.
+type Lister struct {
+	client kubernetes.Interface
+
+	secretLister      secretLister
+	filterOnlyBroken  bool
+	sortBy            []SortOption
+	labelSelector     string
+	fieldSelector     string
+	storageClasses    []types.MaprStorageClass
+	claimsByClassName map[string][]*types.PersistentVolumeClaim
+}
+
+// NewLister creates a new StorageClass lister. It requires a Kubernetes client to operate on. It
+// also accepts a list of options that can be used to configure the lister.
+func NewLister(client kubernetes.Interface, opts ...ListerOption) *Lister {
+	l := &Lister{
+		client: client,
+		sortBy: DefaultSortBy,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// List returns a list of StorageClasses using one of the MapR CSI provisioners, with one entry per
+// StorageClass and referenced secret.
+func (l *Lister) List() ([]types.MaprStorageClass, error) {
+	if err := l.getClaims(); err != nil {
+		return nil, err
+	}
+
+	if err := l.getStorageClasses(); err != nil {
+		return nil, err
+	}
+
+	l.collectTickets().
+		filterOnlyBrokenTickets().
+		sort()
+
+	return l.storageClasses, nil
+}
+
+// getClaims retrieves all claims in the cluster and groups them by their StorageClass
+func (l *Lister) getClaims() error {
+	claims, err := l.client.CoreV1().PersistentVolumeClaims(metaV1.NamespaceAll).List(context.TODO(), metaV1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	l.claimsByClassName = make(map[string][]*types.PersistentVolumeClaim)
+
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+
+		if claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName == "" {
+			continue
+		}
+
+		className := *claim.Spec.StorageClassName
+		l.claimsByClassName[className] = append(l.claimsByClassName[className], (*types.PersistentVolumeClaim)(claim))
+	}
+
+	return nil
+}
+
+// listOptions returns the options used to list the StorageClasses, applying the label and field
+// selectors of the Lister
+func (l *Lister) listOptions() metaV1.ListOptions {
+	return metaV1.ListOptions{
+		LabelSelector: l.labelSelector,
+		FieldSelector: l.fieldSelector,
+	}
+}
+
+// getStorageClasses retrieves all StorageClasses using one of the MapR CSI provisioners and
+// resolves the secrets referenced by their parameters
+func (l *Lister) getStorageClasses() error {
+	storageClasses, err := l.client.StorageV1().StorageClasses().List(context.TODO(), l.listOptions())
+	if err != nil {
+		return err
+	}
+
+	l.storageClasses = []types.MaprStorageClass{}
+
+	for i := range storageClasses.Items {
+		sc := (*types.StorageClass)(&storageClasses.Items[i])
+
+		if !sc.IsMaprCSIBased() {
+			continue
+		}
+
+		for _, ref := range sc.GetSecretReferences() {
+			l.storageClasses = append(l.storageClasses, l.resolveReference(sc, ref)...)
+		}
+	}
+
+	return nil
+}
+
+// resolveReference resolves the given secret reference of the StorageClass. Incomplete references
+// result in a single unresolved entry, as the CSI sidecars can't resolve them either. References
+// without templates result in a single entry, templated references result in one entry per
+// distinct secret resolved using the claims of the StorageClass, or a single unresolved entry if
+// no claim uses the StorageClass.
+func (l *Lister) resolveReference(sc *types.StorageClass, ref types.StorageClassSecretReference) []types.MaprStorageClass {
+	if !ref.IsComplete() {
+		return []types.MaprStorageClass{
+			{StorageClass: sc, Reference: ref},
+		}
+	}
+
+	if !ref.IsTemplated() {
+		return []types.MaprStorageClass{
+			{StorageClass: sc, Reference: ref, Namespace: ref.Namespace, Name: ref.Name},
+		}
+	}
+
+	var (
+		resolved []types.MaprStorageClass
+		seen     = make(map[string]bool)
+	)
+
+	for _, claim := range l.claimsByClassName[sc.GetName()] {
+		namespace, name, ok := ref.Resolve(claim)
+		if !ok || seen[namespace+"/"+name] {
+			continue
+		}
+
+		seen[namespace+"/"+name] = true
+
+		resolved = append(resolved, types.MaprStorageClass{
+			StorageClass: sc,
+			Reference:    ref,
+			Namespace:    namespace,
+			Name:         name,
+		})
+	}
+
+	if len(resolved) == 0 {
+		return []types.MaprStorageClass{
+			{StorageClass: sc, Reference: ref},
+		}
+	}
+
+	return resolved
+}
+
+// collectTickets collects the MapR tickets for the resolved secret references, if available.
+func (l *Lister) collectTickets() *Lister {
+	// return early if there is no secret lister
+	if l.secretLister == nil {
+		return l
+	}
+
+	// return early if there are no StorageClasses
+	if len(l.storageClasses) == 0 {
+		return l
+	}
+
+	tickets, err := l.secretLister.List()
+	if err != nil {
+		return l
+	}
+
+	ticketsByKey := make(map[string]*types.MaprSecret, len(tickets))
+	for i := range tickets {
+		ticketsByKey[tickets[i].Secret.Namespace+"/"+tickets[i].Secret.Name] = &tickets[i]
+	}
+
+	for i := range l.storageClasses {
+		sc := &l.storageClasses[i]
+
+		if sc.IsResolved() {
+			sc.Ticket = ticketsByKey[sc.Namespace+"/"+sc.Name]
+		}
+	}
+
+	return l
+}
+
+// filterOnlyBrokenTickets filters the StorageClasses to those whose referenced secret does not
+// exist, does not contain a valid ticket or whose ticket has expired
+func (l *Lister) filterOnlyBrokenTickets() *Lister {
+	// if the filter is not enabled, we can skip this step
+	if !l.filterOnlyBroken {
+		return l
+	}
+
+	filtered := make([]types.MaprStorageClass, 0, len(l.storageClasses))
+
+	for _, sc := range l.storageClasses {
+		if sc.IsBroken() {
+			filtered = append(filtered, sc)
+		}
+	}
+
+	l.storageClasses = filtered
+
+	return l
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package storageclass_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/secret"
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/storageclass"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	storageV1 "k8s.io/api/storage/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+type expectedStorageClass struct {
+	name       string
+	secretType string
+	namespace  string
+	secret     string
+	broken     bool
+}
+
+func TestLister_Default(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		opts     []ListerOption
+		expected []expectedStorageClass
+	}{
+		{
+			name:     "no storage classes",
+			expected: []expectedStorageClass{},
+		},
+		{
+			name: "storage class with other provisioner",
+			objects: []runtime.Object{
+				newStorageClass("standard", "random-provisioner", map[string]string{
+					"csi.storage.k8s.io/provisioner-secret-name":      "ticket",
+					"csi.storage.k8s.io/provisioner-secret-namespace": "default",
+				}),
+			},
+			expected: []expectedStorageClass{},
+		},
+		{
+			name: "storage class with fixed secret references",
+			objects: []runtime.Object{
+				newStorageClass("mapr", types.MaprCSIProvisionerKDF, map[string]string{
+					"csi.storage.k8s.io/provisioner-secret-name":       "provisioner-ticket",
+					"csi.storage.k8s.io/provisioner-secret-namespace":  "mapr",
+					"csi.storage.k8s.io/node-publish-secret-name":      "user-ticket",
+					"csi.storage.k8s.io/node-publish-secret-namespace": "mapr",
+				}),
+				newSecret(t, "mapr", "provisioner-ticket", now.Add(time.Hour)),
+				newSecret(t, "mapr", "user-ticket", now.Add(-time.Hour)),
+			},
+			expected: []expectedStorageClass{
+				{name: "mapr", secretType: types.SecretTypeNodePublish, namespace: "mapr", secret: "user-ticket", broken: true},
+				{name: "mapr", secretType: types.SecretTypeProvisioner, namespace: "mapr", secret: "provisioner-ticket"},
+			},
+		},
+		{
+			name: "storage class with templated secret reference",
+			objects: []runtime.Object{
+				newStorageClass("mapr", types.MaprCSIProvisionerNFSKDF, map[string]string{
+					"csi.storage.k8s.io/node-publish-secret-name":      "${pvc.name}-ticket",
+					"csi.storage.k8s.io/node-publish-secret-namespace": "${pvc.namespace}",
+				}),
+				newClaim("team-a", "data", "mapr"),
+				newClaim("team-a", "logs", "other"),
+				newClaim("team-b", "data", "mapr"),
+				newSecret(t, "team-a", "data-ticket", now.Add(time.Hour)),
+			},
+			expected: []expectedStorageClass{
+				{name: "mapr", secretType: types.SecretTypeNodePublish, namespace: "team-a", secret: "data-ticket"},
+				{name: "mapr", secretType: types.SecretTypeNodePublish, namespace: "team-b", secret: "data-ticket", broken: true},
+			},
+		},
+		{
+			name: "storage class with unresolved template",
+			objects: []runtime.Object{
+				newStorageClass("mapr", types.MaprCSIProvisionerKDF, map[string]string{
+					"csi.storage.k8s.io/node-publish-secret-name":      "ticket",
+					"csi.storage.k8s.io/node-publish-secret-namespace": "${pvc.namespace}",
+				}),
+			},
+			expected: []expectedStorageClass{
+				{name: "mapr", secretType: types.SecretTypeNodePublish},
+			},
+		},
+		{
+			name: "storage class with incomplete secret references",
+			objects: []runtime.Object{
+				newStorageClass("mapr", types.MaprCSIProvisionerKDF, map[string]string{
+					"csi.storage.k8s.io/provisioner-secret-name":       "ticket",
+					"csi.storage.k8s.io/node-publish-secret-namespace": "team-a",
+				}),
+				newClaim("team-a", "data", "mapr"),
+				newSecret(t, "team-a", "ticket", now.Add(time.Hour)),
+			},
+			expected: []expectedStorageClass{
+				{name: "mapr", secretType: types.SecretTypeNodePublish, broken: true},
+				{name: "mapr", secretType: types.SecretTypeProvisioner, broken: true},
+			},
+		},
+		{
+			name: "only broken",
+			objects: []runtime.Object{
+				newStorageClass("mapr", types.MaprCSIProvisionerKDF, map[string]string{
+					"csi.storage.k8s.io/provisioner-secret-name":       "provisioner-ticket",
+					"csi.storage.k8s.io/provisioner-secret-namespace":  "mapr",
+					"csi.storage.k8s.io/node-publish-secret-name":      "missing",
+					"csi.storage.k8s.io/node-publish-secret-namespace": "mapr",
+				}),
+				newStorageClass("unused", types.MaprCSIProvisionerKDF, map[string]string{
+					"csi.storage.k8s.io/node-publish-secret-name":      "ticket",
+					"csi.storage.k8s.io/node-publish-secret-namespace": "${pvc.namespace}",
+				}),
+				newStorageClass("incomplete", types.MaprCSIProvisionerKDF, map[string]string{
+					"csi.storage.k8s.io/node-publish-secret-name": "ticket",
+				}),
+				newSecret(t, "mapr", "provisioner-ticket", now.Add(time.Hour)),
+			},
+			opts: []ListerOption{WithFilterOnlyBroken()},
+			expected: []expectedStorageClass{
+				{name: "incomplete", secretType: types.SecretTypeNodePublish, broken: true},
+				{name: "mapr", secretType: types.SecretTypeNodePublish, namespace: "mapr", secret: "missing", broken: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset(test.objects...)
+
+			opts := append([]ListerOption{
+				WithSecretLister(secret.NewLister(client, util.NamespaceAll)),
+			}, test.opts...)
+
+			actual, err := NewLister(client, opts...).List()
+
+			assert.NoError(t, err)
+			assertStorageClasses(t, test.expected, actual)
+		})
+	}
+}
+
+func TestLister_WithSortBy(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	client := fake.NewSimpleClientset(
+		newStorageClass("a", types.MaprCSIProvisionerKDF, map[string]string{
+			"csi.storage.k8s.io/node-publish-secret-name":      "short",
+			"csi.storage.k8s.io/node-publish-secret-namespace": "default",
+		}),
+		newStorageClass("b", types.MaprCSIProvisionerKDF, map[string]string{
+			"csi.storage.k8s.io/node-publish-secret-name":      "long",
+			"csi.storage.k8s.io/node-publish-secret-namespace": "default",
+		}),
+		newSecret(t, "default", "short", now.Add(time.Hour)),
+		newSecret(t, "default", "long", now.Add(2*time.Hour)),
+	)
+
+	l := NewLister(client,
+		WithSecretLister(secret.NewLister(client, util.NamespaceAll)),
+		WithSortBy([]SortOption{SortBySecretName}),
+	)
+
+	actual, err := l.List()
+
+	assert.NoError(t, err)
+	assertStorageClasses(t, []expectedStorageClass{
+		{name: "b", secretType: types.SecretTypeNodePublish, namespace: "default", secret: "long"},
+		{name: "a", secretType: types.SecretTypeNodePublish, namespace: "default", secret: "short"},
+	}, actual)
+}
+
+func assertStorageClasses(t *testing.T, expected []expectedStorageClass, actual []types.MaprStorageClass) {
+	t.Helper()
+
+	if !assert.Len(t, actual, len(expected)) {
+		return
+	}
+
+	for i := range expected {
+		assert.Equal(t, expected[i].name, actual[i].StorageClass.GetName())
+		assert.Equal(t, expected[i].secretType, actual[i].Reference.Type)
+		assert.Equal(t, expected[i].namespace, actual[i].Namespace)
+		assert.Equal(t, expected[i].secret, actual[i].Name)
+		assert.Equal(t, expected[i].broken, actual[i].IsBroken())
+	}
+}
+
+func newStorageClass(name, provisioner string, parameters map[string]string) *storageV1.StorageClass {
+	return &storageV1.StorageClass{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: name,
+		},
+		Provisioner: provisioner,
+		Parameters:  parameters,
+	}
+}
+
+func newClaim(namespace, name, storageClassName string) *coreV1.PersistentVolumeClaim {
+	return &coreV1.PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: coreV1.PersistentVolumeClaimSpec{
+			StorageClassName: ptr.To(storageClassName),
+		},
+	}
+}
+
+func newSecret(t *testing.T, namespace, name string, expiry time.Time) *coreV1.Secret {
+	t.Helper()
+
+	maprTicket := ticket.NewMaprTicket()
+	maprTicket.Cluster = "demo.mapr.com"
+	maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(expiry.Unix()))
+
+	secret, err := ticket.NewSecret(namespace, name, maprTicket)
+	assert.NoError(t, err)
+
+	return secret
+}
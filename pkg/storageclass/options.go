@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package storageclass
+
+// ListerOption is a function that can be used to configure the StorageClass lister.
+type ListerOption func(*Lister)
+
+// WithSecretLister configures the StorageClass lister to use the given secret lister to look up
+// the tickets referenced by the StorageClasses.
+func WithSecretLister(secretLister secretLister) ListerOption {
+	return func(l *Lister) {
+		l.secretLister = secretLister
+	}
+}
+
+// WithFilterOnlyBroken configures the StorageClass lister to only list StorageClasses whose
+// referenced ticket is missing, invalid or expired. Requires a secret lister to be configured.
+func WithFilterOnlyBroken() ListerOption {
+	return func(l *Lister) {
+		l.filterOnlyBroken = true
+	}
+}
+
+// WithSortBy configures the StorageClass lister to sort the StorageClasses by the given sort
+// options.
+func WithSortBy(sortBy []SortOption) ListerOption {
+	return func(l *Lister) {
+		l.sortBy = sortBy
+	}
+}
+
+// WithLabelSelector sets the label selector used by the Lister to select the StorageClasses to list
+func WithLabelSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.labelSelector = selector
+	}
+}
+
+// WithFieldSelector sets the field selector used by the Lister to select the StorageClasses to list
+func WithFieldSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.fieldSelector = selector
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package storageclass
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	storageV1 "k8s.io/api/storage/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+var (
+	tableColumnDefinitions = []metaV1.TableColumnDefinition{
+		{
+			Name:        "Name",
+			Type:        "string",
+			Format:      "name",
+			Description: "Name of the StorageClass",
+			Priority:    0,
+		},
+		{
+			Name:        "Provisioner",
+			Type:        "string",
+			Description: "MapR CSI provisioner used by the StorageClass",
+			Priority:    1,
+		},
+		{
+			Name:        "Secret Type",
+			Type:        "string",
+			Description: "Type of the secret referenced by the StorageClass, e.g. provisioner or node-publish",
+			Priority:    0,
+		},
+		{
+			Name:        "Template",
+			Type:        "string",
+			Description: "Secret reference as specified in the StorageClass parameters",
+			Priority:    1,
+		},
+		{
+			Name:        "Secret Namespace",
+			Type:        "string",
+			Description: "Namespace of the secret containing the MapR ticket",
+			Priority:    0,
+		},
+		{
+			Name:        "Secret",
+			Type:        "string",
+			Description: "Name of the secret containing the MapR ticket",
+			Priority:    0,
+		},
+		{
+			Name:        "Ticket Status",
+			Type:        "string",
+			Description: "Status of the MapR ticket",
+			Priority:    0,
+		},
+		{
+			Name:        "Age",
+			Type:        "string",
+			Format:      "date-time",
+			Description: "Creation time of the StorageClass",
+			Priority:    0,
+		},
+	}
+)
+
+// Print prints the StorageClasses to the given output stream in a tabular format known by
+// kubectl.
+func Print(cmd *cobra.Command, storageClasses []types.MaprStorageClass) error {
+	format := cmd.Flag("output").Value.String()
+
+	// generate the table
+	table := generateTable(storageClasses)
+
+	// print the table
+	printer := printers.NewTablePrinter(printers.PrintOptions{
+		Wide: format == "wide",
+	})
+
+	err := printer.PrintObj(table, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PrintObjects prints the StorageClasses as a structured list using the given printer, e.g. a
+// JSON, YAML, JSONPath, Go template or custom columns printer.
+func PrintObjects(cmd *cobra.Command, printer printers.ResourcePrinter, storageClasses []types.MaprStorageClass) error {
+	list, err := generateList(storageClasses)
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(list, cmd.OutOrStdout())
+}
+
+// generateTable generates a table from the given StorageClasses.
+func generateTable(storageClasses []types.MaprStorageClass) *metaV1.Table {
+	rows := generateRows(storageClasses)
+
+	return &metaV1.Table{
+		ColumnDefinitions: tableColumnDefinitions,
+		Rows:              rows,
+	}
+}
+
+// generateList generates a list of structured items from the given StorageClasses.
+func generateList(storageClasses []types.MaprStorageClass) (*unstructured.UnstructuredList, error) {
+	items := make([]*types.MaprStorageClassItem, 0, len(storageClasses))
+
+	for i := range storageClasses {
+		items = append(items, storageClasses[i].Item())
+	}
+
+	return types.NewList(items)
+}
+
+// generateRows generates the rows for the given StorageClasses.
+func generateRows(storageClasses []types.MaprStorageClass) []metaV1.TableRow {
+	rows := make([]metaV1.TableRow, 0, len(storageClasses))
+
+	for _, sc := range storageClasses {
+		rows = append(rows, *generateRow(&sc))
+	}
+
+	return rows
+}
+
+// generateRow generates a row for the given StorageClass.
+func generateRow(sc *types.MaprStorageClass) *metaV1.TableRow {
+	row := &metaV1.TableRow{
+		Object: runtime.RawExtension{
+			Object: (*storageV1.StorageClass)(sc.StorageClass),
+		},
+	}
+
+	row.Cells = []any{
+		sc.StorageClass.GetName(),
+		sc.StorageClass.Provisioner,
+		sc.Reference.Type,
+		sc.Reference.String(),
+		sc.Namespace,
+		sc.Name,
+		sc.GetStatusString(),
+		util.ShortHumanDurationUntilNow(sc.StorageClass.CreationTimestamp.Time),
+	}
+
+	return row
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package storageclass
+
+import (
+	"sort"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+)
+
+// SortOption is the type of a sort option, basically a wrapper around a string to provide
+// type safety.
+type SortOption string
+
+// All valid sort options are defined here
+const (
+	SortByName            SortOption = "name"
+	SortBySecretType      SortOption = "secret.type"
+	SortBySecretNamespace SortOption = "secret.namespace"
+	SortBySecretName      SortOption = "secret.name"
+	SortByExpiration      SortOption = "expiration"
+)
+
+var (
+	// SortOptionsList is the list of valid sort options
+	SortOptionsList = []string{
+		SortByName.String(),
+		SortBySecretType.String(),
+		SortBySecretNamespace.String(),
+		SortBySecretName.String(),
+		SortByExpiration.String(),
+	}
+
+	// DefaultSortBy is the default sort order
+	DefaultSortBy = []SortOption{
+		SortByName,
+		SortBySecretType,
+		SortBySecretNamespace,
+		SortBySecretName,
+	}
+)
+
+// String returns the string representation of the sort option.
+func (s SortOption) String() string {
+	return string(s)
+}
+
+func sortByName(storageClasses []types.MaprStorageClass) {
+	sort.Slice(storageClasses, func(i, j int) bool {
+		return storageClasses[i].StorageClass.GetName() < storageClasses[j].StorageClass.GetName()
+	})
+}
+
+func sortBySecretType(storageClasses []types.MaprStorageClass) {
+	sort.Slice(storageClasses, func(i, j int) bool {
+		return storageClasses[i].Reference.Type < storageClasses[j].Reference.Type
+	})
+}
+
+func sortBySecretNamespace(storageClasses []types.MaprStorageClass) {
+	sort.Slice(storageClasses, func(i, j int) bool {
+		return storageClasses[i].Namespace < storageClasses[j].Namespace
+	})
+}
+
+func sortBySecretName(storageClasses []types.MaprStorageClass) {
+	sort.Slice(storageClasses, func(i, j int) bool {
+		return storageClasses[i].Name < storageClasses[j].Name
+	})
+}
+
+func sortByExpiration(storageClasses []types.MaprStorageClass) {
+	sort.Slice(storageClasses, func(i, j int) bool {
+		return storageClasses[i].Ticket.GetExpirationTime().Before(storageClasses[j].Ticket.GetExpirationTime())
+	})
+}
+
+// sort sorts the items by the specified sort options, in reverse order of the
+// order in which they are specified. This makes for a more natural sort result
+// when using multiple sort options.
+func (l *Lister) sort() *Lister {
+	// reverse the order of the sort options
+	order := make([]SortOption, len(l.sortBy))
+	for i, j := 0, len(l.sortBy)-1; i < len(l.sortBy); i, j = i+1, j-1 {
+		order[i] = l.sortBy[j]
+	}
+
+	// sort the items by the specified sort options
+	for _, sortOption := range order {
+		switch sortOption {
+		case SortByName:
+			sortByName(l.storageClasses)
+		case SortBySecretType:
+			sortBySecretType(l.storageClasses)
+		case SortBySecretNamespace:
+			sortBySecretNamespace(l.storageClasses)
+		case SortBySecretName:
+			sortBySecretName(l.storageClasses)
+		case SortByExpiration:
+			sortByExpiration(l.storageClasses)
+		}
+	}
+
+	return l
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package ticket
+
+import (
+	"fmt"
+
+	"github.com/nobbs/mapr-ticket-parser/pkg/parse"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// LabelCluster is the label and annotation key used to store the MapR cluster of a ticket on
+	// the secret containing it
+	LabelCluster = "mapr.com/cluster"
+
+	// LabelUser is the label and annotation key used to store the MapR user of a ticket on the
+	// secret containing it
+	LabelUser = "mapr.com/user"
+
+	// LabelTicket is the label key used to mark secrets containing a ticket, so they can be listed
+	// using a label selector instead of retrieving all secrets
+	LabelTicket = "mapr.com/ticket"
+
+	// LabelTicketValue is the value of LabelTicket on secrets containing a ticket
+	LabelTicketValue = "true"
+
+	// TicketSelector is the label selector matching secrets marked as containing a ticket
+	TicketSelector = LabelTicket + "=" + LabelTicketValue
+
+	// AnnotationExpiry is the annotation key used to store the expiration time of a ticket on the
+	// secret containing it, formatted using DefaultTimeFormat
+	AnnotationExpiry = "mapr.com/ticket-expiry"
+
+	// AnnotationStatus is the annotation key used by the controller to store the status of the
+	// ticket on the secret containing it, e.g. whether it is expired or expiring soon
+	AnnotationStatus = "mapr.com/ticket-status"
+)
+
+// NewSecret returns a new secret with the given namespace and name that contains the encoded
+// ticket under SecretMaprTicketKey. The cluster, user and expiration time of the ticket are added
+// as annotations. The cluster and user are also added as labels, as long as they are valid label
+// values, and the secret is marked as containing a ticket using LabelTicket.
+func NewSecret(namespace, name string, ticket *Ticket) (*coreV1.Secret, error) {
+	return NewSecretWithKey(namespace, name, SecretMaprTicketKey, ticket)
+}
+
+// NewSecretWithKey works like NewSecret, but stores the encoded ticket under the given key
+func NewSecretWithKey(namespace, name, key string, ticket *Ticket) (*coreV1.Secret, error) {
+	ticketBytes, err := parse.Marshal(ticket.AsMaprTicket())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ticket: %w", err)
+	}
+
+	return newSecret(namespace, name, key, ticketBytes, ticket), nil
+}
+
+// NewSecretFromBytes works like NewSecretWithKey, but stores the given encoded ticket as is instead
+// of encoding the parsed ticket again, so the secret contains exactly the ticket it was created
+// from. It returns an error if the ticket can't be parsed.
+func NewSecretFromBytes(namespace, name, key string, ticketBytes []byte) (*coreV1.Secret, error) {
+	ticket, err := parse.Unmarshal(ticketBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ticket: %w", err)
+	}
+
+	return newSecret(namespace, name, key, ticketBytes, (*Ticket)(ticket)), nil
+}
+
+// newSecret returns a new secret containing the encoded ticket under the given key, with the
+// metadata describing the ticket set
+func newSecret(namespace, name, key string, ticketBytes []byte, ticket *Ticket) *coreV1.Secret {
+	secret := &coreV1.Secret{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Type: coreV1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			key: ticketBytes,
+		},
+	}
+
+	SetSecretMetadata(secret, ticket)
+
+	return secret
+}
+
+// SetSecretMetadata sets the labels and annotations describing the given ticket on the secret,
+// overwriting any existing values for the same keys, and marks it as containing a ticket
+func SetSecretMetadata(secret *coreV1.Secret, ticket *Ticket) {
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+
+	metadata := map[string]string{
+		LabelCluster: ticket.GetCluster(),
+		LabelUser:    ticket.GetUser(),
+	}
+
+	for key, value := range metadata {
+		secret.Annotations[key] = value
+
+		// only set the label if the value can actually be used as a label value, otherwise make
+		// sure we don't leave a stale label from a previous ticket behind
+		if len(validation.IsValidLabelValue(value)) == 0 {
+			secret.Labels[key] = value
+		} else {
+			delete(secret.Labels, key)
+		}
+	}
+
+	secret.Labels[LabelTicket] = LabelTicketValue
+	secret.Annotations[AnnotationExpiry] = ticket.ExpirationTime().UTC().Format(DefaultTimeFormat)
+}
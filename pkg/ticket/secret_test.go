@@ -0,0 +1,132 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package ticket_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/mapr-ticket-parser/pkg/parse"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestNewSecret(t *testing.T) {
+	t.Parallel()
+
+	expiry := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newTicket := func(cluster, user string) *Ticket {
+		ticket := NewMaprTicket()
+		ticket.Cluster = cluster
+		ticket.UserCreds.UserName = ptr.To(user)
+		ticket.TicketAndKey.ExpiryTime = ptr.To(uint64(expiry.Unix()))
+		return ticket
+	}
+
+	tests := []struct {
+		name            string
+		ticket          *Ticket
+		wantLabels      map[string]string
+		wantAnnotations map[string]string
+	}{
+		{
+			name:   "valid label values",
+			ticket: newTicket("demo.mapr.com", "mapr"),
+			wantLabels: map[string]string{
+				LabelCluster: "demo.mapr.com",
+				LabelUser:    "mapr",
+				LabelTicket:  LabelTicketValue,
+			},
+			wantAnnotations: map[string]string{
+				LabelCluster:     "demo.mapr.com",
+				LabelUser:        "mapr",
+				AnnotationExpiry: "2030-01-02T03:04:05Z",
+			},
+		},
+		{
+			name:   "invalid label value is only set as annotation",
+			ticket: newTicket("demo.mapr.com", "DOMAIN\\mapr"),
+			wantLabels: map[string]string{
+				LabelCluster: "demo.mapr.com",
+				LabelTicket:  LabelTicketValue,
+			},
+			wantAnnotations: map[string]string{
+				LabelCluster:     "demo.mapr.com",
+				LabelUser:        "DOMAIN\\mapr",
+				AnnotationExpiry: "2030-01-02T03:04:05Z",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			secret, err := NewSecret("default", "secret", test.ticket)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "default", secret.Namespace)
+			assert.Equal(t, "secret", secret.Name)
+			assert.Equal(t, coreV1.SecretTypeOpaque, secret.Type)
+			assert.Equal(t, test.wantLabels, secret.Labels)
+			assert.Equal(t, test.wantAnnotations, secret.Annotations)
+
+			// the ticket stored in the secret must be the same as the one we started with
+			parsed, err := NewMaprTicketFromSecret(secret)
+
+			assert.NoError(t, err)
+			assert.True(t, parse.Equal(test.ticket.AsMaprTicket(), parsed.AsMaprTicket()))
+		})
+	}
+}
+
+func TestNewSecretFromBytes(t *testing.T) {
+	t.Parallel()
+
+	ticketBytes := []byte("demo.mapr.com HQvWzXnHpDGcBithT6ohjzlVGzHvOUfUC4PK5XNpQ0qi99wP/+GXU6XII5VEK0tQn00HyZCno8YJUrM=")
+
+	secret, err := NewSecretFromBytes("default", "secret", "TICKET", ticketBytes)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "demo.mapr.com", secret.Labels[LabelCluster])
+	assert.Equal(t, "mapr", secret.Labels[LabelUser])
+
+	// the ticket must be stored as is, encoding it again would change the encrypted payload
+	assert.Equal(t, map[string][]byte{"TICKET": ticketBytes}, secret.Data)
+
+	_, err = NewSecretFromBytes("default", "secret", "TICKET", []byte("not a ticket"))
+	assert.ErrorContains(t, err, "failed to parse ticket")
+}
+
+func TestSetSecretMetadata(t *testing.T) {
+	t.Parallel()
+
+	secret := &coreV1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Labels: map[string]string{
+				"app":     "test",
+				LabelUser: "old-user",
+			},
+		},
+	}
+
+	ticket := NewMaprTicket()
+	ticket.Cluster = "demo.mapr.com"
+	ticket.UserCreds.UserName = ptr.To("invalid user")
+
+	SetSecretMetadata(secret, ticket)
+
+	assert.Equal(t, map[string]string{"app": "test", LabelCluster: "demo.mapr.com", LabelTicket: LabelTicketValue}, secret.Labels)
+	assert.Equal(t, "invalid user", secret.Annotations[LabelUser])
+	assert.Contains(t, secret.Annotations, AnnotationExpiry)
+}
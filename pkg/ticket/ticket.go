@@ -14,6 +14,9 @@ package ticket
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
@@ -55,10 +58,31 @@ func (err ErrSecretDoesNotContainMaprTicket) Error() string {
 // SecretContainsMaprTicket returns true if the secret contains the key typically
 // used for MapR tickets
 func SecretContainsMaprTicket(secret *coreV1.Secret) bool {
-	_, ok := secret.Data[SecretMaprTicketKey]
+	return SecretContainsMaprTicketKey(secret, SecretMaprTicketKey)
+}
+
+// SecretContainsMaprTicketKey returns true if the secret contains the given key
+func SecretContainsMaprTicketKey(secret *coreV1.Secret, key string) bool {
+	_, ok := secret.Data[key]
 	return ok
 }
 
+// DetectMaprTicketKeys returns the sorted keys of all data entries of the secret that can be
+// parsed as a MapR ticket
+func DetectMaprTicketKeys(secret *coreV1.Secret) []string {
+	var keys []string
+
+	for key, value := range secret.Data {
+		if _, err := parse.Unmarshal(value); err == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
 // Wrapper around parse.Ticket to add methods
 type Ticket parse.MaprTicket
 
@@ -67,10 +91,53 @@ func NewMaprTicket() *Ticket {
 	return (*Ticket)(parse.NewMaprTicket())
 }
 
-// NewMaprTicketFromSecret parses the ticket from the secret and returns it
+// SecretTicketKeys returns the data keys of the secret that are expected to contain MapR tickets.
+// If detect is true, all keys that can be parsed as a ticket are returned, falling back to the given
+// key if none can be parsed.
+func SecretTicketKeys(secret *coreV1.Secret, key string, detect bool) []string {
+	if detect {
+		if keys := DetectMaprTicketKeys(secret); len(keys) > 0 {
+			return keys
+		}
+	}
+
+	if SecretContainsMaprTicketKey(secret, key) {
+		return []string{key}
+	}
+
+	return nil
+}
+
+// SelectSecretTicketKey returns the data key of the secret the MapR ticket is stored under, as
+// returned by SecretTicketKeys. If several tickets are found, the given key is preferred. It
+// returns an error if the secret does not contain a ticket, or if it holds several tickets and none
+// of them is stored under the given key.
+func SelectSecretTicketKey(secret *coreV1.Secret, key string, detect bool) (string, error) {
+	keys := SecretTicketKeys(secret, key, detect)
+
+	switch {
+	case len(keys) == 0:
+		return "", NewErrSecretDoesNotContainMaprTicket(secret.Namespace, secret.Name)
+	case len(keys) == 1:
+		return keys[0], nil
+	case slices.Contains(keys, key):
+		return key, nil
+	default:
+		return "", fmt.Errorf("secret %s/%s holds several MapR tickets under the keys %s", secret.Namespace, secret.Name, strings.Join(keys, ", "))
+	}
+}
+
+// NewMaprTicketFromSecret parses the ticket stored under the default key SecretMaprTicketKey of
+// the secret and returns it
 func NewMaprTicketFromSecret(secret *coreV1.Secret) (*Ticket, error) {
+	return NewMaprTicketFromSecretKey(secret, SecretMaprTicketKey)
+}
+
+// NewMaprTicketFromSecretKey parses the ticket stored under the given key of the secret and
+// returns it
+func NewMaprTicketFromSecretKey(secret *coreV1.Secret, key string) (*Ticket, error) {
 	// get ticket from secret
-	ticketBytes, ok := secret.Data[SecretMaprTicketKey]
+	ticketBytes, ok := secret.Data[key]
 	if !ok {
 		return nil, NewErrSecretDoesNotContainMaprTicket(secret.Namespace, secret.Name)
 	}
@@ -84,22 +151,32 @@ func NewMaprTicketFromSecret(secret *coreV1.Secret) (*Ticket, error) {
 	return (*Ticket)(ticket), nil
 }
 
-// NewMaprTicketFromBytes parses the ticket from the given bytes and returns it
+// NewMaprTicketFromBytes parses the ticket from the given bytes and returns it. Tickets in secret
+// manifests are expected to be stored under the default key SecretMaprTicketKey.
 func NewMaprTicketFromBytes(ticketBytes []byte) (*Ticket, error) {
+	ticket, _, err := DecodeMaprTicket(ticketBytes, SecretMaprTicketKey, false)
+	return ticket, err
+}
+
+// DecodeMaprTicket parses the ticket from the given bytes like NewMaprTicketFromBytes, with the
+// ticket of secret manifests selected by key and detect as done by SelectSecretTicketKey. It also
+// returns the encoded ticket as stored in MapR ticket files and secrets, ie. the input itself or
+// the ticket unwrapped from base64 encoding or a secret manifest.
+func DecodeMaprTicket(in []byte, key string, detect bool) (*Ticket, []byte, error) {
 	// try to parse ticket directly
-	ticket, errTicket := parseTicket(ticketBytes)
+	ticket, ticketBytes, errTicket := parseTicket(in)
 	if errTicket == nil {
-		return ticket, nil
+		return ticket, ticketBytes, nil
 	}
 
 	// try to parse as secret
-	ticket, errSecret := parseSecret(ticketBytes)
+	ticket, ticketBytes, errSecret := parseSecret(in, key, detect)
 	if errSecret == nil {
-		return ticket, nil
+		return ticket, ticketBytes, nil
 	}
 
 	// if we get here, we couldn't parse the ticket
-	return nil, errors.Join(errTicket, errSecret)
+	return nil, nil, errors.Join(errTicket, errSecret)
 }
 
 // GetCluster returns the cluster that the ticket is for
@@ -145,41 +222,59 @@ func (ticket *Ticket) AsMaprTicket() *parse.MaprTicket {
 	return (*parse.MaprTicket)(ticket)
 }
 
-// String returns a string representation of the ticket
-func parseTicket(ticketBytes []byte) (*Ticket, error) {
+// parseTicket parses the ticket, either plain or base64 encoded, and returns it together with the
+// plain encoded ticket
+func parseTicket(ticketBytes []byte) (*Ticket, []byte, error) {
 	// try to parse ticket directly
 	ticket, errPlain := parse.Unmarshal(ticketBytes)
 	if errPlain == nil {
-		return (*Ticket)(ticket), nil
+		return (*Ticket)(ticket), ticketBytes, nil
 	}
 
 	// try to parse ticket as base64 encoded
 	ticketBytes, errDecode := util.DecodeBase64(string(ticketBytes))
 	ticket, errBase64 := parse.Unmarshal(ticketBytes)
 	if errBase64 == nil {
-		return (*Ticket)(ticket), nil
+		return (*Ticket)(ticket), ticketBytes, nil
 	}
 
 	// if we get here, we couldn't parse the ticket
-	return nil, errors.Join(errPlain, errDecode, errBase64)
+	return nil, nil, errors.Join(errPlain, errDecode, errBase64)
 }
 
-// parseSecret parses the secret and returns the ticket if it contains one
-func parseSecret(secretBytes []byte) (*Ticket, error) {
+// parseSecret parses the secret and returns the ticket selected by key and detect together with
+// the encoded ticket if it contains one
+func parseSecret(secretBytes []byte, key string, detect bool) (*Ticket, []byte, error) {
 	// try to parse as YAML into a secret
 	var secret coreV1.Secret
 	var errYAML error
 	var errJSON error
 
 	if errYAML = yaml.Unmarshal(secretBytes, &secret); errYAML == nil {
-		return NewMaprTicketFromSecret(&secret)
+		return parseSecretTicket(&secret, key, detect)
 	}
 
 	// try to parse as JSON into a secret
 	if errJSON = json.Unmarshal(secretBytes, &secret); errJSON == nil {
-		return NewMaprTicketFromSecret(&secret)
+		return parseSecretTicket(&secret, key, detect)
 	}
 
 	// if we get here, we couldn't parse the secret
-	return nil, errors.Join(errYAML, errJSON)
+	return nil, nil, errors.Join(errYAML, errJSON)
+}
+
+// parseSecretTicket returns the ticket stored in the secret under the key selected by key and
+// detect together with the encoded ticket
+func parseSecretTicket(secret *coreV1.Secret, key string, detect bool) (*Ticket, []byte, error) {
+	key, err := SelectSecretTicketKey(secret, key, detect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ticket, err := NewMaprTicketFromSecretKey(secret, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ticket, secret.Data[key], nil
 }
@@ -6,6 +6,7 @@
 package ticket_test
 
 import (
+	"encoding/base64"
 	"errors"
 	"testing"
 	"time"
@@ -95,6 +96,78 @@ func TestNewTicketFromSecret(t *testing.T) {
 	}
 }
 
+func TestDecodeMaprTicket(t *testing.T) {
+	t.Parallel()
+
+	ticketBytes := []byte("demo.mapr.com HQvWzXnHpDGcBithT6ohjzlVGzHvOUfUC4PK5XNpQ0qi99wP/+GXU6XII5VEK0tQn00HyZCno8YJUrM=")
+
+	customKeyManifest := []byte("apiVersion: v1\nkind: Secret\ndata:\n  TICKET: " + base64.StdEncoding.EncodeToString(ticketBytes) + "\n")
+
+	tests := []struct {
+		name    string
+		in      []byte
+		key     string
+		detect  bool
+		wantErr bool
+	}{
+		{
+			name: "plain ticket",
+			in:   ticketBytes,
+		},
+		{
+			name: "base64 encoded ticket",
+			in:   []byte(base64.StdEncoding.EncodeToString(ticketBytes)),
+		},
+		{
+			name: "secret manifest",
+			in:   []byte("apiVersion: v1\nkind: Secret\ndata:\n  CONTAINER_TICKET: " + base64.StdEncoding.EncodeToString(ticketBytes) + "\n"),
+		},
+		{
+			name: "secret manifest with custom key",
+			in:   customKeyManifest,
+			key:  "TICKET",
+		},
+		{
+			name:   "secret manifest with detected key",
+			in:     customKeyManifest,
+			detect: true,
+		},
+		{
+			name:    "secret manifest without ticket under key",
+			in:      customKeyManifest,
+			wantErr: true,
+		},
+		{
+			name:    "invalid ticket",
+			in:      []byte("not a ticket"),
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			key := test.key
+			if key == "" {
+				key = SecretMaprTicketKey
+			}
+
+			ticket, got, err := DecodeMaprTicket(test.in, key, test.detect)
+
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "demo.mapr.com", ticket.GetCluster())
+			assert.Equal(t, ticketBytes, got)
+		})
+	}
+}
+
 func TestSecretContainsMaprTicket(t *testing.T) {
 	t.Parallel()
 
@@ -136,6 +209,64 @@ func TestSecretContainsMaprTicket(t *testing.T) {
 	}
 }
 
+func TestSecretTicketKeys(t *testing.T) {
+	t.Parallel()
+
+	validTicket := []byte("demo.mapr.com +Cze+qwYCbAXGbz56OO7UF+lGqL3WPXrNkO1SLawEEDmSbgNl019xBeBY3kvh+R13iz/mCnwpzsLQw4Y5jEnv5GtuIWbeoC95ha8VKwX8MKcE6Kn9nZ2AF0QminkHwNVBx6TDriGZffyJCfZzivBwBSdKoQEWhBOPFCIMAi7w2zV/SX5Ut7u4qIKvEpr0JHV7sLMWYLhYncM6CKMd7iECGvECsBvEZRVj+dpbEY0BaRN/W54/7wNWaSVELUF6JWHQ8dmsqty4cZlI0/MV10HZzIbl9sMLFQ=")
+
+	tests := []struct {
+		name     string
+		data     map[string][]byte
+		key      string
+		detect   bool
+		expected []string
+	}{
+		{
+			name:     "default key",
+			data:     map[string][]byte{SecretMaprTicketKey: validTicket},
+			key:      SecretMaprTicketKey,
+			expected: []string{SecretMaprTicketKey},
+		},
+		{
+			name:     "custom key",
+			data:     map[string][]byte{SecretMaprTicketKey: validTicket, "maprticket": validTicket},
+			key:      "maprticket",
+			expected: []string{"maprticket"},
+		},
+		{
+			name:     "missing key",
+			data:     map[string][]byte{"maprticket": validTicket},
+			key:      SecretMaprTicketKey,
+			expected: nil,
+		},
+		{
+			name:     "detect several tickets",
+			data:     map[string][]byte{"user-b": validTicket, "user-a": validTicket, "other": []byte("not a ticket")},
+			key:      SecretMaprTicketKey,
+			detect:   true,
+			expected: []string{"user-a", "user-b"},
+		},
+		{
+			name:     "detect falls back to unparsable key",
+			data:     map[string][]byte{SecretMaprTicketKey: []byte("invalid ticket")},
+			key:      SecretMaprTicketKey,
+			detect:   true,
+			expected: []string{SecretMaprTicketKey},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			secret := &coreV1.Secret{Data: test.data}
+
+			assert.Equal(t, test.expected, SecretTicketKeys(secret, test.key, test.detect))
+		})
+	}
+}
+
 func TestExpirationTime(t *testing.T) {
 	t.Parallel()
 
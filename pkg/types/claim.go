@@ -19,6 +19,10 @@ type MaprVolumeClaim struct {
 	Claim  *PersistentVolumeClaim
 	Volume *PersistentVolume
 	Ticket *MaprSecret
+
+	// Context is the kubeconfig context the claim was listed from, if listing across multiple
+	// contexts
+	Context string
 }
 
 // GetNamespace returns the namespace of the claim
@@ -3,7 +3,7 @@
 //
 // SPDX-License-Identifier: MIT
 
-// Package types defines some common types for Secrets, PersistentVolumes and PersistentVolumeClaims
-// based on the core Kubernetes types, wrapped into their own types to provide additional
+// Package types defines some common types for Secrets, PersistentVolumes, PersistentVolumeClaims
+// and Pods based on the core Kubernetes types, wrapped into their own types to provide additional
 // functionality required for this tool.
 package types
@@ -0,0 +1,341 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package types
+
+import (
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// ItemAPIVersion is the API version of the structured items returned by the listers, used for
+	// machine readable output formats like JSON and YAML
+	ItemAPIVersion = "mapr-ticket.nobbs.dev/v1alpha1"
+
+	// kinds of the structured items returned by the listers
+	KindMaprSecret       = "MaprSecret"
+	KindMaprVolume       = "MaprVolume"
+	KindMaprVolumeClaim  = "MaprVolumeClaim"
+	KindMaprPod          = "MaprPod"
+	KindMaprStorageClass = "MaprStorageClass"
+)
+
+// ObjectReference is a reference to a namespaced or cluster-scoped Kubernetes object
+type ObjectReference struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// TicketItem is the structured representation of the fields parsed from a MapR ticket
+type TicketItem struct {
+	Cluster        string      `json:"cluster"`
+	User           string      `json:"user"`
+	UID            uint32      `json:"uid"`
+	GIDs           []uint32    `json:"gids"`
+	CreationTime   metaV1.Time `json:"creationTime"`
+	ExpirationTime metaV1.Time `json:"expirationTime"`
+	Expired        bool        `json:"expired"`
+}
+
+// VolumeItem is the structured representation of the MapR specific fields of a persistent volume
+type VolumeItem struct {
+	Name   string `json:"name"`
+	Driver string `json:"driver"`
+	Path   string `json:"path"`
+	Handle string `json:"handle"`
+}
+
+// MaprSecretItem is the structured representation of a MaprSecret
+type MaprSecretItem struct {
+	metaV1.TypeMeta `json:",inline"`
+
+	Context string          `json:"context,omitempty"`
+	Secret  ObjectReference `json:"secret"`
+	Key     string          `json:"key,omitempty"`
+	Ticket  *TicketItem     `json:"ticket"`
+	Error   string          `json:"error,omitempty"`
+	NumPVs  *uint32         `json:"numPVs,omitempty"`
+
+	NumInlineVolumes  *uint32 `json:"numInlineVolumes,omitempty"`
+	NumStorageClasses *uint32 `json:"numStorageClasses,omitempty"`
+}
+
+// MaprVolumeItem is the structured representation of a MaprVolume
+type MaprVolumeItem struct {
+	metaV1.TypeMeta `json:",inline"`
+
+	Context string           `json:"context,omitempty"`
+	Volume  VolumeItem       `json:"volume"`
+	Claim   *ObjectReference `json:"claim"`
+	Secret  *ObjectReference `json:"secret"`
+	Ticket  *TicketItem      `json:"ticket"`
+
+	BrokenReason BrokenReason `json:"brokenReason,omitempty"`
+}
+
+// MaprVolumeClaimItem is the structured representation of a MaprVolumeClaim
+type MaprVolumeClaimItem struct {
+	metaV1.TypeMeta `json:",inline"`
+
+	Context string           `json:"context,omitempty"`
+	Claim   ObjectReference  `json:"claim"`
+	Volume  VolumeItem       `json:"volume"`
+	Secret  *ObjectReference `json:"secret"`
+	Ticket  *TicketItem      `json:"ticket"`
+}
+
+// MaprPodItem is the structured representation of a MaprPod
+type MaprPodItem struct {
+	metaV1.TypeMeta `json:",inline"`
+
+	Pod        ObjectReference      `json:"pod"`
+	Node       string               `json:"node"`
+	Controller *ControllerReference `json:"controller"`
+	Claim      *ObjectReference     `json:"claim"`
+	Inline     bool                 `json:"inline"`
+	Volume     VolumeItem           `json:"volume"`
+	Secret     *ObjectReference     `json:"secret"`
+	Ticket     *TicketItem          `json:"ticket"`
+}
+
+// MaprStorageClassItem is the structured representation of a MaprStorageClass
+type MaprStorageClassItem struct {
+	metaV1.TypeMeta `json:",inline"`
+
+	StorageClass string           `json:"storageClass"`
+	Provisioner  string           `json:"provisioner"`
+	SecretType   string           `json:"secretType"`
+	Template     ObjectReference  `json:"template"`
+	Secret       *ObjectReference `json:"secret"`
+	Ticket       *TicketItem      `json:"ticket"`
+	Broken       bool             `json:"broken"`
+}
+
+// NewTicketItem returns the structured representation of the given ticket, or nil if there is no
+// ticket
+func NewTicketItem(t *ticket.Ticket) *TicketItem {
+	if t == nil {
+		return nil
+	}
+
+	gids := t.UserCreds.GetGids()
+	if gids == nil {
+		gids = []uint32{}
+	}
+
+	return &TicketItem{
+		Cluster:        t.GetCluster(),
+		User:           t.GetUser(),
+		UID:            t.UserCreds.GetUid(),
+		GIDs:           gids,
+		CreationTime:   metaV1.NewTime(t.CreationTime()),
+		ExpirationTime: metaV1.NewTime(t.ExpirationTime()),
+		Expired:        t.IsExpired(),
+	}
+}
+
+// Item returns the structured representation of the secret. If withInUse is true, the number of
+// persistent volumes, inline volumes and StorageClasses using the secret is included.
+func (t *MaprSecret) Item(withInUse bool) *MaprSecretItem {
+	item := &MaprSecretItem{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: ItemAPIVersion,
+			Kind:       KindMaprSecret,
+		},
+		Secret: ObjectReference{
+			Namespace: t.GetSecretNamespace(),
+			Name:      t.GetSecretName(),
+		},
+	}
+
+	if t != nil {
+		item.Context = t.Context
+		item.Key = t.Key
+		item.Ticket = NewTicketItem(t.Ticket)
+		item.Error = t.GetParseError()
+	}
+
+	if withInUse && t != nil {
+		numPVs := t.NumPVC
+		item.NumPVs = &numPVs
+
+		numInline := t.NumInline
+		item.NumInlineVolumes = &numInline
+
+		numStorageClasses := t.NumStorageClasses
+		item.NumStorageClasses = &numStorageClasses
+	}
+
+	return item
+}
+
+// Item returns the structured representation of the volume
+func (v *MaprVolume) Item() *MaprVolumeItem {
+	item := &MaprVolumeItem{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: ItemAPIVersion,
+			Kind:       KindMaprVolume,
+		},
+		Context: v.Context,
+		Volume:  newVolumeItem(v.Volume),
+		Claim:   newObjectReference(v.Volume.GetClaimNamespace(), v.Volume.GetClaimName()),
+		Secret:  newObjectReference(v.Volume.GetSecretNamespace(), v.Volume.GetSecretName()),
+
+		BrokenReason: v.BrokenReason,
+	}
+
+	if v.Ticket != nil {
+		item.Ticket = NewTicketItem(v.Ticket.Ticket)
+	}
+
+	return item
+}
+
+// Item returns the structured representation of the volume claim
+func (c *MaprVolumeClaim) Item() *MaprVolumeClaimItem {
+	item := &MaprVolumeClaimItem{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: ItemAPIVersion,
+			Kind:       KindMaprVolumeClaim,
+		},
+		Context: c.Context,
+		Claim: ObjectReference{
+			Namespace: c.Claim.GetNamespace(),
+			Name:      c.Claim.GetName(),
+		},
+		Volume: newVolumeItem(c.Volume),
+		Secret: newObjectReference(c.Volume.GetSecretNamespace(), c.Volume.GetSecretName()),
+	}
+
+	if c.Ticket != nil {
+		item.Ticket = NewTicketItem(c.Ticket.Ticket)
+	}
+
+	return item
+}
+
+// Item returns the structured representation of the pod
+func (p *MaprPod) Item() *MaprPodItem {
+	item := &MaprPodItem{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: ItemAPIVersion,
+			Kind:       KindMaprPod,
+		},
+		Pod: ObjectReference{
+			Namespace: p.Pod.GetNamespace(),
+			Name:      p.Pod.GetName(),
+		},
+		Node:       p.Pod.GetNodeName(),
+		Controller: p.Controller,
+	}
+
+	if p.Claim != nil {
+		claim := p.Claim.Item()
+
+		item.Claim = &claim.Claim
+		item.Volume = claim.Volume
+		item.Secret = claim.Secret
+		item.Ticket = claim.Ticket
+	}
+
+	if p.Inline != nil {
+		item.Inline = true
+		item.Volume = VolumeItem{
+			Name:   p.Inline.GetName(),
+			Driver: p.Inline.GetDriver(),
+			Path:   p.Inline.GetVolumePath(),
+		}
+		item.Secret = newObjectReference(p.Inline.GetSecretNamespace(), p.Inline.GetSecretName())
+
+		if p.Inline.Ticket != nil {
+			item.Ticket = NewTicketItem(p.Inline.Ticket.Ticket)
+		}
+	}
+
+	return item
+}
+
+// Item returns the structured representation of the StorageClass
+func (sc *MaprStorageClass) Item() *MaprStorageClassItem {
+	item := &MaprStorageClassItem{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: ItemAPIVersion,
+			Kind:       KindMaprStorageClass,
+		},
+		StorageClass: sc.StorageClass.GetName(),
+		SecretType:   sc.Reference.Type,
+		Template: ObjectReference{
+			Namespace: sc.Reference.Namespace,
+			Name:      sc.Reference.Name,
+		},
+		Secret: newObjectReference(sc.Namespace, sc.Name),
+		Broken: sc.IsBroken(),
+	}
+
+	if sc.StorageClass != nil {
+		item.Provisioner = sc.StorageClass.Provisioner
+	}
+
+	if sc.Ticket != nil {
+		item.Ticket = NewTicketItem(sc.Ticket.Ticket)
+	}
+
+	return item
+}
+
+// NewList converts the given structured items into a generic Kubernetes list object that can be
+// passed to any of the printers provided by k8s.io/cli-runtime
+func NewList[T any](items []*T) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{
+		Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "List",
+		},
+		Items: make([]unstructured.Unstructured, 0, len(items)),
+	}
+
+	for _, item := range items {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+
+		list.Items = append(list.Items, unstructured.Unstructured{Object: obj})
+	}
+
+	return list, nil
+}
+
+// newVolumeItem returns the structured representation of the MapR specific fields of the volume
+func newVolumeItem(v *PersistentVolume) VolumeItem {
+	item := VolumeItem{
+		Name:   v.GetName(),
+		Path:   v.GetVolumePath(),
+		Handle: v.GetVolumeHandle(),
+	}
+
+	if v != nil && v.Spec.CSI != nil {
+		item.Driver = v.Spec.CSI.Driver
+	}
+
+	return item
+}
+
+// newObjectReference returns a reference to the object with the given namespace and name, or nil
+// if the name is empty
+func newObjectReference(namespace, name string) *ObjectReference {
+	if name == "" {
+		return nil
+	}
+
+	return &ObjectReference{
+		Namespace: namespace,
+		Name:      name,
+	}
+}
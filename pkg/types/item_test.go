@@ -0,0 +1,170 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMaprSecret_Item(t *testing.T) {
+	t.Parallel()
+
+	secret := NewMaprSecret(&Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			ticket.SecretMaprTicketKey: testTicketsRaw[0],
+		},
+	}, ticket.SecretMaprTicketKey)
+	secret.NumPVC = 2
+
+	tests := []struct {
+		name       string
+		t          *MaprSecret
+		withInUse  bool
+		wantSecret ObjectReference
+		wantTicket bool
+		wantNumPVs *uint32
+	}{
+		{
+			name:       "nil",
+			t:          nil,
+			wantSecret: ObjectReference{},
+		},
+		{
+			name:       "ticket",
+			t:          secret,
+			wantSecret: ObjectReference{Namespace: "default", Name: "test"},
+			wantTicket: true,
+		},
+		{
+			name:       "ticket with in use",
+			t:          secret,
+			withInUse:  true,
+			wantSecret: ObjectReference{Namespace: "default", Name: "test"},
+			wantTicket: true,
+			wantNumPVs: func() *uint32 { n := uint32(2); return &n }(),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := test.t.Item(test.withInUse)
+
+			assert.Equal(t, ItemAPIVersion, got.APIVersion)
+			assert.Equal(t, KindMaprSecret, got.Kind)
+			assert.Equal(t, test.wantSecret, got.Secret)
+			assert.Equal(t, test.wantNumPVs, got.NumPVs)
+
+			if !test.wantTicket {
+				assert.Nil(t, got.Ticket)
+				return
+			}
+
+			assert.Equal(t, "demo.mapr.com", got.Ticket.Cluster)
+			assert.Equal(t, "mapr", got.Ticket.User)
+			assert.Equal(t, uint32(5000), got.Ticket.UID)
+			assert.Equal(t, []uint32{5000, 0, 5001}, got.Ticket.GIDs)
+			assert.False(t, got.Ticket.Expired)
+		})
+	}
+}
+
+func TestMaprVolume_Item(t *testing.T) {
+	t.Parallel()
+
+	volume := &MaprVolume{
+		Volume: &PersistentVolume{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name: "test-pv",
+			},
+			Spec: coreV1.PersistentVolumeSpec{
+				ClaimRef: &coreV1.ObjectReference{
+					Namespace: "default",
+					Name:      "test-pvc",
+				},
+				PersistentVolumeSource: coreV1.PersistentVolumeSource{
+					CSI: &coreV1.CSIPersistentVolumeSource{
+						Driver:       MaprCSIProvisionerKDF,
+						VolumeHandle: "test-handle",
+						VolumeAttributes: map[string]string{
+							"volumePath": "/test",
+						},
+						NodePublishSecretRef: &coreV1.SecretReference{
+							Namespace: "mapr",
+							Name:      "test-secret",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	got := volume.Item()
+
+	assert.Equal(t, KindMaprVolume, got.Kind)
+	assert.Equal(t, VolumeItem{Name: "test-pv", Driver: MaprCSIProvisionerKDF, Path: "/test", Handle: "test-handle"}, got.Volume)
+	assert.Equal(t, &ObjectReference{Namespace: "default", Name: "test-pvc"}, got.Claim)
+	assert.Equal(t, &ObjectReference{Namespace: "mapr", Name: "test-secret"}, got.Secret)
+	assert.Nil(t, got.Ticket)
+
+	claim := &MaprVolumeClaim{
+		Claim: &PersistentVolumeClaim{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "test-pvc",
+				Namespace: "default",
+			},
+		},
+		Volume: volume.Volume,
+	}
+
+	gotClaim := claim.Item()
+
+	assert.Equal(t, KindMaprVolumeClaim, gotClaim.Kind)
+	assert.Equal(t, ObjectReference{Namespace: "default", Name: "test-pvc"}, gotClaim.Claim)
+	assert.Equal(t, got.Volume, gotClaim.Volume)
+	assert.Equal(t, got.Secret, gotClaim.Secret)
+}
+
+func TestNewList(t *testing.T) {
+	t.Parallel()
+
+	items := []*MaprSecretItem{
+		(&MaprSecret{}).Item(false),
+		NewMaprSecret(&Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      "test",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				ticket.SecretMaprTicketKey: testTicketsRaw[0],
+			},
+		}, ticket.SecretMaprTicketKey).Item(false),
+	}
+
+	got, err := NewList(items)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "List", got.GetKind())
+	assert.Equal(t, "v1", got.GetAPIVersion())
+	assert.Len(t, got.Items, 2)
+	assert.Equal(t, KindMaprSecret, got.Items[1].GetKind())
+	assert.Equal(t, "test", got.Items[1].Object["secret"].(map[string]any)["name"])
+	assert.Equal(t, "demo.mapr.com", got.Items[1].Object["ticket"].(map[string]any)["cluster"])
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package types
+
+import (
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Pod is a wrapper around coreV1.Pod that provides additional functionality.
+type Pod coreV1.Pod
+
+// ControllerReference is a reference to the workload controlling a pod, e.g. a Deployment or
+// a StatefulSet
+type ControllerReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// MaprPod is used to store a pod together with one of the MapR-backed volumes it mounts, either
+// through a volume claim or as an inline CSI volume. A pod mounting multiple such volumes results
+// in multiple MaprPods.
+type MaprPod struct {
+	Pod        *Pod
+	Controller *ControllerReference
+	Claim      *MaprVolumeClaim
+	Inline     *MaprInlineVolume
+}
+
+// MaprInlineVolume is used to store an inline CSI volume of a pod that uses one of the MapR CSI
+// drivers, and the ticket referenced by that volume. No persistent volume exists for such volumes.
+type MaprInlineVolume struct {
+	Pod    *Pod
+	Volume *coreV1.Volume
+	Ticket *MaprSecret
+}
+
+// GetName returns the name of the pod
+func (p *Pod) GetName() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.Name
+}
+
+// GetNamespace returns the namespace of the pod
+func (p *Pod) GetNamespace() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.Namespace
+}
+
+// GetNodeName returns the name of the node the pod is scheduled on
+func (p *Pod) GetNodeName() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.Spec.NodeName
+}
+
+// GetClaimNames returns the names of all persistent volume claims mounted by the pod
+func (p *Pod) GetClaimNames() []string {
+	if p == nil {
+		return nil
+	}
+
+	var names []string
+
+	for _, volume := range p.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			names = append(names, volume.PersistentVolumeClaim.ClaimName)
+		}
+	}
+
+	return names
+}
+
+// GetMaprInlineVolumes returns all inline CSI volumes of the pod that use one of the MapR CSI
+// drivers
+func (p *Pod) GetMaprInlineVolumes() []MaprInlineVolume {
+	if p == nil {
+		return nil
+	}
+
+	var volumes []MaprInlineVolume
+
+	for i := range p.Spec.Volumes {
+		if IsMaprCSIInlineVolume(&p.Spec.Volumes[i]) {
+			volumes = append(volumes, MaprInlineVolume{
+				Pod:    p,
+				Volume: &p.Spec.Volumes[i],
+			})
+		}
+	}
+
+	return volumes
+}
+
+// GetController returns a reference to the direct controller of the pod, or nil if the pod is not
+// controlled by anything
+func (p *Pod) GetController() *ControllerReference {
+	if p == nil {
+		return nil
+	}
+
+	owner := metaV1.GetControllerOf((*coreV1.Pod)(p))
+	if owner == nil {
+		return nil
+	}
+
+	return &ControllerReference{
+		Kind: owner.Kind,
+		Name: owner.Name,
+	}
+}
+
+// GetKind returns the kind of the controller
+func (c *ControllerReference) GetKind() string {
+	if c == nil {
+		return ""
+	}
+
+	return c.Kind
+}
+
+// String returns the controller reference in the form kind/name
+func (c *ControllerReference) String() string {
+	if c == nil {
+		return ""
+	}
+
+	return c.Kind + "/" + c.Name
+}
+
+// IsMaprCSIInlineVolume returns true if the given pod volume is an inline CSI volume using one of
+// the MapR CSI drivers
+func IsMaprCSIInlineVolume(volume *coreV1.Volume) bool {
+	if volume == nil || volume.CSI == nil {
+		return false
+	}
+
+	for _, provisioner := range MaprCSIProvisioners {
+		if volume.CSI.Driver == provisioner {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetName returns the name of the volume in the pod spec
+func (v *MaprInlineVolume) GetName() string {
+	if v == nil || v.Volume == nil {
+		return ""
+	}
+
+	return v.Volume.Name
+}
+
+// GetDriver returns the name of the CSI driver of the volume
+func (v *MaprInlineVolume) GetDriver() string {
+	if v == nil || v.Volume == nil || v.Volume.CSI == nil {
+		return ""
+	}
+
+	return v.Volume.CSI.Driver
+}
+
+// GetVolumePath returns the volume path of the volume
+func (v *MaprInlineVolume) GetVolumePath() string {
+	if v == nil || v.Volume == nil || v.Volume.CSI == nil {
+		return ""
+	}
+
+	return v.Volume.CSI.VolumeAttributes["volumePath"]
+}
+
+// GetSecretName returns the name of the secret referenced by the volume
+func (v *MaprInlineVolume) GetSecretName() string {
+	if v == nil || v.Volume == nil || v.Volume.CSI == nil || v.Volume.CSI.NodePublishSecretRef == nil {
+		return ""
+	}
+
+	return v.Volume.CSI.NodePublishSecretRef.Name
+}
+
+// GetSecretNamespace returns the namespace of the secret referenced by the volume, which is always
+// the namespace of the pod
+func (v *MaprInlineVolume) GetSecretNamespace() string {
+	if v == nil || v.GetSecretName() == "" {
+		return ""
+	}
+
+	return v.Pod.GetNamespace()
+}
+
+// UsesSecret returns true if the volume uses the specified secret and false otherwise. The special
+// values NamespaceAll and SecretAll are handled the same way as for persistent volumes.
+func (v *MaprInlineVolume) UsesSecret(namespace, name string) bool {
+	secretName := v.GetSecretName()
+	if secretName == "" {
+		return false
+	}
+
+	// Check if we want secrets from all namespaces
+	if namespace == util.NamespaceAll {
+		return true
+	}
+
+	if v.GetSecretNamespace() != namespace {
+		return false
+	}
+
+	return name == util.SecretAll || secretName == name
+}
+
+// GetClaimName returns the name of the claim mounted by the pod, or an empty string for inline
+// volumes
+func (p *MaprPod) GetClaimName() string {
+	if p == nil || p.Claim == nil {
+		return ""
+	}
+
+	return p.Claim.Claim.GetName()
+}
+
+// GetVolumeName returns the name of the persistent volume bound to the claim, or the name of the
+// inline volume in the pod spec
+func (p *MaprPod) GetVolumeName() string {
+	if p == nil {
+		return ""
+	}
+
+	if p.Inline != nil {
+		return p.Inline.GetName()
+	}
+
+	if p.Claim != nil {
+		return p.Claim.Volume.GetName()
+	}
+
+	return ""
+}
+
+// GetSecretNamespace returns the namespace of the secret used by the volume
+func (p *MaprPod) GetSecretNamespace() string {
+	if p == nil {
+		return ""
+	}
+
+	if p.Inline != nil {
+		return p.Inline.GetSecretNamespace()
+	}
+
+	if p.Claim != nil {
+		return p.Claim.Volume.GetSecretNamespace()
+	}
+
+	return ""
+}
+
+// GetSecretName returns the name of the secret used by the volume
+func (p *MaprPod) GetSecretName() string {
+	if p == nil {
+		return ""
+	}
+
+	if p.Inline != nil {
+		return p.Inline.GetSecretName()
+	}
+
+	if p.Claim != nil {
+		return p.Claim.Volume.GetSecretName()
+	}
+
+	return ""
+}
+
+// GetTicket returns the ticket used by the volume, if available
+func (p *MaprPod) GetTicket() *MaprSecret {
+	if p == nil {
+		return nil
+	}
+
+	if p.Inline != nil {
+		return p.Inline.Ticket
+	}
+
+	if p.Claim != nil {
+		return p.Claim.Ticket
+	}
+
+	return nil
+}
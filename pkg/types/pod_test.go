@@ -0,0 +1,239 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestPod_GetClaimNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		p    *Pod
+		want []string
+	}{
+		{
+			name: "nil",
+			p:    nil,
+			want: nil,
+		},
+		{
+			name: "empty",
+			p:    &Pod{},
+			want: nil,
+		},
+		{
+			name: "claims and other volumes",
+			p: &Pod{
+				Spec: coreV1.PodSpec{
+					Volumes: []coreV1.Volume{
+						{
+							Name: "data",
+							VolumeSource: coreV1.VolumeSource{
+								PersistentVolumeClaim: &coreV1.PersistentVolumeClaimVolumeSource{ClaimName: "claim-1"},
+							},
+						},
+						{
+							Name: "tmp",
+							VolumeSource: coreV1.VolumeSource{
+								EmptyDir: &coreV1.EmptyDirVolumeSource{},
+							},
+						},
+						{
+							Name: "logs",
+							VolumeSource: coreV1.VolumeSource{
+								PersistentVolumeClaim: &coreV1.PersistentVolumeClaimVolumeSource{ClaimName: "claim-2"},
+							},
+						},
+					},
+				},
+			},
+			want: []string{"claim-1", "claim-2"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := test.p.GetClaimNames()
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestPod_GetController(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		p    *Pod
+		want string
+	}{
+		{
+			name: "nil",
+			p:    nil,
+			want: "",
+		},
+		{
+			name: "no owner",
+			p:    &Pod{},
+			want: "",
+		},
+		{
+			name: "owner that is not a controller",
+			p: &Pod{
+				ObjectMeta: metaV1.ObjectMeta{
+					OwnerReferences: []metaV1.OwnerReference{
+						{Kind: "ConfigMap", Name: "config"},
+					},
+				},
+			},
+			want: "",
+		},
+		{
+			name: "controller",
+			p: &Pod{
+				ObjectMeta: metaV1.ObjectMeta{
+					OwnerReferences: []metaV1.OwnerReference{
+						{Kind: "ConfigMap", Name: "config"},
+						{Kind: "StatefulSet", Name: "db", Controller: ptr.To(true)},
+					},
+				},
+			},
+			want: "StatefulSet/db",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := test.p.GetController()
+
+			assert.Equal(t, test.want, got.String())
+		})
+	}
+}
+
+func TestMaprInlineVolume_UsesSecret(t *testing.T) {
+	t.Parallel()
+
+	pod := &Pod{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace: "default",
+		},
+		Spec: coreV1.PodSpec{
+			Volumes: []coreV1.Volume{
+				{
+					Name: "data",
+					VolumeSource: coreV1.VolumeSource{
+						CSI: &coreV1.CSIVolumeSource{
+							Driver:               MaprCSIProvisionerKDF,
+							NodePublishSecretRef: &coreV1.LocalObjectReference{Name: "ticket"},
+						},
+					},
+				},
+				{
+					Name: "no-secret",
+					VolumeSource: coreV1.VolumeSource{
+						CSI: &coreV1.CSIVolumeSource{
+							Driver: MaprCSIProvisionerNFSKDF,
+						},
+					},
+				},
+				{
+					Name: "other-driver",
+					VolumeSource: coreV1.VolumeSource{
+						CSI: &coreV1.CSIVolumeSource{
+							Driver:               "random-driver",
+							NodePublishSecretRef: &coreV1.LocalObjectReference{Name: "ticket"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	volumes := pod.GetMaprInlineVolumes()
+	assert.Len(t, volumes, 2)
+
+	tests := []struct {
+		name      string
+		v         *MaprInlineVolume
+		namespace string
+		secret    string
+		want      bool
+	}{
+		{
+			name:      "nil",
+			v:         nil,
+			namespace: util.NamespaceAll,
+			secret:    util.SecretAll,
+			want:      false,
+		},
+		{
+			name:      "same secret",
+			v:         &volumes[0],
+			namespace: "default",
+			secret:    "ticket",
+			want:      true,
+		},
+		{
+			name:      "all secrets in namespace",
+			v:         &volumes[0],
+			namespace: "default",
+			secret:    util.SecretAll,
+			want:      true,
+		},
+		{
+			name:      "other namespace",
+			v:         &volumes[0],
+			namespace: "other",
+			secret:    "ticket",
+			want:      false,
+		},
+		{
+			name:      "other secret",
+			v:         &volumes[0],
+			namespace: "default",
+			secret:    "other",
+			want:      false,
+		},
+		{
+			name:      "volume without secret",
+			v:         &volumes[1],
+			namespace: util.NamespaceAll,
+			secret:    util.SecretAll,
+			want:      false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := test.v.UsesSecret(test.namespace, test.secret)
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
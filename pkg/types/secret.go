@@ -6,6 +6,7 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -23,20 +24,46 @@ type MaprSecret struct {
 	Secret *Secret        `json:"secret"`
 	Ticket *ticket.Ticket `json:"ticket"`
 	NumPVC uint32         `json:"-"`
+
+	// Key is the data key of the secret the ticket is stored under. A secret holding several
+	// tickets under different keys results in multiple MaprSecrets.
+	Key string `json:"key"`
+
+	// NumInline is the number of inline CSI volumes in pod specs using the ticket
+	NumInline uint32 `json:"-"`
+
+	// NumStorageClasses is the number of StorageClasses referencing the ticket
+	NumStorageClasses uint32 `json:"-"`
+
+	// ParseError is the error returned when parsing the ticket of the secret failed, in which
+	// case Ticket is nil
+	ParseError error `json:"-"`
+
+	// Context is the kubeconfig context the secret was listed from, if listing across multiple
+	// contexts
+	Context string `json:"-"`
 }
 
-// NewMaprSecret creates a new MaprSecret from a Secret
-func NewMaprSecret(s *Secret) *MaprSecret {
+// NewMaprSecret creates a new MaprSecret from the ticket stored under the given key of a Secret
+func NewMaprSecret(s *Secret, key string) *MaprSecret {
 	if s == nil {
 		return &MaprSecret{}
 	}
 
 	v := &MaprSecret{
 		Secret: s,
+		Key:    key,
 	}
 
-	ticket, err := ticket.NewMaprTicketFromSecret((*coreV1.Secret)(s))
+	var errNoTicket ticket.ErrSecretDoesNotContainMaprTicket
+
+	ticket, err := ticket.NewMaprTicketFromSecretKey((*coreV1.Secret)(s), key)
 	if err != nil {
+		// only remember the error if the secret actually contains a ticket that can't be parsed
+		if !errors.As(err, &errNoTicket) {
+			v.ParseError = err
+		}
+
 		return v
 	}
 
@@ -45,6 +72,15 @@ func NewMaprSecret(s *Secret) *MaprSecret {
 	return v
 }
 
+// GetKey returns the data key of the secret the ticket is stored under
+func (t *MaprSecret) GetKey() string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Key
+}
+
 // GetSecretName returns the name of the secret
 func (t *MaprSecret) GetSecretName() string {
 	if t == nil || t.Secret == nil {
@@ -81,6 +117,32 @@ func (t *MaprSecret) GetUser() string {
 	return t.Ticket.GetUser()
 }
 
+// IsInvalid returns true if the secret contains a ticket that could not be parsed
+func (t *MaprSecret) IsInvalid() bool {
+	return t != nil && t.Ticket == nil && t.ParseError != nil
+}
+
+// IsExpired returns true if the secret contains a valid ticket that has expired
+func (t *MaprSecret) IsExpired() bool {
+	return t != nil && t.Ticket != nil && t.Ticket.IsExpired()
+}
+
+// IsInUse returns true if the ticket is used by at least one persistent volume, inline volume or
+// StorageClass
+func (t *MaprSecret) IsInUse() bool {
+	return t != nil && (t.NumPVC > 0 || t.NumInline > 0 || t.NumStorageClasses > 0)
+}
+
+// GetParseError returns the error message of the ticket parse error, or an empty string if the
+// ticket was parsed successfully
+func (t *MaprSecret) GetParseError() string {
+	if !t.IsInvalid() {
+		return ""
+	}
+
+	return t.ParseError.Error()
+}
+
 // GetExpirationTime returns the expiration time of the ticket
 func (t *MaprSecret) GetExpirationTime() time.Time {
 	if t == nil || t.Ticket == nil {
@@ -109,6 +171,10 @@ func (t *MaprSecret) GetStatusString() string {
 		return "No secret found"
 	}
 
+	if t.IsInvalid() {
+		return "Invalid"
+	}
+
 	if t == nil || t.Ticket == nil {
 		return "No ticket found"
 	}
@@ -31,6 +31,7 @@ func TestNewMaprSecret(t *testing.T) {
 	tests := []struct {
 		name string
 		s    *Secret
+		key  string
 		want *MaprSecret
 	}{
 		{
@@ -58,6 +59,33 @@ func TestNewMaprSecret(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ticket under custom key",
+			s: &Secret{
+				Data: map[string][]byte{
+					"TICKET": testTicketsRaw[0],
+				},
+			},
+			key: "TICKET",
+			want: &MaprSecret{
+				Ticket: &ticket.Ticket{
+					Cluster: "demo.mapr.com",
+				},
+				Key: "TICKET",
+			},
+		},
+		{
+			name: "no ticket under custom key",
+			s: &Secret{
+				Data: map[string][]byte{
+					ticket.SecretMaprTicketKey: testTicketsRaw[0],
+				},
+			},
+			key: "TICKET",
+			want: &MaprSecret{
+				Key: "TICKET",
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -65,15 +93,22 @@ func TestNewMaprSecret(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := NewMaprSecret(test.s)
+			key := test.key
+			if key == "" {
+				key = ticket.SecretMaprTicketKey
+			}
+
+			got := NewMaprSecret(test.s, key)
 
 			if test.want.Secret != nil {
 				assert.Equal(t, test.want.Secret, got.Secret)
 			}
 
-			if test.want.Ticket != nil {
-				assert.NotNil(t, got.Ticket)
+			if test.want.Key != "" {
+				assert.Equal(t, test.want.Key, got.GetKey())
 			}
+
+			assert.Equal(t, test.want.Ticket != nil, got.Ticket != nil)
 		})
 	}
 }
@@ -199,6 +234,7 @@ func TestMaprSecret_GetCluster(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[0],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: "demo.mapr.com",
 		},
@@ -242,6 +278,7 @@ func TestMaprSecret_GetUser(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[0],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: "mapr",
 		},
@@ -253,6 +290,7 @@ func TestMaprSecret_GetUser(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[1],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: "mapr",
 		},
@@ -264,6 +302,7 @@ func TestMaprSecret_GetUser(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[2],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: "mapr",
 		},
@@ -307,6 +346,7 @@ func TestMaprSecret_GetExpirationTime(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[0],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: time.Date(29229672, time.June, 17, 19, 31, 17, 0, time.Local),
 		},
@@ -318,6 +358,7 @@ func TestMaprSecret_GetExpirationTime(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[1],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: time.Date(2019, time.February, 19, 13, 13, 49, 0, time.Local),
 		},
@@ -329,6 +370,7 @@ func TestMaprSecret_GetExpirationTime(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[2],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: time.Date(2021, time.April, 30, 0, 32, 46, 0, time.Local),
 		},
@@ -373,6 +415,7 @@ func TestMaprSecret_GetCreationTime(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[0],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: time.Date(2018, time.April, 4, 16, 31, 37, 0, time.UTC),
 		},
@@ -384,6 +427,7 @@ func TestMaprSecret_GetCreationTime(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[1],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: time.Date(2019, time.February, 5, 13, 13, 49, 0, time.Local),
 		},
@@ -395,6 +439,7 @@ func TestMaprSecret_GetCreationTime(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[2],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			want: time.Date(2021, time.April, 16, 0, 32, 46, 0, time.Local),
 		},
@@ -413,6 +458,53 @@ func TestMaprSecret_GetCreationTime(t *testing.T) {
 	}
 }
 
+func TestMaprSecret_IsInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		s    *Secret
+		want bool
+	}{
+		{
+			name: "empty secret",
+			s:    &Secret{},
+			want: false,
+		},
+		{
+			name: "ticket",
+			s: &Secret{
+				Data: map[string][]byte{
+					ticket.SecretMaprTicketKey: testTicketsRaw[0],
+				},
+			},
+			want: false,
+		},
+		{
+			name: "invalid ticket",
+			s: &Secret{
+				Data: map[string][]byte{
+					ticket.SecretMaprTicketKey: []byte("invalid ticket data"),
+				},
+			},
+			want: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := NewMaprSecret(test.s, ticket.SecretMaprTicketKey)
+
+			assert.Equal(t, test.want, got.IsInvalid())
+			assert.Equal(t, test.want, got.GetParseError() != "")
+			assert.False(t, got.IsExpired())
+		})
+	}
+}
+
 func TestMaprSecret_GetStatusString(t *testing.T) {
 	t.Parallel()
 
@@ -438,6 +530,18 @@ func TestMaprSecret_GetStatusString(t *testing.T) {
 			},
 			shouldContain: "No ticket found",
 		},
+		{
+			name: "invalid ticket",
+			t: NewMaprSecret(
+				&Secret{
+					Data: map[string][]byte{
+						ticket.SecretMaprTicketKey: []byte("invalid ticket data"),
+					},
+				},
+				ticket.SecretMaprTicketKey,
+			),
+			shouldContain: "Invalid",
+		},
 		{
 			name: "demo.mapr.com",
 			t: NewMaprSecret(
@@ -446,6 +550,7 @@ func TestMaprSecret_GetStatusString(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[0],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			shouldContain: "Valid",
 		},
@@ -457,6 +562,7 @@ func TestMaprSecret_GetStatusString(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[1],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			shouldContain: "Expired",
 		},
@@ -468,6 +574,7 @@ func TestMaprSecret_GetStatusString(t *testing.T) {
 						ticket.SecretMaprTicketKey: testTicketsRaw[2],
 					},
 				},
+				ticket.SecretMaprTicketKey,
 			),
 			shouldContain: "Expired",
 		},
@@ -0,0 +1,293 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package types
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	storageV1 "k8s.io/api/storage/v1"
+)
+
+const (
+	// storageClassSecretParameterPrefix is the prefix of all StorageClass parameters referencing
+	// secrets passed to the CSI driver
+	storageClassSecretParameterPrefix = "csi.storage.k8s.io/"
+
+	// types of the secrets that can be referenced by StorageClass parameters
+	SecretTypeProvisioner       = "provisioner"
+	SecretTypeControllerPublish = "controller-publish"
+	SecretTypeNodeStage         = "node-stage"
+	SecretTypeNodePublish       = "node-publish"
+	SecretTypeControllerExpand  = "controller-expand"
+)
+
+var (
+	// StorageClassSecretTypes is the list of the secret types that can be referenced by
+	// StorageClass parameters, in the order they are used during the lifecycle of a volume
+	StorageClassSecretTypes = []string{
+		SecretTypeProvisioner,
+		SecretTypeControllerPublish,
+		SecretTypeNodeStage,
+		SecretTypeNodePublish,
+		SecretTypeControllerExpand,
+	}
+
+	// templateVariableRegexp matches template variables like ${pvc.namespace} in secret references
+	templateVariableRegexp = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+	// annotationVariableRegexp matches the ${pvc.annotations['key']} template variable
+	annotationVariableRegexp = regexp.MustCompile(`^pvc\.annotations\['([^']*)'\]$`)
+)
+
+// StorageClass is a wrapper around storageV1.StorageClass that provides additional functionality.
+type StorageClass storageV1.StorageClass
+
+// StorageClassSecretReference is a reference to a secret in the parameters of a StorageClass. The
+// namespace and name may contain template variables like ${pvc.namespace} that are resolved by the
+// CSI sidecars for each claim. Either of them is empty if the corresponding parameter is missing,
+// making the reference incomplete.
+type StorageClassSecretReference struct {
+	Type      string
+	Namespace string
+	Name      string
+}
+
+// MaprStorageClass is used to store a StorageClass using one of the MapR CSI provisioners together
+// with one of the secrets referenced by its parameters. If the reference contains template
+// variables, it is resolved against the claims using the StorageClass, resulting in one
+// MaprStorageClass per distinct secret. Namespace and Name are empty if the reference could not be
+// resolved, as no claim uses the StorageClass yet or the reference is incomplete.
+type MaprStorageClass struct {
+	StorageClass *StorageClass
+	Reference    StorageClassSecretReference
+	Namespace    string
+	Name         string
+	Ticket       *MaprSecret
+}
+
+// GetName returns the name of the StorageClass
+func (sc *StorageClass) GetName() string {
+	if sc == nil {
+		return ""
+	}
+
+	return sc.Name
+}
+
+// GetCluster returns the name of the MapR cluster volumes of the StorageClass are provisioned on,
+// as specified in the parameters
+func (sc *StorageClass) GetCluster() string {
+	if sc == nil {
+		return ""
+	}
+
+	return sc.Parameters["cluster"]
+}
+
+// IsMaprCSIBased returns true if the StorageClass uses one of the MapR CSI provisioners
+func (sc *StorageClass) IsMaprCSIBased() bool {
+	if sc == nil {
+		return false
+	}
+
+	for _, provisioner := range MaprCSIProvisioners {
+		if sc.Provisioner == provisioner {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetSecretReferences returns all secrets referenced by the parameters of the StorageClass. The CSI
+// sidecars require both the name and the namespace parameter of a secret, so if only one of them is
+// given, the reference is returned as incomplete instead of guessing the missing value.
+func (sc *StorageClass) GetSecretReferences() []StorageClassSecretReference {
+	if sc == nil {
+		return nil
+	}
+
+	var refs []StorageClassSecretReference
+
+	for _, secretType := range StorageClassSecretTypes {
+		name := sc.Parameters[storageClassSecretParameterPrefix+secretType+"-secret-name"]
+		namespace := sc.Parameters[storageClassSecretParameterPrefix+secretType+"-secret-namespace"]
+
+		if name == "" && namespace == "" {
+			continue
+		}
+
+		refs = append(refs, StorageClassSecretReference{
+			Type:      secretType,
+			Namespace: namespace,
+			Name:      name,
+		})
+	}
+
+	return refs
+}
+
+// IsComplete returns true if both the namespace and the name of the referenced secret are given
+func (r StorageClassSecretReference) IsComplete() bool {
+	return r.Namespace != "" && r.Name != ""
+}
+
+// IsTemplated returns true if the namespace or the name of the reference contains template
+// variables
+func (r StorageClassSecretReference) IsTemplated() bool {
+	return templateVariableRegexp.MatchString(r.Namespace) || templateVariableRegexp.MatchString(r.Name)
+}
+
+// Resolve replaces the template variables in the reference using the given claim and returns the
+// resulting secret namespace and name. The variables ${pvc.namespace}, ${pvc.name}, ${pv.name} and
+// ${pvc.annotations['key']} are supported. It returns false if any variable can't be resolved.
+func (r StorageClassSecretReference) Resolve(claim *PersistentVolumeClaim) (string, string, bool) {
+	namespace, ok := resolveTemplate(r.Namespace, claim)
+	if !ok {
+		return "", "", false
+	}
+
+	name, ok := resolveTemplate(r.Name, claim)
+	if !ok {
+		return "", "", false
+	}
+
+	return namespace, name, true
+}
+
+// Matches returns true if the given secret could be referenced by the reference for some claim,
+// treating template variables as wildcards
+func (r StorageClassSecretReference) Matches(namespace, name string) bool {
+	return templateToRegexp(r.Namespace).MatchString(namespace) && templateToRegexp(r.Name).MatchString(name)
+}
+
+// String returns the reference in the form namespace/name, using <none> for missing parts
+func (r StorageClassSecretReference) String() string {
+	namespace, name := r.Namespace, r.Name
+
+	if namespace == "" {
+		namespace = "<none>"
+	}
+
+	if name == "" {
+		name = "<none>"
+	}
+
+	return namespace + "/" + name
+}
+
+// IsResolved returns true if the referenced secret is known, ie. the reference does not contain
+// templates or could be resolved using a claim
+func (sc *MaprStorageClass) IsResolved() bool {
+	return sc != nil && sc.Name != ""
+}
+
+// IsIncomplete returns true if the secret name or namespace parameter is missing, so the CSI
+// sidecars fail to provision or mount volumes of the StorageClass
+func (sc *MaprStorageClass) IsIncomplete() bool {
+	return sc != nil && !sc.Reference.IsComplete()
+}
+
+// IsBroken returns true if the reference is incomplete or if the referenced secret is known but
+// does not exist, does not contain a valid ticket or the ticket has expired
+func (sc *MaprStorageClass) IsBroken() bool {
+	if sc.IsIncomplete() {
+		return true
+	}
+
+	if !sc.IsResolved() {
+		return false
+	}
+
+	return sc.Ticket == nil || sc.Ticket.Ticket == nil || sc.Ticket.IsExpired()
+}
+
+// UsesSecret returns true if the StorageClass uses the specified secret. Unresolved references
+// match every secret they could resolve to, incomplete references never match. The special values
+// NamespaceAll and SecretAll are handled the same way as for persistent volumes.
+func (sc *MaprStorageClass) UsesSecret(namespace, name string) bool {
+	if sc == nil || sc.IsIncomplete() {
+		return false
+	}
+
+	if namespace == util.NamespaceAll {
+		return true
+	}
+
+	if !sc.IsResolved() {
+		if name == util.SecretAll {
+			return templateToRegexp(sc.Reference.Namespace).MatchString(namespace)
+		}
+
+		return sc.Reference.Matches(namespace, name)
+	}
+
+	if sc.Namespace != namespace {
+		return false
+	}
+
+	return name == util.SecretAll || sc.Name == name
+}
+
+// GetStatusString returns a human readable string describing the status of the referenced ticket
+func (sc *MaprStorageClass) GetStatusString() string {
+	if sc.IsIncomplete() {
+		return "Incomplete reference"
+	}
+
+	if !sc.IsResolved() {
+		return "Unresolved template"
+	}
+
+	return sc.Ticket.GetStatusString()
+}
+
+// resolveTemplate replaces all template variables in the given value using the given claim
+func resolveTemplate(value string, claim *PersistentVolumeClaim) (string, bool) {
+	resolved := true
+
+	result := templateVariableRegexp.ReplaceAllStringFunc(value, func(match string) string {
+		variable := strings.TrimSpace(templateVariableRegexp.FindStringSubmatch(match)[1])
+
+		var replacement string
+
+		switch {
+		case claim == nil:
+		case variable == "pvc.namespace":
+			replacement = claim.Namespace
+		case variable == "pvc.name":
+			replacement = claim.Name
+		case variable == "pv.name":
+			replacement = claim.Spec.VolumeName
+		case annotationVariableRegexp.MatchString(variable):
+			key := annotationVariableRegexp.FindStringSubmatch(variable)[1]
+			replacement = claim.Annotations[key]
+		}
+
+		if replacement == "" {
+			resolved = false
+		}
+
+		return replacement
+	})
+
+	return result, resolved
+}
+
+// templateToRegexp converts the given template to a regular expression matching all values the
+// template could resolve to
+func templateToRegexp(template string) *regexp.Regexp {
+	parts := templateVariableRegexp.Split(template, -1)
+
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+
+	return regexp.MustCompile("^" + strings.Join(parts, ".+") + "$")
+}
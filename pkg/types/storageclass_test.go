@@ -0,0 +1,276 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStorageClass_GetCluster(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sc   *StorageClass
+		want string
+	}{
+		{
+			name: "nil",
+			sc:   nil,
+			want: "",
+		},
+		{
+			name: "no parameters",
+			sc:   &StorageClass{},
+			want: "",
+		},
+		{
+			name: "cluster",
+			sc: &StorageClass{
+				Parameters: map[string]string{
+					"cluster": "demo.mapr.com",
+				},
+			},
+			want: "demo.mapr.com",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, test.sc.GetCluster())
+		})
+	}
+}
+
+func TestStorageClass_GetSecretReferences(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sc   *StorageClass
+		want []StorageClassSecretReference
+	}{
+		{
+			name: "nil",
+			sc:   nil,
+			want: nil,
+		},
+		{
+			name: "no parameters",
+			sc:   &StorageClass{},
+			want: nil,
+		},
+		{
+			name: "provisioner and node-publish secrets",
+			sc: &StorageClass{
+				Parameters: map[string]string{
+					"csi.storage.k8s.io/provisioner-secret-name":       "provisioner",
+					"csi.storage.k8s.io/provisioner-secret-namespace":  "mapr",
+					"csi.storage.k8s.io/node-publish-secret-name":      "${pvc.name}",
+					"csi.storage.k8s.io/node-publish-secret-namespace": "",
+					"csi.storage.k8s.io/node-stage-secret-namespace":   "mapr",
+				},
+			},
+			want: []StorageClassSecretReference{
+				{Type: SecretTypeProvisioner, Namespace: "mapr", Name: "provisioner"},
+				{Type: SecretTypeNodeStage, Namespace: "mapr"},
+				{Type: SecretTypeNodePublish, Name: "${pvc.name}"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, test.sc.GetSecretReferences())
+		})
+	}
+}
+
+func TestStorageClassSecretReference_Resolve(t *testing.T) {
+	t.Parallel()
+
+	claim := &PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace:   "team-a",
+			Name:        "data",
+			Annotations: map[string]string{"mapr.com/ticket": "custom"},
+		},
+		Spec: coreV1.PersistentVolumeClaimSpec{
+			VolumeName: "pv-1",
+		},
+	}
+
+	tests := []struct {
+		name          string
+		ref           StorageClassSecretReference
+		claim         *PersistentVolumeClaim
+		wantNamespace string
+		wantName      string
+		wantOk        bool
+	}{
+		{
+			name:          "no templates",
+			ref:           StorageClassSecretReference{Namespace: "mapr", Name: "ticket"},
+			claim:         nil,
+			wantNamespace: "mapr",
+			wantName:      "ticket",
+			wantOk:        true,
+		},
+		{
+			name:          "claim namespace and name",
+			ref:           StorageClassSecretReference{Namespace: "${pvc.namespace}", Name: "${pvc.name}-ticket"},
+			claim:         claim,
+			wantNamespace: "team-a",
+			wantName:      "data-ticket",
+			wantOk:        true,
+		},
+		{
+			name:          "volume name and annotation",
+			ref:           StorageClassSecretReference{Namespace: "mapr", Name: "${ pv.name }-${pvc.annotations['mapr.com/ticket']}"},
+			claim:         claim,
+			wantNamespace: "mapr",
+			wantName:      "pv-1-custom",
+			wantOk:        true,
+		},
+		{
+			name:   "missing annotation",
+			ref:    StorageClassSecretReference{Namespace: "mapr", Name: "${pvc.annotations['missing']}"},
+			claim:  claim,
+			wantOk: false,
+		},
+		{
+			name:   "unknown variable",
+			ref:    StorageClassSecretReference{Namespace: "mapr", Name: "${pvc.unknown}"},
+			claim:  claim,
+			wantOk: false,
+		},
+		{
+			name:   "template without claim",
+			ref:    StorageClassSecretReference{Namespace: "${pvc.namespace}", Name: "ticket"},
+			claim:  nil,
+			wantOk: false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			namespace, name, ok := test.ref.Resolve(test.claim)
+
+			assert.Equal(t, test.wantOk, ok)
+			assert.Equal(t, test.wantNamespace, namespace)
+			assert.Equal(t, test.wantName, name)
+		})
+	}
+}
+
+func TestMaprStorageClass_UsesSecret(t *testing.T) {
+	t.Parallel()
+
+	resolved := &MaprStorageClass{
+		Reference: StorageClassSecretReference{Namespace: "mapr", Name: "ticket"},
+		Namespace: "mapr",
+		Name:      "ticket",
+	}
+
+	unresolved := &MaprStorageClass{
+		Reference: StorageClassSecretReference{Namespace: "${pvc.namespace}", Name: "${pvc.name}-ticket"},
+	}
+
+	incomplete := &MaprStorageClass{
+		Reference: StorageClassSecretReference{Name: "ticket"},
+	}
+
+	tests := []struct {
+		name      string
+		sc        *MaprStorageClass
+		namespace string
+		secret    string
+		want      bool
+	}{
+		{
+			name:      "nil",
+			sc:        nil,
+			namespace: util.NamespaceAll,
+			secret:    util.SecretAll,
+			want:      false,
+		},
+		{
+			name:      "resolved same secret",
+			sc:        resolved,
+			namespace: "mapr",
+			secret:    "ticket",
+			want:      true,
+		},
+		{
+			name:      "resolved other namespace",
+			sc:        resolved,
+			namespace: "default",
+			secret:    "ticket",
+			want:      false,
+		},
+		{
+			name:      "resolved all secrets in namespace",
+			sc:        resolved,
+			namespace: "mapr",
+			secret:    util.SecretAll,
+			want:      true,
+		},
+		{
+			name:      "unresolved matching template",
+			sc:        unresolved,
+			namespace: "team-a",
+			secret:    "data-ticket",
+			want:      true,
+		},
+		{
+			name:      "unresolved not matching template",
+			sc:        unresolved,
+			namespace: "team-a",
+			secret:    "ticket",
+			want:      false,
+		},
+		{
+			name:      "incomplete reference with same name",
+			sc:        incomplete,
+			namespace: "team-a",
+			secret:    "ticket",
+			want:      false,
+		},
+		{
+			name:      "incomplete reference all secrets in namespace",
+			sc:        incomplete,
+			namespace: "team-a",
+			secret:    util.SecretAll,
+			want:      false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, test.sc.UsesSecret(test.namespace, test.secret))
+		})
+	}
+}
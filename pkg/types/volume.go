@@ -30,10 +30,69 @@ var (
 // functionality.
 type PersistentVolume coreV1.PersistentVolume
 
+// BrokenReason describes why the ticket referenced by a volume can't be used
+type BrokenReason string
+
+const (
+	// BrokenReasonSecretMissing indicates that the referenced secret does not exist
+	BrokenReasonSecretMissing BrokenReason = "SecretMissing"
+
+	// BrokenReasonSecretForbidden indicates that the referenced secret can't be read due to
+	// missing permissions, so it is unknown whether it exists
+	BrokenReasonSecretForbidden BrokenReason = "SecretForbidden"
+
+	// BrokenReasonNoTicket indicates that the referenced secret exists but does not contain a
+	// ticket under the expected key
+	BrokenReasonNoTicket BrokenReason = "NoTicket"
+
+	// BrokenReasonTicketUnparsable indicates that the ticket of the referenced secret can't be
+	// parsed
+	BrokenReasonTicketUnparsable BrokenReason = "TicketUnparsable"
+
+	// BrokenReasonTicketExpired indicates that the ticket of the referenced secret has expired
+	BrokenReasonTicketExpired BrokenReason = "TicketExpired"
+)
+
+var (
+	// brokenReasonStatus maps the reasons to a human readable status of the ticket
+	brokenReasonStatus = map[BrokenReason]string{
+		BrokenReasonSecretMissing:   "Secret not found",
+		BrokenReasonSecretForbidden: "Secret forbidden",
+		BrokenReasonNoTicket:        "No ticket found",
+	}
+)
+
 // MaprVolume is a wrapper around a PersistentVolume that provides additional functionality.
 type MaprVolume struct {
 	Volume *PersistentVolume
 	Ticket *MaprSecret
+
+	// BrokenReason describes why the referenced ticket can't be used, if it was determined by the
+	// lister. It is empty if the ticket is usable or the reason was not determined.
+	BrokenReason BrokenReason
+
+	// Context is the kubeconfig context the volume was listed from, if listing across multiple
+	// contexts
+	Context string
+}
+
+// IsBroken returns true if the lister determined that the referenced ticket can't be used
+func (v *MaprVolume) IsBroken() bool {
+	return v != nil && v.BrokenReason != ""
+}
+
+// GetStatusString returns a human readable string describing the status of the ticket used by
+// the volume, taking the reason into account why it is broken, if known
+func (v *MaprVolume) GetStatusString() string {
+	if v == nil {
+		return ""
+	}
+
+	if status, ok := brokenReasonStatus[v.BrokenReason]; ok {
+		return status
+	}
+
+	return v.Ticket.GetStatusString()
 }
 
 // GetName returns the name of the volume
@@ -77,6 +136,16 @@ func (v *PersistentVolume) GetVolumePath() string {
 	return value
 }
 
+// GetCluster returns the name of the MapR cluster the volume is stored on, as specified in the
+// volume attributes
+func (v *PersistentVolume) GetCluster() string {
+	if v == nil || v.Spec.CSI == nil || v.Spec.CSI.VolumeAttributes == nil {
+		return ""
+	}
+
+	return v.Spec.CSI.VolumeAttributes["cluster"]
+}
+
 // GetVolumeHandle returns the volume handle of the volume
 func (v *PersistentVolume) GetVolumeHandle() string {
 	if v == nil || v.Spec.CSI == nil {
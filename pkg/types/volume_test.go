@@ -228,6 +228,66 @@ func TestPersistentVolume_GetVolumePath(t *testing.T) {
 	}
 }
 
+func TestPersistentVolume_GetCluster(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		v    *PersistentVolume
+		want string
+	}{
+		{
+			name: "nil",
+			v:    nil,
+			want: "",
+		},
+		{
+			name: "nil CSI",
+			v:    &PersistentVolume{},
+			want: "",
+		},
+		{
+			name: "cluster",
+			v: &PersistentVolume{
+				Spec: coreV1.PersistentVolumeSpec{
+					PersistentVolumeSource: coreV1.PersistentVolumeSource{
+						CSI: &coreV1.CSIPersistentVolumeSource{
+							VolumeAttributes: map[string]string{
+								"cluster": "demo.mapr.com",
+							},
+						},
+					},
+				},
+			},
+			want: "demo.mapr.com",
+		},
+		{
+			name: "no cluster",
+			v: &PersistentVolume{
+				Spec: coreV1.PersistentVolumeSpec{
+					PersistentVolumeSource: coreV1.PersistentVolumeSource{
+						CSI: &coreV1.CSIPersistentVolumeSource{
+							VolumeAttributes: map[string]string{},
+						},
+					},
+				},
+			},
+			want: "",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := test.v.GetCluster()
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
 func TestPersistentVolume_GetVolumeHandle(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,101 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// ContextResult is the result of running a function for a single kubeconfig context
+type ContextResult[T any] struct {
+	// Context is the name of the kubeconfig context
+	Context string
+
+	// Items are the items returned for the context
+	Items []T
+
+	// Err is the error returned for the context, if any
+	Err error
+}
+
+// ContextsFromFlags returns the kubeconfig contexts to fan out over. If allContexts is true, all
+// contexts of the kubeconfig are returned. Otherwise, the value of the --context flag is split
+// into a comma separated list of contexts. It returns nil if at most one context was requested, in
+// which case the flags can be used as they are. The returned contexts are sorted and unique.
+func ContextsFromFlags(flags *genericclioptions.ConfigFlags, allContexts bool) ([]string, error) {
+	var contexts []string
+
+	if allContexts {
+		config, err := flags.ToRawKubeConfigLoader().RawConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		for name := range config.Contexts {
+			contexts = append(contexts, name)
+		}
+	} else if flags.Context != nil && strings.Contains(*flags.Context, ",") {
+		for _, name := range strings.Split(*flags.Context, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				contexts = append(contexts, name)
+			}
+		}
+	}
+
+	slices.Sort(contexts)
+	contexts = slices.Compact(contexts)
+
+	if len(contexts) == 0 {
+		return nil, nil
+	}
+
+	return contexts, nil
+}
+
+// ConfigFlagsForContext returns a copy of the given flags that uses the given kubeconfig context.
+// Only the flags that apply to all contexts are copied, ie. overrides of the cluster, user or
+// server of a single context are dropped.
+func ConfigFlagsForContext(flags *genericclioptions.ConfigFlags, context string) *genericclioptions.ConfigFlags {
+	contextFlags := genericclioptions.NewConfigFlags(true)
+
+	contextFlags.KubeConfig = flags.KubeConfig
+	contextFlags.CacheDir = flags.CacheDir
+	contextFlags.Namespace = flags.Namespace
+	contextFlags.Impersonate = flags.Impersonate
+	contextFlags.ImpersonateUID = flags.ImpersonateUID
+	contextFlags.ImpersonateGroup = flags.ImpersonateGroup
+	contextFlags.Timeout = flags.Timeout
+	contextFlags.Context = &context
+
+	return contextFlags
+}
+
+// ForEachContext runs fn concurrently for each of the given contexts and returns the results in
+// the order of the contexts. An error returned for one context does not affect the others.
+func ForEachContext[T any](contexts []string, fn func(context string) ([]T, error)) []ContextResult[T] {
+	results := make([]ContextResult[T], len(contexts))
+
+	var wg sync.WaitGroup
+
+	for i, context := range contexts {
+		wg.Add(1)
+
+		go func(i int, context string) {
+			defer wg.Done()
+
+			items, err := fn(context)
+			results[i] = ContextResult[T]{Context: context, Items: items, Err: err}
+		}(i, context)
+	}
+
+	wg.Wait()
+
+	return results
+}
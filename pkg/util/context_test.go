@@ -0,0 +1,178 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package util_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: a
+  cluster:
+    server: https://a.example.com
+- name: b
+  cluster:
+    server: https://b.example.com
+users:
+- name: user
+  user:
+    token: token
+contexts:
+- name: staging
+  context:
+    cluster: b
+    user: user
+- name: prod
+  context:
+    cluster: a
+    user: user
+current-context: prod
+`
+
+func TestContextsFromFlags(t *testing.T) {
+	t.Parallel()
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		context     string
+		allContexts bool
+		want        []string
+	}{
+		{
+			name: "no context",
+			want: nil,
+		},
+		{
+			name:    "single context",
+			context: "prod",
+			want:    nil,
+		},
+		{
+			name:    "comma separated contexts",
+			context: "staging, prod,,staging",
+			want:    []string{"prod", "staging"},
+		},
+		{
+			name:        "all contexts",
+			context:     "prod",
+			allContexts: true,
+			want:        []string{"prod", "staging"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			flags := genericclioptions.NewConfigFlags(false)
+			flags.KubeConfig = &kubeconfig
+			flags.Context = &test.context
+
+			got, err := ContextsFromFlags(flags, test.allContexts)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestForEachContext(t *testing.T) {
+	t.Parallel()
+
+	errUnreachable := errors.New("unreachable")
+
+	results := ForEachContext([]string{"a", "b", "c"}, func(context string) ([]string, error) {
+		if context == "b" {
+			return nil, errUnreachable
+		}
+
+		return []string{context + "-1", context + "-2"}, nil
+	})
+
+	want := []ContextResult[string]{
+		{Context: "a", Items: []string{"a-1", "a-2"}},
+		{Context: "b", Err: errUnreachable},
+		{Context: "c", Items: []string{"c-1", "c-2"}},
+	}
+
+	assert.Equal(t, want, results)
+}
+
+func TestAddContextColumn(t *testing.T) {
+	t.Parallel()
+
+	newTable := func() *metaV1.Table {
+		return &metaV1.Table{
+			ColumnDefinitions: []metaV1.TableColumnDefinition{{Name: "Name"}},
+			Rows: []metaV1.TableRow{
+				{Cells: []any{"one"}},
+				{Cells: []any{"two"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		contexts    []string
+		wantColumns []string
+		wantRows    [][]any
+	}{
+		{
+			name:        "single context",
+			contexts:    []string{"", ""},
+			wantColumns: []string{"Name"},
+			wantRows:    [][]any{{"one"}, {"two"}},
+		},
+		{
+			name:        "multiple contexts",
+			contexts:    []string{"prod", "staging"},
+			wantColumns: []string{"Context", "Name"},
+			wantRows:    [][]any{{"prod", "one"}, {"staging", "two"}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			table := newTable()
+			AddContextColumn(table, test.contexts)
+
+			columns := make([]string, 0, len(table.ColumnDefinitions))
+			for _, column := range table.ColumnDefinitions {
+				columns = append(columns, column.Name)
+			}
+
+			rows := make([][]any, 0, len(table.Rows))
+			for _, row := range table.Rows {
+				rows = append(rows, row.Cells)
+			}
+
+			assert.Equal(t, test.wantColumns, columns)
+			assert.Equal(t, test.wantRows, rows)
+		})
+	}
+}
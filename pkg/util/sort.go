@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sort direction syntax of the sort options, e.g. "-expiration", "expiration:desc" or
+// "expiration:asc"
+const (
+	SortDescendingPrefix = "-"
+	SortAscendingSuffix  = ":asc"
+	SortDescendingSuffix = ":desc"
+)
+
+// ParseSortOption splits the sort option into the field to sort by and the sort direction. The
+// direction is descending if the field is prefixed with SortDescendingPrefix or suffixed with
+// SortDescendingSuffix, and ascending otherwise.
+func ParseSortOption(option string) (field string, descending bool) {
+	field = option
+
+	if strings.HasPrefix(field, SortDescendingPrefix) {
+		field = strings.TrimPrefix(field, SortDescendingPrefix)
+		descending = true
+	}
+
+	switch {
+	case strings.HasSuffix(field, SortDescendingSuffix):
+		field = strings.TrimSuffix(field, SortDescendingSuffix)
+		descending = true
+	case strings.HasSuffix(field, SortAscendingSuffix):
+		field = strings.TrimSuffix(field, SortAscendingSuffix)
+	}
+
+	return field, descending
+}
+
+// ValidateDirectionalSortOptions validates the specified sort options like ValidateSortOptions,
+// but also accepts the sort direction syntax of ParseSortOption. Options combining the prefix and
+// a suffix are rejected, as their direction is ambiguous.
+func ValidateDirectionalSortOptions(validSortOptions, sortOptions []string) error {
+	fields := make([]string, 0, len(sortOptions))
+
+	for _, sortOption := range sortOptions {
+		field, _ := ParseSortOption(sortOption)
+
+		if strings.HasPrefix(sortOption, SortDescendingPrefix) && field != strings.TrimPrefix(sortOption, SortDescendingPrefix) {
+			return fmt.Errorf("invalid sort option: %s. Use either the %q prefix or the %q and %q suffixes to set the sort direction", sortOption, SortDescendingPrefix, SortAscendingSuffix, SortDescendingSuffix)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return ValidateSortOptions(validSortOptions, fields)
+}
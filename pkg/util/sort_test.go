@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/util"
+)
+
+func TestParseSortOption(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		option         string
+		wantField      string
+		wantDescending bool
+	}{
+		{option: "expiration", wantField: "expiration", wantDescending: false},
+		{option: "-expiration", wantField: "expiration", wantDescending: true},
+		{option: "expiration:desc", wantField: "expiration", wantDescending: true},
+		{option: "expiration:asc", wantField: "expiration", wantDescending: false},
+		{option: "secret.namespace:desc", wantField: "secret.namespace", wantDescending: true},
+		{option: "-mapr.user", wantField: "mapr.user", wantDescending: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.option, func(t *testing.T) {
+			t.Parallel()
+
+			field, descending := ParseSortOption(test.option)
+
+			assert.Equal(t, test.wantField, field)
+			assert.Equal(t, test.wantDescending, descending)
+		})
+	}
+}
+
+func TestValidateDirectionalSortOptions(t *testing.T) {
+	t.Parallel()
+
+	validSortOptions := []string{"name", "namespace", "expiration"}
+
+	tests := []struct {
+		name        string
+		sortOptions []string
+		wantErr     bool
+	}{
+		{
+			name:        "ascending sort options",
+			sortOptions: []string{"namespace", "name"},
+			wantErr:     false,
+		},
+		{
+			name:        "mixed directions",
+			sortOptions: []string{"namespace", "-expiration", "name:desc", "name:asc"},
+			wantErr:     false,
+		},
+		{
+			name:        "invalid field",
+			sortOptions: []string{"-invalid"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid direction",
+			sortOptions: []string{"name:descending"},
+			wantErr:     true,
+		},
+		{
+			name:        "prefix and suffix",
+			sortOptions: []string{"-name:asc"},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateDirectionalSortOptions(validSortOptions, test.sortOptions)
+
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
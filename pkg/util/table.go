@@ -0,0 +1,35 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"slices"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// contextTableColumn is the column showing the kubeconfig context a row was listed from
+var contextTableColumn = metaV1.TableColumnDefinition{
+	Name:        "Context",
+	Type:        "string",
+	Description: "Kubeconfig context the resource was listed from",
+	Priority:    0,
+}
+
+// AddContextColumn prepends a column showing the kubeconfig context of each row to the table. The
+// contexts must be given in the order of the rows. The column is only added if at least one of
+// the contexts is set, ie. when listing across multiple contexts.
+func AddContextColumn(table *metaV1.Table, contexts []string) {
+	if !slices.ContainsFunc(contexts, func(context string) bool { return context != "" }) {
+		return
+	}
+
+	table.ColumnDefinitions = append([]metaV1.TableColumnDefinition{contextTableColumn}, table.ColumnDefinitions...)
+
+	for i := range table.Rows {
+		table.Rows[i].Cells = append([]any{contexts[i]}, table.Rows[i].Cells...)
+	}
+}
@@ -10,16 +10,23 @@ package volume
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
 
+	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
 // secretLister is the interface that a secret lister must implement.
 type secretLister interface {
-	List() ([]types.MaprSecret, error)
+	ListReferenced(refs []k8sTypes.NamespacedName) ([]types.MaprSecret, error)
 }
 
 // Lister is a volume lister that lists volumes that are provisioned by one of the MapR CSI
@@ -29,10 +36,20 @@ type Lister struct {
 	namespace  string
 	secretName string
 
-	secretLister secretLister
-	sortBy       []SortOption
+	secretLister       secretLister
+	ticketKey          string
+	detectTicketKeys   bool
+	filterOnlyBroken   bool
+	sortBy             []SortOption
+	labelSelector      string
+	fieldSelector      string
+	filterByExpression *filter.Expression
 
 	volumes []types.MaprVolume
+
+	// err holds the error of collecting the secrets, as volumes can't be reported as broken if
+	// their secrets could not be listed
+	err error
 }
 
 // NewLister returns a new volume lister that lists volumes that are provisioned by one of the
@@ -43,6 +60,7 @@ func NewLister(client kubernetes.Interface, secretName string, namespace string,
 		secretName: secretName,
 		namespace:  namespace,
 		sortBy:     DefaultSortBy,
+		ticketKey:  ticket.SecretMaprTicketKey,
 	}
 
 	for _, opt := range opts {
@@ -54,6 +72,8 @@ func NewLister(client kubernetes.Interface, secretName string, namespace string,
 
 // List returns a list of volumes using the MapR CSI provisioners and the specified secret.
 func (l *Lister) List() ([]types.MaprVolume, error) {
+	l.err = nil
+
 	if err := l.getVolumes(); err != nil {
 		return nil, err
 	}
@@ -61,14 +81,30 @@ func (l *Lister) List() ([]types.MaprVolume, error) {
 	l.filterVolumesToMaprCSI().
 		filterVolumeUsesTicket().
 		collectSecrets().
+		collectBrokenReasons().
+		filterVolumesOnlyBroken().
+		filterVolumesByExpression().
 		sort()
 
+	if l.err != nil {
+		return nil, l.err
+	}
+
 	return l.volumes, nil
 }
 
+// listOptions returns the options used to list the persistent volumes, applying the label and field
+// selectors of the Lister
+func (l *Lister) listOptions() metaV1.ListOptions {
+	return metaV1.ListOptions{
+		LabelSelector: l.labelSelector,
+		FieldSelector: l.fieldSelector,
+	}
+}
+
 // getVolumes gets all persistent volumes in the cluster.
 func (l *Lister) getVolumes() error {
-	pvs, err := l.client.CoreV1().PersistentVolumes().List(context.TODO(), metaV1.ListOptions{})
+	pvs, err := l.client.CoreV1().PersistentVolumes().List(context.TODO(), l.listOptions())
 	if err != nil {
 		return err
 	}
@@ -120,6 +156,28 @@ func (l *Lister) filterVolumeUsesTicket() *Lister {
 	return l
 }
 
+// filterVolumesByExpression filters volumes to those matching the filter expression, if one was
+// provided to the Lister. It runs after the secrets have been collected, so the expression can
+// refer to the tickets used by the volumes.
+func (l *Lister) filterVolumesByExpression() *Lister {
+	// if the filter is not enabled, we can skip this step
+	if l.filterByExpression == nil {
+		return l
+	}
+
+	var filtered []types.MaprVolume
+
+	for i := range l.volumes {
+		if l.filterByExpression.Matches(filter.VolumeActivation(&l.volumes[i])) {
+			filtered = append(filtered, l.volumes[i])
+		}
+	}
+
+	l.volumes = filtered
+
+	return l
+}
+
 // collectSecrets collects secrets and tickets referenced by the volumes, if a secret lister was
 // provided to the Lister.
 func (l *Lister) collectSecrets() *Lister {
@@ -133,9 +191,19 @@ func (l *Lister) collectSecrets() *Lister {
 		return l
 	}
 
-	// collect all tickets via the secret lister
-	secrets, err := l.secretLister.List()
+	// collect all tickets via the secret lister, getting the referenced secrets one by one if they
+	// can't be listed
+	refs := make([]k8sTypes.NamespacedName, 0, len(l.volumes))
+	for i := range l.volumes {
+		refs = append(refs, k8sTypes.NamespacedName{
+			Namespace: l.volumes[i].Volume.GetSecretNamespace(),
+			Name:      l.volumes[i].Volume.GetSecretName(),
+		})
+	}
+
+	secrets, err := l.secretLister.ListReferenced(refs)
 	if err != nil {
+		l.err = fmt.Errorf("failed to list secrets referenced by persistent volumes: %w", err)
 		return l
 	}
 
@@ -151,3 +219,106 @@ func (l *Lister) collectSecrets() *Lister {
 
 	return l
 }
+
+// collectBrokenReasons determines for each volume why its ticket can't be used, if only broken
+// volumes should be listed. Volumes without a ticket have their secret looked up individually to
+// tell apart missing secrets, secrets that can't be read due to missing permissions and secrets
+// that don't contain a ticket.
+func (l *Lister) collectBrokenReasons() *Lister {
+	// if the filter is not enabled, we can skip this step
+	if !l.filterOnlyBroken {
+		return l
+	}
+
+	// remember the reasons by secret, as many volumes may reference the same secret
+	secretReasons := make(map[string]types.BrokenReason)
+
+	for i := range l.volumes {
+		volume := &l.volumes[i]
+
+		switch {
+		case volume.Ticket.IsInvalid():
+			volume.BrokenReason = types.BrokenReasonTicketUnparsable
+		case volume.Ticket.IsExpired():
+			volume.BrokenReason = types.BrokenReasonTicketExpired
+		case volume.Ticket == nil || volume.Ticket.Ticket == nil:
+			namespace, name := volume.Volume.GetSecretNamespace(), volume.Volume.GetSecretName()
+
+			reason, ok := secretReasons[namespace+"/"+name]
+			if !ok {
+				reason = l.secretBrokenReason(namespace, name)
+				secretReasons[namespace+"/"+name] = reason
+			}
+
+			volume.BrokenReason = reason
+		}
+	}
+
+	return l
+}
+
+// secretBrokenReason looks up the secret that was not found by the secret lister and returns why
+// its ticket can't be used, based on the tickets stored in the secret. An empty reason is returned
+// if the secret contains a usable ticket or the lookup failed for other reasons.
+func (l *Lister) secretBrokenReason(namespace, name string) types.BrokenReason {
+	secret, err := l.client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+
+	switch {
+	case err == nil:
+		return l.ticketBrokenReason(secret)
+	case apiErrors.IsNotFound(err):
+		return types.BrokenReasonSecretMissing
+	case apiErrors.IsForbidden(err):
+		return types.BrokenReasonSecretForbidden
+	default:
+		slog.Debug("failed to get secret referenced by persistent volume", "namespace", namespace, "name", name, "error", err)
+		return ""
+	}
+}
+
+// ticketBrokenReason parses the tickets stored in the secret and returns why none of them can be
+// used. An empty reason is returned if at least one of the tickets is usable.
+func (l *Lister) ticketBrokenReason(secret *coreV1.Secret) types.BrokenReason {
+	keys := ticket.SecretTicketKeys(secret, l.ticketKey, l.detectTicketKeys)
+	if len(keys) == 0 {
+		return types.BrokenReasonNoTicket
+	}
+
+	reason := types.BrokenReasonTicketUnparsable
+
+	for _, key := range keys {
+		t, err := ticket.NewMaprTicketFromSecretKey(secret, key)
+
+		switch {
+		case err != nil:
+			continue
+		case t.IsExpired():
+			reason = types.BrokenReasonTicketExpired
+		default:
+			return ""
+		}
+	}
+
+	return reason
+}
+
+// filterVolumesOnlyBroken filters volumes to those whose ticket can't be used, if only broken
+// volumes should be listed.
+func (l *Lister) filterVolumesOnlyBroken() *Lister {
+	// if the filter is not enabled, we can skip this step
+	if !l.filterOnlyBroken {
+		return l
+	}
+
+	var filtered []types.MaprVolume
+
+	for _, volume := range l.volumes {
+		if volume.IsBroken() {
+			filtered = append(filtered, volume)
+		}
+	}
+
+	l.volumes = filtered
+
+	return l
+}
@@ -7,6 +7,7 @@ package volume_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -22,9 +23,12 @@ import (
 	"github.com/nobbs/mapr-ticket-parser/pkg/parse"
 
 	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	clientTesting "k8s.io/client-go/testing"
 )
 
 const (
@@ -1150,6 +1154,202 @@ func TestLister_WithSortByAge(t *testing.T) {
 	}
 }
 
+func TestLister_WithFilterOnlyBroken(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newCSIVolume("csi-volume-1", CSIProvisionerMapr, withSecretRef("mapr", "valid")),
+		secretFromTicketJSON(t, "mapr", "valid", ticketWithExpiryTime(t, time.Now().Add(time.Hour))),
+		newCSIVolume("csi-volume-2", CSIProvisionerMapr, withSecretRef("mapr", "expired")),
+		secretFromTicketJSON(t, "mapr", "expired", ticketWithExpiryTime(t, time.Now().Add(-time.Hour))),
+		newCSIVolume("csi-volume-3", CSIProvisionerMapr, withSecretRef("mapr", "missing")),
+		newCSIVolume("csi-volume-4", CSIProvisionerMapr, withSecretRef("mapr", "missing")),
+		newCSIVolume("csi-volume-5", CSIProvisionerMapr, withSecretRef("mapr", "no-ticket")),
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "mapr", Name: "no-ticket"},
+			Data:       map[string][]byte{"other": []byte("data")},
+		},
+		newCSIVolume("csi-volume-6", CSIProvisionerMapr, withSecretRef("mapr", "unparsable")),
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "mapr", Name: "unparsable"},
+			Data:       map[string][]byte{ticket.SecretMaprTicketKey: []byte("not a ticket")},
+		},
+		newCSIVolume("csi-volume-7", CSIProvisionerMapr, withSecretRef("restricted", "forbidden")),
+	)
+
+	// deny reading the secrets of the restricted namespace
+	client.PrependReactor("get", "secrets", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() != "restricted" {
+			return false, nil, nil
+		}
+
+		return true, nil, apiErrors.NewForbidden(coreV1.Resource("secrets"), "forbidden", errors.New("access denied"))
+	})
+
+	l := NewLister(client, util.SecretAll, util.NamespaceAll,
+		WithFilterOnlyBroken(),
+		WithSortBy([]SortOption{SortByName}),
+		WithSecretLister(secret.NewLister(client, util.NamespaceAll)),
+	)
+
+	got, err := l.List()
+	assert.NoError(t, err)
+
+	reasons := make(map[string]types.BrokenReason, len(got))
+	for _, v := range got {
+		reasons[v.Volume.Name] = v.BrokenReason
+	}
+
+	assert.Equal(t, map[string]types.BrokenReason{
+		"csi-volume-2": types.BrokenReasonTicketExpired,
+		"csi-volume-3": types.BrokenReasonSecretMissing,
+		"csi-volume-4": types.BrokenReasonSecretMissing,
+		"csi-volume-5": types.BrokenReasonNoTicket,
+		"csi-volume-6": types.BrokenReasonTicketUnparsable,
+		"csi-volume-7": types.BrokenReasonSecretForbidden,
+	}, reasons)
+}
+
+func TestLister_WithFilterOnlyBroken_SecretsNotListed(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newCSIVolume("csi-volume-1", CSIProvisionerMapr, withSecretRef("mapr", "valid")),
+		secretFromTicketJSON(t, "mapr", "valid", ticketWithExpiryTime(t, time.Now().Add(time.Hour))),
+		newCSIVolume("csi-volume-2", CSIProvisionerMapr, withSecretRef("mapr", "expired")),
+		secretFromTicketJSON(t, "mapr", "expired", ticketWithExpiryTime(t, time.Now().Add(-time.Hour))),
+		newCSIVolume("csi-volume-3", CSIProvisionerMapr, withSecretRef("mapr", "no-ticket")),
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "mapr", Name: "no-ticket"},
+			Data:       map[string][]byte{"other": []byte("data")},
+		},
+		newCSIVolume("csi-volume-4", CSIProvisionerMapr, withSecretRef("mapr", "unparsable")),
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "mapr", Name: "unparsable"},
+			Data:       map[string][]byte{ticket.SecretMaprTicketKey: []byte("not a ticket")},
+		},
+	)
+
+	// the secret lister only sees another namespace, so the tickets are determined by looking up
+	// the secrets individually
+	l := NewLister(client, util.SecretAll, util.NamespaceAll,
+		WithFilterOnlyBroken(),
+		WithSortBy([]SortOption{SortByName}),
+		WithSecretLister(secret.NewLister(client, "other")),
+	)
+
+	got, err := l.List()
+	assert.NoError(t, err)
+
+	reasons := make(map[string]types.BrokenReason, len(got))
+	for _, v := range got {
+		reasons[v.Volume.Name] = v.BrokenReason
+	}
+
+	assert.Equal(t, map[string]types.BrokenReason{
+		"csi-volume-2": types.BrokenReasonTicketExpired,
+		"csi-volume-3": types.BrokenReasonNoTicket,
+		"csi-volume-4": types.BrokenReasonTicketUnparsable,
+	}, reasons)
+}
+
+func TestLister_SecretsListForbidden(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newCSIVolume("csi-volume-1", CSIProvisionerMapr, withSecretRef("mapr", "valid")),
+		secretFromTicketJSON(t, "mapr", "valid", ticketWithExpiryTime(t, time.Now().Add(time.Hour))),
+		newCSIVolume("csi-volume-2", CSIProvisionerMapr, withSecretRef("mapr", "expired")),
+		secretFromTicketJSON(t, "mapr", "expired", ticketWithExpiryTime(t, time.Now().Add(-time.Hour))),
+		newCSIVolume("csi-volume-3", CSIProvisionerMapr, withSecretRef("mapr", "missing")),
+		newCSIVolume("csi-volume-4", CSIProvisionerMapr, withSecretRef("restricted", "forbidden")),
+	)
+
+	// deny listing secrets cluster-wide and reading the secrets of the restricted namespace, but
+	// allow getting the secrets of other namespaces
+	client.PrependReactor("list", "secrets", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apiErrors.NewForbidden(coreV1.Resource("secrets"), "", errors.New("access denied"))
+	})
+	client.PrependReactor("get", "secrets", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() != "restricted" {
+			return false, nil, nil
+		}
+
+		return true, nil, apiErrors.NewForbidden(coreV1.Resource("secrets"), "forbidden", errors.New("access denied"))
+	})
+
+	t.Run("all volumes", func(t *testing.T) {
+		t.Parallel()
+
+		l := NewLister(client, util.SecretAll, util.NamespaceAll,
+			WithSortBy([]SortOption{SortByName}),
+			WithSecretLister(secret.NewLister(client, util.NamespaceAll)),
+		)
+
+		got, err := l.List()
+		assert.NoError(t, err)
+
+		tickets := make(map[string]bool, len(got))
+		for _, v := range got {
+			tickets[v.Volume.Name] = v.Ticket != nil && v.Ticket.Ticket != nil
+		}
+
+		assert.Equal(t, map[string]bool{
+			"csi-volume-1": true,
+			"csi-volume-2": true,
+			"csi-volume-3": false,
+			"csi-volume-4": false,
+		}, tickets)
+	})
+
+	t.Run("only broken volumes", func(t *testing.T) {
+		t.Parallel()
+
+		l := NewLister(client, util.SecretAll, util.NamespaceAll,
+			WithFilterOnlyBroken(),
+			WithSortBy([]SortOption{SortByName}),
+			WithSecretLister(secret.NewLister(client, util.NamespaceAll)),
+		)
+
+		got, err := l.List()
+		assert.NoError(t, err)
+
+		reasons := make(map[string]types.BrokenReason, len(got))
+		for _, v := range got {
+			reasons[v.Volume.Name] = v.BrokenReason
+		}
+
+		assert.Equal(t, map[string]types.BrokenReason{
+			"csi-volume-2": types.BrokenReasonTicketExpired,
+			"csi-volume-3": types.BrokenReasonSecretMissing,
+			"csi-volume-4": types.BrokenReasonSecretForbidden,
+		}, reasons)
+	})
+}
+
+func TestLister_SecretListerError(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		newCSIVolume("csi-volume-1", CSIProvisionerMapr, withSecretRef("mapr", "valid")),
+		secretFromTicketJSON(t, "mapr", "valid", ticketWithExpiryTime(t, time.Now().Add(time.Hour))),
+	)
+
+	// fail listing secrets for reasons other than missing permissions
+	client.PrependReactor("list", "secrets", func(action clientTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apiErrors.NewInternalError(errors.New("etcd unavailable"))
+	})
+
+	l := NewLister(client, util.SecretAll, util.NamespaceAll,
+		WithFilterOnlyBroken(),
+		WithSecretLister(secret.NewLister(client, util.NamespaceAll)),
+	)
+
+	got, err := l.List()
+	assert.True(t, apiErrors.IsInternalError(err))
+	assert.Empty(t, got)
+}
+
 type listerFields struct {
 	client     kubernetes.Interface
 	secretName string
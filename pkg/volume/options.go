@@ -5,6 +5,8 @@
 
 package volume
 
+import "github.com/nobbs/kubectl-mapr-ticket/pkg/filter"
+
 // ListerOption is a function that can be used to configure the volume lister.
 type ListerOption func(*Lister)
 
@@ -22,3 +24,50 @@ func WithSecretLister(secretLister secretLister) ListerOption {
 		l.secretLister = secretLister
 	}
 }
+
+// WithTicketKey sets the data key the Lister expects MapR tickets to be stored under when looking
+// up the secrets of broken volumes
+func WithTicketKey(key string) ListerOption {
+	return func(l *Lister) {
+		l.ticketKey = key
+	}
+}
+
+// WithDetectTicketKeys configures the Lister to try to parse every data key of the secrets of
+// broken volumes as a MapR ticket
+func WithDetectTicketKeys() ListerOption {
+	return func(l *Lister) {
+		l.detectTicketKeys = true
+	}
+}
+
+// WithFilterOnlyBroken configures the Lister to only list the persistent volumes whose ticket is
+// missing, unreadable, invalid or expired, together with the reason. Requires a secret lister to
+// be configured.
+func WithFilterOnlyBroken() ListerOption {
+	return func(l *Lister) {
+		l.filterOnlyBroken = true
+	}
+}
+
+// WithLabelSelector sets the label selector used by the Lister to select the persistent volumes to list
+func WithLabelSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.labelSelector = selector
+	}
+}
+
+// WithFieldSelector sets the field selector used by the Lister to select the persistent volumes to list
+func WithFieldSelector(selector string) ListerOption {
+	return func(l *Lister) {
+		l.fieldSelector = selector
+	}
+}
+
+// WithFilterByExpression sets the filter expression used by the Lister to only return the
+// persistent volumes matching the expression
+func WithFilterByExpression(expression *filter.Expression) ListerOption {
+	return func(l *Lister) {
+		l.filterByExpression = expression
+	}
+}
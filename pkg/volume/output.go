@@ -13,6 +13,7 @@ import (
 
 	coreV1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/printers"
 )
@@ -85,6 +86,14 @@ func Print(cmd *cobra.Command, volumes []types.MaprVolume) error {
 	// generate the table
 	table := generableTable(volumes)
 
+	// add the context column when listing across multiple contexts
+	contexts := make([]string, 0, len(volumes))
+	for i := range volumes {
+		contexts = append(contexts, volumes[i].Context)
+	}
+
+	util.AddContextColumn(table, contexts)
+
 	// print the table
 	printer := printers.NewTablePrinter(printers.PrintOptions{
 		Wide: format == "wide",
@@ -98,6 +107,17 @@ func Print(cmd *cobra.Command, volumes []types.MaprVolume) error {
 	return nil
 }
 
+// PrintObjects prints the volumes as a structured list using the given printer, e.g. a JSON, YAML,
+// JSONPath, Go template or custom columns printer.
+func PrintObjects(cmd *cobra.Command, printer printers.ResourcePrinter, volumes []types.MaprVolume) error {
+	list, err := generateList(volumes)
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(list, cmd.OutOrStdout())
+}
+
 // generableTable generates a table from the specified volumes.
 func generableTable(volumes []types.MaprVolume) *metaV1.Table {
 	rows := generateRows(volumes)
@@ -108,6 +128,17 @@ func generableTable(volumes []types.MaprVolume) *metaV1.Table {
 	}
 }
 
+// generateList generates a list of structured items from the given volumes.
+func generateList(volumes []types.MaprVolume) (*unstructured.UnstructuredList, error) {
+	items := make([]*types.MaprVolumeItem, 0, len(volumes))
+
+	for i := range volumes {
+		items = append(items, volumes[i].Item())
+	}
+
+	return types.NewList(items)
+}
+
 // generateRows generates the rows for the table from the specified volumes.
 func generateRows(volumes []types.MaprVolume) []metaV1.TableRow {
 	rows := make([]metaV1.TableRow, 0, len(volumes))
@@ -135,7 +166,7 @@ func generateRow(volume *types.MaprVolume) *metaV1.TableRow {
 		volume.Volume.GetClaimName(),
 		volume.Volume.GetVolumePath(),
 		volume.Volume.GetVolumeHandle(),
-		volume.Ticket.GetStatusString(),
+		volume.GetStatusString(),
 		util.ShortHumanDurationUntilNow(volume.Volume.CreationTimestamp.Time),
 	}
 
@@ -6,9 +6,11 @@
 package volume
 
 import (
-	"sort"
+	"cmp"
+	"slices"
 
 	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/util"
 )
 
 // SortOption is the type of a sort option, basically a wrapper around a string to provide
@@ -53,99 +55,104 @@ func (s SortOption) String() string {
 	return string(s)
 }
 
-func sortByName(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		return volumes[i].Volume.GetName() < volumes[j].Volume.GetName()
-	})
+// compareByName compares the volumes by name
+func compareByName(a, b *types.MaprVolume) int {
+	return cmp.Compare(a.Volume.GetName(), b.Volume.GetName())
 }
 
-func sortBySecretNamespace(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		return volumes[i].Volume.GetSecretNamespace() < volumes[j].Volume.GetSecretNamespace()
-	})
+// compareBySecretNamespace compares the volumes by namespace of the secret they use
+func compareBySecretNamespace(a, b *types.MaprVolume) int {
+	return cmp.Compare(a.Volume.GetSecretNamespace(), b.Volume.GetSecretNamespace())
 }
 
-func sortBySecretName(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		return volumes[i].Volume.GetSecretName() < volumes[j].Volume.GetSecretName()
-	})
+// compareBySecretName compares the volumes by name of the secret they use
+func compareBySecretName(a, b *types.MaprVolume) int {
+	return cmp.Compare(a.Volume.GetSecretName(), b.Volume.GetSecretName())
 }
 
-func sortByClaimNamespace(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		return volumes[i].Volume.GetClaimNamespace() < volumes[j].Volume.GetClaimNamespace()
-	})
+// compareByClaimNamespace compares the volumes by namespace of the claim they are bound to
+func compareByClaimNamespace(a, b *types.MaprVolume) int {
+	return cmp.Compare(a.Volume.GetClaimNamespace(), b.Volume.GetClaimNamespace())
 }
 
-func sortByClaimName(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		return volumes[i].Volume.GetClaimName() < volumes[j].Volume.GetClaimName()
-	})
+// compareByClaimName compares the volumes by name of the claim they are bound to
+func compareByClaimName(a, b *types.MaprVolume) int {
+	return cmp.Compare(a.Volume.GetClaimName(), b.Volume.GetClaimName())
 }
 
-func sortByVolumePath(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		return volumes[i].Volume.GetVolumePath() < volumes[j].Volume.GetVolumePath()
-	})
+// compareByVolumePath compares the volumes by MapR volume path
+func compareByVolumePath(a, b *types.MaprVolume) int {
+	return cmp.Compare(a.Volume.GetVolumePath(), b.Volume.GetVolumePath())
 }
 
-func sortByVolumeHandle(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		return volumes[i].Volume.GetVolumeHandle() < volumes[j].Volume.GetVolumeHandle()
-	})
+// compareByVolumeHandle compares the volumes by CSI volume handle
+func compareByVolumeHandle(a, b *types.MaprVolume) int {
+	return cmp.Compare(a.Volume.GetVolumeHandle(), b.Volume.GetVolumeHandle())
 }
 
-func sortByExpiration(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		if volumes[i].Ticket == nil {
-			return true
-		} else if volumes[j].Ticket == nil {
-			return false
-		}
+// compareByExpiration compares the volumes by expiry time of the ticket, volumes without a ticket first
+func compareByExpiration(a, b *types.MaprVolume) int {
+	return a.Ticket.GetExpirationTime().Compare(b.Ticket.GetExpirationTime())
+}
 
-		return volumes[i].Ticket.GetExpirationTime().Before(volumes[j].Ticket.GetExpirationTime())
-	})
+// compareByAge compares the volumes by creation timestamp
+func compareByAge(a, b *types.MaprVolume) int {
+	return a.Volume.CreationTimestamp.Time.Compare(b.Volume.CreationTimestamp.Time)
 }
 
-func sortByAge(volumes []types.MaprVolume) {
-	sort.Slice(volumes, func(i, j int) bool {
-		return volumes[i].Volume.CreationTimestamp.Before(&volumes[j].Volume.CreationTimestamp)
-	})
+// comparators maps the sort options to the functions comparing the volumes by the option
+var comparators = map[SortOption]func(a, b *types.MaprVolume) int{
+	SortByName:            compareByName,
+	SortBySecretNamespace: compareBySecretNamespace,
+	SortBySecretName:      compareBySecretName,
+	SortByClaimNamespace:  compareByClaimNamespace,
+	SortByClaimName:       compareByClaimName,
+	SortByVolumePath:      compareByVolumePath,
+	SortByVolumeHandle:    compareByVolumeHandle,
+	SortByExpiration:      compareByExpiration,
+	SortByAge:             compareByAge,
 }
 
-// sort sorts the items by the specified sort options, in reverse order of the
-// order in which they are specified. This makes for a more natural sort result
-// when using multiple sort options.
+// sort sorts the items by the sort options of the lister.
 func (l *Lister) sort() *Lister {
-	// reverse the order of the sort options
-	order := make([]SortOption, len(l.sortBy))
-	for i, j := 0, len(l.sortBy)-1; i < len(l.sortBy); i, j = i+1, j-1 {
-		order[i] = l.sortBy[j]
+	SortItems(l.volumes, l.sortBy)
+
+	return l
+}
+
+// SortItems sorts the given persistent volumes by the specified sort options. Items that are equal by
+// the first option are sorted by the second one, and so on. Options prefixed with "-" or suffixed
+// with ":desc" sort in descending order, see util.ParseSortOption. The sort is stable, so items
+// equal by all options keep their order.
+func SortItems(items []types.MaprVolume, sortBy []SortOption) {
+	type sortKey struct {
+		compare    func(a, b *types.MaprVolume) int
+		descending bool
 	}
 
-	// sort the items by the specified sort options
-	for _, sortOption := range order {
-		switch sortOption {
-		case SortByName:
-			sortByName(l.volumes)
-		case SortBySecretNamespace:
-			sortBySecretNamespace(l.volumes)
-		case SortBySecretName:
-			sortBySecretName(l.volumes)
-		case SortByClaimNamespace:
-			sortByClaimNamespace(l.volumes)
-		case SortByClaimName:
-			sortByClaimName(l.volumes)
-		case SortByVolumePath:
-			sortByVolumePath(l.volumes)
-		case SortByVolumeHandle:
-			sortByVolumeHandle(l.volumes)
-		case SortByExpiration:
-			sortByExpiration(l.volumes)
-		case SortByAge:
-			sortByAge(l.volumes)
+	keys := make([]sortKey, 0, len(sortBy))
+
+	for _, sortOption := range sortBy {
+		field, descending := util.ParseSortOption(sortOption.String())
+
+		if compare, ok := comparators[SortOption(field)]; ok {
+			keys = append(keys, sortKey{compare: compare, descending: descending})
 		}
 	}
 
-	return l
+	slices.SortStableFunc(items, func(a, b types.MaprVolume) int {
+		for _, key := range keys {
+			result := key.compare(&a, &b)
+
+			if key.descending {
+				result = -result
+			}
+
+			if result != 0 {
+				return result
+			}
+		}
+
+		return 0
+	})
 }
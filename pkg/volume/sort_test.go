@@ -4,3 +4,102 @@
 // SPDX-License-Identifier: MIT
 
 package volume_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/volume"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestSortItems(t *testing.T) {
+	t.Parallel()
+
+	// newItem returns a volume using the given secret, with a ticket expiring after the given
+	// duration or no ticket if the duration is 0
+	newItem := func(name, secretName string, expiresIn time.Duration) types.MaprVolume {
+		item := types.MaprVolume{
+			Volume: &types.PersistentVolume{
+				ObjectMeta: metaV1.ObjectMeta{Name: name},
+				Spec: coreV1.PersistentVolumeSpec{
+					PersistentVolumeSource: coreV1.PersistentVolumeSource{
+						CSI: &coreV1.CSIPersistentVolumeSource{
+							NodePublishSecretRef: &coreV1.SecretReference{Namespace: "default", Name: secretName},
+						},
+					},
+				},
+			},
+		}
+
+		if expiresIn != 0 {
+			maprTicket := ticket.NewMaprTicket()
+			maprTicket.TicketAndKey.ExpiryTime = ptr.To(uint64(time.Now().Add(expiresIn).Unix()))
+			item.Ticket = &types.MaprSecret{Ticket: maprTicket}
+		}
+
+		return item
+	}
+
+	tests := []struct {
+		name   string
+		sortBy []SortOption
+		want   []string
+	}{
+		{
+			name:   "default",
+			sortBy: DefaultSortBy,
+			want:   []string{"pv-1", "pv-2", "pv-3", "pv-4"},
+		},
+		{
+			name:   "expiration ascending",
+			sortBy: []SortOption{SortByExpiration},
+			want:   []string{"pv-4", "pv-2", "pv-1", "pv-3"},
+		},
+		{
+			name:   "expiration descending",
+			sortBy: []SortOption{"-expiration"},
+			want:   []string{"pv-3", "pv-1", "pv-2", "pv-4"},
+		},
+		{
+			name:   "mixed directions",
+			sortBy: []SortOption{SortBySecretName, "expiration:desc"},
+			want:   []string{"pv-3", "pv-2", "pv-1", "pv-4"},
+		},
+		{
+			name:   "stable for equal items",
+			sortBy: []SortOption{"secret.name:desc"},
+			want:   []string{"pv-1", "pv-4", "pv-3", "pv-2"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			items := []types.MaprVolume{
+				newItem("pv-3", "secret-a", 72*time.Hour),
+				newItem("pv-1", "secret-b", 48*time.Hour),
+				newItem("pv-4", "secret-b", 0),
+				newItem("pv-2", "secret-a", 24*time.Hour),
+			}
+
+			SortItems(items, test.sortBy)
+
+			got := make([]string, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.Volume.GetName())
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
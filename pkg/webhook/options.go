@@ -0,0 +1,41 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+// Option is a function that can be used to configure the validator.
+type Option func(*Validator)
+
+// WithTicketKey configures the validator to look for MapR tickets stored under the given data key
+// of the secrets
+func WithTicketKey(key string) Option {
+	return func(v *Validator) {
+		v.ticketKey = key
+	}
+}
+
+// WithDetectTicketKeys configures the validator to try to parse every data key of the secrets as
+// a MapR ticket
+func WithDetectTicketKeys() Option {
+	return func(v *Validator) {
+		v.detectTicketKeys = true
+	}
+}
+
+// WithAllowedClusters configures the validator to reject tickets issued for clusters other than
+// the given ones. An empty list allows all clusters.
+func WithAllowedClusters(clusters []string) Option {
+	return func(v *Validator) {
+		v.allowedClusters = clusters
+	}
+}
+
+// WithAllowedUsers configures the validator to reject tickets issued for users other than the
+// given ones. An empty list allows all users.
+func WithAllowedUsers(users []string) Option {
+	return func(v *Validator) {
+		v.allowedUsers = users
+	}
+}
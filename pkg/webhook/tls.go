@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateLoader loads a TLS certificate and key from files and reloads them once the files
+// change, so certificates rotated by e.g. cert-manager are picked up without a restart.
+type CertificateLoader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewCertificateLoader returns a new certificate loader for the given files. The certificate is
+// loaded immediately, so invalid files are reported on startup.
+func NewCertificateLoader(certFile, keyFile string) (*CertificateLoader, error) {
+	l := &CertificateLoader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if _, err := l.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// GetCertificate returns the current certificate, reloading it if the files were modified since
+// they were last loaded. It can be used as tls.Config.GetCertificate. If reloading fails, the
+// previously loaded certificate is returned.
+func (l *CertificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	certInfo, err := os.Stat(l.certFile)
+	if err != nil {
+		return l.fallback(fmt.Errorf("failed to stat certificate: %w", err))
+	}
+
+	keyInfo, err := os.Stat(l.keyFile)
+	if err != nil {
+		return l.fallback(fmt.Errorf("failed to stat key: %w", err))
+	}
+
+	if l.certificate != nil && certInfo.ModTime().Equal(l.certModTime) && keyInfo.ModTime().Equal(l.keyModTime) {
+		return l.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return l.fallback(fmt.Errorf("failed to load certificate: %w", err))
+	}
+
+	l.certificate = &certificate
+	l.certModTime = certInfo.ModTime()
+	l.keyModTime = keyInfo.ModTime()
+
+	return l.certificate, nil
+}
+
+// fallback returns the previously loaded certificate, or the given error if there is none
+func (l *CertificateLoader) fallback(err error) (*tls.Certificate, error) {
+	if l.certificate == nil {
+		return nil, err
+	}
+
+	slog.Error("failed to reload certificate, serving the previous one", "error", err)
+
+	return l.certificate, nil
+}
+
+// TLSConfig returns a TLS configuration serving the certificate of the loader, requiring at least
+// TLS 1.2
+func (l *CertificateLoader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: l.GetCertificate,
+	}
+}
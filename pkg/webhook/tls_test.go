@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package webhook_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/webhook"
+)
+
+func TestCertificateLoader(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	// loading fails as long as there are no files
+	_, err := NewCertificateLoader(certFile, keyFile)
+	assert.Error(t, err)
+
+	writeCertificate(t, certFile, keyFile, "first", time.Now().Add(-time.Minute))
+
+	loader, err := NewCertificateLoader(certFile, keyFile)
+	assert.NoError(t, err)
+
+	first, err := loader.TLSConfig().GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, first))
+
+	// rotated certificates are picked up
+	writeCertificate(t, certFile, keyFile, "second", time.Now())
+
+	second, err := loader.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", commonName(t, second))
+
+	// the previous certificate is kept if the files become invalid
+	assert.NoError(t, os.WriteFile(keyFile, []byte("invalid"), 0o600))
+	assert.NoError(t, os.Chtimes(keyFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	current, err := loader.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", commonName(t, current))
+}
+
+// commonName returns the common name of the leaf of the certificate chain
+func commonName(t *testing.T, certificate *tls.Certificate) string {
+	t.Helper()
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	assert.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+// writeCertificate writes a self-signed certificate with the given common name and sets the
+// modification time of the files
+func writeCertificate(t *testing.T, certFile, keyFile, name string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	assert.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	assert.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
@@ -0,0 +1,318 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+// Package webhook implements a validating admission webhook for secrets containing MapR tickets
+// and persistent volumes using them.
+//
+// Secrets containing a ticket are rejected if the ticket can't be parsed, is expired or was issued
+// for a cluster or user that is not allowed. Persistent volumes provisioned by one of the MapR CSI
+// provisioners are rejected if they reference a secret that does not exist or does not contain a
+// ticket. On update, only the tickets whose data changed are validated, so secrets holding an
+// expired ticket can still be modified in other ways. Likewise, persistent volumes are only
+// validated on update if their secret reference changed and they are not being deleted. All other
+// objects and operations are allowed.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/ticket"
+	"github.com/nobbs/kubectl-mapr-ticket/pkg/types"
+
+	admissionV1 "k8s.io/api/admission/v1"
+	coreV1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// maxRequestSize is the maximum size of an admission review request, same as the maximum size
+	// of objects accepted by the API server
+	maxRequestSize = 3 * 1024 * 1024
+)
+
+var (
+	secretResource           = metaV1.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	persistentVolumeResource = metaV1.GroupVersionResource{Version: "v1", Resource: "persistentvolumes"}
+)
+
+// ErrRejected is the error returned if an object is rejected by the validator
+type ErrRejected struct {
+	Reasons []string
+}
+
+// Error returns the reasons of the rejection joined into a single message
+func (err ErrRejected) Error() string {
+	return strings.Join(err.Reasons, "; ")
+}
+
+// Validator validates secrets containing MapR tickets and persistent volumes using them.
+type Validator struct {
+	client kubernetes.Interface
+
+	ticketKey        string
+	detectTicketKeys bool
+	allowedClusters  []string
+	allowedUsers     []string
+}
+
+// NewValidator creates a new validator. It requires a Kubernetes client to look up the secrets
+// referenced by persistent volumes. It also accepts a list of options that can be used to configure
+// the validator.
+func NewValidator(client kubernetes.Interface, opts ...Option) *Validator {
+	v := &Validator{
+		client:    client,
+		ticketKey: ticket.SecretMaprTicketKey,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// ValidateSecret validates the tickets contained in the secret. Secrets not containing a ticket
+// are always valid. It returns an ErrRejected listing all reasons if the secret is rejected.
+func (v *Validator) ValidateSecret(secret *coreV1.Secret) error {
+	return v.validateSecretKeys(secret, ticket.SecretTicketKeys(secret, v.ticketKey, v.detectTicketKeys))
+}
+
+// ValidateSecretUpdate validates the tickets of the updated secret whose data changed compared to
+// the old secret. Unchanged tickets are not validated, so secrets holding an expired ticket can
+// still be labeled, annotated or have their finalizers removed. It returns an ErrRejected listing
+// all reasons if the update is rejected.
+func (v *Validator) ValidateSecretUpdate(oldSecret, secret *coreV1.Secret) error {
+	var changed []string
+
+	for _, key := range ticket.SecretTicketKeys(secret, v.ticketKey, v.detectTicketKeys) {
+		if old, ok := oldSecret.Data[key]; !ok || !bytes.Equal(old, secret.Data[key]) {
+			changed = append(changed, key)
+		}
+	}
+
+	return v.validateSecretKeys(secret, changed)
+}
+
+// validateSecretKeys validates the tickets stored under the given keys of the secret
+func (v *Validator) validateSecretKeys(secret *coreV1.Secret, keys []string) error {
+	var reasons []string
+
+	for _, key := range keys {
+		t, err := ticket.NewMaprTicketFromSecretKey(secret, key)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("MapR ticket stored under key %s can't be parsed: %v", key, err))
+			continue
+		}
+
+		if t.IsExpired() {
+			reasons = append(reasons, fmt.Sprintf("MapR ticket stored under key %s expired at %s", key, t.ExpirationTime().UTC().Format(ticket.DefaultTimeFormat)))
+		}
+
+		if len(v.allowedClusters) > 0 && !slices.Contains(v.allowedClusters, t.GetCluster()) {
+			reasons = append(reasons, fmt.Sprintf("MapR ticket stored under key %s is issued for cluster %q, which is not allowed", key, t.GetCluster()))
+		}
+
+		if len(v.allowedUsers) > 0 && !slices.Contains(v.allowedUsers, t.GetUser()) {
+			reasons = append(reasons, fmt.Sprintf("MapR ticket stored under key %s is issued for user %q, which is not allowed", key, t.GetUser()))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return ErrRejected{Reasons: reasons}
+	}
+
+	return nil
+}
+
+// ValidatePersistentVolume validates that a persistent volume provisioned by one of the MapR CSI
+// provisioners references an existing secret containing a ticket. It returns an ErrRejected if
+// the volume is rejected, or any other error if the secret can't be retrieved.
+func (v *Validator) ValidatePersistentVolume(volume *coreV1.PersistentVolume) error {
+	pv := (*types.PersistentVolume)(volume)
+
+	if !pv.IsMaprCSIBased() || pv.GetSecretName() == "" {
+		return nil
+	}
+
+	namespace, name := pv.GetSecretNamespace(), pv.GetSecretName()
+
+	secret, err := v.client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metaV1.GetOptions{})
+	if apiErrors.IsNotFound(err) {
+		return ErrRejected{Reasons: []string{fmt.Sprintf("referenced secret %s/%s does not exist", namespace, name)}}
+	} else if err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+
+	if len(ticket.SecretTicketKeys(secret, v.ticketKey, v.detectTicketKeys)) == 0 {
+		return ErrRejected{Reasons: []string{fmt.Sprintf("referenced secret %s/%s does not contain a MapR ticket", namespace, name)}}
+	}
+
+	return nil
+}
+
+// ValidatePersistentVolumeUpdate validates the updated persistent volume like
+// ValidatePersistentVolume, but only if its secret reference changed compared to the old volume
+// and it is not being deleted. Otherwise, volumes whose secret was deleted could no longer be
+// labeled or have their finalizers removed, blocking their deletion.
+func (v *Validator) ValidatePersistentVolumeUpdate(oldVolume, volume *coreV1.PersistentVolume) error {
+	oldPV, pv := (*types.PersistentVolume)(oldVolume), (*types.PersistentVolume)(volume)
+
+	if volume.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if oldPV.GetSecretNamespace() == pv.GetSecretNamespace() && oldPV.GetSecretName() == pv.GetSecretName() {
+		return nil
+	}
+
+	return v.ValidatePersistentVolume(volume)
+}
+
+// Review validates the object of the admission request and returns the admission response. Only
+// secrets and persistent volumes are validated on creation and update, everything else is allowed.
+func (v *Validator) Review(request *admissionV1.AdmissionRequest) *admissionV1.AdmissionResponse {
+	response := &admissionV1.AdmissionResponse{
+		UID:     request.UID,
+		Allowed: true,
+	}
+
+	if request.Operation != admissionV1.Create && request.Operation != admissionV1.Update {
+		return response
+	}
+
+	var err error
+
+	switch request.Resource {
+	case secretResource:
+		err = v.reviewSecret(request)
+	case persistentVolumeResource:
+		err = v.reviewPersistentVolume(request)
+	}
+
+	var rejected ErrRejected
+
+	switch {
+	case err == nil:
+		return response
+	case errors.As(err, &rejected):
+		response.Allowed = false
+		response.Result = &metaV1.Status{
+			Status:  metaV1.StatusFailure,
+			Reason:  metaV1.StatusReasonForbidden,
+			Code:    http.StatusForbidden,
+			Message: rejected.Error(),
+		}
+	default:
+		response.Allowed = false
+		response.Result = &metaV1.Status{
+			Status:  metaV1.StatusFailure,
+			Reason:  metaV1.StatusReasonInternalError,
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+
+	slog.Info("rejected object",
+		"kind", request.Kind.Kind,
+		"namespace", request.Namespace,
+		"name", request.Name,
+		"operation", request.Operation,
+		"reason", response.Result.Message,
+	)
+
+	return response
+}
+
+// reviewSecret validates the secret of the admission request. On update, only the tickets that
+// changed compared to the old secret are validated.
+func (v *Validator) reviewSecret(request *admissionV1.AdmissionRequest) error {
+	secret := &coreV1.Secret{}
+	if err := json.Unmarshal(request.Object.Raw, secret); err != nil {
+		return err
+	}
+
+	if request.Operation != admissionV1.Update || len(request.OldObject.Raw) == 0 {
+		return v.ValidateSecret(secret)
+	}
+
+	oldSecret := &coreV1.Secret{}
+	if err := json.Unmarshal(request.OldObject.Raw, oldSecret); err != nil {
+		return err
+	}
+
+	return v.ValidateSecretUpdate(oldSecret, secret)
+}
+
+// reviewPersistentVolume validates the persistent volume of the admission request. On update, the
+// volume is only validated if its secret reference changed and it is not being deleted.
+func (v *Validator) reviewPersistentVolume(request *admissionV1.AdmissionRequest) error {
+	volume := &coreV1.PersistentVolume{}
+	if err := json.Unmarshal(request.Object.Raw, volume); err != nil {
+		return err
+	}
+
+	if request.Operation != admissionV1.Update || len(request.OldObject.Raw) == 0 {
+		return v.ValidatePersistentVolume(volume)
+	}
+
+	oldVolume := &coreV1.PersistentVolume{}
+	if err := json.Unmarshal(request.OldObject.Raw, oldVolume); err != nil {
+		return err
+	}
+
+	return v.ValidatePersistentVolumeUpdate(oldVolume, volume)
+}
+
+// Handler returns the HTTP handler serving admission reviews of the admission.k8s.io/v1 API
+func (v *Validator) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST requests are supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+			http.Error(w, fmt.Sprintf("unsupported content type %q, expected application/json", contentType), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		review := &admissionV1.AdmissionReview{}
+		if err := json.Unmarshal(body, review); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if review.Request == nil {
+			http.Error(w, "admission review does not contain a request", http.StatusBadRequest)
+			return
+		}
+
+		review.Response = v.Review(review.Request)
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			slog.Error("failed to write admission review", "error", err)
+		}
+	})
+}
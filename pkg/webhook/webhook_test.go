@@ -0,0 +1,222 @@
+// Copyright (c) 2024 Alexej Disterhoft
+// Use of this source code is governed by a MIT license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: MIT
+
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/nobbs/kubectl-mapr-ticket/pkg/webhook"
+
+	admissionV1 "k8s.io/api/admission/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// ticket stored in the fixtures for cluster demo.mapr.com and user mapr, expiring in 2100
+const validTicket = "demo.mapr.com HQvWzXnHpDGcBithT6ohjzlVGzHvOUfUC4PK5XNpQ0qi99wP/+GXU6XII5VEK0tQn00HyZCno8YJUrM="
+
+func TestValidator_Handler(t *testing.T) {
+	t.Parallel()
+
+	// objects existing in the cluster, referenced by the persistent volume fixtures
+	objects := []runtime.Object{
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "team-a", Name: "mapr-ticket-secret"},
+			Data:       map[string][]byte{"CONTAINER_TICKET": []byte(validTicket)},
+		},
+		&coreV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{Namespace: "team-a", Name: "app-config"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		},
+	}
+
+	tests := []struct {
+		fixture string
+		opts    []Option
+		allowed bool
+		message string
+	}{
+		{
+			fixture: "secret-create-valid.json",
+			allowed: true,
+		},
+		{
+			fixture: "secret-create-expired.json",
+			message: "MapR ticket stored under key CONTAINER_TICKET expired at 2024-01-01T00:00:00Z",
+		},
+		{
+			fixture: "secret-create-truncated.json",
+			message: "MapR ticket stored under key CONTAINER_TICKET can't be parsed: invalid mapr ticket: illegal base64 data at input byte 32",
+		},
+		{
+			fixture: "secret-create-other-cluster.json",
+			allowed: true,
+		},
+		{
+			fixture: "secret-create-other-cluster.json",
+			opts:    []Option{WithAllowedClusters([]string{"demo.mapr.com"})},
+			message: `MapR ticket stored under key CONTAINER_TICKET is issued for cluster "other.mapr.com", which is not allowed`,
+		},
+		{
+			fixture: "secret-create-valid.json",
+			opts:    []Option{WithAllowedClusters([]string{"demo.mapr.com"}), WithAllowedUsers([]string{"other"})},
+			message: `MapR ticket stored under key CONTAINER_TICKET is issued for user "mapr", which is not allowed`,
+		},
+		{
+			fixture: "secret-create-valid.json",
+			opts:    []Option{WithTicketKey("TICKET")},
+			allowed: true,
+		},
+		{
+			fixture: "secret-create-without-ticket.json",
+			allowed: true,
+		},
+		{
+			fixture: "secret-update-expired.json",
+			message: "MapR ticket stored under key CONTAINER_TICKET expired at 2024-01-01T00:00:00Z",
+		},
+		{
+			fixture: "secret-update-metadata-expired.json",
+			allowed: true,
+		},
+		{
+			fixture: "secret-delete-expired.json",
+			allowed: true,
+		},
+		{
+			fixture: "pv-create-existing-secret.json",
+			allowed: true,
+		},
+		{
+			fixture: "pv-create-missing-secret.json",
+			message: "referenced secret team-a/missing-secret does not exist",
+		},
+		{
+			fixture: "pv-create-secret-without-ticket.json",
+			message: "referenced secret team-a/app-config does not contain a MapR ticket",
+		},
+		{
+			fixture: "pv-create-other-driver.json",
+			allowed: true,
+		},
+		{
+			fixture: "persistentvolume-update-finalizer-missing-secret.json",
+			allowed: true,
+		},
+		{
+			fixture: "persistentvolume-update-labels-missing-secret.json",
+			allowed: true,
+		},
+		{
+			fixture: "persistentvolume-update-secret-changed.json",
+			message: "referenced secret team-a/missing-secret does not exist",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			body, err := os.ReadFile(filepath.Join("testdata", test.fixture))
+			assert.NoError(t, err)
+
+			var request admissionV1.AdmissionReview
+			assert.NoError(t, json.Unmarshal(body, &request))
+
+			validator := NewValidator(fake.NewSimpleClientset(objects...), test.opts...)
+
+			req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			validator.Handler().ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+
+			var review admissionV1.AdmissionReview
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &review))
+
+			assert.Equal(t, "admission.k8s.io/v1", review.APIVersion)
+			assert.Equal(t, "AdmissionReview", review.Kind)
+			assert.Nil(t, review.Request)
+			assert.Equal(t, request.Request.UID, review.Response.UID)
+			assert.Equal(t, test.allowed, review.Response.Allowed)
+
+			if test.allowed {
+				assert.Nil(t, review.Response.Result)
+			} else {
+				assert.Equal(t, int32(http.StatusForbidden), review.Response.Result.Code)
+				assert.Equal(t, test.message, review.Response.Result.Message)
+			}
+		})
+	}
+}
+
+func TestValidator_Handler_InvalidRequests(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{
+			name:        "wrong method",
+			method:      http.MethodGet,
+			contentType: "application/json",
+			code:        http.StatusMethodNotAllowed,
+		},
+		{
+			name:        "wrong content type",
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        "{}",
+			code:        http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "invalid json",
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        "{",
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "missing request",
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`,
+			code:        http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(test.method, "/validate", bytes.NewBufferString(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+
+			recorder := httptest.NewRecorder()
+			NewValidator(fake.NewSimpleClientset()).Handler().ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.code, recorder.Code)
+		})
+	}
+}